@@ -81,6 +81,10 @@ const (
 	AggregateStoreOpLoad = "load"
 	// Errors during saving of aggregates.
 	AggregateStoreOpSave = "save"
+	// Errors during rebuilding of snapshots.
+	AggregateStoreOpRebuildSnapshot = "rebuild_snapshot"
+	// Errors during taking of snapshots in the background.
+	AggregateStoreOpSnapshot = "snapshot"
 )
 
 // AggregateStoreError contains related info about errors in the store.
@@ -207,5 +211,18 @@ func CreateAggregate(aggregateType AggregateType, id uuid.UUID) (Aggregate, erro
 	return nil, ErrAggregateNotRegistered
 }
 
+// RegisteredAggregates returns the factories of all registered aggregate types.
+func RegisteredAggregates() map[AggregateType]func(uuid.UUID) Aggregate {
+	aggregatesMu.RLock()
+	defer aggregatesMu.RUnlock()
+
+	mapCopy := make(map[AggregateType]func(uuid.UUID) Aggregate, len(aggregates))
+	for key, val := range aggregates {
+		mapCopy[key] = val
+	}
+
+	return mapCopy
+}
+
 var aggregates = make(map[AggregateType]func(uuid.UUID) Aggregate)
 var aggregatesMu sync.RWMutex
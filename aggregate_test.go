@@ -46,6 +46,10 @@ func TestCreateAggregate(t *testing.T) {
 	if aggregate.EntityID() != id {
 		t.Error("the ID should be correct:", aggregate.EntityID())
 	}
+
+	if _, ok := RegisteredAggregates()[TestAggregateRegisterType]; !ok {
+		t.Error("the aggregate should be listed as registered")
+	}
 }
 
 func TestRegisterAggregateEmptyName(t *testing.T) {
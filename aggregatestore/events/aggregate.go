@@ -44,3 +44,12 @@ type VersionedAggregate interface {
 	// IncrementVersion.
 	ApplyEvent(context.Context, eh.Event) error
 }
+
+// ValidatedAggregate is an optional interface for aggregates that validate the
+// data of their appended events, which is implemented by AggregateBase. The
+// AggregateStore does not save the events of an aggregate with an error.
+type ValidatedAggregate interface {
+	// UncommittedEventsError returns the error of the first invalid
+	// uncommitted event, if any.
+	UncommittedEventsError() error
+}
@@ -64,6 +64,7 @@ type AggregateBase struct {
 	t      eh.AggregateType
 	v      int
 	events []eh.Event
+	err    error
 }
 
 // NewAggregateBase creates an aggregate.
@@ -104,10 +105,23 @@ func (a *AggregateBase) UncommittedEvents() []eh.Event {
 // interface.
 func (a *AggregateBase) ClearUncommittedEvents() {
 	a.events = nil
+	a.err = nil
 }
 
-// AppendEvent appends an event for later retrieval by Events().
+// UncommittedEventsError returns the validation error of the first appended
+// event with invalid data since the uncommitted events were last cleared. The
+// AggregateStore refuses to save the events of an aggregate with an error.
+func (a *AggregateBase) UncommittedEventsError() error {
+	return a.err
+}
+
+// AppendEvent appends an event for later retrieval by Events(). The data of
+// the event is validated with eh.ValidateEventData, see UncommittedEventsError.
 func (a *AggregateBase) AppendEvent(t eh.EventType, data eh.EventData, timestamp time.Time, options ...eh.EventOption) eh.Event {
+	if err := eh.ValidateEventData(t, data); err != nil && a.err == nil {
+		a.err = err
+	}
+
 	options = append(options, eh.ForAggregate(
 		a.AggregateType(),
 		a.EntityID(),
@@ -16,11 +16,13 @@ package events
 
 import (
 	"context"
+	"errors"
 	"reflect"
 	"testing"
 	"time"
 
 	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/eventstore/memory"
 	"github.com/Clarilab/eventhorizon/uuid"
 )
 
@@ -194,3 +196,53 @@ func (a *TestAggregate) ApplyEvent(ctx context.Context, event eh.Event) error {
 
 	return nil
 }
+
+func TestAggregateEvents_Validation(t *testing.T) {
+	agg := NewTestAggregate(uuid.New())
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	agg.AppendEvent(TestAggregateEventType, &TestEventData{"event1"}, timestamp)
+
+	if err := agg.UncommittedEventsError(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	agg.AppendEvent(TestAggregateEventType, &TestEventData{}, timestamp)
+	agg.AppendEvent(TestAggregateEventType, &TestEventData{"event3"}, timestamp)
+
+	dataErr := &eh.EventDataError{}
+	if err := agg.UncommittedEventsError(); !errors.As(err, &dataErr) {
+		t.Fatal("there should be an event data error:", err)
+	}
+
+	if !reflect.DeepEqual(dataErr.Fields, []string{"Content"}) {
+		t.Error("the invalid fields should be listed:", dataErr.Fields)
+	}
+
+	// The invalid events should not be saved.
+	eventStore, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	store, err := NewAggregateStore(eventStore)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+
+	if err := store.Save(ctx, agg); !errors.As(err, &dataErr) {
+		t.Error("there should be an event data error:", err)
+	}
+
+	if _, err := eventStore.Load(ctx, agg.EntityID()); !errors.Is(err, eh.ErrAggregateNotFound) {
+		t.Error("no events should be saved:", err)
+	}
+
+	agg.ClearUncommittedEvents()
+
+	if err := agg.UncommittedEventsError(); err != nil {
+		t.Error("the error should be cleared:", err)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	eh "github.com/Clarilab/eventhorizon"
@@ -28,10 +29,21 @@ import (
 // uses an event store for loading and saving events used to build the aggregate
 // and an event handler to handle resulting events.
 type AggregateStore struct {
-	store            eh.EventStore
-	snapshotStore    eh.SnapshotStore
-	isSnapshotStore  bool
-	snapshotStrategy eh.SnapshotStrategy
+	store                eh.EventStore
+	snapshotStore        eh.SnapshotStore
+	isSnapshotStore      bool
+	snapshotHistoryStore eh.SnapshotHistoryStore
+	snapshotStrategy     eh.SnapshotStrategy
+	rebuildSnapshots     bool
+	rebuilding           sync.Map
+
+	// Asynchronous snapshots, see WithAsyncSnapshots.
+	snapshotWorkers   int
+	snapshotQueue     chan snapshotJob
+	snapshotQueueMu   sync.RWMutex
+	snapshotQueueDone bool
+	snapshotWg        sync.WaitGroup
+	errCh             chan error
 }
 
 var (
@@ -41,6 +53,24 @@ var (
 	ErrAggregateNotVersioned = errors.New("aggregate is not versioned")
 	// ErrMismatchedEventType occurs when loaded events from ID does not match aggregate type.
 	ErrMismatchedEventType = errors.New("mismatched event type and aggregate type")
+	// ErrAggregateNotSnapshotable is when rebuilding a snapshot of an aggregate
+	// that does not implement the Snapshotable interface.
+	ErrAggregateNotSnapshotable = errors.New("aggregate is not snapshotable")
+	// ErrSnapshotsNotSupported is when rebuilding a snapshot with an event
+	// store that does not implement the SnapshotStore interface.
+	ErrSnapshotsNotSupported = eh.ErrSnapshotsNotSupported
+	// ErrInvalidAggregateVersion is when loading an aggregate at a version below 1.
+	ErrInvalidAggregateVersion = errors.New("invalid aggregate version")
+	// ErrSnapshotQueueFull is when a snapshot is skipped because the queue of
+	// asynchronous snapshots is full.
+	ErrSnapshotQueueFull = errors.New("snapshot queue is full")
+	// ErrMultiStreamSaveNotSupported is when saving multiple aggregates with an
+	// event store that does not implement the MultiStreamEventStore interface.
+	ErrMultiStreamSaveNotSupported = errors.New("event store does not support saving multiple aggregates")
+	// ErrMissingEvents is when the loaded events of an aggregate do not continue
+	// from the version to load from, for example when replaying an aggregate
+	// from the start after its events have been truncated.
+	ErrMissingEvents = errors.New("missing events of aggregate")
 )
 
 // NewAggregateStore creates an aggregate store with an event store and an event
@@ -53,6 +83,7 @@ func NewAggregateStore(store eh.EventStore, options ...Option) (*AggregateStore,
 
 	d := &AggregateStore{
 		store: store,
+		errCh: make(chan error, 100),
 	}
 
 	d.snapshotStrategy = &NoSnapshotStrategy{}
@@ -63,7 +94,12 @@ func NewAggregateStore(store eh.EventStore, options ...Option) (*AggregateStore,
 		}
 	}
 
-	d.snapshotStore, d.isSnapshotStore = store.(eh.SnapshotStore)
+	d.snapshotStore, d.isSnapshotStore = eh.FindEventStore[eh.SnapshotStore](store)
+	d.snapshotHistoryStore, _ = eh.FindEventStore[eh.SnapshotHistoryStore](store)
+
+	if d.snapshotQueue != nil {
+		d.startSnapshotWorkers()
+	}
 
 	return d, nil
 }
@@ -80,11 +116,67 @@ func WithSnapshotStrategy(s eh.SnapshotStrategy) Option {
 	}
 }
 
+// WithSnapshotRebuild rebuilds snapshots with an outdated schema version in the
+// background when loading aggregates, see RebuildSnapshot. Without it outdated
+// snapshots are ignored until a new snapshot is taken by the snapshot strategy.
+func WithSnapshotRebuild() Option {
+	return func(as *AggregateStore) error {
+		as.rebuildSnapshots = true
+
+		return nil
+	}
+}
+
+// WithAsyncSnapshots takes snapshots in the background instead of when saving
+// an aggregate. The snapshot decisions are queued, with room for queueSize
+// snapshots, and handled by the number of workers which reload the aggregate
+// to snapshot it. Snapshots are skipped when the queue is full. Errors are
+// sent on the Errors channel and Close waits for queued snapshots.
+func WithAsyncSnapshots(workers, queueSize int) Option {
+	return func(as *AggregateStore) error {
+		if workers < 1 {
+			return fmt.Errorf("invalid number of snapshot workers: %d", workers)
+		}
+
+		if queueSize < 1 {
+			return fmt.Errorf("invalid snapshot queue size: %d", queueSize)
+		}
+
+		as.snapshotWorkers = workers
+		as.snapshotQueue = make(chan snapshotJob, queueSize)
+
+		return nil
+	}
+}
+
 // Load implements the Load method of the eventhorizon.AggregateStore interface.
 // It loads an aggregate from the event store by creating a new aggregate of the
 // type with the ID and then applies all events to it, thus making it the most
 // current version of the aggregate.
 func (r *AggregateStore) Load(ctx context.Context, aggregateType eh.AggregateType, id uuid.UUID) (eh.Aggregate, error) {
+	return r.load(ctx, aggregateType, id, 0)
+}
+
+// LoadAt loads an aggregate as it was at a version, by applying the events up
+// to and including the version. If the event store is an
+// eventhorizon.SnapshotHistoryStore the latest snapshot at or before the
+// version is used, otherwise the latest snapshot is only used if it is not
+// newer than the version.
+func (r *AggregateStore) LoadAt(ctx context.Context, aggregateType eh.AggregateType, id uuid.UUID, version int) (eh.Aggregate, error) {
+	if version < 1 {
+		return nil, &eh.AggregateStoreError{
+			Err:           ErrInvalidAggregateVersion,
+			Op:            eh.AggregateStoreOpLoad,
+			AggregateType: aggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	return r.load(ctx, aggregateType, id, version)
+}
+
+// load loads an aggregate up to the max version, or the latest version if 0.
+func (r *AggregateStore) load(ctx context.Context, aggregateType eh.AggregateType, id uuid.UUID, maxVersion int) (eh.Aggregate, error) {
 	agg, err := eh.CreateAggregate(aggregateType, id)
 	if err != nil {
 		return nil, &eh.AggregateStoreError{
@@ -105,10 +197,34 @@ func (r *AggregateStore) Load(ctx context.Context, aggregateType eh.AggregateTyp
 		}
 	}
 
+	start := time.Now()
 	fromVersion := 1
 
 	if sa, ok := a.(eh.Snapshotable); ok && r.isSnapshotStore {
-		snapshot, err := r.snapshotStore.LoadSnapshot(ctx, id)
+		var snapshot *eh.Snapshot
+		if maxVersion > 0 && r.snapshotHistoryStore != nil {
+			snapshot, err = r.snapshotHistoryStore.LoadSnapshotAt(ctx, id, maxVersion)
+		} else {
+			snapshot, err = r.snapshotStore.LoadSnapshot(ctx, id)
+		}
+
+		// Snapshots with an outdated schema version are ignored and the
+		// aggregate is loaded by replaying all events instead, which fails
+		// with ErrMissingEvents if the events have been truncated.
+		if errors.Is(err, eh.ErrSnapshotSchemaMismatch) {
+			snapshot, err = nil, nil
+
+			if r.rebuildSnapshots {
+				r.rebuildSnapshotInBackground(ctx, aggregateType, id)
+			}
+		}
+
+		// Wrapper stores refuse snapshots if their inner store doesn't
+		// support them, the events are replayed instead.
+		if errors.Is(err, eh.ErrSnapshotsNotSupported) {
+			snapshot, err = nil, nil
+		}
+
 		if err != nil {
 			return nil, &eh.AggregateStoreError{
 				Err:           err,
@@ -118,14 +234,15 @@ func (r *AggregateStore) Load(ctx context.Context, aggregateType eh.AggregateTyp
 			}
 		}
 
-		if snapshot != nil {
+		if snapshot != nil && (maxVersion == 0 || snapshot.Version <= maxVersion) {
 			sa.ApplySnapshot(snapshot)
+			a.SetAggregateVersion(snapshot.Version)
 			fromVersion = snapshot.Version + 1
 		}
 	}
 
-	events, err := r.store.LoadFrom(ctx, a.EntityID(), fromVersion)
-	if err != nil && !errors.Is(err, eh.ErrAggregateNotFound) {
+	applied, err := r.loadEvents(ctx, a, fromVersion, maxVersion)
+	if err != nil {
 		return nil, &eh.AggregateStoreError{
 			Err:           err,
 			Op:            eh.AggregateStoreOpLoad,
@@ -134,13 +251,10 @@ func (r *AggregateStore) Load(ctx context.Context, aggregateType eh.AggregateTyp
 		}
 	}
 
-	if err := r.applyEvents(ctx, a, events); err != nil {
-		return nil, &eh.AggregateStoreError{
-			Err:           err,
-			Op:            eh.AggregateStoreOpLoad,
-			AggregateType: aggregateType,
-			AggregateID:   id,
-		}
+	// Only loading of the latest version is measured, which is what the next
+	// snapshot decision is based on.
+	if o, ok := r.snapshotStrategy.(ReplayObserver); ok && maxVersion == 0 {
+		o.ObserveReplay(aggregateType, id, applied, time.Since(start))
 	}
 
 	return a, nil
@@ -165,6 +279,15 @@ func (r *AggregateStore) Save(ctx context.Context, agg eh.Aggregate) error {
 		return nil
 	}
 
+	if err := uncommittedEventsError(a); err != nil {
+		return &eh.AggregateStoreError{
+			Err:           err,
+			Op:            eh.AggregateStoreOpSave,
+			AggregateType: agg.AggregateType(),
+			AggregateID:   agg.EntityID(),
+		}
+	}
+
 	if err := r.store.Save(ctx, events, a.AggregateVersion()); err != nil {
 		return &eh.AggregateStoreError{
 			Err:           err,
@@ -190,13 +313,110 @@ func (r *AggregateStore) Save(ctx context.Context, agg eh.Aggregate) error {
 	return r.takeSnapshot(ctx, agg, events[len(events)-1])
 }
 
+// SaveMany saves the uncommitted events of multiple aggregates as one unit of
+// work, either all or none of the events are saved. The event store must
+// implement the eventhorizon.MultiStreamEventStore interface.
+func (r *AggregateStore) SaveMany(ctx context.Context, aggs []eh.Aggregate) error {
+	store, ok := eh.FindEventStore[eh.MultiStreamEventStore](r.store)
+	if !ok {
+		return &eh.AggregateStoreError{
+			Err: ErrMultiStreamSaveNotSupported,
+			Op:  eh.AggregateStoreOpSave,
+		}
+	}
+
+	versioned := make([]VersionedAggregate, 0, len(aggs))
+	streams := make([]eh.EventStreamSave, 0, len(aggs))
+
+	for _, agg := range aggs {
+		a, ok := agg.(VersionedAggregate)
+		if !ok {
+			return &eh.AggregateStoreError{
+				Err:           ErrAggregateNotVersioned,
+				Op:            eh.AggregateStoreOpSave,
+				AggregateType: agg.AggregateType(),
+				AggregateID:   agg.EntityID(),
+			}
+		}
+
+		// Retrieve any new events to store.
+		events := a.UncommittedEvents()
+		if len(events) == 0 {
+			continue
+		}
+
+		if err := uncommittedEventsError(a); err != nil {
+			return &eh.AggregateStoreError{
+				Err:           err,
+				Op:            eh.AggregateStoreOpSave,
+				AggregateType: agg.AggregateType(),
+				AggregateID:   agg.EntityID(),
+			}
+		}
+
+		versioned = append(versioned, a)
+		streams = append(streams, eh.EventStreamSave{
+			Events:          events,
+			OriginalVersion: a.AggregateVersion(),
+		})
+	}
+
+	if len(streams) == 0 {
+		return nil
+	}
+
+	if err := store.SaveMany(ctx, streams); err != nil {
+		storeErr := &eh.AggregateStoreError{
+			Err: err,
+			Op:  eh.AggregateStoreOpSave,
+		}
+
+		var esErr *eh.EventStoreError
+		if errors.As(err, &esErr) {
+			storeErr.AggregateType = esErr.AggregateType
+			storeErr.AggregateID = esErr.AggregateID
+		}
+
+		return storeErr
+	}
+
+	for i, a := range versioned {
+		events := streams[i].Events
+
+		a.ClearUncommittedEvents()
+
+		// Apply the events in case the aggregate needs to be further used
+		// after this save.
+		if err := r.applyEvents(ctx, a, events); err != nil {
+			return &eh.AggregateStoreError{
+				Err:           err,
+				Op:            eh.AggregateStoreOpSave,
+				AggregateType: a.AggregateType(),
+				AggregateID:   a.EntityID(),
+			}
+		}
+
+		if err := r.takeSnapshot(ctx, a, events[len(events)-1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (r *AggregateStore) takeSnapshot(ctx context.Context, agg eh.Aggregate, lastEvent eh.Event) error {
 	a, ok := agg.(eh.Snapshotable)
 	if !ok || !r.isSnapshotStore {
 		return nil
 	}
 
-	s, err := r.snapshotStore.LoadSnapshot(ctx, agg.EntityID())
+	if r.snapshotQueue != nil {
+		r.queueSnapshot(ctx, agg.AggregateType(), agg.EntityID(), lastEvent)
+
+		return nil
+	}
+
+	take, err := r.shouldTakeSnapshot(ctx, agg.EntityID(), lastEvent)
 	if err != nil {
 		return &eh.AggregateStoreError{
 			Err:           err,
@@ -206,6 +426,38 @@ func (r *AggregateStore) takeSnapshot(ctx context.Context, agg eh.Aggregate, las
 		}
 	}
 
+	if take {
+		if err = r.snapshotStore.SaveSnapshot(ctx, agg.EntityID(), *a.CreateSnapshot()); err != nil {
+			return &eh.AggregateStoreError{
+				Err:           err,
+				Op:            eh.AggregateStoreOpSave,
+				AggregateType: agg.AggregateType(),
+				AggregateID:   agg.EntityID(),
+			}
+		}
+	}
+
+	return nil
+}
+
+// shouldTakeSnapshot asks the snapshot strategy if a snapshot should be taken
+// after the last event, based on the current snapshot.
+func (r *AggregateStore) shouldTakeSnapshot(ctx context.Context, id uuid.UUID, lastEvent eh.Event) (bool, error) {
+	// An outdated snapshot is handled as if there is no snapshot.
+	s, err := r.snapshotStore.LoadSnapshot(ctx, id)
+	if errors.Is(err, eh.ErrSnapshotSchemaMismatch) {
+		s, err = nil, nil
+	}
+
+	// No snapshots are taken if the store refuses them.
+	if errors.Is(err, eh.ErrSnapshotsNotSupported) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
 	version := 0
 	timestamp := time.Now()
 
@@ -214,15 +466,193 @@ func (r *AggregateStore) takeSnapshot(ctx context.Context, agg eh.Aggregate, las
 		timestamp = s.Timestamp
 	}
 
-	if res := r.snapshotStrategy.ShouldTakeSnapshot(version, timestamp, lastEvent); res {
-		if err = r.snapshotStore.SaveSnapshot(ctx, agg.EntityID(), *a.CreateSnapshot()); err != nil {
-			return &eh.AggregateStoreError{
-				Err:           err,
-				Op:            eh.AggregateStoreOpSave,
-				AggregateType: agg.AggregateType(),
-				AggregateID:   agg.EntityID(),
+	return r.snapshotStrategy.ShouldTakeSnapshot(version, timestamp, lastEvent), nil
+}
+
+// RebuildSnapshot is a maintenance command that takes a new snapshot of an
+// aggregate by replaying all of its events, for example to replace a snapshot
+// with an outdated schema version. Nothing is done for aggregates without events.
+func (r *AggregateStore) RebuildSnapshot(ctx context.Context, aggregateType eh.AggregateType, id uuid.UUID) error {
+	if !r.isSnapshotStore {
+		return &eh.AggregateStoreError{
+			Err:           ErrSnapshotsNotSupported,
+			Op:            eh.AggregateStoreOpRebuildSnapshot,
+			AggregateType: aggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	agg, err := eh.CreateAggregate(aggregateType, id)
+	if err != nil {
+		return &eh.AggregateStoreError{
+			Err:           err,
+			Op:            eh.AggregateStoreOpRebuildSnapshot,
+			AggregateType: aggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	a, ok := agg.(VersionedAggregate)
+	if !ok {
+		return &eh.AggregateStoreError{
+			Err:           ErrAggregateNotVersioned,
+			Op:            eh.AggregateStoreOpRebuildSnapshot,
+			AggregateType: aggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	sa, ok := agg.(eh.Snapshotable)
+	if !ok {
+		return &eh.AggregateStoreError{
+			Err:           ErrAggregateNotSnapshotable,
+			Op:            eh.AggregateStoreOpRebuildSnapshot,
+			AggregateType: aggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	applied, err := r.loadEvents(ctx, a, 1, 0)
+	if err != nil {
+		return &eh.AggregateStoreError{
+			Err:           err,
+			Op:            eh.AggregateStoreOpRebuildSnapshot,
+			AggregateType: aggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	if applied == 0 {
+		return nil
+	}
+
+	if err := r.snapshotStore.SaveSnapshot(ctx, id, *sa.CreateSnapshot()); err != nil {
+		return &eh.AggregateStoreError{
+			Err:           err,
+			Op:            eh.AggregateStoreOpRebuildSnapshot,
+			AggregateType: aggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	return nil
+}
+
+// rebuildSnapshotInBackground rebuilds a snapshot in a goroutine, unless it is
+// already being rebuilt or the store is closed. Errors are sent on the error
+// channel, see Errors.
+func (r *AggregateStore) rebuildSnapshotInBackground(ctx context.Context, aggregateType eh.AggregateType, id uuid.UUID) {
+	r.snapshotQueueMu.RLock()
+	defer r.snapshotQueueMu.RUnlock()
+
+	if r.snapshotQueueDone {
+		return
+	}
+
+	if _, ok := r.rebuilding.LoadOrStore(id, struct{}{}); ok {
+		return
+	}
+
+	// Keep the values of the context (like the namespace) but not the cancellation.
+	ctx = context.WithoutCancel(ctx)
+
+	// Let Close wait for the rebuild.
+	r.snapshotWg.Add(1)
+
+	go func() {
+		defer r.snapshotWg.Done()
+		defer r.rebuilding.Delete(id)
+
+		if err := r.RebuildSnapshot(ctx, aggregateType, id); err != nil {
+			r.sendError(err)
+		}
+	}()
+}
+
+// loadEvents applies the events from the version up to the max version, or all
+// events if 0, to the aggregate. With an eventhorizon.StreamingEventStore the
+// events are applied as they are loaded, instead of loading all events first.
+// Returns the number of applied events.
+func (r *AggregateStore) loadEvents(ctx context.Context, a VersionedAggregate, fromVersion, maxVersion int) (int, error) {
+	streamingStore, ok := eh.FindEventStore[eh.StreamingEventStore](r.store)
+	if !ok {
+		events, err := r.store.LoadFrom(ctx, a.EntityID(), fromVersion)
+		if err != nil && !errors.Is(err, eh.ErrAggregateNotFound) {
+			return 0, err
+		}
+
+		// Only apply the events up to the max version.
+		if maxVersion > 0 {
+			for i, e := range events {
+				if e.Version() > maxVersion {
+					events = events[:i]
+
+					break
+				}
+			}
+		}
+
+		for i, e := range events {
+			if err := r.applyLoadedEvent(ctx, a, e, fromVersion+i); err != nil {
+				return i, err
 			}
 		}
+
+		return len(events), nil
+	}
+
+	iter, err := streamingStore.LoadFromIter(ctx, a.EntityID(), fromVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	var applied int
+
+	for iter.Next(ctx) {
+		event, ok := iter.Value().(eh.Event)
+		if !ok {
+			iter.Close(ctx)
+
+			return applied, fmt.Errorf("loaded value is of incorrect type %T", iter.Value())
+		}
+
+		// Only apply the events up to the max version.
+		if maxVersion > 0 && event.Version() > maxVersion {
+			break
+		}
+
+		if err := r.applyLoadedEvent(ctx, a, event, fromVersion+applied); err != nil {
+			iter.Close(ctx)
+
+			return applied, err
+		}
+
+		applied++
+	}
+
+	if err := iter.Close(ctx); err != nil {
+		return applied, err
+	}
+
+	return applied, nil
+}
+
+// applyLoadedEvent applies a loaded event which should have the expected
+// version, to not silently load an aggregate with missing events.
+func (r *AggregateStore) applyLoadedEvent(ctx context.Context, a VersionedAggregate, event eh.Event, version int) error {
+	// Events of other aggregate types are rejected when applying.
+	if event.AggregateType() == a.AggregateType() && event.Version() != version {
+		return fmt.Errorf("%w: expected version %d, got %d", ErrMissingEvents, version, event.Version())
+	}
+
+	return r.applyEvent(ctx, a, event)
+}
+
+// uncommittedEventsError returns the validation error of the uncommitted
+// events of the aggregate, see ValidatedAggregate.
+func uncommittedEventsError(a VersionedAggregate) error {
+	if va, ok := a.(ValidatedAggregate); ok {
+		return va.UncommittedEventsError()
 	}
 
 	return nil
@@ -230,16 +660,24 @@ func (r *AggregateStore) takeSnapshot(ctx context.Context, agg eh.Aggregate, las
 
 func (r *AggregateStore) applyEvents(ctx context.Context, a VersionedAggregate, events []eh.Event) error {
 	for _, event := range events {
-		if event.AggregateType() != a.AggregateType() {
-			return ErrMismatchedEventType
+		if err := r.applyEvent(ctx, a, event); err != nil {
+			return err
 		}
+	}
 
-		if err := a.ApplyEvent(ctx, event); err != nil {
-			return fmt.Errorf("could not apply event %s: %w", event, err)
-		}
+	return nil
+}
 
-		a.SetAggregateVersion(event.Version())
+func (r *AggregateStore) applyEvent(ctx context.Context, a VersionedAggregate, event eh.Event) error {
+	if event.AggregateType() != a.AggregateType() {
+		return ErrMismatchedEventType
 	}
 
+	if err := a.ApplyEvent(ctx, event); err != nil {
+		return fmt.Errorf("could not apply event %s: %w", event, err)
+	}
+
+	a.SetAggregateVersion(event.Version())
+
 	return nil
 }
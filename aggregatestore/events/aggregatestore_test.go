@@ -23,6 +23,7 @@ import (
 	"time"
 
 	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/eventstore/memory"
 	"github.com/Clarilab/eventhorizon/mocks"
 	"github.com/Clarilab/eventhorizon/uuid"
 	"github.com/stretchr/testify/assert"
@@ -255,6 +256,105 @@ func TestAggregateStore_SaveEvents(t *testing.T) {
 	agg.err = nil
 }
 
+func TestAggregateStore_SaveMany(t *testing.T) {
+	eventStore, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	store, err := NewAggregateStore(eventStore)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	agg1 := NewTestAggregateOther(uuid.New())
+	agg2 := NewTestAggregateOther(uuid.New())
+	agg3 := NewTestAggregateOther(uuid.New())
+
+	agg1.AppendEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp)
+	agg2.AppendEvent(mocks.EventType, &mocks.EventData{Content: "event2"}, timestamp)
+	agg2.AppendEvent(mocks.EventType, &mocks.EventData{Content: "event3"}, timestamp)
+
+	// Aggregates without events are skipped.
+	if err := store.SaveMany(ctx, []eh.Aggregate{agg1, agg2, agg3}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	for _, agg := range []*TestAggregateOther{agg1, agg2} {
+		if len(agg.UncommittedEvents()) != 0 {
+			t.Error("there should be no uncommitted events:", agg.UncommittedEvents())
+		}
+
+		events, err := eventStore.Load(ctx, agg.EntityID())
+		if err != nil {
+			t.Error("there should be no error:", err)
+		}
+
+		if len(events) != agg.AggregateVersion() {
+			t.Error("the events should be stored:", len(events), agg.AggregateVersion())
+		}
+	}
+
+	if agg2.AggregateVersion() != 2 {
+		t.Error("the aggregate version should be 2:", agg2.AggregateVersion())
+	}
+
+	// Conflict for one aggregate, nothing should be saved.
+	agg1.AppendEvent(mocks.EventType, &mocks.EventData{Content: "event4"}, timestamp)
+	agg2.AppendEvent(mocks.EventType, &mocks.EventData{Content: "event5"}, timestamp)
+
+	other, err := store.Load(ctx, agg2.AggregateType(), agg2.EntityID())
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	otherAgg, ok := other.(*TestAggregateOther)
+	if !ok {
+		t.Fatal("wrong aggregate type")
+	}
+
+	otherAgg.AppendEvent(mocks.EventType, &mocks.EventData{Content: "other"}, timestamp)
+
+	if err := store.Save(ctx, otherAgg); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	aggStoreErr := &eh.AggregateStoreError{}
+
+	err = store.SaveMany(ctx, []eh.Aggregate{agg1, agg2})
+	if !errors.As(err, &aggStoreErr) || !errors.Is(err, eh.ErrEventConflictFromOtherSave) {
+		t.Error("there should be a conflict error:", err)
+	}
+
+	if aggStoreErr.AggregateID != agg2.EntityID() {
+		t.Error("the error should be for the conflicting aggregate:", aggStoreErr.AggregateID)
+	}
+
+	events, err := eventStore.Load(ctx, agg1.EntityID())
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(events) != 1 {
+		t.Error("there should be no new events saved:", len(events))
+	}
+
+	if len(agg1.UncommittedEvents()) != 1 {
+		t.Error("the events should still be uncommitted:", agg1.UncommittedEvents())
+	}
+
+	// Event store without support for saving multiple aggregates.
+	store, _ = createStore(t)
+
+	err = store.SaveMany(ctx, []eh.Aggregate{agg1})
+	if !errors.Is(err, ErrMultiStreamSaveNotSupported) {
+		t.Error("there should be a ErrMultiStreamSaveNotSupported error:", err)
+	}
+}
+
 func TestAggregateStore_TakeSnapshot(t *testing.T) {
 	eventStore := &mocks.EventStore{
 		Events: make([]eh.Event, 0),
@@ -295,6 +395,559 @@ func TestAggregateStore_TakeSnapshot(t *testing.T) {
 	assert.Equal(t, 1, a.appliedEvents)
 }
 
+func TestAggregateStore_SnapshotsNotSupported(t *testing.T) {
+	eventStore := &refusingSnapshotStore{&mocks.EventStore{
+		Events: make([]eh.Event, 0),
+	}}
+
+	store, err := NewAggregateStore(eventStore, WithSnapshotStrategy(NewEveryNumberEventSnapshotStrategy(2)))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+
+	id := uuid.New()
+	agg := NewTestAggregateOther(id)
+
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		agg.AppendEvent(mocks.EventType, &mocks.EventData{Content: fmt.Sprintf("event%d", i)}, timestamp)
+
+		if err := store.Save(ctx, agg); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+
+	agg2, err := store.Load(ctx, agg.AggregateType(), agg.EntityID())
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// All events should be replayed.
+	assert.Equal(t, 3, agg2.(*TestAggregateOther).appliedEvents)
+}
+
+// refusingSnapshotStore is an event store that refuses snapshots, like a
+// wrapper store does when the wrapped store does not support them.
+type refusingSnapshotStore struct {
+	*mocks.EventStore
+}
+
+func (s *refusingSnapshotStore) LoadSnapshot(ctx context.Context, id uuid.UUID) (*eh.Snapshot, error) {
+	return nil, eh.ErrSnapshotsNotSupported
+}
+
+func (s *refusingSnapshotStore) SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot eh.Snapshot) error {
+	return eh.ErrSnapshotsNotSupported
+}
+
+func TestAggregateStore_LoadAt(t *testing.T) {
+	eventStore, err := memory.NewEventStore(memory.WithSnapshotRetention(0))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	store, err := NewAggregateStore(eventStore, WithSnapshotStrategy(NewEveryNumberEventSnapshotStrategy(2)))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eh.RegisterSnapshotData(TestAggregateType, func(id uuid.UUID) eh.SnapshotData {
+		return NewTestAggregateOther(id)
+	})
+	defer eh.UnregisterSnapshotData(TestAggregateType)
+
+	ctx := context.Background()
+	id := uuid.New()
+	agg := NewTestAggregateOther(id)
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	// Snapshots are taken at version 2 and 4.
+	for i := 0; i < 5; i++ {
+		agg.AppendEvent(mocks.EventType, &mocks.EventData{Content: fmt.Sprintf("event%d", i)}, timestamp)
+
+		if err := store.Save(ctx, agg); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		version         int
+		expectedVersion int
+		appliedEvents   int
+	}{
+		{version: 1, expectedVersion: 1, appliedEvents: 1},
+		{version: 2, expectedVersion: 2, appliedEvents: 0},
+		{version: 3, expectedVersion: 3, appliedEvents: 1},
+		{version: 5, expectedVersion: 5, appliedEvents: 1},
+		{version: 10, expectedVersion: 5, appliedEvents: 1},
+	} {
+		loaded, err := store.LoadAt(ctx, TestAggregateOtherType, id, tc.version)
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		a, ok := loaded.(*TestAggregateOther)
+		if !ok {
+			t.Fatal("wrong aggregate type")
+		}
+
+		assert.Equal(t, tc.expectedVersion, a.AggregateVersion(), "version %d", tc.version)
+		assert.Equal(t, tc.appliedEvents, a.appliedEvents, "version %d", tc.version)
+	}
+
+	_, err = store.LoadAt(ctx, TestAggregateOtherType, id, 0)
+	if !errors.Is(err, ErrInvalidAggregateVersion) {
+		t.Error("there should be a ErrInvalidAggregateVersion error:", err)
+	}
+}
+
+func TestAggregateStore_SnapshotSchemaVersion(t *testing.T) {
+	eventStore, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	store, err := NewAggregateStore(eventStore,
+		WithSnapshotStrategy(NewEveryNumberEventSnapshotStrategy(2)),
+		WithSnapshotRebuild(),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	factory := func(id uuid.UUID) eh.SnapshotData {
+		return NewTestAggregateOther(id)
+	}
+
+	eh.RegisterSnapshotData(TestAggregateType, factory)
+	defer eh.UnregisterSnapshotData(TestAggregateType)
+
+	ctx := context.Background()
+	id := uuid.New()
+	agg := NewTestAggregateOther(id)
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	// A snapshot is taken at version 2.
+	for i := 0; i < 3; i++ {
+		agg.AppendEvent(mocks.EventType, &mocks.EventData{Content: fmt.Sprintf("event%d", i)}, timestamp)
+
+		if err := store.Save(ctx, agg); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	// Change the schema version, the snapshot should be ignored.
+	eh.UnregisterSnapshotData(TestAggregateType)
+	eh.RegisterSnapshotData(TestAggregateType, factory, eh.WithSnapshotSchemaVersion(2))
+
+	loaded, err := store.Load(ctx, TestAggregateOtherType, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	a, ok := loaded.(*TestAggregateOther)
+	if !ok {
+		t.Fatal("wrong aggregate type")
+	}
+
+	assert.Equal(t, 3, a.AggregateVersion())
+	assert.Equal(t, 3, a.appliedEvents)
+
+	// The snapshot should be rebuilt in the background, Close waits for it.
+	if err := store.Close(); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	snapshot, err := eventStore.LoadSnapshot(ctx, id)
+	if err != nil || snapshot == nil {
+		t.Fatal("the snapshot should be rebuilt:", err)
+	}
+
+	assert.Equal(t, 3, snapshot.Version)
+	assert.Equal(t, 2, snapshot.SchemaVersion)
+
+	loaded, err = store.Load(ctx, TestAggregateOtherType, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	a, ok = loaded.(*TestAggregateOther)
+	if !ok {
+		t.Fatal("wrong aggregate type")
+	}
+
+	assert.Equal(t, 3, a.AggregateVersion())
+	assert.Equal(t, 0, a.appliedEvents)
+
+	// Rebuilding without snapshot support.
+	store, err = NewAggregateStore(struct{ eh.EventStore }{eventStore})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	err = store.RebuildSnapshot(ctx, TestAggregateOtherType, id)
+	if !errors.Is(err, ErrSnapshotsNotSupported) {
+		t.Error("there should be a ErrSnapshotsNotSupported error:", err)
+	}
+}
+
+func TestAggregateStore_SnapshotRebuildErrors(t *testing.T) {
+	memoryStore, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eventStore := &failingSnapshotStore{EventStore: memoryStore}
+
+	store, err := NewAggregateStore(eventStore,
+		WithSnapshotStrategy(NewEveryNumberEventSnapshotStrategy(2)),
+		WithSnapshotRebuild(),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	factory := func(id uuid.UUID) eh.SnapshotData {
+		return NewTestAggregateOther(id)
+	}
+
+	eh.RegisterSnapshotData(TestAggregateType, factory)
+	defer eh.UnregisterSnapshotData(TestAggregateType)
+
+	ctx := context.Background()
+	id := uuid.New()
+	agg := NewTestAggregateOther(id)
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	// A snapshot is taken at version 2.
+	for i := 0; i < 3; i++ {
+		agg.AppendEvent(mocks.EventType, &mocks.EventData{Content: fmt.Sprintf("event%d", i)}, timestamp)
+
+		if err := store.Save(ctx, agg); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	// Change the schema version and fail the rebuild.
+	eh.UnregisterSnapshotData(TestAggregateType)
+	eh.RegisterSnapshotData(TestAggregateType, factory, eh.WithSnapshotSchemaVersion(2))
+
+	snapshotErr := errors.New("snapshot error")
+	eventStore.err = snapshotErr
+
+	if _, err := store.Load(ctx, TestAggregateOtherType, id); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	select {
+	case err := <-store.Errors():
+		if !errors.Is(err, snapshotErr) {
+			t.Error("there should be a snapshot error:", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("there should be an error")
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// No snapshots are rebuilt after closing.
+	if _, err := store.Load(ctx, TestAggregateOtherType, id); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err, ok := <-store.Errors(); ok {
+		t.Error("there should be no more errors:", err)
+	}
+}
+
+// failingSnapshotStore is an event store that fails saving snapshots if err is set.
+type failingSnapshotStore struct {
+	*memory.EventStore
+	err error
+}
+
+func (s *failingSnapshotStore) SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot eh.Snapshot) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	return s.EventStore.SaveSnapshot(ctx, id, snapshot)
+}
+
+func TestAggregateStore_LoadTruncatedEvents(t *testing.T) {
+	eventStore, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	store, err := NewAggregateStore(eventStore,
+		WithSnapshotStrategy(NewEveryNumberEventSnapshotStrategy(2)),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	factory := func(id uuid.UUID) eh.SnapshotData {
+		return NewTestAggregateOther(id)
+	}
+
+	eh.RegisterSnapshotData(TestAggregateType, factory)
+	defer eh.UnregisterSnapshotData(TestAggregateType)
+
+	ctx := context.Background()
+	id := uuid.New()
+	agg := NewTestAggregateOther(id)
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	// A snapshot is taken at version 2.
+	for i := 0; i < 3; i++ {
+		agg.AppendEvent(mocks.EventType, &mocks.EventData{Content: fmt.Sprintf("event%d", i)}, timestamp)
+
+		if err := store.Save(ctx, agg); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	// The events covered by the snapshot are truncated and the snapshot is
+	// outdated, the aggregate can not be replayed from the start.
+	eh.UnregisterSnapshotData(TestAggregateType)
+	eh.RegisterSnapshotData(TestAggregateType, factory, eh.WithSnapshotSchemaVersion(2))
+
+	store, err = NewAggregateStore(&truncatedEventStore{eventStore, eventStore, 3})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, err := store.Load(ctx, TestAggregateOtherType, id); !errors.Is(err, ErrMissingEvents) {
+		t.Error("there should be a ErrMissingEvents error:", err)
+	}
+
+	if err := store.RebuildSnapshot(ctx, TestAggregateOtherType, id); !errors.Is(err, ErrMissingEvents) {
+		t.Error("there should be a ErrMissingEvents error:", err)
+	}
+}
+
+// truncatedEventStore is an event store that only loads the events from a version.
+type truncatedEventStore struct {
+	eh.EventStore
+	eh.SnapshotStore
+	version int
+}
+
+func (s *truncatedEventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([]eh.Event, error) {
+	if version < s.version {
+		version = s.version
+	}
+
+	return s.EventStore.LoadFrom(ctx, id, version)
+}
+
+func TestAggregateStore_AsyncSnapshots(t *testing.T) {
+	eventStore, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, err := NewAggregateStore(eventStore, WithAsyncSnapshots(0, 10)); err == nil {
+		t.Error("there should be an error for invalid workers")
+	}
+
+	if _, err := NewAggregateStore(eventStore, WithAsyncSnapshots(2, 0)); err == nil {
+		t.Error("there should be an error for an invalid queue size")
+	}
+
+	store, err := NewAggregateStore(eventStore,
+		WithSnapshotStrategy(NewEveryNumberEventSnapshotStrategy(2)),
+		WithAsyncSnapshots(2, 10),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eh.RegisterSnapshotData(TestAggregateType, func(id uuid.UUID) eh.SnapshotData {
+		return NewTestAggregateOther(id)
+	})
+	defer eh.UnregisterSnapshotData(TestAggregateType)
+
+	ctx := context.Background()
+	id := uuid.New()
+	agg := NewTestAggregateOther(id)
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		agg.AppendEvent(mocks.EventType, &mocks.EventData{Content: fmt.Sprintf("event%d", i)}, timestamp)
+
+		if err := store.Save(ctx, agg); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	// Closing should wait for the queued snapshots.
+	if err := store.Close(); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	for err := range store.Errors() {
+		t.Error("there should be no error:", err)
+	}
+
+	snapshot, err := eventStore.LoadSnapshot(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if snapshot == nil || snapshot.Version < 2 {
+		t.Fatal("a snapshot should be taken:", snapshot)
+	}
+
+	// Snapshots are not taken after closing.
+	agg.AppendEvent(mocks.EventType, &mocks.EventData{Content: "event3"}, timestamp)
+
+	if err := store.Save(ctx, agg); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+func TestAggregateStore_ReplaySnapshotStrategy(t *testing.T) {
+	eventStore, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	store, err := NewAggregateStore(eventStore, WithSnapshotStrategy(NewReplaySnapshotStrategy(2, 0)))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eh.RegisterSnapshotData(TestAggregateType, func(id uuid.UUID) eh.SnapshotData {
+		return NewTestAggregateOther(id)
+	})
+	defer eh.UnregisterSnapshotData(TestAggregateType)
+
+	ctx := context.Background()
+	id := uuid.New()
+	agg := NewTestAggregateOther(id)
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		agg.AppendEvent(mocks.EventType, &mocks.EventData{Content: fmt.Sprintf("event%d", i)}, timestamp)
+
+		if err := store.Save(ctx, agg); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	snapshot, err := eventStore.LoadSnapshot(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assert.Nil(t, snapshot, "no snapshot should be taken before loading")
+
+	// Loading replays 3 events, which should be snapshotted on the next save.
+	loaded, err := store.Load(ctx, agg.AggregateType(), id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	a, ok := loaded.(*TestAggregateOther)
+	if !ok {
+		t.Fatal("wrong aggregate type")
+	}
+
+	a.AppendEvent(mocks.EventType, &mocks.EventData{Content: "event3"}, timestamp)
+
+	if err := store.Save(ctx, a); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if snapshot, err = eventStore.LoadSnapshot(ctx, id); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if assert.NotNil(t, snapshot, "snapshot should be taken") {
+		assert.Equal(t, 4, snapshot.Version)
+	}
+}
+
+// streamingEventStore only loads events for aggregates with LoadFromIter.
+type streamingEventStore struct {
+	*memory.EventStore
+}
+
+func (s streamingEventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([]eh.Event, error) {
+	return nil, errors.New("events should be streamed")
+}
+
+func TestAggregateStore_LoadStreaming(t *testing.T) {
+	eventStore, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	store, err := NewAggregateStore(streamingEventStore{eventStore})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	agg := NewTestAggregateOther(uuid.New())
+	for i := 0; i < 5; i++ {
+		agg.AppendEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp)
+	}
+
+	if err := store.Save(ctx, agg); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	loaded, err := store.Load(ctx, agg.AggregateType(), agg.EntityID())
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	loadedAgg, ok := loaded.(*TestAggregateOther)
+	if !ok {
+		t.Fatal("wrong aggregate type")
+	}
+
+	if loadedAgg.AggregateVersion() != 5 || loadedAgg.appliedEvents != 5 {
+		t.Error("all events should be applied:", loadedAgg.AggregateVersion(), loadedAgg.appliedEvents)
+	}
+
+	// Only the events up to the version are applied.
+	if loaded, err = store.LoadAt(ctx, agg.AggregateType(), agg.EntityID(), 3); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if loadedAgg, ok = loaded.(*TestAggregateOther); !ok {
+		t.Fatal("wrong aggregate type")
+	}
+
+	if loadedAgg.AggregateVersion() != 3 || loadedAgg.appliedEvents != 3 {
+		t.Error("the events up to the version should be applied:", loadedAgg.AggregateVersion(), loadedAgg.appliedEvents)
+	}
+
+	// A new aggregate has no events.
+	if loaded, err = store.Load(ctx, agg.AggregateType(), uuid.New()); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if loaded.(*TestAggregateOther).AggregateVersion() != 0 {
+		t.Error("the aggregate should have no version")
+	}
+}
+
 func TestAggregateStore_AggregateNotRegistered(t *testing.T) {
 	store, _ := createStore(t)
 
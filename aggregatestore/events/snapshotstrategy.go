@@ -15,11 +15,21 @@
 package events
 
 import (
+	"container/list"
+	"sync"
 	"time"
 
 	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
 )
 
+// ReplayObserver can be implemented by snapshot strategies to be told how many
+// events were replayed, and how long it took, each time an aggregate is loaded
+// by the AggregateStore.
+type ReplayObserver interface {
+	ObserveReplay(aggregateType eh.AggregateType, id uuid.UUID, events int, d time.Duration)
+}
+
 // NoSnapshotStrategy no snapshot should be taken.
 type NoSnapshotStrategy struct {
 }
@@ -63,3 +73,218 @@ func (s *PeriodSnapshotStrategy) ShouldTakeSnapshot(_ int,
 	event eh.Event) bool {
 	return event.Timestamp().Sub(lastSnapshotTimestamp) >= s.snapshotThreshold
 }
+
+// AnyOfSnapshotStrategy takes a snapshot if any of its strategies would take one.
+type AnyOfSnapshotStrategy struct {
+	strategies []eh.SnapshotStrategy
+}
+
+// AnyOf combines strategies to take a snapshot if any of them would take one.
+func AnyOf(strategies ...eh.SnapshotStrategy) *AnyOfSnapshotStrategy {
+	return &AnyOfSnapshotStrategy{
+		strategies: strategies,
+	}
+}
+
+func (s *AnyOfSnapshotStrategy) ShouldTakeSnapshot(lastSnapshotVersion int,
+	lastSnapshotTimestamp time.Time,
+	event eh.Event) bool {
+	take := false
+
+	// All strategies are asked, as they may keep state per decision.
+	for _, strategy := range s.strategies {
+		if strategy.ShouldTakeSnapshot(lastSnapshotVersion, lastSnapshotTimestamp, event) {
+			take = true
+		}
+	}
+
+	return take
+}
+
+// ObserveReplay implements the ReplayObserver interface for its strategies.
+func (s *AnyOfSnapshotStrategy) ObserveReplay(aggregateType eh.AggregateType, id uuid.UUID, events int, d time.Duration) {
+	for _, strategy := range s.strategies {
+		observeReplay(strategy, aggregateType, id, events, d)
+	}
+}
+
+// AllOfSnapshotStrategy takes a snapshot if all of its strategies would take
+// one, and never if it has no strategies.
+type AllOfSnapshotStrategy struct {
+	strategies []eh.SnapshotStrategy
+}
+
+// AllOf combines strategies to take a snapshot if all of them would take one.
+func AllOf(strategies ...eh.SnapshotStrategy) *AllOfSnapshotStrategy {
+	return &AllOfSnapshotStrategy{
+		strategies: strategies,
+	}
+}
+
+func (s *AllOfSnapshotStrategy) ShouldTakeSnapshot(lastSnapshotVersion int,
+	lastSnapshotTimestamp time.Time,
+	event eh.Event) bool {
+	take := len(s.strategies) > 0
+
+	// All strategies are asked, as they may keep state per decision.
+	for _, strategy := range s.strategies {
+		if !strategy.ShouldTakeSnapshot(lastSnapshotVersion, lastSnapshotTimestamp, event) {
+			take = false
+		}
+	}
+
+	return take
+}
+
+// ObserveReplay implements the ReplayObserver interface for its strategies.
+func (s *AllOfSnapshotStrategy) ObserveReplay(aggregateType eh.AggregateType, id uuid.UUID, events int, d time.Duration) {
+	for _, strategy := range s.strategies {
+		observeReplay(strategy, aggregateType, id, events, d)
+	}
+}
+
+// AggregateTypeSnapshotStrategy uses a strategy per aggregate type, with a
+// default strategy for other aggregate types.
+type AggregateTypeSnapshotStrategy struct {
+	strategies      map[eh.AggregateType]eh.SnapshotStrategy
+	defaultStrategy eh.SnapshotStrategy
+}
+
+// NewAggregateTypeSnapshotStrategy creates a strategy that uses the strategy
+// for the aggregate type of the event, or the default strategy (which can be
+// nil to take no snapshot) for other aggregate types.
+func NewAggregateTypeSnapshotStrategy(strategies map[eh.AggregateType]eh.SnapshotStrategy,
+	defaultStrategy eh.SnapshotStrategy) *AggregateTypeSnapshotStrategy {
+	if defaultStrategy == nil {
+		defaultStrategy = &NoSnapshotStrategy{}
+	}
+
+	s := &AggregateTypeSnapshotStrategy{
+		strategies:      make(map[eh.AggregateType]eh.SnapshotStrategy, len(strategies)),
+		defaultStrategy: defaultStrategy,
+	}
+
+	for aggregateType, strategy := range strategies {
+		s.strategies[aggregateType] = strategy
+	}
+
+	return s
+}
+
+func (s *AggregateTypeSnapshotStrategy) ShouldTakeSnapshot(lastSnapshotVersion int,
+	lastSnapshotTimestamp time.Time,
+	event eh.Event) bool {
+	strategy, ok := s.strategies[event.AggregateType()]
+	if !ok {
+		strategy = s.defaultStrategy
+	}
+
+	return strategy.ShouldTakeSnapshot(lastSnapshotVersion, lastSnapshotTimestamp, event)
+}
+
+// ObserveReplay implements the ReplayObserver interface for the strategy of
+// the aggregate type.
+func (s *AggregateTypeSnapshotStrategy) ObserveReplay(aggregateType eh.AggregateType, id uuid.UUID, events int, d time.Duration) {
+	strategy, ok := s.strategies[aggregateType]
+	if !ok {
+		strategy = s.defaultStrategy
+	}
+
+	observeReplay(strategy, aggregateType, id, events, d)
+}
+
+// defaultReplayCapacity is the max number of aggregates that a
+// ReplaySnapshotStrategy keeps track of.
+const defaultReplayCapacity = 10000
+
+// ReplaySnapshotStrategy use to take a snapshot when loading an aggregate
+// replayed more events, or took longer, than a threshold. It is measured by
+// the AggregateStore each time an aggregate is loaded, and used for the next
+// snapshot decision of the aggregate.
+//
+// Only the aggregates that exceeded a threshold on their last load are kept
+// track of, at most 10000 of them. The least recently loaded aggregates are
+// forgotten first, for example aggregates that are only loaded for reading.
+type ReplaySnapshotStrategy struct {
+	maxEvents   int
+	maxDuration time.Duration
+	capacity    int
+
+	mu sync.Mutex
+	// replayed has the elements of the aggregate IDs in the order list, where
+	// the most recently loaded aggregate is at the front.
+	replayed map[uuid.UUID]*list.Element
+	order    *list.List
+}
+
+// NewReplaySnapshotStrategy creates a strategy that takes a snapshot when
+// loading an aggregate replayed more than maxEvents events or took longer than
+// maxDuration. A zero threshold is not used.
+func NewReplaySnapshotStrategy(maxEvents int, maxDuration time.Duration) *ReplaySnapshotStrategy {
+	return &ReplaySnapshotStrategy{
+		maxEvents:   maxEvents,
+		maxDuration: maxDuration,
+		capacity:    defaultReplayCapacity,
+		replayed:    map[uuid.UUID]*list.Element{},
+		order:       list.New(),
+	}
+}
+
+// ObserveReplay implements the ReplayObserver interface.
+func (s *ReplaySnapshotStrategy) ObserveReplay(_ eh.AggregateType, id uuid.UUID, events int, d time.Duration) {
+	exceeded := (s.maxEvents > 0 && events > s.maxEvents) ||
+		(s.maxDuration > 0 && d > s.maxDuration)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !exceeded {
+		s.remove(id)
+
+		return
+	}
+
+	if e, ok := s.replayed[id]; ok {
+		s.order.MoveToFront(e)
+
+		return
+	}
+
+	s.replayed[id] = s.order.PushFront(id)
+
+	if s.order.Len() > s.capacity {
+		if id, ok := s.order.Back().Value.(uuid.UUID); ok {
+			s.remove(id)
+		}
+	}
+}
+
+func (s *ReplaySnapshotStrategy) ShouldTakeSnapshot(_ int,
+	_ time.Time,
+	event eh.Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The measurement is only used once.
+	if _, ok := s.replayed[event.AggregateID()]; !ok {
+		return false
+	}
+
+	s.remove(event.AggregateID())
+
+	return true
+}
+
+// remove stops keeping track of an aggregate, must be called with the lock held.
+func (s *ReplaySnapshotStrategy) remove(id uuid.UUID) {
+	if e, ok := s.replayed[id]; ok {
+		s.order.Remove(e)
+		delete(s.replayed, id)
+	}
+}
+
+func observeReplay(strategy eh.SnapshotStrategy, aggregateType eh.AggregateType, id uuid.UUID, events int, d time.Duration) {
+	if o, ok := strategy.(ReplayObserver); ok {
+		o.ObserveReplay(aggregateType, id, events, d)
+	}
+}
@@ -68,3 +68,113 @@ func TestPeriodSnapshotStrategy_ShouldTakeSnapshot(t *testing.T) {
 
 	assert.False(t, result)
 }
+
+func TestAnyOfAllOfSnapshotStrategy_ShouldTakeSnapshot(t *testing.T) {
+	timestamp := time.Date(2009, time.November, 10, 23, 20, 0, 0, time.UTC)
+	lastEvt := eh.NewEvent(mocks.EventType,
+		&mocks.EventData{Content: "event"}, timestamp,
+		eh.ForAggregate("testAgg", uuid.New(), 4))
+
+	number := NewEveryNumberEventSnapshotStrategy(2)
+	period := NewPeriodSnapshotStrategy(10 * time.Minute)
+
+	// Only the number strategy should take a snapshot.
+	snapshotTimestamp := time.Date(2009, time.November, 10, 23, 15, 0, 0, time.UTC)
+
+	assert.True(t, AnyOf(number, period).ShouldTakeSnapshot(2, snapshotTimestamp, lastEvt))
+	assert.False(t, AllOf(number, period).ShouldTakeSnapshot(2, snapshotTimestamp, lastEvt))
+
+	// Both strategies should take a snapshot.
+	snapshotTimestamp = time.Date(2009, time.November, 10, 23, 10, 0, 0, time.UTC)
+
+	assert.True(t, AnyOf(number, period).ShouldTakeSnapshot(2, snapshotTimestamp, lastEvt))
+	assert.True(t, AllOf(number, period).ShouldTakeSnapshot(2, snapshotTimestamp, lastEvt))
+
+	assert.False(t, AnyOf().ShouldTakeSnapshot(2, snapshotTimestamp, lastEvt))
+	assert.False(t, AllOf().ShouldTakeSnapshot(2, snapshotTimestamp, lastEvt))
+}
+
+func TestAggregateTypeSnapshotStrategy_ShouldTakeSnapshot(t *testing.T) {
+	strategy := NewAggregateTypeSnapshotStrategy(map[eh.AggregateType]eh.SnapshotStrategy{
+		"testAgg": NewEveryNumberEventSnapshotStrategy(2),
+	}, nil)
+
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	lastEvt := eh.NewEvent(mocks.EventType,
+		&mocks.EventData{Content: "event"}, timestamp,
+		eh.ForAggregate("testAgg", uuid.New(), 4))
+
+	assert.True(t, strategy.ShouldTakeSnapshot(2, timestamp, lastEvt))
+
+	otherEvt := eh.NewEvent(mocks.EventType,
+		&mocks.EventData{Content: "event"}, timestamp,
+		eh.ForAggregate("otherAgg", uuid.New(), 4))
+
+	assert.False(t, strategy.ShouldTakeSnapshot(2, timestamp, otherEvt))
+}
+
+func TestReplaySnapshotStrategy_ShouldTakeSnapshot(t *testing.T) {
+	strategy := NewReplaySnapshotStrategy(10, time.Second)
+
+	// The strategy is used for the replay measurements through combinators.
+	combined := AnyOf(NewAggregateTypeSnapshotStrategy(nil, strategy))
+
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	lastEvt := eh.NewEvent(mocks.EventType,
+		&mocks.EventData{Content: "event"}, timestamp,
+		eh.ForAggregate("testAgg", id, 20))
+
+	// Without a measurement.
+	assert.False(t, combined.ShouldTakeSnapshot(0, timestamp, lastEvt))
+
+	combined.ObserveReplay("testAgg", id, 5, time.Millisecond)
+	assert.False(t, combined.ShouldTakeSnapshot(0, timestamp, lastEvt))
+
+	combined.ObserveReplay("testAgg", id, 11, time.Millisecond)
+	assert.True(t, combined.ShouldTakeSnapshot(0, timestamp, lastEvt))
+
+	// The measurement should only be used once.
+	assert.False(t, combined.ShouldTakeSnapshot(0, timestamp, lastEvt))
+
+	combined.ObserveReplay("testAgg", id, 5, 2*time.Second)
+	assert.True(t, combined.ShouldTakeSnapshot(0, timestamp, lastEvt))
+
+	// A later load below the thresholds replaces the measurement.
+	combined.ObserveReplay("testAgg", id, 11, time.Millisecond)
+	combined.ObserveReplay("testAgg", id, 5, time.Millisecond)
+	assert.False(t, combined.ShouldTakeSnapshot(0, timestamp, lastEvt))
+	assert.Equal(t, 0, len(strategy.replayed))
+
+	// Only the strategy of the aggregate type is told.
+	other := NewReplaySnapshotStrategy(10, time.Second)
+	combined = AnyOf(NewAggregateTypeSnapshotStrategy(
+		map[eh.AggregateType]eh.SnapshotStrategy{"otherAgg": other}, strategy))
+
+	combined.ObserveReplay("testAgg", id, 11, time.Millisecond)
+	assert.Equal(t, 1, len(strategy.replayed))
+	assert.Equal(t, 0, len(other.replayed))
+	assert.True(t, combined.ShouldTakeSnapshot(0, timestamp, lastEvt))
+}
+
+func TestReplaySnapshotStrategy_Capacity(t *testing.T) {
+	strategy := NewReplaySnapshotStrategy(10, 0)
+	strategy.capacity = 2
+
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+
+	strategy.ObserveReplay("testAgg", ids[0], 11, 0)
+	strategy.ObserveReplay("testAgg", ids[1], 11, 0)
+	strategy.ObserveReplay("testAgg", ids[0], 11, 0)
+	strategy.ObserveReplay("testAgg", ids[2], 11, 0)
+
+	assert.Equal(t, 2, len(strategy.replayed))
+
+	// The least recently loaded aggregate should be forgotten.
+	for i, expected := range []bool{true, false, true} {
+		event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+			eh.ForAggregate("testAgg", ids[i], 20))
+		assert.Equal(t, expected, strategy.ShouldTakeSnapshot(0, timestamp, event))
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"log"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// snapshotJob is a queued snapshot decision for an aggregate.
+type snapshotJob struct {
+	ctx           context.Context
+	aggregateType eh.AggregateType
+	id            uuid.UUID
+	lastEvent     eh.Event
+}
+
+// Errors returns an error channel where errors from taking and rebuilding
+// snapshots in the background will be sent, see WithAsyncSnapshots and
+// WithSnapshotRebuild. It is closed by Close.
+func (r *AggregateStore) Errors() <-chan error {
+	return r.errCh
+}
+
+// Close waits for all queued snapshots to be taken and snapshots being rebuilt,
+// and stops the snapshot workers. Snapshots of aggregates saved after closing
+// are not taken, and outdated snapshots loaded after closing are not rebuilt.
+func (r *AggregateStore) Close() error {
+	r.snapshotQueueMu.Lock()
+
+	if r.snapshotQueueDone {
+		r.snapshotQueueMu.Unlock()
+
+		return nil
+	}
+
+	r.snapshotQueueDone = true
+
+	if r.snapshotQueue != nil {
+		close(r.snapshotQueue)
+	}
+
+	r.snapshotQueueMu.Unlock()
+
+	r.snapshotWg.Wait()
+	close(r.errCh)
+
+	return nil
+}
+
+func (r *AggregateStore) startSnapshotWorkers() {
+	for i := 0; i < r.snapshotWorkers; i++ {
+		r.snapshotWg.Add(1)
+
+		go func() {
+			defer r.snapshotWg.Done()
+
+			for job := range r.snapshotQueue {
+				if err := r.snapshot(job); err != nil {
+					r.sendError(&eh.AggregateStoreError{
+						Err:           err,
+						Op:            eh.AggregateStoreOpSnapshot,
+						AggregateType: job.aggregateType,
+						AggregateID:   job.id,
+					})
+				}
+			}
+		}()
+	}
+}
+
+// queueSnapshot queues a snapshot decision without blocking, it is skipped if
+// the queue is full or closed.
+func (r *AggregateStore) queueSnapshot(ctx context.Context, aggregateType eh.AggregateType, id uuid.UUID, lastEvent eh.Event) {
+	r.snapshotQueueMu.RLock()
+	defer r.snapshotQueueMu.RUnlock()
+
+	if r.snapshotQueueDone {
+		return
+	}
+
+	job := snapshotJob{
+		// Keep the values of the context (like the namespace) but not the cancellation.
+		ctx:           context.WithoutCancel(ctx),
+		aggregateType: aggregateType,
+		id:            id,
+		lastEvent:     lastEvent,
+	}
+
+	select {
+	case r.snapshotQueue <- job:
+	default:
+		r.sendError(&eh.AggregateStoreError{
+			Err:           ErrSnapshotQueueFull,
+			Op:            eh.AggregateStoreOpSnapshot,
+			AggregateType: aggregateType,
+			AggregateID:   id,
+		})
+	}
+}
+
+// snapshot takes a snapshot if the snapshot strategy decides so, by reloading
+// the aggregate.
+func (r *AggregateStore) snapshot(job snapshotJob) error {
+	take, err := r.shouldTakeSnapshot(job.ctx, job.id, job.lastEvent)
+	if err != nil || !take {
+		return err
+	}
+
+	agg, err := r.Load(job.ctx, job.aggregateType, job.id)
+	if err != nil {
+		return err
+	}
+
+	a, ok := agg.(eh.Snapshotable)
+	if !ok {
+		return ErrAggregateNotSnapshotable
+	}
+
+	return r.snapshotStore.SaveSnapshot(job.ctx, job.id, *a.CreateSnapshot())
+}
+
+func (r *AggregateStore) sendError(err error) {
+	select {
+	case r.errCh <- err:
+	default:
+		log.Printf("eventhorizon: missed error in aggregate store: %s", err)
+	}
+}
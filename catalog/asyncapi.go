@@ -0,0 +1,176 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package catalog
+
+import (
+	"strings"
+
+	eh "github.com/Clarilab/eventhorizon"
+)
+
+// AsyncAPIVersion is the version of the generated AsyncAPI documents.
+const AsyncAPIVersion = "2.6.0"
+
+// AsyncAPI is an AsyncAPI document, with the subset of fields used to describe
+// the events published on a channel.
+type AsyncAPI struct {
+	AsyncAPI   string                     `json:"asyncapi"`
+	Info       AsyncAPIInfo               `json:"info"`
+	Channels   map[string]AsyncAPIChannel `json:"channels"`
+	Components AsyncAPIComponents         `json:"components"`
+}
+
+// AsyncAPIInfo is the info of an AsyncAPI document.
+type AsyncAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// AsyncAPIChannel is a channel of an AsyncAPI document.
+type AsyncAPIChannel struct {
+	Description string `json:"description,omitempty"`
+	// Subscribe is the operation of consumers of the channel.
+	Subscribe *AsyncAPIOperation `json:"subscribe,omitempty"`
+}
+
+// AsyncAPIOperation is an operation of an AsyncAPI channel.
+type AsyncAPIOperation struct {
+	OperationID string          `json:"operationId,omitempty"`
+	Message     AsyncAPIMessage `json:"message"`
+}
+
+// AsyncAPIMessage is a message, or a reference to a message or a choice of
+// messages, in an AsyncAPI document.
+type AsyncAPIMessage struct {
+	Ref         string            `json:"$ref,omitempty"`
+	OneOf       []AsyncAPIMessage `json:"oneOf,omitempty"`
+	Name        string            `json:"name,omitempty"`
+	Title       string            `json:"title,omitempty"`
+	Summary     string            `json:"summary,omitempty"`
+	ContentType string            `json:"contentType,omitempty"`
+	Headers     *Schema           `json:"headers,omitempty"`
+	Payload     *Schema           `json:"payload,omitempty"`
+	Tags        []AsyncAPITag     `json:"tags,omitempty"`
+}
+
+// AsyncAPITag is a tag of an AsyncAPI message.
+type AsyncAPITag struct {
+	Name string `json:"name"`
+}
+
+// AsyncAPIComponents are the reusable messages and schemas of an AsyncAPI document.
+type AsyncAPIComponents struct {
+	Messages map[string]AsyncAPIMessage `json:"messages,omitempty"`
+	Schemas  map[string]*Schema         `json:"schemas,omitempty"`
+}
+
+// AsyncAPI creates an AsyncAPI document describing the events of the catalog
+// as published on a channel by an event bus, like the topic of eventbus/kafka.
+// The payload of each message is the event encoded with codec/json, with the
+// event data described by a schema in the components. The aggregates that
+// emit an event are added as tags of its message.
+func (c *Catalog) AsyncAPI(info AsyncAPIInfo, channel string) *AsyncAPI {
+	doc := &AsyncAPI{
+		AsyncAPI: AsyncAPIVersion,
+		Info:     info,
+		Channels: map[string]AsyncAPIChannel{},
+		Components: AsyncAPIComponents{
+			Messages: map[string]AsyncAPIMessage{},
+			Schemas:  map[string]*Schema{},
+		},
+	}
+
+	var messages []AsyncAPIMessage
+
+	for _, e := range c.Events {
+		name := e.EventType.String()
+
+		data := &Schema{}
+		if e.Schema != nil {
+			schema := *e.Schema
+			schema.Schema = ""
+			doc.Components.Schemas[name] = &schema
+			data = &Schema{Ref: "#/components/schemas/" + name}
+		}
+
+		var (
+			aggregateTypes []interface{}
+			tags           []AsyncAPITag
+			names          []string
+		)
+
+		for _, aggregateType := range e.AggregateTypes {
+			aggregateTypes = append(aggregateTypes, aggregateType.String())
+			tags = append(tags, AsyncAPITag{Name: aggregateType.String()})
+			names = append(names, aggregateType.String())
+		}
+
+		message := AsyncAPIMessage{
+			Name:        name,
+			Title:       name,
+			ContentType: "application/json",
+			Headers: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"event_type":     {Type: "string", Const: name},
+					"aggregate_type": {Type: "string", Enum: aggregateTypes},
+				},
+			},
+			Payload: eventPayloadSchema(e.EventType, data, aggregateTypes),
+			Tags:    tags,
+		}
+
+		if len(names) > 0 {
+			message.Summary = "Emitted by " + strings.Join(names, ", ") + "."
+		}
+
+		doc.Components.Messages[name] = message
+		messages = append(messages, AsyncAPIMessage{Ref: "#/components/messages/" + name})
+	}
+
+	operation := &AsyncAPIOperation{OperationID: "receiveEvents"}
+	if len(messages) == 1 {
+		operation.Message = messages[0]
+	} else {
+		operation.Message = AsyncAPIMessage{OneOf: messages}
+	}
+
+	doc.Channels[channel] = AsyncAPIChannel{
+		Description: "Events published by the aggregates, keyed by aggregate ID.",
+		Subscribe:   operation,
+	}
+
+	return doc
+}
+
+// eventPayloadSchema returns the schema of an event encoded with codec/json.
+func eventPayloadSchema(eventType eh.EventType, data *Schema, aggregateTypes []interface{}) *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"event_type":     {Type: "string", Const: eventType.String()},
+			"data":           data,
+			"schema_version": {Type: "integer"},
+			"timestamp":      {Type: "string", Format: "date-time"},
+			"aggregate_type": {Type: "string", Enum: aggregateTypes},
+			"aggregate_id":   {Type: "string", Format: "uuid"},
+			"version":        {Type: "integer"},
+			"metadata":       {Type: "object"},
+			"context":        {Type: "object"},
+		},
+		Required: []string{"event_type", "timestamp", "aggregate_type", "aggregate_id", "version"},
+	}
+}
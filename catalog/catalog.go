@@ -0,0 +1,189 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package catalog describes the registered events, commands and aggregates of
+// an application, as JSON Schemas of their data and as an AsyncAPI document of
+// the published events.
+package catalog
+
+import (
+	"fmt"
+	"sort"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// Catalog is a catalog of the registered events, commands and aggregates.
+type Catalog struct {
+	// Events are the events sorted by event type.
+	Events []Event
+	// Commands are the commands sorted by command type.
+	Commands []Command
+	// Aggregates are the aggregates sorted by aggregate type.
+	Aggregates []Aggregate
+
+	emits map[eh.AggregateType][]eh.EventType
+}
+
+// Event is an event type in the catalog.
+type Event struct {
+	EventType eh.EventType
+	// Schema is the schema of the event data, or nil for events without data.
+	Schema *Schema
+	// SchemaVersion is the current schema version of the event data.
+	SchemaVersion int
+	// AggregateTypes are the aggregates that emit the event.
+	AggregateTypes []eh.AggregateType
+}
+
+// Command is a command type in the catalog.
+type Command struct {
+	CommandType   eh.CommandType
+	AggregateType eh.AggregateType
+	Schema        *Schema
+}
+
+// Aggregate is an aggregate type in the catalog.
+type Aggregate struct {
+	AggregateType eh.AggregateType
+	// Commands are the commands handled by the aggregate.
+	Commands []eh.CommandType
+	// Events are the events emitted by the aggregate.
+	Events []eh.EventType
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*Catalog) error
+
+// WithAggregateEvents declares the events emitted by an aggregate, which can
+// not be found from the registries. Events without data only need to be
+// declared, they are not registered with RegisterEventData.
+func WithAggregateEvents(aggregateType eh.AggregateType, eventTypes ...eh.EventType) Option {
+	return func(c *Catalog) error {
+		if _, err := eh.CreateAggregate(aggregateType, uuid.New()); err != nil {
+			return fmt.Errorf("%w: %s", err, aggregateType)
+		}
+
+		c.emits[aggregateType] = append(c.emits[aggregateType], eventTypes...)
+
+		return nil
+	}
+}
+
+// New creates a catalog from the registered event data, commands and
+// aggregates, see eventhorizon.RegisterEventData, eventhorizon.RegisterCommand
+// and eventhorizon.RegisterAggregate.
+func New(options ...Option) (*Catalog, error) {
+	c := &Catalog{
+		emits: map[eh.AggregateType][]eh.EventType{},
+	}
+
+	for _, option := range options {
+		if err := option(c); err != nil {
+			return nil, fmt.Errorf("error while applying option: %w", err)
+		}
+	}
+
+	events := map[eh.EventType]*Event{}
+
+	for eventType, factory := range eh.RegisteredEventData() {
+		events[eventType] = &Event{
+			EventType:     eventType,
+			Schema:        SchemaFor(factory()),
+			SchemaVersion: eh.EventSchemaVersion(eventType),
+		}
+	}
+
+	aggregates := map[eh.AggregateType]*Aggregate{}
+
+	for aggregateType := range eh.RegisteredAggregates() {
+		aggregates[aggregateType] = &Aggregate{AggregateType: aggregateType}
+	}
+
+	for aggregateType, eventTypes := range c.emits {
+		a := aggregates[aggregateType]
+
+		for _, eventType := range eventTypes {
+			e, ok := events[eventType]
+			if !ok {
+				e = &Event{EventType: eventType}
+				events[eventType] = e
+			}
+
+			e.AggregateTypes = append(e.AggregateTypes, aggregateType)
+			a.Events = append(a.Events, eventType)
+		}
+	}
+
+	for commandType, factory := range eh.RegisteredCommands() {
+		cmd := factory()
+		c.Commands = append(c.Commands, Command{
+			CommandType:   commandType,
+			AggregateType: cmd.AggregateType(),
+			Schema:        SchemaFor(cmd),
+		})
+
+		if a, ok := aggregates[cmd.AggregateType()]; ok {
+			a.Commands = append(a.Commands, commandType)
+		}
+	}
+
+	sort.Slice(c.Commands, func(i, j int) bool {
+		return c.Commands[i].CommandType < c.Commands[j].CommandType
+	})
+
+	for _, e := range events {
+		sort.Slice(e.AggregateTypes, func(i, j int) bool {
+			return e.AggregateTypes[i] < e.AggregateTypes[j]
+		})
+
+		c.Events = append(c.Events, *e)
+	}
+
+	sort.Slice(c.Events, func(i, j int) bool {
+		return c.Events[i].EventType < c.Events[j].EventType
+	})
+
+	for _, a := range aggregates {
+		sort.Slice(a.Commands, func(i, j int) bool { return a.Commands[i] < a.Commands[j] })
+		sort.Slice(a.Events, func(i, j int) bool { return a.Events[i] < a.Events[j] })
+
+		c.Aggregates = append(c.Aggregates, *a)
+	}
+
+	sort.Slice(c.Aggregates, func(i, j int) bool {
+		return c.Aggregates[i].AggregateType < c.Aggregates[j].AggregateType
+	})
+
+	return c, nil
+}
+
+// JSONSchemas returns the JSON Schemas of the data of all events and commands,
+// by event or command type. Events without data are not included.
+func (c *Catalog) JSONSchemas() map[string]*Schema {
+	schemas := make(map[string]*Schema, len(c.Events)+len(c.Commands))
+
+	for _, e := range c.Events {
+		if e.Schema != nil {
+			schemas[e.EventType.String()] = e.Schema
+		}
+	}
+
+	for _, cmd := range c.Commands {
+		schemas[cmd.CommandType.String()] = cmd.Schema
+	}
+
+	return schemas
+}
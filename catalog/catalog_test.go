@@ -0,0 +1,193 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+const (
+	testAggregateType eh.AggregateType = "CatalogAggregate"
+
+	testCreatedEvent eh.EventType = "CatalogCreated"
+	testDeletedEvent eh.EventType = "CatalogDeleted"
+
+	testCreateCommand eh.CommandType = "CatalogCreate"
+)
+
+type testAggregate struct {
+	id uuid.UUID
+}
+
+func (a *testAggregate) EntityID() uuid.UUID                                     { return a.id }
+func (a *testAggregate) AggregateType() eh.AggregateType                         { return testAggregateType }
+func (a *testAggregate) HandleCommand(ctx context.Context, cmd eh.Command) error { return nil }
+
+type testCreatedData struct {
+	Name    string           `json:"name"`
+	Tags    []string         `json:"tags,omitempty"`
+	Created time.Time        `json:"created"`
+	Owner   uuid.UUID        `json:"owner"`
+	Labels  map[string]int   `json:"labels,omitempty"`
+	Raw     []byte           `json:"raw,omitempty"`
+	Parent  *testCreatedData `json:"parent,omitempty"`
+	Ignored string           `json:"-"`
+	Extra   interface{}      `json:"extra,omitempty"`
+	testEmbedded
+}
+
+type testEmbedded struct {
+	Score float64
+}
+
+type testCreateCommandData struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+func (c *testCreateCommandData) AggregateID() uuid.UUID          { return c.ID }
+func (c *testCreateCommandData) AggregateType() eh.AggregateType { return testAggregateType }
+func (c *testCreateCommandData) CommandType() eh.CommandType     { return testCreateCommand }
+
+func register(t *testing.T) {
+	eh.RegisterAggregate(func(id uuid.UUID) eh.Aggregate { return &testAggregate{id: id} })
+	eh.RegisterEventData(testCreatedEvent, func() eh.EventData { return &testCreatedData{} })
+	eh.RegisterCommand(func() eh.Command { return &testCreateCommandData{} })
+
+	t.Cleanup(func() {
+		eh.UnregisterAggregate(testAggregateType)
+		eh.UnregisterEventData(testCreatedEvent)
+		eh.UnregisterCommand(testCreateCommand)
+	})
+}
+
+func TestNew(t *testing.T) {
+	register(t)
+
+	c, err := New(WithAggregateEvents(testAggregateType, testCreatedEvent, testDeletedEvent))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(c.Events) != 2 ||
+		c.Events[0].EventType != testCreatedEvent ||
+		c.Events[1].EventType != testDeletedEvent {
+		t.Fatal("the events should be in the catalog:", c.Events)
+	}
+
+	if c.Events[0].Schema == nil || c.Events[0].SchemaVersion != 1 {
+		t.Error("the event with data should have a schema:", c.Events[0])
+	}
+
+	if c.Events[1].Schema != nil {
+		t.Error("the event without data should not have a schema:", c.Events[1].Schema)
+	}
+
+	if !reflect.DeepEqual(c.Events[1].AggregateTypes, []eh.AggregateType{testAggregateType}) {
+		t.Error("the emitting aggregate should be set:", c.Events[1].AggregateTypes)
+	}
+
+	expectedAggregates := []Aggregate{{
+		AggregateType: testAggregateType,
+		Commands:      []eh.CommandType{testCreateCommand},
+		Events:        []eh.EventType{testCreatedEvent, testDeletedEvent},
+	}}
+	if !reflect.DeepEqual(c.Aggregates, expectedAggregates) {
+		t.Error("the aggregates should be correct:", c.Aggregates)
+	}
+
+	if len(c.Commands) != 1 || c.Commands[0].AggregateType != testAggregateType {
+		t.Error("the commands should be correct:", c.Commands)
+	}
+
+	if schemas := c.JSONSchemas(); len(schemas) != 2 ||
+		schemas[testCreatedEvent.String()] == nil || schemas[testCreateCommand.String()] == nil {
+		t.Error("the schemas of the events and commands with data should be returned:", schemas)
+	}
+
+	if _, err := New(WithAggregateEvents("NoSuchAggregate")); !errors.Is(err, eh.ErrAggregateNotRegistered) {
+		t.Error("there should be an aggregate not registered error:", err)
+	}
+}
+
+func TestSchemaFor(t *testing.T) {
+	data, err := json.Marshal(SchemaFor(&testCreatedData{}))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	expected := `{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"testCreatedData","type":"object",` +
+		`"properties":{"Score":{"type":"number"},"created":{"type":"string","format":"date-time"},"extra":{},` +
+		`"labels":{"type":"object","additionalProperties":{"type":"integer"}},"name":{"type":"string"},` +
+		`"owner":{"type":"string","format":"uuid"},"parent":{},"raw":{"type":"string","contentEncoding":"base64"},` +
+		`"tags":{"type":"array","items":{"type":"string"}}},"required":["name","created","owner","Score"]}`
+	if string(data) != expected {
+		t.Errorf("the schema should be correct:\ngot:  %s\nwant: %s", data, expected)
+	}
+}
+
+func TestCatalog_AsyncAPI(t *testing.T) {
+	register(t)
+
+	c, err := New(WithAggregateEvents(testAggregateType, testCreatedEvent, testDeletedEvent))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	doc := c.AsyncAPI(AsyncAPIInfo{Title: "Catalog", Version: "1.0.0"}, "app_events")
+
+	channel, ok := doc.Channels["app_events"]
+	if !ok || channel.Subscribe == nil {
+		t.Fatal("there should be a channel:", doc.Channels)
+	}
+
+	if len(channel.Subscribe.Message.OneOf) != 2 ||
+		channel.Subscribe.Message.OneOf[0].Ref != "#/components/messages/CatalogCreated" {
+		t.Error("the channel should reference the messages:", channel.Subscribe.Message)
+	}
+
+	message, ok := doc.Components.Messages[testCreatedEvent.String()]
+	if !ok {
+		t.Fatal("there should be a message for the event")
+	}
+
+	if !reflect.DeepEqual(message.Tags, []AsyncAPITag{{Name: testAggregateType.String()}}) {
+		t.Error("the emitting aggregate should be tagged:", message.Tags)
+	}
+
+	if message.Payload.Properties["data"].Ref != "#/components/schemas/CatalogCreated" {
+		t.Error("the data should reference the schema:", message.Payload.Properties["data"])
+	}
+
+	if schema, ok := doc.Components.Schemas[testCreatedEvent.String()]; !ok || schema.Schema != "" {
+		t.Error("the data schema should be a component:", schema)
+	}
+
+	if _, ok := doc.Components.Schemas[testDeletedEvent.String()]; ok {
+		t.Error("there should be no schema for an event without data")
+	}
+
+	if _, err := json.Marshal(doc); err != nil {
+		t.Error("the document should be encodable:", err)
+	}
+}
@@ -31,3 +31,15 @@ type CommandCodec interface {
 	// UnmarshalCommand unmarshals a command and supported parts of context from bytes.
 	UnmarshalCommand(context.Context, []byte) (Command, context.Context, error)
 }
+
+// SnapshotCodec is a codec for the stored bytes of snapshots, for example to
+// compress or encrypt them. The name of the codec is stored with each snapshot
+// so that snapshots written with other codecs can still be decoded.
+type SnapshotCodec interface {
+	// Name returns the name of the codec stored with the snapshots.
+	Name() string
+	// Encode encodes the bytes of a snapshot.
+	Encode(context.Context, []byte) ([]byte, error)
+	// Decode decodes the bytes of a snapshot encoded by the codec.
+	Decode(context.Context, []byte) ([]byte, error)
+}
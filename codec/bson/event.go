@@ -47,6 +47,8 @@ func (c *EventCodec) MarshalEvent(ctx context.Context, event eh.Event) ([]byte,
 		if e.RawData, err = bson.Marshal(event.Data()); err != nil {
 			return nil, fmt.Errorf("could not marshal event data: %w", err)
 		}
+
+		e.SchemaVersion = eh.EventSchemaVersion(event.EventType())
 	}
 
 	// Marshal the event (using BSON for now).
@@ -69,6 +71,10 @@ func (c *EventCodec) UnmarshalEvent(ctx context.Context, b []byte) (eh.Event, co
 	// Create an event of the correct type and decode from raw BSON.
 	if len(e.RawData) > 0 {
 		var err error
+		if e.RawData, err = UpcastRawData(ctx, e.EventType, e.SchemaVersion, e.RawData); err != nil {
+			return nil, nil, fmt.Errorf("could not upcast event data: %w", err)
+		}
+
 		if e.data, err = eh.CreateEventData(e.EventType); err != nil {
 			return nil, nil, fmt.Errorf("could not create event data: %w", err)
 		}
@@ -109,6 +115,7 @@ type evt struct {
 	EventType     eh.EventType           `bson:"event_type"`
 	RawData       bson.Raw               `bson:"data,omitempty"`
 	data          eh.EventData           `bson:"-"`
+	SchemaVersion int                    `bson:"schema_version,omitempty"`
 	Timestamp     time.Time              `bson:"timestamp"`
 	AggregateType eh.AggregateType       `bson:"aggregate_type"`
 	AggregateID   string                 `bson:"_id"`
@@ -0,0 +1,132 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bson
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+func TestUpcastRawData(t *testing.T) {
+	const eventType eh.EventType = "UpcastCodecEvent"
+
+	type dataV1 struct {
+		Name string    `json:"name" bson:"name"`
+		Time time.Time `json:"time" bson:"time"`
+	}
+
+	type dataV2 struct {
+		FullName string    `json:"full_name" bson:"full_name"`
+		Count    int       `json:"count" bson:"count"`
+		Time     time.Time `json:"time" bson:"time"`
+	}
+
+	ctx := context.Background()
+	c := &EventCodec{}
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	id := uuid.New()
+
+	eh.RegisterEventData(eventType, func() eh.EventData { return &dataV1{} })
+
+	b, err := c.MarshalEvent(ctx, eh.NewEvent(eventType, &dataV1{Name: "name", Time: timestamp}, timestamp,
+		eh.ForAggregate("Aggregate", id, 1)))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eh.UnregisterEventData(eventType)
+	eh.RegisterEventData(eventType, func() eh.EventData { return &dataV2{} })
+	eh.RegisterUpcaster(eventType, 1, func(ctx context.Context, data map[string]interface{}) (map[string]interface{}, error) {
+		data["full_name"] = data["name"]
+		data["count"] = 1
+		delete(data, "name")
+
+		return data, nil
+	})
+
+	defer func() {
+		eh.UnregisterUpcasters(eventType)
+		eh.UnregisterEventData(eventType)
+	}()
+
+	event, _, err := c.UnmarshalEvent(ctx, b)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(event.Data(), &dataV2{FullName: "name", Count: 1, Time: timestamp}) {
+		t.Error("the event data should be upcasted:", event.Data())
+	}
+
+	// Events in the current version should not be upcasted.
+	b, err = c.MarshalEvent(ctx, eh.NewEvent(eventType, &dataV2{FullName: "full name", Count: 2, Time: timestamp}, timestamp,
+		eh.ForAggregate("Aggregate", id, 2)))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if event, _, err = c.UnmarshalEvent(ctx, b); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(event.Data(), &dataV2{FullName: "full name", Count: 2, Time: timestamp}) {
+		t.Error("the event data should not be upcasted:", event.Data())
+	}
+}
+
+func TestUpcastRawData_Numbers(t *testing.T) {
+	const eventType eh.EventType = "UpcastCodecNumbersEvent"
+
+	eh.RegisterUpcaster(eventType, 1, func(ctx context.Context, data map[string]interface{}) (map[string]interface{}, error) {
+		return data, nil
+	})
+
+	defer eh.UnregisterUpcasters(eventType)
+
+	raw, err := bson.Marshal(bson.D{
+		{Key: "small", Value: int32(42)},
+		{Key: "large", Value: int64(1<<53 + 1)},
+		{Key: "float", Value: 1.5},
+	})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	upcasted, err := UpcastRawData(context.Background(), eventType, 1, raw)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	var data bson.M
+	if err := bson.Unmarshal(upcasted, &data); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	expected := bson.M{
+		"small": int32(42),
+		"large": int64(1<<53 + 1),
+		"float": 1.5,
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Error("the numbers should be kept:", data)
+	}
+}
@@ -46,6 +46,8 @@ func (c *EventCodec) MarshalEvent(ctx context.Context, event eh.Event) ([]byte,
 		if e.RawData, err = json.Marshal(event.Data()); err != nil {
 			return nil, fmt.Errorf("could not marshal event data: %w", err)
 		}
+
+		e.SchemaVersion = eh.EventSchemaVersion(event.EventType())
 	}
 
 	// Marshal the event (using JSON for now).
@@ -68,6 +70,10 @@ func (c *EventCodec) UnmarshalEvent(ctx context.Context, b []byte) (eh.Event, co
 	// Create an event of the correct type and decode from raw JSON.
 	if len(e.RawData) > 0 {
 		var err error
+		if e.RawData, err = UpcastRawData(ctx, e.EventType, e.SchemaVersion, e.RawData); err != nil {
+			return nil, nil, fmt.Errorf("could not upcast event data: %w", err)
+		}
+
 		if e.data, err = eh.CreateEventData(e.EventType); err != nil {
 			return nil, nil, fmt.Errorf("could not create event data: %w", err)
 		}
@@ -108,6 +114,7 @@ type evt struct {
 	EventType     eh.EventType           `json:"event_type"`
 	RawData       json.RawMessage        `json:"data,omitempty"`
 	data          eh.EventData           `json:"-"`
+	SchemaVersion int                    `json:"schema_version,omitempty"`
 	Timestamp     time.Time              `json:"timestamp"`
 	AggregateType eh.AggregateType       `json:"aggregate_type"`
 	AggregateID   string                 `json:"aggregate_id"`
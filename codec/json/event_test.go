@@ -40,6 +40,7 @@ func TestEventCodec(t *testing.T) {
 		  "StructRef": { "Bool": true, "String": "string", "Number": 42 },
 		  "NullStruct": null
 		},
+		"schema_version": 1,
 		"timestamp": "2009-11-10T23:00:00Z",
 		"aggregate_type": "Aggregate",
 		"aggregate_id": "10a7ec0f-7f2b-46f5-bca1-877b6e33c9fd",
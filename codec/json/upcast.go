@@ -0,0 +1,56 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	eh "github.com/Clarilab/eventhorizon"
+)
+
+// UpcastRawData upcasts raw JSON event data stored with a schema version to the
+// current schema version of the event type, using the upcasters registered with
+// eventhorizon.RegisterUpcaster. The data is returned as is if it is already in
+// the current schema version.
+func UpcastRawData(ctx context.Context, eventType eh.EventType, schemaVersion int, raw []byte) ([]byte, error) {
+	if len(raw) == 0 || !eh.NeedsUpcast(eventType, schemaVersion) {
+		return raw, nil
+	}
+
+	// Decode numbers as json.Number to keep large integers exact and the
+	// integer types when encoding again.
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var data map[string]interface{}
+	if err := dec.Decode(&data); err != nil {
+		return nil, fmt.Errorf("could not unmarshal event data for upcasting: %w", err)
+	}
+
+	data, err := eh.UpcastEventData(ctx, eventType, schemaVersion, data)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal upcasted event data: %w", err)
+	}
+
+	return b, nil
+}
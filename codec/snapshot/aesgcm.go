@@ -0,0 +1,149 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/internal/cryptoutils"
+)
+
+// AESGCMPrefix is the prefix of the name of AES-GCM codecs, followed by the
+// name of the wrapped codec.
+const AESGCMPrefix = "aes-gcm+"
+
+var (
+	// ErrMissingKeyStore is when an AES-GCM codec is created without a key store.
+	ErrMissingKeyStore = errors.New("missing key store")
+	// ErrMissingKeyID is when an AES-GCM codec is created without a key ID.
+	ErrMissingKeyID = errors.New("missing key ID")
+	// ErrKeyIDTooLong is when an AES-GCM codec is created with a key ID longer
+	// than 255 bytes, which can not be stored with the encrypted bytes.
+	ErrKeyIDTooLong = errors.New("key ID is too long")
+	// ErrInvalidCiphertext is when an encrypted snapshot can not be decoded.
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// AESGCM is a codec that encrypts the output of another codec with AES-GCM.
+// The keys are loaded from a key store using key IDs as subjects. The ID of
+// the key is stored with the encrypted bytes, so keys can be rotated by
+// changing the key ID used for encrypting while keeping the old keys in the
+// key store for decrypting older snapshots.
+type AESGCM struct {
+	codec    eh.SnapshotCodec
+	keyStore eh.KeyStore
+	keyID    string
+}
+
+// NewAESGCM creates a new AES-GCM codec that wraps a codec, usually a
+// compressing one as encrypted bytes do not compress. New snapshots are
+// encrypted with the key with keyID, which is created in the key store if
+// it does not exist.
+func NewAESGCM(codec eh.SnapshotCodec, keyStore eh.KeyStore, keyID string) (*AESGCM, error) {
+	if codec == nil {
+		codec = None()
+	}
+
+	if keyStore == nil {
+		return nil, ErrMissingKeyStore
+	}
+
+	if keyID == "" {
+		return nil, ErrMissingKeyID
+	}
+
+	if len(keyID) > 255 {
+		return nil, ErrKeyIDTooLong
+	}
+
+	return &AESGCM{
+		codec:    codec,
+		keyStore: keyStore,
+		keyID:    keyID,
+	}, nil
+}
+
+// Name implements the Name method of the eventhorizon.SnapshotCodec interface.
+func (c *AESGCM) Name() string {
+	return AESGCMPrefix + c.codec.Name()
+}
+
+// Encode implements the Encode method of the eventhorizon.SnapshotCodec interface.
+// The format is the length of the key ID as one byte, the key ID, the nonce
+// and the sealed data.
+func (c *AESGCM) Encode(ctx context.Context, data []byte) ([]byte, error) {
+	data, err := c.codec.Encode(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := c.keyStore.CreateKey(ctx, c.keyID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get key: %w", err)
+	}
+
+	gcm, err := cryptoutils.NewGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, 1+len(c.keyID)+gcm.NonceSize()+len(data)+gcm.Overhead())
+	out = append(out, byte(len(c.keyID)))
+	out = append(out, c.keyID...)
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("could not create nonce: %w", err)
+	}
+
+	out = append(out, nonce...)
+
+	return gcm.Seal(out, nonce, data, nil), nil
+}
+
+// Decode implements the Decode method of the eventhorizon.SnapshotCodec interface.
+func (c *AESGCM) Decode(ctx context.Context, data []byte) ([]byte, error) {
+	if len(data) < 1 || len(data) < 1+int(data[0]) {
+		return nil, ErrInvalidCiphertext
+	}
+
+	keyID := string(data[1 : 1+int(data[0])])
+	data = data[1+len(keyID):]
+
+	key, err := c.keyStore.Key(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get key %q: %w", keyID, err)
+	}
+
+	gcm, err := cryptoutils.NewGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCiphertext, err)
+	}
+
+	return c.codec.Decode(ctx, plaintext)
+}
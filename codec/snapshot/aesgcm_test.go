@@ -0,0 +1,110 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/encryption/memory"
+)
+
+func TestAESGCM(t *testing.T) {
+	ctx := context.Background()
+	keyStore := memory.NewKeyStore()
+	data := []byte(`{"content":"snapshot"}`)
+
+	if _, err := NewAESGCM(Gzip(), nil, "key-1"); !errors.Is(err, ErrMissingKeyStore) {
+		t.Error("there should be a missing key store error:", err)
+	}
+
+	if _, err := NewAESGCM(Gzip(), keyStore, ""); !errors.Is(err, ErrMissingKeyID) {
+		t.Error("there should be a missing key ID error:", err)
+	}
+
+	if _, err := NewAESGCM(Gzip(), keyStore, strings.Repeat("k", 256)); !errors.Is(err, ErrKeyIDTooLong) {
+		t.Error("there should be a key ID too long error:", err)
+	}
+
+	oldCodec, err := NewAESGCM(Gzip(), keyStore, "key-1")
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if oldCodec.Name() != "aes-gcm+gzip" {
+		t.Error("the name should be correct:", oldCodec.Name())
+	}
+
+	oldData, err := oldCodec.Encode(ctx, data)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if bytes.Contains(oldData, []byte("snapshot")) {
+		t.Error("the data should be encrypted")
+	}
+
+	// Rotate the key, snapshots encrypted with the old key should still decode.
+	newCodec, err := NewAESGCM(Gzip(), keyStore, "key-2")
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	newData, err := newCodec.Encode(ctx, data)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	for _, encoded := range [][]byte{oldData, newData} {
+		decoded, err := newCodec.Decode(ctx, encoded)
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		if !bytes.Equal(decoded, data) {
+			t.Error("the decoded data should be correct:", string(decoded))
+		}
+	}
+
+	tampered := append([]byte{}, newData...)
+	tampered[len(tampered)-1] ^= 1
+
+	if _, err := newCodec.Decode(ctx, tampered); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Error("there should be an invalid ciphertext error:", err)
+	}
+
+	if err := keyStore.DeleteKey(ctx, "key-1"); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, err := newCodec.Decode(ctx, oldData); !errors.Is(err, eh.ErrKeyNotFound) {
+		t.Error("there should be a key not found error:", err)
+	}
+
+	codecs := NewCodecs(newCodec)
+
+	name, encoded, err := codecs.Encode(ctx, data)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if decoded, err := codecs.Decode(ctx, name, encoded); err != nil || !bytes.Equal(decoded, data) {
+		t.Error("the data should decode with the codecs:", err, string(decoded))
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snapshot contains codecs for compressing and encrypting the stored
+// bytes of snapshots, for use by snapshot capable event stores.
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+
+	eh "github.com/Clarilab/eventhorizon"
+)
+
+// ErrUnknownCodec is when a snapshot was encoded with a codec that is not known.
+var ErrUnknownCodec = errors.New("unknown snapshot codec")
+
+const (
+	// NoneName is the name of the codec that leaves the bytes as is.
+	NoneName = "none"
+	// GzipName is the name of the gzip codec.
+	GzipName = "gzip"
+	// ZstdName is the name of the zstd codec.
+	ZstdName = "zstd"
+	// SnappyName is the name of the snappy codec.
+	SnappyName = "snappy"
+)
+
+// None returns a codec that leaves the bytes as is.
+func None() eh.SnapshotCodec { return noneCodec{} }
+
+// Gzip returns a codec that compresses with gzip.
+func Gzip() eh.SnapshotCodec { return gzipCodec{} }
+
+// Zstd returns a codec that compresses with zstd.
+func Zstd() eh.SnapshotCodec { return zstdCodec{} }
+
+// Snappy returns a codec that compresses with snappy.
+func Snappy() eh.SnapshotCodec { return snappyCodec{} }
+
+type noneCodec struct{}
+
+func (noneCodec) Name() string { return NoneName }
+
+func (noneCodec) Encode(_ context.Context, data []byte) ([]byte, error) { return data, nil }
+
+func (noneCodec) Decode(_ context.Context, data []byte) ([]byte, error) { return data, nil }
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string { return GzipName }
+
+func (gzipCodec) Encode(_ context.Context, data []byte) ([]byte, error) {
+	var b bytes.Buffer
+
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("could not compress: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("could not compress: %w", err)
+	}
+
+	return b.Bytes(), nil
+}
+
+func (gzipCodec) Decode(_ context.Context, data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress: %w", err)
+	}
+
+	data, err = io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress: %w", err)
+	}
+
+	return data, nil
+}
+
+// The zstd encoder and decoder are safe for concurrent use of EncodeAll and
+// DecodeAll and are expensive to create, so they are shared.
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+type zstdCodec struct{}
+
+func (zstdCodec) Name() string { return ZstdName }
+
+func (zstdCodec) Encode(_ context.Context, data []byte) ([]byte, error) {
+	if err := initZstd(); err != nil {
+		return nil, err
+	}
+
+	return zstdEncoder.EncodeAll(data, nil), nil
+}
+
+func (zstdCodec) Decode(_ context.Context, data []byte) ([]byte, error) {
+	if err := initZstd(); err != nil {
+		return nil, err
+	}
+
+	data, err := zstdDecoder.DecodeAll(data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress: %w", err)
+	}
+
+	return data, nil
+}
+
+func initZstd() error {
+	zstdOnce.Do(func() {
+		if zstdEncoder, zstdErr = zstd.NewWriter(nil); zstdErr != nil {
+			return
+		}
+
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+
+	if zstdErr != nil {
+		return fmt.Errorf("could not create zstd codec: %w", zstdErr)
+	}
+
+	return nil
+}
+
+type snappyCodec struct{}
+
+func (snappyCodec) Name() string { return SnappyName }
+
+func (snappyCodec) Encode(_ context.Context, data []byte) ([]byte, error) {
+	return snappy.Encode(nil, data), nil
+}
+
+func (snappyCodec) Decode(_ context.Context, data []byte) ([]byte, error) {
+	data, err := snappy.Decode(nil, data)
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress: %w", err)
+	}
+
+	return data, nil
+}
+
+// Codecs encodes snapshots with one codec and decodes snapshots with the codec
+// they were encoded with, by its stored name. The built in compression codecs
+// are always known, other codecs used for earlier snapshots (for example an
+// encrypting codec with other settings) must be passed as decoders.
+type Codecs struct {
+	encoder  eh.SnapshotCodec
+	decoders map[string]eh.SnapshotCodec
+}
+
+// NewCodecs creates a new Codecs encoding with the codec, or gzip if nil.
+func NewCodecs(encoder eh.SnapshotCodec, decoders ...eh.SnapshotCodec) *Codecs {
+	if encoder == nil {
+		encoder = Gzip()
+	}
+
+	c := &Codecs{
+		encoder:  encoder,
+		decoders: map[string]eh.SnapshotCodec{},
+	}
+
+	for _, d := range []eh.SnapshotCodec{None(), Gzip(), Zstd(), Snappy()} {
+		c.decoders[d.Name()] = d
+	}
+
+	for _, d := range decoders {
+		if d != nil {
+			c.decoders[d.Name()] = d
+		}
+	}
+
+	c.decoders[encoder.Name()] = encoder
+
+	return c
+}
+
+// Encode encodes the bytes of a snapshot, returning the name of the codec to
+// store with them.
+func (c *Codecs) Encode(ctx context.Context, data []byte) (string, []byte, error) {
+	data, err := c.encoder.Encode(ctx, data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return c.encoder.Name(), data, nil
+}
+
+// Decode decodes the bytes of a snapshot using the codec with the name.
+func (c *Codecs) Decode(ctx context.Context, name string, data []byte) ([]byte, error) {
+	codec, ok := c.decoders[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCodec, name)
+	}
+
+	return codec.Decode(ctx, data)
+}
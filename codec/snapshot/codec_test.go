@@ -0,0 +1,91 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	eh "github.com/Clarilab/eventhorizon"
+)
+
+func TestCodecs(t *testing.T) {
+	ctx := context.Background()
+	data := bytes.Repeat([]byte(`{"content":"snapshot"}`), 100)
+
+	for _, codec := range []eh.SnapshotCodec{None(), Gzip(), Zstd(), Snappy()} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			encoded, err := codec.Encode(ctx, data)
+			if err != nil {
+				t.Fatal("there should be no error:", err)
+			}
+
+			if codec.Name() != NoneName && len(encoded) >= len(data) {
+				t.Error("the data should be compressed:", len(encoded))
+			}
+
+			decoded, err := codec.Decode(ctx, encoded)
+			if err != nil {
+				t.Fatal("there should be no error:", err)
+			}
+
+			if !bytes.Equal(decoded, data) {
+				t.Error("the decoded data should be correct:", string(decoded))
+			}
+		})
+	}
+}
+
+func TestCodecs_Mixed(t *testing.T) {
+	ctx := context.Background()
+	data := []byte(`{"content":"snapshot"}`)
+
+	oldName, oldData, err := NewCodecs(nil).Encode(ctx, data)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if oldName != GzipName {
+		t.Error("the default codec should be gzip:", oldName)
+	}
+
+	codecs := NewCodecs(Zstd())
+
+	newName, newData, err := codecs.Encode(ctx, data)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if newName != ZstdName {
+		t.Error("the codec should be zstd:", newName)
+	}
+
+	for name, encoded := range map[string][]byte{oldName: oldData, newName: newData} {
+		decoded, err := codecs.Decode(ctx, name, encoded)
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		if !bytes.Equal(decoded, data) {
+			t.Error("the decoded data should be correct:", string(decoded))
+		}
+	}
+
+	if _, err := codecs.Decode(ctx, "unknown", newData); !errors.Is(err, ErrUnknownCodec) {
+		t.Error("there should be an unknown codec error:", err)
+	}
+}
@@ -35,6 +35,9 @@ var ErrNilAggregateStore = errors.New("aggregate store is nil")
 // 4. The aggregate stores events in response to the command.
 // 5. The new events are stored in the event store.
 // 6. The events are published on the event bus after a successful store.
+//
+// A save that is rejected with ErrDuplicateSave, which event stores that
+// deduplicate saves return for an already handled command, is treated as success.
 type CommandHandler struct {
 	t         eh.AggregateType
 	store     eh.AggregateStore
@@ -141,5 +144,11 @@ func (h *CommandHandler) handleCommand(ctx context.Context, cmd eh.Command) erro
 		return &eh.AggregateError{Err: err}
 	}
 
-	return h.store.Save(ctx, a)
+	// The events of a retried command could already have been saved, which is
+	// reported by event stores that deduplicate saves.
+	if err := h.store.Save(ctx, a); err != nil && !errors.Is(err, eh.ErrDuplicateSave) {
+		return err
+	}
+
+	return nil
 }
@@ -175,6 +175,35 @@ func TestCommandHandler_ErrorWhenSaving(t *testing.T) {
 	}
 }
 
+func TestCommandHandler_DuplicateSave(t *testing.T) {
+	a, h, store := createAggregateAndHandler(t)
+
+	h.store = &duplicateSaveStore{store}
+	cmd := &mocks.Command{
+		ID:      a.EntityID(),
+		Content: "command1",
+	}
+
+	if err := h.HandleCommand(context.Background(), cmd); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+// duplicateSaveStore is an aggregate store that fails saves with ErrDuplicateSave.
+type duplicateSaveStore struct {
+	*mocks.AggregateStore
+}
+
+func (s *duplicateSaveStore) Save(ctx context.Context, a eh.Aggregate) error {
+	return &eh.AggregateStoreError{
+		Err: &eh.EventStoreError{
+			Err: eh.ErrDuplicateSave,
+			Op:  eh.EventStoreOpSave,
+		},
+		Op: eh.AggregateStoreOpSave,
+	}
+}
+
 func TestCommandHandler_NoHandlers(t *testing.T) {
 	_, h, _ := createAggregateAndHandler(t)
 
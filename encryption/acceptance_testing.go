@@ -0,0 +1,108 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	eh "github.com/Clarilab/eventhorizon"
+)
+
+// KeyStoreAcceptanceTest is the acceptance test that all implementations of
+// KeyStore should pass. It should manually be called from a test case in each
+// implementation:
+//
+//	func TestKeyStore(t *testing.T) {
+//	    store := NewKeyStore()
+//	    encryption.KeyStoreAcceptanceTest(t, store, context.Background())
+//	}
+func KeyStoreAcceptanceTest(t *testing.T, store eh.KeyStore, ctx context.Context) {
+	// Missing subject.
+	if _, err := store.Key(ctx, ""); !errors.Is(err, eh.ErrMissingKeySubject) {
+		t.Error("there should be a missing subject error:", err)
+	}
+
+	if _, err := store.CreateKey(ctx, ""); !errors.Is(err, eh.ErrMissingKeySubject) {
+		t.Error("there should be a missing subject error:", err)
+	}
+
+	if err := store.DeleteKey(ctx, ""); !errors.Is(err, eh.ErrMissingKeySubject) {
+		t.Error("there should be a missing subject error:", err)
+	}
+
+	// No key.
+	if _, err := store.Key(ctx, "subject1"); !errors.Is(err, eh.ErrKeyNotFound) {
+		t.Error("there should be a key not found error:", err)
+	}
+
+	// Create and get.
+	key1, err := store.CreateKey(ctx, "subject1")
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(key1) != 32 {
+		t.Error("the key should be 32 bytes:", len(key1))
+	}
+
+	key, err := store.Key(ctx, "subject1")
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !bytes.Equal(key, key1) {
+		t.Error("the key should be correct:", key)
+	}
+
+	// Create again should return the existing key.
+	if key, err = store.CreateKey(ctx, "subject1"); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !bytes.Equal(key, key1) {
+		t.Error("the key should not be changed:", key)
+	}
+
+	// Another subject.
+	key2, err := store.CreateKey(ctx, "subject2")
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if bytes.Equal(key2, key1) {
+		t.Error("the keys should be different")
+	}
+
+	// Delete.
+	if err := store.DeleteKey(ctx, "subject1"); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if _, err := store.Key(ctx, "subject1"); !errors.Is(err, eh.ErrKeyNotFound) {
+		t.Error("there should be a key not found error:", err)
+	}
+
+	if key, err = store.Key(ctx, "subject2"); err != nil || !bytes.Equal(key, key2) {
+		t.Error("the other key should not be deleted:", err)
+	}
+
+	// Delete again.
+	if err := store.DeleteKey(ctx, "subject1"); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryption
+
+import (
+	"context"
+	"fmt"
+
+	eh "github.com/Clarilab/eventhorizon"
+)
+
+// EventCodec is an eventhorizon.EventCodec that encrypts personal data in
+// events before marshaling them with another codec, and decrypts it after
+// unmarshaling. It can be used with event buses and outboxes to keep personal
+// data encrypted outside of the application.
+type EventCodec struct {
+	codec     eh.EventCodec
+	encrypter *Encrypter
+}
+
+// NewEventCodec creates a new EventCodec that encrypts the events marshaled by
+// another codec using an Encrypter.
+func NewEventCodec(codec eh.EventCodec, encrypter *Encrypter) (*EventCodec, error) {
+	if codec == nil {
+		return nil, fmt.Errorf("missing codec")
+	}
+
+	if encrypter == nil {
+		return nil, fmt.Errorf("missing encrypter")
+	}
+
+	return &EventCodec{
+		codec:     codec,
+		encrypter: encrypter,
+	}, nil
+}
+
+// MarshalEvent implements the MarshalEvent method of the eventhorizon.EventCodec interface.
+func (c *EventCodec) MarshalEvent(ctx context.Context, event eh.Event) ([]byte, error) {
+	encrypted, err := c.encrypter.EncryptEvent(ctx, event)
+	if err != nil {
+		return nil, fmt.Errorf("could not encrypt event: %w", err)
+	}
+
+	return c.codec.MarshalEvent(ctx, encrypted)
+}
+
+// UnmarshalEvent implements the UnmarshalEvent method of the eventhorizon.EventCodec interface.
+func (c *EventCodec) UnmarshalEvent(ctx context.Context, b []byte) (eh.Event, context.Context, error) {
+	event, ctx, err := c.codec.UnmarshalEvent(ctx, b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decrypted, err := c.encrypter.DecryptEvent(ctx, event)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not decrypt event: %w", err)
+	}
+
+	return decrypted, ctx, nil
+}
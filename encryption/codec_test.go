@@ -0,0 +1,84 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryption
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/codec/json"
+	"github.com/Clarilab/eventhorizon/encryption/memory"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+func TestEventCodec(t *testing.T) {
+	ctx := context.Background()
+	keyStore := memory.NewKeyStore()
+
+	encrypter, err := NewEncrypter(keyStore)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, err := NewEventCodec(nil, encrypter); err == nil || err.Error() != "missing codec" {
+		t.Error("there should be a missing codec error:", err)
+	}
+
+	c, err := NewEventCodec(&json.EventCodec{}, encrypter)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event := eh.NewEvent(personEventType, &personEventData{ID: "person1", Name: "Jane Doe"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 1))
+
+	b, err := c.MarshalEvent(ctx, event)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if strings.Contains(string(b), "Jane Doe") || !strings.Contains(string(b), ciphertextPrefix) {
+		t.Error("the personal data should be encrypted:", string(b))
+	}
+
+	decoded, _, err := c.UnmarshalEvent(ctx, b)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := eh.CompareEvents(decoded, event); err != nil {
+		t.Error("the event should be correct:", err)
+	}
+
+	// Shred the data by deleting the key.
+	if err := keyStore.DeleteKey(ctx, id.String()); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if decoded, _, err = c.UnmarshalEvent(ctx, b); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(decoded.Data(), &personEventData{ID: "person1", Name: DefaultRedactedValue}) {
+		t.Error("the personal data should be redacted:", decoded.Data())
+	}
+}
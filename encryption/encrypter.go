@@ -0,0 +1,376 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryption
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jinzhu/copier"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/internal/cryptoutils"
+)
+
+const (
+	// DefaultRedactedValue is the value of personal data fields that can not
+	// be decrypted because the key of the subject has been deleted.
+	DefaultRedactedValue = "[redacted]"
+
+	// ciphertextPrefix marks encrypted values, to leave unencrypted values
+	// (for example from before encryption was used) as is when decrypting.
+	ciphertextPrefix = "eh:pii:v1:"
+)
+
+var (
+	// ErrMissingKeyStore is when an Encrypter is created without a key store.
+	ErrMissingKeyStore = errors.New("missing key store")
+	// ErrUnsupportedField is when a personal data field is not a string, or
+	// a struct, slice or map containing strings.
+	ErrUnsupportedField = errors.New("unsupported personal data field")
+	// ErrInvalidCiphertext is when an encrypted value can not be decoded.
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// SubjectFunc returns the subject used to look up the key for the personal
+// data in an event. It must return the same subject for the event both
+// before encrypting and after decrypting, so it can not depend on encrypted
+// fields.
+type SubjectFunc func(event eh.Event) string
+
+// AggregateSubject uses the aggregate ID of the event as subject, to have one
+// key per aggregate. It is the default SubjectFunc.
+func AggregateSubject(event eh.Event) string {
+	return event.AggregateID().String()
+}
+
+// Encrypter encrypts fields with personal data in event data, using a key per
+// subject from a key store. Fields are marked with the `eh:"pii"` struct tag
+// and must be strings, or structs, slices or maps that contain strings; all
+// strings in a tagged field are encrypted. Deleting the key of a subject from
+// the key store makes its fields decrypt to a redacted value.
+type Encrypter struct {
+	keyStore      eh.KeyStore
+	subject       SubjectFunc
+	redactedValue string
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*Encrypter) error
+
+// WithSubjectFunc uses a custom func to select the subject for an event,
+// instead of the aggregate ID.
+func WithSubjectFunc(f SubjectFunc) Option {
+	return func(e *Encrypter) error {
+		if f == nil {
+			return fmt.Errorf("missing subject func")
+		}
+
+		e.subject = f
+
+		return nil
+	}
+}
+
+// WithRedactedValue uses a custom value for personal data that can not be
+// decrypted, instead of DefaultRedactedValue.
+func WithRedactedValue(v string) Option {
+	return func(e *Encrypter) error {
+		e.redactedValue = v
+
+		return nil
+	}
+}
+
+// NewEncrypter creates a new Encrypter using a key store.
+func NewEncrypter(keyStore eh.KeyStore, options ...Option) (*Encrypter, error) {
+	if keyStore == nil {
+		return nil, ErrMissingKeyStore
+	}
+
+	e := &Encrypter{
+		keyStore:      keyStore,
+		subject:       AggregateSubject,
+		redactedValue: DefaultRedactedValue,
+	}
+
+	for i := range options {
+		if err := options[i](e); err != nil {
+			return nil, fmt.Errorf("error while applying option: %w", err)
+		}
+	}
+
+	return e, nil
+}
+
+// EncryptEvent returns a copy of the event with all personal data fields of
+// its data encrypted, creating a key for the subject if needed. The original
+// event is not modified.
+func (e *Encrypter) EncryptEvent(ctx context.Context, event eh.Event) (eh.Event, error) {
+	if !hasPersonalData(event.Data()) {
+		return event, nil
+	}
+
+	subject := e.subject(event)
+
+	key, err := e.keyStore.CreateKey(ctx, subject)
+	if err != nil {
+		return nil, fmt.Errorf("could not get key: %w", err)
+	}
+
+	gcm, err := cryptoutils.NewGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.transformEvent(event, func(v string) (string, error) {
+		if strings.HasPrefix(v, ciphertextPrefix) {
+			return v, nil
+		}
+
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", fmt.Errorf("could not create nonce: %w", err)
+		}
+
+		ciphertext := gcm.Seal(nonce, nonce, []byte(v), []byte(subject))
+
+		return ciphertextPrefix + base64.RawStdEncoding.EncodeToString(ciphertext), nil
+	})
+}
+
+// DecryptEvent returns a copy of the event with all personal data fields of
+// its data decrypted. Fields are set to the redacted value if the key of the
+// subject has been deleted. The original event is not modified.
+func (e *Encrypter) DecryptEvent(ctx context.Context, event eh.Event) (eh.Event, error) {
+	if !hasPersonalData(event.Data()) {
+		return event, nil
+	}
+
+	subject := e.subject(event)
+
+	var (
+		gcm     cipher.AEAD
+		shred   bool
+		keyRead bool
+	)
+
+	return e.transformEvent(event, func(v string) (string, error) {
+		if !strings.HasPrefix(v, ciphertextPrefix) {
+			return v, nil
+		}
+
+		// Only get the key if there is encrypted data.
+		if !keyRead {
+			keyRead = true
+
+			key, err := e.keyStore.Key(ctx, subject)
+			if errors.Is(err, eh.ErrKeyNotFound) {
+				shred = true
+			} else if err != nil {
+				return "", fmt.Errorf("could not get key: %w", err)
+			} else if gcm, err = cryptoutils.NewGCM(key); err != nil {
+				return "", err
+			}
+		}
+
+		if shred {
+			return e.redactedValue, nil
+		}
+
+		ciphertext, err := base64.RawStdEncoding.DecodeString(v[len(ciphertextPrefix):])
+		if err != nil || len(ciphertext) < gcm.NonceSize() {
+			return "", ErrInvalidCiphertext
+		}
+
+		nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+
+		plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(subject))
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrInvalidCiphertext, err)
+		}
+
+		return string(plaintext), nil
+	})
+}
+
+// transformEvent returns a copy of the event with a deep copy of the data where
+// all strings in personal data fields are transformed.
+func (e *Encrypter) transformEvent(event eh.Event, f func(string) (string, error)) (eh.Event, error) {
+	data, err := eh.CreateEventData(event.EventType())
+	if err != nil {
+		return nil, fmt.Errorf("could not create event data: %w", err)
+	}
+
+	if err := copier.CopyWithOption(data, event.Data(), copier.Option{DeepCopy: true, IgnoreEmpty: true}); err != nil {
+		return nil, fmt.Errorf("could not copy event data: %w", err)
+	}
+
+	if err := transform(reflect.ValueOf(data), false, f); err != nil {
+		return nil, err
+	}
+
+	return eh.NewEvent(
+		event.EventType(),
+		data,
+		event.Timestamp(),
+		eh.ForAggregate(
+			event.AggregateType(),
+			event.AggregateID(),
+			event.Version(),
+		),
+		eh.WithMetadata(event.Metadata()),
+	), nil
+}
+
+// transform calls f for all non-empty strings in v that are in personal data
+// fields, and sets them to the result.
+func transform(v reflect.Value, pii bool, f func(string) (string, error)) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+
+		return transform(v.Elem(), pii, f)
+	case reflect.Interface:
+		if v.IsNil() || !pii {
+			return nil
+		}
+
+		// Values in interfaces are not settable, transform a copy.
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+
+		if err := transform(elem, pii, f); err != nil {
+			return err
+		}
+
+		v.Set(elem)
+	case reflect.Struct:
+		t := v.Type()
+
+		for i := 0; i < v.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			if err := transform(v.Field(i), pii || isPersonalData(field), f); err != nil {
+				return fmt.Errorf("%s: %w", field.Name, err)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := transform(v.Index(i), pii, f); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			// Map values are not settable, transform a copy.
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+
+			if err := transform(elem, pii, f); err != nil {
+				return err
+			}
+
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	case reflect.String:
+		if !pii || v.Len() == 0 {
+			return nil
+		}
+
+		s, err := f(v.String())
+		if err != nil {
+			return err
+		}
+
+		v.SetString(s)
+	default:
+		if pii {
+			return fmt.Errorf("%w: %s", ErrUnsupportedField, v.Type())
+		}
+	}
+
+	return nil
+}
+
+// hasPersonalData returns true if the event data has any fields with personal data.
+func hasPersonalData(data eh.EventData) bool {
+	if data == nil {
+		return false
+	}
+
+	t := reflect.TypeOf(data)
+
+	if has, ok := personalDataTypes.Load(t); ok {
+		return has.(bool)
+	}
+
+	has := typeHasPersonalData(t, map[reflect.Type]bool{})
+	personalDataTypes.Store(t, has)
+
+	return has
+}
+
+var personalDataTypes sync.Map
+
+func typeHasPersonalData(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return typeHasPersonalData(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			if isPersonalData(field) || typeHasPersonalData(field.Type, seen) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isPersonalData returns true if the field is tagged with `eh:"pii"`.
+func isPersonalData(field reflect.StructField) bool {
+	for _, v := range strings.Split(field.Tag.Get("eh"), ",") {
+		if strings.TrimSpace(v) == "pii" {
+			return true
+		}
+	}
+
+	return false
+}
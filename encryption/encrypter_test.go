@@ -0,0 +1,277 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryption
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/encryption/memory"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+const (
+	personEventType      eh.EventType = "EncryptionPersonEvent"
+	unsupportedEventType eh.EventType = "EncryptionUnsupportedEvent"
+)
+
+type personEventData struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name" eh:"pii"`
+	Email   string            `json:"email" eh:"pii"`
+	Address *address          `json:"address" eh:"pii"`
+	Aliases []string          `json:"aliases" eh:"pii"`
+	Notes   map[string]string `json:"notes" eh:"pii"`
+	Friends []friend          `json:"friends"`
+}
+
+type address struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+}
+
+type friend struct {
+	Name  string `json:"name" eh:"pii"`
+	Since int    `json:"since"`
+}
+
+type unsupportedEventData struct {
+	Age int `json:"age" eh:"pii"`
+}
+
+func init() {
+	eh.RegisterEventData(personEventType, func() eh.EventData { return &personEventData{} })
+	eh.RegisterEventData(unsupportedEventType, func() eh.EventData { return &unsupportedEventData{} })
+}
+
+func newPersonEventData() *personEventData {
+	return &personEventData{
+		ID:      "person1",
+		Name:    "Jane Doe",
+		Email:   "jane@example.com",
+		Address: &address{Street: "Main Street 1", City: "Gothenburg"},
+		Aliases: []string{"Jane", ""},
+		Notes:   map[string]string{"note": "secret"},
+		Friends: []friend{{Name: "John Doe", Since: 2009}},
+	}
+}
+
+func TestEncrypter(t *testing.T) {
+	ctx := context.Background()
+	keyStore := memory.NewKeyStore()
+
+	if _, err := NewEncrypter(nil); !errors.Is(err, ErrMissingKeyStore) {
+		t.Error("there should be a missing key store error:", err)
+	}
+
+	e, err := NewEncrypter(keyStore)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event := eh.NewEvent(personEventType, newPersonEventData(), timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 1),
+		eh.WithMetadata(map[string]interface{}{"meta": "data"}))
+
+	encrypted, err := e.EncryptEvent(ctx, event)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(event.Data(), newPersonEventData()) {
+		t.Error("the original event should not be modified:", event.Data())
+	}
+
+	data, ok := encrypted.Data().(*personEventData)
+	if !ok {
+		t.Fatal("the event data should be correct:", encrypted.Data())
+	}
+
+	for _, v := range []string{data.Name, data.Email, data.Address.Street, data.Address.City,
+		data.Aliases[0], data.Notes["note"], data.Friends[0].Name} {
+		if !strings.HasPrefix(v, ciphertextPrefix) {
+			t.Error("the personal data should be encrypted:", v)
+		}
+	}
+
+	if data.ID != "person1" || data.Aliases[1] != "" || data.Friends[0].Since != 2009 {
+		t.Error("the other data should not be encrypted:", data)
+	}
+
+	if encrypted.EventType() != event.EventType() ||
+		!encrypted.Timestamp().Equal(event.Timestamp()) ||
+		encrypted.AggregateType() != event.AggregateType() ||
+		encrypted.AggregateID() != event.AggregateID() ||
+		encrypted.Version() != event.Version() ||
+		!reflect.DeepEqual(encrypted.Metadata(), event.Metadata()) {
+		t.Error("the event should be correct:", encrypted)
+	}
+
+	// Encrypting twice should not change the encrypted values.
+	encryptedTwice, err := e.EncryptEvent(ctx, encrypted)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(encryptedTwice.Data(), encrypted.Data()) {
+		t.Error("the data should not be encrypted twice:", encryptedTwice.Data())
+	}
+
+	decrypted, err := e.DecryptEvent(ctx, encrypted)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := eh.CompareEvents(decrypted, event); err != nil {
+		t.Error("the decrypted event should be correct:", err)
+	}
+
+	// Shred the data by deleting the key.
+	if err := keyStore.DeleteKey(ctx, id.String()); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	redacted, err := e.DecryptEvent(ctx, encrypted)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	expected := &personEventData{
+		ID:      "person1",
+		Name:    DefaultRedactedValue,
+		Email:   DefaultRedactedValue,
+		Address: &address{Street: DefaultRedactedValue, City: DefaultRedactedValue},
+		Aliases: []string{DefaultRedactedValue, ""},
+		Notes:   map[string]string{"note": DefaultRedactedValue},
+		Friends: []friend{{Name: DefaultRedactedValue, Since: 2009}},
+	}
+	if !reflect.DeepEqual(redacted.Data(), expected) {
+		t.Error("the personal data should be redacted:", redacted.Data())
+	}
+
+	// Events without personal data are not changed.
+	other := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "content"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 2))
+
+	if e, err := e.EncryptEvent(ctx, other); err != nil || e != other {
+		t.Error("the event should not be changed:", e, err)
+	}
+
+	// Unsupported fields.
+	unsupported := eh.NewEvent(unsupportedEventType, &unsupportedEventData{Age: 42}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 2))
+
+	if _, err := e.EncryptEvent(ctx, unsupported); !errors.Is(err, ErrUnsupportedField) {
+		t.Error("there should be an unsupported field error:", err)
+	}
+}
+
+func TestEncrypterInvalidCiphertext(t *testing.T) {
+	ctx := context.Background()
+	keyStore := memory.NewKeyStore()
+
+	e, err := NewEncrypter(keyStore)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	encrypted, err := e.EncryptEvent(ctx, eh.NewEvent(personEventType, &personEventData{Name: "name"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 1)))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Decrypting with the key of another subject should fail.
+	otherID := uuid.New()
+	if _, err := keyStore.CreateKey(ctx, otherID.String()); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	tampered := eh.NewEvent(personEventType, encrypted.Data(), timestamp,
+		eh.ForAggregate(mocks.AggregateType, otherID, 1))
+
+	if _, err := e.DecryptEvent(ctx, tampered); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Error("there should be an invalid ciphertext error:", err)
+	}
+
+	invalid := eh.NewEvent(personEventType, &personEventData{Name: ciphertextPrefix + "!"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 1))
+
+	if _, err := e.DecryptEvent(ctx, invalid); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Error("there should be an invalid ciphertext error:", err)
+	}
+}
+
+func TestEncrypterOptions(t *testing.T) {
+	ctx := context.Background()
+	keyStore := memory.NewKeyStore()
+
+	if _, err := NewEncrypter(keyStore, WithSubjectFunc(nil)); err == nil ||
+		err.Error() != "error while applying option: missing subject func" {
+		t.Error("there should be an error:", err)
+	}
+
+	// Use the ID in the data as subject.
+	e, err := NewEncrypter(keyStore,
+		WithSubjectFunc(func(event eh.Event) string {
+			if data, ok := event.Data().(*personEventData); ok {
+				return data.ID
+			}
+
+			return ""
+		}),
+		WithRedactedValue("***"),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event := eh.NewEvent(personEventType, &personEventData{ID: "person1", Name: "name"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+
+	encrypted, err := e.EncryptEvent(ctx, event)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, err := keyStore.Key(ctx, "person1"); err != nil {
+		t.Error("there should be a key for the subject:", err)
+	}
+
+	if err := keyStore.DeleteKey(ctx, "person1"); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	redacted, err := e.DecryptEvent(ctx, encrypted)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(redacted.Data(), &personEventData{ID: "person1", Name: "***"}) {
+		t.Error("the personal data should be redacted:", redacted.Data())
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryption
+
+import (
+	"context"
+	"fmt"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// Replace implements the Replace method of the eventhorizon.EventStoreMaintenance interface.
+func (s *EventStore) Replace(ctx context.Context, event eh.Event) error {
+	maintenance, err := s.maintenance(eh.EventStoreOpReplace)
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := s.encrypt(ctx, []eh.Event{event}, eh.EventStoreOpReplace)
+	if err != nil {
+		return err
+	}
+
+	return maintenance.Replace(ctx, encrypted[0])
+}
+
+// RenameEvent implements the RenameEvent method of the eventhorizon.EventStoreMaintenance interface.
+func (s *EventStore) RenameEvent(ctx context.Context, from, to eh.EventType) error {
+	maintenance, err := s.maintenance(eh.EventStoreOpRename)
+	if err != nil {
+		return err
+	}
+
+	return maintenance.RenameEvent(ctx, from, to)
+}
+
+// Remove implements the Remove method of the eventhorizon.EventStoreMaintenance interface.
+func (s *EventStore) Remove(ctx context.Context, id uuid.UUID) error {
+	maintenance, err := s.maintenance(eh.EventStoreOpRemove)
+	if err != nil {
+		return err
+	}
+
+	return maintenance.Remove(ctx, id)
+}
+
+// Clear implements the Clear method of the eventhorizon.EventStoreMaintenance interface.
+func (s *EventStore) Clear(ctx context.Context) error {
+	maintenance, err := s.maintenance(eh.EventStoreOpClear)
+	if err != nil {
+		return err
+	}
+
+	return maintenance.Clear(ctx)
+}
+
+// Transform implements the Transform method of the eventhorizon.EventStoreTransformer interface.
+// The transform func gets the decrypted events and the transformed events are
+// encrypted again. The matcher gets the encrypted events, as do the changes in
+// the report of a dry run.
+func (s *EventStore) Transform(ctx context.Context, matcher eh.EventMatcher, transform eh.EventTransformFunc, options ...eh.TransformOption) (*eh.TransformReport, error) {
+	transformer, ok := eh.FindEventStore[eh.EventStoreTransformer](s.EventStore)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("event store does not support transforming events"),
+			Op:  eh.EventStoreOpTransform,
+		}
+	}
+
+	return transformer.Transform(ctx, matcher, func(event eh.Event) (eh.Event, error) {
+		decrypted, err := s.encrypter.DecryptEvent(ctx, event)
+		if err != nil {
+			return nil, fmt.Errorf("could not decrypt event: %w", err)
+		}
+
+		transformed, err := transform(decrypted)
+		if err != nil || transformed == nil {
+			return nil, err
+		}
+
+		// Check for changes before encrypting, which always gives a new
+		// ciphertext.
+		if eh.CompareEvents(decrypted, transformed) == nil {
+			return nil, nil
+		}
+
+		encrypted, err := s.encrypter.EncryptEvent(ctx, transformed)
+		if err != nil {
+			return nil, fmt.Errorf("could not encrypt event: %w", err)
+		}
+
+		return encrypted, nil
+	}, options...)
+}
+
+// maintenance returns the maintenance interface of the underlying store.
+func (s *EventStore) maintenance(op eh.EventStoreOperation) (eh.EventStoreMaintenance, error) {
+	maintenance, ok := eh.FindEventStore[eh.EventStoreMaintenance](s.EventStore)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("event store does not support maintenance"),
+			Op:  op,
+		}
+	}
+
+	return maintenance, nil
+}
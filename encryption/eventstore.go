@@ -0,0 +1,361 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryption
+
+import (
+	"context"
+	"fmt"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// EventStore is an eventhorizon.EventStore that encrypts personal data in
+// events before saving them in another event store, and decrypts it when
+// loading. Personal data of subjects with deleted keys is loaded as redacted.
+//
+// The optional event store interfaces are implemented by forwarding to the
+// underlying store, encrypting and decrypting the events, and return an error
+// if the underlying store does not support them. Snapshots are refused with
+// eventhorizon.ErrSnapshotsNotSupported, as they would contain the personal
+// data unencrypted. Event handlers of the underlying store will get the
+// encrypted events, use WithEventHandler to handle the unencrypted events.
+type EventStore struct {
+	eh.EventStore
+	encrypter    *Encrypter
+	eventHandler eh.EventHandler
+}
+
+// EventStoreOption is an option setter used to configure creation.
+type EventStoreOption func(*EventStore) error
+
+// WithEventHandler adds an event handler that will be called with the
+// unencrypted events after saving them.
+func WithEventHandler(h eh.EventHandler) EventStoreOption {
+	return func(s *EventStore) error {
+		if h == nil {
+			return eh.ErrMissingHandler
+		}
+
+		s.eventHandler = h
+
+		return nil
+	}
+}
+
+// NewEventStore creates a new EventStore that encrypts the events of another
+// event store using an Encrypter.
+func NewEventStore(eventStore eh.EventStore, encrypter *Encrypter, options ...EventStoreOption) (*EventStore, error) {
+	if eventStore == nil {
+		return nil, fmt.Errorf("missing event store")
+	}
+
+	if encrypter == nil {
+		return nil, fmt.Errorf("missing encrypter")
+	}
+
+	s := &EventStore{
+		EventStore: eventStore,
+		encrypter:  encrypter,
+	}
+
+	for i := range options {
+		if err := options[i](s); err != nil {
+			return nil, fmt.Errorf("error while applying option: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// Save implements the Save method of the eventhorizon.EventStore interface.
+func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersion int) error {
+	encrypted, err := s.encrypt(ctx, events, eh.EventStoreOpSave)
+	if err != nil {
+		return err
+	}
+
+	if err := s.EventStore.Save(ctx, encrypted, originalVersion); err != nil {
+		return err
+	}
+
+	return s.handleEvents(ctx, events)
+}
+
+// SaveMany implements the SaveMany method of the eventhorizon.MultiStreamEventStore interface.
+func (s *EventStore) SaveMany(ctx context.Context, streams []eh.EventStreamSave) error {
+	store, ok := eh.FindEventStore[eh.MultiStreamEventStore](s.EventStore)
+	if !ok {
+		return &eh.EventStoreError{
+			Err: fmt.Errorf("event store does not support saving multiple streams"),
+			Op:  eh.EventStoreOpSave,
+		}
+	}
+
+	encrypted := make([]eh.EventStreamSave, len(streams))
+
+	for i, stream := range streams {
+		events, err := s.encrypt(ctx, stream.Events, eh.EventStoreOpSave)
+		if err != nil {
+			return err
+		}
+
+		encrypted[i] = eh.EventStreamSave{
+			Events:          events,
+			OriginalVersion: stream.OriginalVersion,
+		}
+	}
+
+	if err := store.SaveMany(ctx, encrypted); err != nil {
+		return err
+	}
+
+	for _, stream := range streams {
+		if err := s.handleEvents(ctx, stream.Events); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handleEvents lets the optional event handler handle the unencrypted events.
+func (s *EventStore) handleEvents(ctx context.Context, events []eh.Event) error {
+	if s.eventHandler == nil {
+		return nil
+	}
+
+	for _, e := range events {
+		if err := s.eventHandler.HandleEvent(ctx, e); err != nil {
+			return &eh.EventHandlerError{
+				Err:   err,
+				Event: e,
+			}
+		}
+	}
+
+	return nil
+}
+
+// Load implements the Load method of the eventhorizon.EventStore interface.
+func (s *EventStore) Load(ctx context.Context, id uuid.UUID) ([]eh.Event, error) {
+	events, err := s.EventStore.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.decrypt(ctx, events)
+}
+
+// LoadFrom implements the LoadFrom method of the eventhorizon.EventStore interface.
+func (s *EventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([]eh.Event, error) {
+	events, err := s.EventStore.LoadFrom(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.decrypt(ctx, events)
+}
+
+// LoadUntil implements the LoadUntil method of the eventhorizon.EventStore interface.
+func (s *EventStore) LoadUntil(ctx context.Context, id uuid.UUID, version int) ([]eh.Event, error) {
+	events, err := s.EventStore.LoadUntil(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.decrypt(ctx, events)
+}
+
+// LoadFromIter implements the LoadFromIter method of the eventhorizon.StreamingEventStore interface.
+func (s *EventStore) LoadFromIter(ctx context.Context, id uuid.UUID, version int) (eh.Iter, error) {
+	store, ok := eh.FindEventStore[eh.StreamingEventStore](s.EventStore)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err:         fmt.Errorf("event store does not support streaming loads"),
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
+	iter, err := store.LoadFromIter(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptIter{iter: iter, encrypter: s.encrypter}, nil
+}
+
+// LoadAllFrom implements the LoadAllFrom method of the eventhorizon.GlobalEventStore interface.
+func (s *EventStore) LoadAllFrom(ctx context.Context, position, limit int) ([]eh.Event, error) {
+	store, ok := eh.FindEventStore[eh.GlobalEventStore](s.EventStore)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("event store does not support global reads"),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+
+	events, err := store.LoadAllFrom(ctx, position, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.decrypt(ctx, events)
+}
+
+// QueryEvents implements the QueryEvents method of the eventhorizon.EventQuerier interface.
+// Personal data is encrypted when matching the query, and only decrypted in
+// the found events.
+func (s *EventStore) QueryEvents(ctx context.Context, query eh.EventQuery) (eh.Iter, error) {
+	querier, ok := eh.FindEventStore[eh.EventQuerier](s.EventStore)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("event store does not support queries"),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+
+	iter, err := querier.QueryEvents(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptIter{iter: iter, encrypter: s.encrypter}, nil
+}
+
+// CheckConsistency implements the CheckConsistency method of the eventhorizon.EventStoreChecker interface.
+func (s *EventStore) CheckConsistency(ctx context.Context, repair bool) ([]eh.Inconsistency, error) {
+	checker, ok := eh.FindEventStore[eh.EventStoreChecker](s.EventStore)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("event store does not support consistency checks"),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+
+	return checker.CheckConsistency(ctx, repair)
+}
+
+// LoadSnapshot implements the LoadSnapshot method of the eventhorizon.SnapshotStore interface.
+// Snapshots are not supported, as they would contain the personal data unencrypted.
+func (s *EventStore) LoadSnapshot(ctx context.Context, id uuid.UUID) (*eh.Snapshot, error) {
+	return nil, &eh.EventStoreError{
+		Err:         eh.ErrSnapshotsNotSupported,
+		Op:          eh.EventStoreOpLoadSnapshot,
+		AggregateID: id,
+	}
+}
+
+// SaveSnapshot implements the SaveSnapshot method of the eventhorizon.SnapshotStore interface.
+// Snapshots are not supported, as they would contain the personal data unencrypted.
+func (s *EventStore) SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot eh.Snapshot) error {
+	return &eh.EventStoreError{
+		Err:         eh.ErrSnapshotsNotSupported,
+		Op:          eh.EventStoreOpSaveSnapshot,
+		AggregateID: id,
+	}
+}
+
+// encrypt returns copies of the events with their personal data encrypted.
+func (s *EventStore) encrypt(ctx context.Context, events []eh.Event, op eh.EventStoreOperation) ([]eh.Event, error) {
+	encrypted := make([]eh.Event, len(events))
+
+	for i, event := range events {
+		var err error
+		if encrypted[i], err = s.encrypter.EncryptEvent(ctx, event); err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              fmt.Errorf("could not encrypt event: %w", err),
+				Op:               op,
+				AggregateType:    event.AggregateType(),
+				AggregateID:      event.AggregateID(),
+				AggregateVersion: event.Version(),
+				Events:           events,
+			}
+		}
+	}
+
+	return encrypted, nil
+}
+
+func (s *EventStore) decrypt(ctx context.Context, events []eh.Event) ([]eh.Event, error) {
+	decrypted := make([]eh.Event, len(events))
+
+	for i, event := range events {
+		var err error
+		if decrypted[i], err = s.encrypter.DecryptEvent(ctx, event); err != nil {
+			return nil, decryptError(err, event, decrypted[:i])
+		}
+	}
+
+	return decrypted, nil
+}
+
+// decryptError returns the error when an event could not be decrypted.
+func decryptError(err error, event eh.Event, decrypted []eh.Event) error {
+	return &eh.EventStoreError{
+		Err:              fmt.Errorf("could not decrypt event: %w", err),
+		Op:               eh.EventStoreOpLoad,
+		AggregateType:    event.AggregateType(),
+		AggregateID:      event.AggregateID(),
+		AggregateVersion: event.Version(),
+		Events:           decrypted,
+	}
+}
+
+// decryptIter decrypts the events of another iterator, the iterator is not
+// thread safe.
+type decryptIter struct {
+	iter      eh.Iter
+	encrypter *Encrypter
+	event     eh.Event
+	err       error
+}
+
+func (i *decryptIter) Next(ctx context.Context) bool {
+	if i.err != nil || !i.iter.Next(ctx) {
+		return false
+	}
+
+	event, ok := i.iter.Value().(eh.Event)
+	if !ok {
+		i.err = fmt.Errorf("invalid event in iterator: %T", i.iter.Value())
+
+		return false
+	}
+
+	decrypted, err := i.encrypter.DecryptEvent(ctx, event)
+	if err != nil {
+		i.err = decryptError(err, event, nil)
+
+		return false
+	}
+
+	i.event = decrypted
+
+	return true
+}
+
+func (i *decryptIter) Value() interface{} {
+	return i.event
+}
+
+func (i *decryptIter) Close(ctx context.Context) error {
+	if err := i.iter.Close(ctx); err != nil {
+		return err
+	}
+
+	return i.err
+}
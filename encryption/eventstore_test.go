@@ -0,0 +1,399 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryption
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/aggregatestore/events"
+	"github.com/Clarilab/eventhorizon/encryption/memory"
+	"github.com/Clarilab/eventhorizon/eventstore"
+	eventstoreMemory "github.com/Clarilab/eventhorizon/eventstore/memory"
+	"github.com/Clarilab/eventhorizon/eventstore/validation"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/tracing"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+const personAggregateType eh.AggregateType = "EncryptionPerson"
+
+type personAggregate struct {
+	*events.AggregateBase
+	name string
+}
+
+func (a *personAggregate) HandleCommand(ctx context.Context, cmd eh.Command) error {
+	return nil
+}
+
+func (a *personAggregate) ApplyEvent(ctx context.Context, event eh.Event) error {
+	if data, ok := event.Data().(*personEventData); ok {
+		a.name = data.Name
+	}
+
+	return nil
+}
+
+func init() {
+	eh.RegisterAggregate(func(id uuid.UUID) eh.Aggregate {
+		return &personAggregate{
+			AggregateBase: events.NewAggregateBase(personAggregateType, id),
+		}
+	})
+}
+
+func TestEventStore(t *testing.T) {
+	ctx := context.Background()
+	keyStore := memory.NewKeyStore()
+
+	encrypter, err := NewEncrypter(keyStore)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	innerStore, err := eventstoreMemory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, err := NewEventStore(nil, encrypter); err == nil || err.Error() != "missing event store" {
+		t.Error("there should be a missing event store error:", err)
+	}
+
+	if _, err := NewEventStore(innerStore, nil); err == nil || err.Error() != "missing encrypter" {
+		t.Error("there should be a missing encrypter error:", err)
+	}
+
+	h := mocks.NewEventHandler("handler")
+
+	store, err := NewEventStore(innerStore, encrypter, WithEventHandler(h))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// The store should work as any other event store.
+	eventstore.AcceptanceTest(t, store, ctx)
+
+	// Snapshots should be refused, as they would not be encrypted.
+	if _, err := store.LoadSnapshot(ctx, uuid.New()); !errors.Is(err, eh.ErrSnapshotsNotSupported) {
+		t.Error("there should be a snapshots not supported error:", err)
+	}
+
+	h.Reset()
+
+	// Save an event with personal data.
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event := eh.NewEvent(personEventType, &personEventData{ID: "person1", Name: "Jane Doe"}, timestamp,
+		eh.ForAggregate(personAggregateType, id, 1))
+
+	if err := store.Save(ctx, []eh.Event{event}, 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// The event handler should get the unencrypted event.
+	if len(h.Events) != 1 {
+		t.Fatal("there should be one handled event:", len(h.Events))
+	}
+
+	if err := eh.CompareEvents(h.Events[0], event); err != nil {
+		t.Error("the handled event should be correct:", err)
+	}
+
+	// The inner store should have the encrypted event.
+	stored, err := innerStore.Load(ctx, id)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(stored) != 1 || !strings.HasPrefix(stored[0].Data().(*personEventData).Name, ciphertextPrefix) {
+		t.Error("the stored event should be encrypted:", stored)
+	}
+
+	// Loading should decrypt.
+	for _, load := range []func() ([]eh.Event, error){
+		func() ([]eh.Event, error) { return store.Load(ctx, id) },
+		func() ([]eh.Event, error) { return store.LoadFrom(ctx, id, 1) },
+		func() ([]eh.Event, error) { return store.LoadUntil(ctx, id, 1) },
+	} {
+		loaded, err := load()
+		if err != nil {
+			t.Error("there should be no error:", err)
+		}
+
+		if len(loaded) != 1 {
+			t.Fatal("there should be one event:", len(loaded))
+		}
+
+		if err := eh.CompareEvents(loaded[0], event, eh.IgnorePositionMetadata()); err != nil {
+			t.Error("the loaded event should be correct:", err)
+		}
+	}
+
+	// Loading the aggregate should work before and after the key is deleted.
+	aggregateStore, err := events.NewAggregateStore(store)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	agg, err := aggregateStore.Load(ctx, personAggregateType, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if name := agg.(*personAggregate).name; name != "Jane Doe" {
+		t.Error("the name should be correct:", name)
+	}
+
+	if err := keyStore.DeleteKey(ctx, id.String()); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	agg, err = aggregateStore.Load(ctx, personAggregateType, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if name := agg.(*personAggregate).name; name != DefaultRedactedValue {
+		t.Error("the name should be redacted:", name)
+	}
+
+	loaded, err := store.Load(ctx, id)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(loaded[0].Data(), &personEventData{ID: "person1", Name: DefaultRedactedValue}) {
+		t.Error("the personal data should be redacted:", loaded[0].Data())
+	}
+}
+
+func TestEventStore_Middleware(t *testing.T) {
+	ctx := context.Background()
+
+	encrypter, err := NewEncrypter(memory.NewKeyStore())
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	innerStore, err := eventstoreMemory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	encryptedStore, err := NewEventStore(innerStore, encrypter)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	store := eh.UseEventStoreMiddleware(encryptedStore,
+		tracing.NewEventStoreMiddleware(),
+		validation.NewEventStoreMiddleware(),
+	)
+
+	id, otherID := uuid.New(), uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	newEvent := func(id uuid.UUID, version int, name string) eh.Event {
+		data := newPersonEventData()
+		data.Name = name
+
+		return eh.NewEvent(personEventType, data, timestamp,
+			eh.ForAggregate(personAggregateType, id, version))
+	}
+
+	// checkEncrypted checks that the inner store has the events encrypted.
+	checkEncrypted := func(id uuid.UUID, count int) {
+		t.Helper()
+
+		stored, err := innerStore.Load(ctx, id)
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		if len(stored) != count {
+			t.Fatal("there should be stored events:", len(stored))
+		}
+
+		for _, e := range stored {
+			if !strings.HasPrefix(e.Data().(*personEventData).Name, ciphertextPrefix) {
+				t.Error("the stored event should be encrypted:", e.Data())
+			}
+		}
+	}
+
+	if err := store.Save(ctx, []eh.Event{newEvent(id, 1, "Jane Doe")}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	multiStreamStore, ok := eh.FindEventStore[eh.MultiStreamEventStore](store)
+	if !ok {
+		t.Fatal("the store should save multiple streams")
+	}
+
+	if err := multiStreamStore.SaveMany(ctx, []eh.EventStreamSave{
+		{Events: []eh.Event{newEvent(id, 2, "Jane Roe")}, OriginalVersion: 1},
+		{Events: []eh.Event{newEvent(otherID, 1, "John Doe")}},
+	}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	invalid := eh.NewEvent(personEventType, &personEventData{}, timestamp,
+		eh.ForAggregate(personAggregateType, otherID, 1))
+	if err := multiStreamStore.SaveMany(ctx, []eh.EventStreamSave{
+		{Events: []eh.Event{invalid}},
+	}); !errors.As(err, new(*eh.EventDataError)) {
+		t.Error("there should be an event data error:", err)
+	}
+
+	checkEncrypted(id, 2)
+	checkEncrypted(otherID, 1)
+
+	// Streaming, global and query loads should decrypt.
+	streamingStore, ok := eh.FindEventStore[eh.StreamingEventStore](store)
+	if !ok {
+		t.Fatal("the store should be a streaming store")
+	}
+
+	iter, err := streamingStore.LoadFromIter(ctx, id, 2)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !iter.Next(ctx) || iter.Value().(eh.Event).Data().(*personEventData).Name != "Jane Roe" {
+		t.Error("the streamed event should be decrypted")
+	}
+
+	if err := iter.Close(ctx); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	globalStore, ok := eh.FindEventStore[eh.GlobalEventStore](store)
+	if !ok {
+		t.Fatal("the store should be a global store")
+	}
+
+	all, err := globalStore.LoadAllFrom(ctx, 1, 0)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(all) != 3 || all[2].Data().(*personEventData).Name != "John Doe" {
+		t.Error("the global events should be decrypted:", all)
+	}
+
+	querier, ok := eh.FindEventStore[eh.EventQuerier](store)
+	if !ok {
+		t.Fatal("the store should be an event querier")
+	}
+
+	iter, err = querier.QueryEvents(ctx, eh.EventQuery{EventTypes: []eh.EventType{personEventType}, Limit: 1})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !iter.Next(ctx) || iter.Value().(eh.Event).Data().(*personEventData).Name != "Jane Doe" {
+		t.Error("the found event should be decrypted")
+	}
+
+	if err := iter.Close(ctx); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Maintenance should validate and encrypt the events.
+	maintenance, ok := eh.FindEventStore[eh.EventStoreMaintenance](store)
+	if !ok {
+		t.Fatal("the store should support maintenance")
+	}
+
+	if err := maintenance.Replace(ctx, newEvent(otherID, 1, "John Roe")); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := maintenance.Replace(ctx, invalid); !errors.As(err, new(*eh.EventDataError)) {
+		t.Error("there should be an event data error:", err)
+	}
+
+	checkEncrypted(otherID, 1)
+
+	transformer, ok := eh.FindEventStore[eh.EventStoreTransformer](store)
+	if !ok {
+		t.Fatal("the store should support transforming events")
+	}
+
+	report, err := transformer.Transform(ctx, eh.MatchEvents{personEventType}, func(event eh.Event) (eh.Event, error) {
+		data := *event.Data().(*personEventData)
+		if data.Name != "Jane Roe" {
+			return event, nil
+		}
+
+		data.Name = "Jane Poe"
+
+		return eh.NewEvent(event.EventType(), &data, event.Timestamp(),
+			eh.ForAggregate(event.AggregateType(), event.AggregateID(), event.Version()),
+			eh.WithMetadata(event.Metadata())), nil
+	})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if report.Changed != 1 {
+		t.Error("one event should be changed:", report.Changed)
+	}
+
+	checkEncrypted(id, 2)
+
+	loaded, err := store.LoadFrom(ctx, id, 2)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if loaded[0].Data().(*personEventData).Name != "Jane Poe" {
+		t.Error("the transformed event should be decrypted:", loaded[0].Data())
+	}
+
+	checker, ok := eh.FindEventStore[eh.EventStoreChecker](store)
+	if !ok {
+		t.Fatal("the store should support consistency checks")
+	}
+
+	if inconsistencies, err := checker.CheckConsistency(ctx, false); err != nil || len(inconsistencies) != 0 {
+		t.Error("there should be no inconsistencies:", inconsistencies, err)
+	}
+
+	// Snapshots should be refused, also through the stack.
+	snapshotStore, ok := eh.FindEventStore[eh.SnapshotStore](store)
+	if !ok {
+		t.Fatal("the store should be a snapshot store")
+	}
+
+	if err := snapshotStore.SaveSnapshot(ctx, id, eh.Snapshot{Version: 1}); !errors.Is(err, eh.ErrSnapshotsNotSupported) {
+		t.Error("there should be a snapshots not supported error:", err)
+	}
+
+	historyStore, ok := eh.FindEventStore[eh.SnapshotHistoryStore](store)
+	if !ok {
+		t.Fatal("the store should be a snapshot history store")
+	}
+
+	if _, err := historyStore.LoadSnapshotAt(ctx, id, 1); !errors.Is(err, eh.ErrSnapshotsNotSupported) {
+		t.Error("there should be a snapshots not supported error:", err)
+	}
+}
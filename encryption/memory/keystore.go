@@ -0,0 +1,97 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sync"
+
+	eh "github.com/Clarilab/eventhorizon"
+)
+
+// KeyStore is an eventhorizon.KeyStore where the keys are stored in memory
+// and not persisted. Useful for testing and experimenting.
+type KeyStore struct {
+	db   map[string][]byte
+	dbMu sync.RWMutex
+}
+
+// NewKeyStore creates a new KeyStore using memory as storage.
+func NewKeyStore() *KeyStore {
+	return &KeyStore{
+		db: map[string][]byte{},
+	}
+}
+
+// Key implements the Key method of the eventhorizon.KeyStore interface.
+func (s *KeyStore) Key(ctx context.Context, subject string) ([]byte, error) {
+	if subject == "" {
+		return nil, eh.ErrMissingKeySubject
+	}
+
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+
+	key, ok := s.db[subject]
+	if !ok {
+		return nil, eh.ErrKeyNotFound
+	}
+
+	return key, nil
+}
+
+// CreateKey implements the CreateKey method of the eventhorizon.KeyStore interface.
+func (s *KeyStore) CreateKey(ctx context.Context, subject string) ([]byte, error) {
+	if subject == "" {
+		return nil, eh.ErrMissingKeySubject
+	}
+
+	s.dbMu.Lock()
+	defer s.dbMu.Unlock()
+
+	if key, ok := s.db[subject]; ok {
+		return key, nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("could not create key: %w", err)
+	}
+
+	s.db[subject] = key
+
+	return key, nil
+}
+
+// DeleteKey implements the DeleteKey method of the eventhorizon.KeyStore interface.
+func (s *KeyStore) DeleteKey(ctx context.Context, subject string) error {
+	if subject == "" {
+		return eh.ErrMissingKeySubject
+	}
+
+	s.dbMu.Lock()
+	defer s.dbMu.Unlock()
+
+	delete(s.db, subject)
+
+	return nil
+}
+
+// Close implements the Close method of the eventhorizon.KeyStore interface.
+func (s *KeyStore) Close() error {
+	return nil
+}
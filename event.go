@@ -103,6 +103,33 @@ func WithGlobalPosition(position int) EventOption {
 	return WithMetadata(md)
 }
 
+// GlobalPosition returns the global event position from the metadata, as set
+// by WithGlobalPosition or by event stores that keep track of the position.
+// The second return value is false if the event has no (valid) position.
+func GlobalPosition(event Event) (int, bool) {
+	if event == nil {
+		return 0, false
+	}
+
+	switch pos := event.Metadata()["position"].(type) {
+	case int:
+		return pos, true
+	case int32:
+		return int(pos), true
+	case int64:
+		return int(pos), true
+	case float64:
+		// Numbers unmarshaled from JSON.
+		return int(pos), true
+	default:
+		return 0, false
+	}
+}
+
+// CommandIDMetadataKey is the event metadata key for the ID of the originating
+// command, as added by FromCommand.
+const CommandIDMetadataKey = "command_id"
+
 // FromCommand adds metadata for the originating command when crating an event.
 // Currently it adds the command type and optionally a command ID (if the
 // CommandIDer interface is implemented).
@@ -112,7 +139,7 @@ func FromCommand(cmd Command) EventOption {
 	}
 
 	if c, ok := cmd.(CommandIDer); ok {
-		md["command_id"] = c.CommandID().String()
+		md[CommandIDMetadataKey] = c.CommandID().String()
 	}
 
 	return WithMetadata(md)
@@ -263,5 +290,18 @@ func CreateEventData(eventType EventType) (EventData, error) {
 	return nil, ErrEventDataNotRegistered
 }
 
+// RegisteredEventData returns the factories of all registered event data types.
+func RegisteredEventData() map[EventType]func() EventData {
+	eventDataFactoriesMu.RLock()
+	defer eventDataFactoriesMu.RUnlock()
+
+	mapCopy := make(map[EventType]func() EventData, len(eventDataFactories))
+	for key, val := range eventDataFactories {
+		mapCopy[key] = val
+	}
+
+	return mapCopy
+}
+
 var eventDataFactories = make(map[EventType]func() EventData)
 var eventDataFactoriesMu sync.RWMutex
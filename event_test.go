@@ -97,6 +97,39 @@ func TestNewEvent(t *testing.T) {
 	}
 }
 
+func TestGlobalPosition(t *testing.T) {
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	if _, ok := GlobalPosition(nil); ok {
+		t.Error("there should be no position for a nil event")
+	}
+
+	event := NewEvent(TestEventType, nil, timestamp)
+	if _, ok := GlobalPosition(event); ok {
+		t.Error("there should be no position:", event.Metadata())
+	}
+
+	for _, p := range []interface{}{42, int32(42), int64(42), 42.0} {
+		event = NewEvent(TestEventType, nil, timestamp,
+			WithMetadata(map[string]interface{}{"position": p}))
+
+		if pos, ok := GlobalPosition(event); !ok || pos != 42 {
+			t.Errorf("the position should be correct for %T: %d", p, pos)
+		}
+	}
+
+	event = NewEvent(TestEventType, nil, timestamp, WithGlobalPosition(7))
+	if pos, ok := GlobalPosition(event); !ok || pos != 7 {
+		t.Error("the position should be correct:", pos)
+	}
+
+	event = NewEvent(TestEventType, nil, timestamp,
+		WithMetadata(map[string]interface{}{"position": "42"}))
+	if _, ok := GlobalPosition(event); ok {
+		t.Error("there should be no position for an invalid type")
+	}
+}
+
 func TestCreateEventData(t *testing.T) {
 	data, err := CreateEventData(TestEventRegisterType)
 	if !errors.Is(err, ErrEventDataNotRegistered) {
@@ -120,7 +153,15 @@ func TestCreateEventData(t *testing.T) {
 		t.Errorf("the event type should be correct: %T", data)
 	}
 
+	if _, ok := RegisteredEventData()[TestEventRegisterType]; !ok {
+		t.Error("the event data should be listed as registered")
+	}
+
 	UnregisterEventData(TestEventRegisterType)
+
+	if _, ok := RegisteredEventData()[TestEventRegisterType]; ok {
+		t.Error("the event data should not be listed as registered")
+	}
 }
 
 func TestRegisterEventEmptyName(t *testing.T) {
@@ -16,6 +16,9 @@ package eventhorizon
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
 
 	"github.com/Clarilab/eventhorizon/uuid"
 )
@@ -36,3 +39,272 @@ type EventStoreMaintenance interface {
 	// Clear clears the event storage.
 	Clear(ctx context.Context) error
 }
+
+// EventStoreTransformer is an optional maintenance interface for event stores
+// that can rewrite events in bulk.
+// NOTE: Should not be used in apps, useful for migration tools etc.
+type EventStoreTransformer interface {
+	// Transform rewrites all events matching the matcher with the result of the
+	// transform func, in batches ordered the same way for every run. The
+	// transformed events must keep the aggregate ID, type and version of the
+	// original events. Events for which the transform func returns nil or an
+	// equal event are left unchanged. The returned report is also set when an
+	// error occurs, its checkpoint can be used to resume the transformation.
+	// The matcher should only use the type, aggregate, timestamp and metadata
+	// of the events, as stores can match events before decoding their data.
+	Transform(ctx context.Context, matcher EventMatcher, transform EventTransformFunc, options ...TransformOption) (*TransformReport, error)
+}
+
+// EventTransformFunc is a function that transforms an event.
+type EventTransformFunc func(Event) (Event, error)
+
+// ErrInvalidTransformedEvent is when a transformed event does not keep the
+// aggregate ID, type and version of the original event.
+var ErrInvalidTransformedEvent = errors.New("invalid transformed event")
+
+// DefaultTransformBatchSize is the default number of events per batch in Transform.
+const DefaultTransformBatchSize = 100
+
+// TransformOptions are the options of a Transform operation, see NewTransformOptions.
+type TransformOptions struct {
+	// DryRun only reports the changes without storing them.
+	DryRun bool
+	// BatchSize is the number of matched events per batch.
+	BatchSize int
+	// Checkpoint is the checkpoint of a previous run to resume after.
+	Checkpoint string
+	// Progress is called after each completed batch.
+	Progress func(ctx context.Context, report TransformReport) error
+}
+
+// TransformOption is an option for a Transform operation.
+type TransformOption func(*TransformOptions)
+
+// NewTransformOptions creates the options for a Transform operation, used by
+// the event store implementations.
+func NewTransformOptions(options ...TransformOption) TransformOptions {
+	o := TransformOptions{
+		BatchSize: DefaultTransformBatchSize,
+	}
+
+	for _, option := range options {
+		option(&o)
+	}
+
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultTransformBatchSize
+	}
+
+	return o
+}
+
+// WithTransformDryRun reports what would change without storing any events,
+// the changes are included in the report.
+func WithTransformDryRun() TransformOption {
+	return func(o *TransformOptions) {
+		o.DryRun = true
+	}
+}
+
+// WithTransformBatchSize sets the number of matched events per batch,
+// default DefaultTransformBatchSize.
+func WithTransformBatchSize(size int) TransformOption {
+	return func(o *TransformOptions) {
+		o.BatchSize = size
+	}
+}
+
+// WithTransformCheckpoint resumes an interrupted transformation after the
+// checkpoint of a previous report.
+func WithTransformCheckpoint(checkpoint string) TransformOption {
+	return func(o *TransformOptions) {
+		o.Checkpoint = checkpoint
+	}
+}
+
+// WithTransformProgress sets a func that is called after each completed batch,
+// for example to persist the checkpoint of the report. Returning an error
+// stops the transformation.
+func WithTransformProgress(f func(ctx context.Context, report TransformReport) error) TransformOption {
+	return func(o *TransformOptions) {
+		o.Progress = f
+	}
+}
+
+// TransformReport is the result of a Transform operation.
+type TransformReport struct {
+	// Matched is the number of events that matched.
+	Matched int
+	// Changed is the number of events that were changed, or would be in a dry-run.
+	Changed int
+	// Changes are the events that would be changed, only set in a dry-run.
+	Changes []TransformChange
+	// Checkpoint is an opaque position after the last completed batch, used
+	// to resume with WithTransformCheckpoint.
+	Checkpoint string
+}
+
+// TransformChange is a change of an event in a Transform operation.
+type TransformChange struct {
+	Before Event
+	After  Event
+}
+
+// TransformEvent runs the transform func on an event and validates the result,
+// used by the event store implementations. Returns nil if the event is unchanged.
+func TransformEvent(event Event, transform EventTransformFunc) (Event, error) {
+	transformed, err := transform(event)
+	if err != nil {
+		return nil, err
+	}
+
+	if transformed == nil || CompareEvents(event, transformed) == nil {
+		return nil, nil
+	}
+
+	if transformed.AggregateID() != event.AggregateID() ||
+		transformed.AggregateType() != event.AggregateType() ||
+		transformed.Version() != event.Version() {
+		return nil, ErrInvalidTransformedEvent
+	}
+
+	return transformed, nil
+}
+
+// EventStoreChecker is an optional maintenance interface for event stores that
+// can check the stored events for inconsistencies.
+// NOTE: Should not be used in apps, useful for maintenance tools etc.
+type EventStoreChecker interface {
+	// CheckConsistency scans all events and returns the found inconsistencies.
+	// With repair the stream records that disagree with their events are
+	// updated, which is marked in the returned inconsistencies.
+	CheckConsistency(ctx context.Context, repair bool) ([]Inconsistency, error)
+}
+
+// InconsistencyKind is the kind of an inconsistency found by an EventStoreChecker.
+type InconsistencyKind string
+
+const (
+	// InconsistencyVersionGap is when versions are missing in the events of an aggregate.
+	InconsistencyVersionGap InconsistencyKind = "version_gap"
+	// InconsistencyDuplicateVersion is when an aggregate has multiple events with a version.
+	InconsistencyDuplicateVersion InconsistencyKind = "duplicate_version"
+	// InconsistencyStreamMismatch is when the stored version or position of an
+	// aggregate does not match its events.
+	InconsistencyStreamMismatch InconsistencyKind = "stream_mismatch"
+	// InconsistencyUnregisteredEventData is when events have data of a type
+	// that is not registered with RegisterEventData.
+	InconsistencyUnregisteredEventData InconsistencyKind = "unregistered_event_data"
+	// InconsistencySnapshotAhead is when a snapshot has a newer version than the aggregate.
+	InconsistencySnapshotAhead InconsistencyKind = "snapshot_ahead"
+)
+
+// Inconsistency is an inconsistency found by an EventStoreChecker.
+type Inconsistency struct {
+	// Kind is the kind of inconsistency.
+	Kind InconsistencyKind
+	// AggregateType is the type of the aggregate, if known.
+	AggregateType AggregateType
+	// AggregateID is the ID of the aggregate, if the inconsistency is for one.
+	AggregateID uuid.UUID
+	// Version is the version of the event or snapshot, if applicable.
+	Version int
+	// EventType is the type of the events with unregistered data.
+	EventType EventType
+	// Details describes the inconsistency.
+	Details string
+	// Repaired is true if the inconsistency has been repaired.
+	Repaired bool
+}
+
+// String implements the String method of the fmt.Stringer interface.
+func (i Inconsistency) String() string {
+	s := string(i.Kind)
+
+	if i.AggregateID != uuid.Nil {
+		s += fmt.Sprintf(" %s(%s)", i.AggregateType, i.AggregateID)
+	}
+
+	if i.Version != 0 {
+		s += fmt.Sprintf(" v%d", i.Version)
+	}
+
+	if i.EventType != "" {
+		s += " " + string(i.EventType)
+	}
+
+	if i.Details != "" {
+		s += ": " + i.Details
+	}
+
+	if i.Repaired {
+		s += " (repaired)"
+	}
+
+	return s
+}
+
+// CheckEventVersions checks the versions of the events of an aggregate, in
+// ascending order, for gaps and duplicates. The versions are required to
+// start at 1 if fromStart is set, used by the event store implementations.
+func CheckEventVersions(aggregateType AggregateType, id uuid.UUID, versions []int, fromStart bool) []Inconsistency {
+	var inconsistencies []Inconsistency
+
+	for i, v := range versions {
+		prev := 0
+		if i > 0 {
+			prev = versions[i-1]
+		} else if !fromStart {
+			continue
+		}
+
+		switch {
+		case v == prev:
+			inconsistencies = append(inconsistencies, Inconsistency{
+				Kind:          InconsistencyDuplicateVersion,
+				AggregateType: aggregateType,
+				AggregateID:   id,
+				Version:       v,
+			})
+		case v > prev+1:
+			inconsistencies = append(inconsistencies, Inconsistency{
+				Kind:          InconsistencyVersionGap,
+				AggregateType: aggregateType,
+				AggregateID:   id,
+				Version:       prev + 1,
+				Details:       fmt.Sprintf("missing versions %d to %d", prev+1, v-1),
+			})
+		}
+	}
+
+	return inconsistencies
+}
+
+// IsEventDataRegistered returns true if the data of the event type has been
+// registered with RegisterEventData.
+func IsEventDataRegistered(eventType EventType) bool {
+	_, err := CreateEventData(eventType)
+
+	return !errors.Is(err, ErrEventDataNotRegistered)
+}
+
+// UnregisteredEventDataInconsistencies returns the inconsistencies for counts
+// of events with unregistered data per event type, sorted by event type. Used
+// by the event store implementations.
+func UnregisteredEventDataInconsistencies(counts map[EventType]int) []Inconsistency {
+	inconsistencies := make([]Inconsistency, 0, len(counts))
+
+	for eventType, n := range counts {
+		inconsistencies = append(inconsistencies, Inconsistency{
+			Kind:      InconsistencyUnregisteredEventData,
+			EventType: eventType,
+			Details:   fmt.Sprintf("%d events", n),
+		})
+	}
+
+	sort.Slice(inconsistencies, func(i, j int) bool {
+		return inconsistencies[i].EventType < inconsistencies[j].EventType
+	})
+
+	return inconsistencies
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+func TestTransformEvent(t *testing.T) {
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event := NewEvent("TestEvent", nil, timestamp,
+		ForAggregate("TestAggregate", id, 1))
+
+	// Unchanged events.
+	for _, transform := range []EventTransformFunc{
+		func(e Event) (Event, error) { return nil, nil },
+		func(e Event) (Event, error) { return e, nil },
+		func(e Event) (Event, error) {
+			return NewEvent("TestEvent", nil, timestamp, ForAggregate("TestAggregate", id, 1)), nil
+		},
+	} {
+		transformed, err := TransformEvent(event, transform)
+		if err != nil {
+			t.Error("there should be no error:", err)
+		}
+
+		if transformed != nil {
+			t.Error("there should be no transformed event:", transformed)
+		}
+	}
+
+	// Changed event.
+	transformed, err := TransformEvent(event, func(e Event) (Event, error) {
+		return NewEvent("TestEventOther", nil, timestamp, ForAggregate("TestAggregate", id, 1)), nil
+	})
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if transformed == nil || transformed.EventType() != "TestEventOther" {
+		t.Error("the event should be transformed:", transformed)
+	}
+
+	// Invalid transformed events.
+	for _, invalid := range []Event{
+		NewEvent("TestEvent", nil, timestamp, ForAggregate("TestAggregate", uuid.New(), 1)),
+		NewEvent("TestEvent", nil, timestamp, ForAggregate("TestAggregateOther", id, 1)),
+		NewEvent("TestEvent", nil, timestamp, ForAggregate("TestAggregate", id, 2)),
+	} {
+		invalid := invalid
+
+		if _, err := TransformEvent(event, func(e Event) (Event, error) {
+			return invalid, nil
+		}); !errors.Is(err, ErrInvalidTransformedEvent) {
+			t.Error("there should be a ErrInvalidTransformedEvent error:", err)
+		}
+	}
+
+	// Transform error.
+	transformErr := errors.New("transform error")
+
+	if _, err := TransformEvent(event, func(e Event) (Event, error) {
+		return nil, transformErr
+	}); !errors.Is(err, transformErr) {
+		t.Error("there should be a transform error:", err)
+	}
+}
+
+func TestNewTransformOptions(t *testing.T) {
+	opts := NewTransformOptions()
+	if opts.BatchSize != DefaultTransformBatchSize || opts.DryRun || opts.Checkpoint != "" {
+		t.Error("the default options should be correct:", opts)
+	}
+
+	opts = NewTransformOptions(
+		WithTransformDryRun(),
+		WithTransformBatchSize(10),
+		WithTransformCheckpoint("42"),
+	)
+	if opts.BatchSize != 10 || !opts.DryRun || opts.Checkpoint != "42" {
+		t.Error("the options should be correct:", opts)
+	}
+
+	if opts := NewTransformOptions(WithTransformBatchSize(0)); opts.BatchSize != DefaultTransformBatchSize {
+		t.Error("an invalid batch size should use the default:", opts.BatchSize)
+	}
+}
+
+func TestCheckEventVersions(t *testing.T) {
+	id := uuid.New()
+
+	if inconsistencies := CheckEventVersions("TestAggregate", id, []int{1, 2, 3}, true); len(inconsistencies) != 0 {
+		t.Error("there should be no inconsistencies:", inconsistencies)
+	}
+
+	expected := []Inconsistency{
+		{
+			Kind:          InconsistencyVersionGap,
+			AggregateType: "TestAggregate",
+			AggregateID:   id,
+			Version:       1,
+			Details:       "missing versions 1 to 1",
+		},
+		{
+			Kind:          InconsistencyDuplicateVersion,
+			AggregateType: "TestAggregate",
+			AggregateID:   id,
+			Version:       3,
+		},
+		{
+			Kind:          InconsistencyVersionGap,
+			AggregateType: "TestAggregate",
+			AggregateID:   id,
+			Version:       4,
+			Details:       "missing versions 4 to 5",
+		},
+	}
+
+	inconsistencies := CheckEventVersions("TestAggregate", id, []int{2, 3, 3, 6}, true)
+	if !reflect.DeepEqual(inconsistencies, expected) {
+		t.Errorf("the inconsistencies should be correct:\ngot:  %v\nwant: %v", inconsistencies, expected)
+	}
+
+	// Archived events before the first version are not a gap.
+	inconsistencies = CheckEventVersions("TestAggregate", id, []int{2, 3, 3, 6}, false)
+	if !reflect.DeepEqual(inconsistencies, expected[1:]) {
+		t.Errorf("the inconsistencies should be correct:\ngot:  %v\nwant: %v", inconsistencies, expected[1:])
+	}
+}
+
+func TestIsEventDataRegistered(t *testing.T) {
+	RegisterEventData("RegisteredTestEvent", func() EventData { return &struct{}{} })
+	defer UnregisterEventData("RegisteredTestEvent")
+
+	if !IsEventDataRegistered("RegisteredTestEvent") {
+		t.Error("the event data should be registered")
+	}
+
+	if IsEventDataRegistered("UnregisteredTestEvent") {
+		t.Error("the event data should not be registered")
+	}
+}
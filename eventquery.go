@@ -0,0 +1,100 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// EventQuerier is an optional interface for event stores that can find events
+// across aggregates, for example for operational debugging.
+type EventQuerier interface {
+	// QueryEvents finds events matching the query, ordered by the order they
+	// were saved in. The returned Iter has eh.Event values and should be used
+	// to stream large results, it must always be closed.
+	QueryEvents(ctx context.Context, query EventQuery) (Iter, error)
+}
+
+// EventQuery is a query for events, where all set criteria must match. An
+// empty query matches all events.
+type EventQuery struct {
+	// EventTypes matches events of any of the types.
+	EventTypes []EventType
+	// AggregateTypes matches events for any of the aggregate types.
+	AggregateTypes []AggregateType
+	// From matches events with a timestamp at or after it, if set.
+	From time.Time
+	// Until matches events with a timestamp before it, if set.
+	Until time.Time
+	// Metadata matches events that have all the metadata keys and values.
+	Metadata map[string]interface{}
+	// Limit is the maximum number of events to find, 0 or less finds all.
+	Limit int
+}
+
+// Matches returns true if the event matches all criteria of the query, not
+// taking the limit into account.
+func (q EventQuery) Matches(event Event) bool {
+	if len(q.EventTypes) > 0 {
+		found := false
+
+		for _, t := range q.EventTypes {
+			if event.EventType() == t {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(q.AggregateTypes) > 0 {
+		found := false
+
+		for _, t := range q.AggregateTypes {
+			if event.AggregateType() == t {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if !q.From.IsZero() && event.Timestamp().Before(q.From) {
+		return false
+	}
+
+	if !q.Until.IsZero() && !event.Timestamp().Before(q.Until) {
+		return false
+	}
+
+	for k, v := range q.Metadata {
+		mv, ok := event.Metadata()[k]
+		if !ok || !reflect.DeepEqual(mv, v) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+func TestEventQueryMatches(t *testing.T) {
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event := NewEvent(TestEventType, &TestEventData{"event1"}, timestamp,
+		ForAggregate(TestAggregateType, uuid.New(), 1),
+		WithMetadata(map[string]interface{}{"user": "user1", "num": 42}))
+
+	testCases := map[string]struct {
+		query   EventQuery
+		matches bool
+	}{
+		"empty":                       {EventQuery{}, true},
+		"event type":                  {EventQuery{EventTypes: []EventType{"other", TestEventType}}, true},
+		"other event type":            {EventQuery{EventTypes: []EventType{"other"}}, false},
+		"aggregate type":              {EventQuery{AggregateTypes: []AggregateType{TestAggregateType}}, true},
+		"other aggregate type":        {EventQuery{AggregateTypes: []AggregateType{"other"}}, false},
+		"from":                        {EventQuery{From: timestamp}, true},
+		"from after":                  {EventQuery{From: timestamp.Add(time.Second)}, false},
+		"until":                       {EventQuery{Until: timestamp.Add(time.Second)}, true},
+		"until exclusive":             {EventQuery{Until: timestamp}, false},
+		"metadata":                    {EventQuery{Metadata: map[string]interface{}{"user": "user1", "num": 42}}, true},
+		"other metadata value":        {EventQuery{Metadata: map[string]interface{}{"user": "user2"}}, false},
+		"missing metadata":            {EventQuery{Metadata: map[string]interface{}{"other": "user1"}}, false},
+		"all criteria":                {EventQuery{EventTypes: []EventType{TestEventType}, AggregateTypes: []AggregateType{TestAggregateType}, From: timestamp, Until: timestamp.Add(time.Hour), Metadata: map[string]interface{}{"user": "user1"}}, true},
+		"all criteria but event type": {EventQuery{EventTypes: []EventType{"other"}, AggregateTypes: []AggregateType{TestAggregateType}, From: timestamp, Until: timestamp.Add(time.Hour), Metadata: map[string]interface{}{"user": "user1"}}, false},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if m := tc.query.Matches(event); m != tc.matches {
+				t.Error("the query should match:", tc.matches, m)
+			}
+		})
+	}
+}
@@ -25,7 +25,8 @@ import (
 
 // EventStore is an interface for an event sourcing event store.
 type EventStore interface {
-	// Save appends all events in the event stream to the store.
+	// Save appends all events in the event stream to the store. The original
+	// version is the version of the aggregate that the events are based on.
 	Save(ctx context.Context, events []Event, originalVersion int) error
 
 	// Load loads all events for the aggregate id from the store.
@@ -41,12 +42,108 @@ type EventStore interface {
 	Close() error
 }
 
+// Expected version modes that can be used as the original version when saving
+// events, instead of an exact version of the aggregate. They are supported by
+// the memory, mongodb and mongodb_v2 event stores, but not by the file and SQL
+// event stores.
+const (
+	// AnyVersion appends the events to the aggregate regardless of its current
+	// version, the stream is created if it does not exist. The versions of the
+	// events are assigned by the store, continuing from the current version.
+	AnyVersion = -1
+	// NoStream saves the events only if there are no events for the aggregate,
+	// the events should start at version 1.
+	NoStream = -2
+	// StreamExists appends the events only if there are events for the aggregate.
+	// The versions of the events are assigned by the store, continuing from the
+	// current version.
+	StreamExists = -3
+)
+
+// EventWithVersion returns a new event with the version set, used by the event
+// stores that assign the versions of the events for the expected version modes.
+func EventWithVersion(event Event, version int) Event {
+	return NewEvent(
+		event.EventType(),
+		event.Data(),
+		event.Timestamp(),
+		ForAggregate(event.AggregateType(), event.AggregateID(), version),
+		WithMetadata(event.Metadata()),
+	)
+}
+
+// GlobalEventStore is an optional interface for event stores that keep track of
+// the global position of all saved events, in addition to the aggregate version.
+// It can be used to read all events in the order they were saved, for example
+// to rebuild projections or to feed integrations from a single ordered log.
+type GlobalEventStore interface {
+	// LoadAllFrom loads events from all aggregates ordered by their global
+	// position, starting from (and including) the given position. At most limit
+	// events are returned, a limit of 0 or less loads all remaining events.
+	// The global position of each event is set in its metadata, see GlobalPosition.
+	LoadAllFrom(ctx context.Context, position, limit int) ([]Event, error)
+}
+
+// GlobalEventWatcher is an optional interface for global event stores that can
+// notify about newly saved events, used for live tailing of the global stream.
+type GlobalEventWatcher interface {
+	// WatchAll returns a channel that receives a notification when new events
+	// have been saved. Multiple saves can be coalesced into one notification,
+	// the new events should be read with LoadAllFrom of the GlobalEventStore.
+	// The channel is closed when the context is cancelled.
+	WatchAll(ctx context.Context) (<-chan struct{}, error)
+}
+
+// StreamingEventStore is an optional interface for event stores that can load
+// the events of an aggregate incrementally, which bounds the memory used when
+// loading aggregates with very many events.
+type StreamingEventStore interface {
+	// LoadFromIter loads all events from version for the aggregate id as an
+	// iterator of Events in version order. An aggregate without events gives
+	// an iterator without values. The iterator must be closed after use.
+	LoadFromIter(ctx context.Context, id uuid.UUID, version int) (Iter, error)
+}
+
+// EventStreamSave is the events to save for one aggregate in a SaveMany
+// operation, together with the original version of the aggregate.
+type EventStreamSave struct {
+	// Events are the events to append to the stream of the aggregate.
+	Events []Event
+	// OriginalVersion is the version of the aggregate that the events are
+	// based on, used for optimistic concurrency control like in Save.
+	OriginalVersion int
+}
+
+// MultiStreamEventStore is an optional interface for event stores that can save
+// events for multiple aggregates atomically.
+type MultiStreamEventStore interface {
+	// SaveMany appends the events of all streams to the store in a single
+	// transaction, either all events are saved or none of them. Each stream is
+	// validated like in Save and must be for a different aggregate.
+	SaveMany(ctx context.Context, streams []EventStreamSave) error
+}
+
 // SnapshotStore is an interface for snapshot store.
 type SnapshotStore interface {
 	LoadSnapshot(ctx context.Context, id uuid.UUID) (*Snapshot, error)
 	SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot Snapshot) error
 }
 
+// SnapshotHistoryStore is an optional interface for snapshot stores that keep
+// the last snapshots of each aggregate instead of only the latest one, older
+// snapshots are pruned when saving according to the retention of the store.
+type SnapshotHistoryStore interface {
+	SnapshotStore
+
+	// LoadSnapshotAt loads the latest snapshot of an aggregate with a version
+	// of at most maxVersion. Returns nil if there is no such snapshot.
+	LoadSnapshotAt(ctx context.Context, id uuid.UUID, maxVersion int) (*Snapshot, error)
+
+	// RemoveSnapshotsAfter removes all snapshots of an aggregate with a version
+	// after the version, which can be used to roll back bad snapshots.
+	RemoveSnapshotsAfter(ctx context.Context, id uuid.UUID, version int) error
+}
+
 var (
 	// Missing events for save operation.
 	ErrMissingEvents = errors.New("missing events")
@@ -60,6 +157,19 @@ var (
 	ErrEventConflictFromOtherSave = errors.New("event conflict from other save")
 	// No matching event could be found (for maintenance operations etc).
 	ErrEventNotFound = errors.New("event not found")
+	// Multiple streams in the same save operation are for the same aggregate.
+	ErrDuplicateEventStream = errors.New("duplicate event stream")
+	// There are already events for the aggregate when saving with NoStream.
+	ErrStreamExists = errors.New("stream already exists")
+	// There are no events for the aggregate when saving with StreamExists.
+	ErrStreamNotFound = errors.New("stream not found")
+	// The events have already been saved for the aggregate by an earlier save
+	// with the same idempotency key, for example by a retried command.
+	ErrDuplicateSave = errors.New("duplicate save")
+	// Snapshots are not supported by the store, for example by a wrapper store
+	// when the wrapped store does not support them. Stores that return it are
+	// handled like stores without snapshots by the aggregate store.
+	ErrSnapshotsNotSupported = errors.New("event store does not support snapshots")
 )
 
 // EventStoreOperation is the operation done when an error happened.
@@ -78,11 +188,17 @@ const (
 	EventStoreOpRemove = "remove"
 	// Errors during clearing of the event store.
 	EventStoreOpClear = "clear"
+	// Errors during transforming of events.
+	EventStoreOpTransform = "transform"
+	// Errors during truncating of events.
+	EventStoreOpTruncate = "truncate"
 
 	// Errors during loading of snapshot.
 	EventStoreOpLoadSnapshot = "load_snapshot"
 	// Errors during saving of snapshot.
 	EventStoreOpSaveSnapshot = "save_snapshot"
+	// Errors during removing of snapshots.
+	EventStoreOpRemoveSnapshots = "remove_snapshots"
 )
 
 // EventStoreError is an error in the event store.
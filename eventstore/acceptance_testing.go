@@ -25,7 +25,6 @@ import (
 	eh "github.com/Clarilab/eventhorizon"
 	"github.com/Clarilab/eventhorizon/mocks"
 	"github.com/Clarilab/eventhorizon/uuid"
-	"github.com/stretchr/testify/assert"
 )
 
 // AcceptanceTest is the acceptance test that all implementations of EventStore
@@ -214,78 +213,148 @@ func AcceptanceTest(t *testing.T, store eh.EventStore, ctx context.Context) []eh
 	return savedEvents
 }
 
-func SnapshotAcceptanceTest(t *testing.T, store eh.EventStore, ctx context.Context) {
-	snapshotStore, ok := store.(eh.SnapshotStore)
-	if !ok {
-		return
+// GlobalAcceptanceTest is the acceptance test that all implementations of
+// GlobalEventStore should pass. It should manually be called from a test case
+// in each implementation:
+//
+//	func TestEventStore(t *testing.T) {
+//	    store := NewEventStore()
+//	    eventstore.GlobalAcceptanceTest(t, store, store, context.Background())
+//	}
+func GlobalAcceptanceTest(t *testing.T, store eh.EventStore, globalStore eh.GlobalEventStore, ctx context.Context) {
+	// Find the last position, the store may already contain events.
+	events, err := globalStore.LoadAllFrom(ctx, 0, 0)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
 	}
 
-	id := uuid.New()
+	lastPosition := 0
 
-	eventStoreErr := &eh.EventStoreError{}
+	for _, e := range events {
+		pos, ok := eh.GlobalPosition(e)
+		if !ok {
+			t.Fatal("there should be a position for the event:", e)
+		}
 
-	err := snapshotStore.SaveSnapshot(ctx, id, eh.Snapshot{})
-	if !errors.As(err, &eventStoreErr) {
-		t.Error("there should be a event store error:", err)
+		if pos <= lastPosition {
+			t.Fatal("the events should be ordered by position:", pos, lastPosition)
+		}
+
+		lastPosition = pos
 	}
 
-	err = snapshotStore.SaveSnapshot(ctx, id, eh.Snapshot{
-		AggregateType: "test",
-	})
-	if !errors.As(err, &eventStoreErr) {
-		t.Error("there should be a event store error:", err)
+	// Load past the end of the stream.
+	events, err = globalStore.LoadAllFrom(ctx, lastPosition+1, 0)
+	if err != nil {
+		t.Error("there should be no error:", err)
 	}
 
-	type TestData struct {
-		Data string
+	if len(events) != 0 {
+		t.Error("there should be no loaded events:", eventsToString(events))
+	}
+
+	// Save interleaved events for two aggregates.
+	id1 := uuid.New()
+	id2 := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event1 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id1, 1))
+	event2 := eh.NewEvent(mocks.EventOtherType, nil, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id1, 2))
+	event3 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event3"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id2, 1),
+		eh.WithMetadata(map[string]interface{}{"meta": "data"}))
+	event4 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event4"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id1, 3))
+
+	if err := store.Save(ctx, []eh.Event{event1, event2}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
 	}
 
-	snapshot := eh.Snapshot{
-		Version:       1,
-		AggregateType: "test",
-		Timestamp:     time.Now(),
-		State: &TestData{
-			Data: "this is incredible data",
-		},
+	if err := store.Save(ctx, []eh.Event{event3}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
 	}
 
-	eh.RegisterSnapshotData("test", func(uuid.UUID) eh.SnapshotData { return &TestData{} })
+	if err := store.Save(ctx, []eh.Event{event4}, 2); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Load all new events.
+	expectedEvents := []eh.Event{event1, event2, event3, event4}
 
-	if err := snapshotStore.SaveSnapshot(ctx, id, snapshot); err != nil {
-		t.Error("there should not be an error")
+	events, err = globalStore.LoadAllFrom(ctx, lastPosition+1, 0)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(events) != len(expectedEvents) {
+		t.Fatalf("incorrect number of loaded events: %d", len(events))
 	}
 
-	loaded, err := snapshotStore.LoadSnapshot(ctx, uuid.New())
-	if loaded != nil {
-		t.Error("snapshot should be nil, it doesnt exists")
+	for i, event := range events {
+		if err := eh.CompareEvents(event, expectedEvents[i],
+			eh.IgnorePositionMetadata(),
+		); err != nil {
+			t.Error("the event was incorrect:", err)
+		}
+
+		if pos, ok := eh.GlobalPosition(event); !ok || pos != lastPosition+i+1 {
+			t.Error("the event position should be correct:", event, pos)
+		}
 	}
 
-	loaded, err = snapshotStore.LoadSnapshot(ctx, id)
+	// Load with a limit.
+	events, err = globalStore.LoadAllFrom(ctx, lastPosition+2, 2)
 	if err != nil {
-		t.Error("there should  be an error")
+		t.Error("there should be no error:", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("incorrect number of loaded events: %d", len(events))
 	}
 
-	assert.Equal(t, snapshot.Version, loaded.Version)
-	assert.Equal(t, snapshot.AggregateType, loaded.AggregateType)
-	assert.Equal(t, snapshot.State, loaded.State)
+	for i, event := range events {
+		if err := eh.CompareEvents(event, expectedEvents[i+1],
+			eh.IgnorePositionMetadata(),
+		); err != nil {
+			t.Error("the event was incorrect:", err)
+		}
+
+		if pos, ok := eh.GlobalPosition(event); !ok || pos != lastPosition+i+2 {
+			t.Error("the event position should be correct:", event, pos)
+		}
+	}
 
-	snapshot.Version += 1
-	snapshot.Timestamp = time.Now()
-	snapshot.State = &TestData{
-		Data: "this is new incredible data",
+	// A failed save should not use any positions.
+	if err := store.Save(ctx, []eh.Event{event4}, 2); err == nil {
+		t.Error("there should be an error")
 	}
-	if err := snapshotStore.SaveSnapshot(ctx, id, snapshot); err != nil {
-		t.Error("there should not be a error")
+
+	event5 := eh.NewEvent(mocks.EventOtherType, nil, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id2, 2))
+
+	if err := store.Save(ctx, []eh.Event{event5}, 1); err != nil {
+		t.Fatal("there should be no error:", err)
 	}
 
-	loaded, err = snapshotStore.LoadSnapshot(ctx, id)
+	events, err = globalStore.LoadAllFrom(ctx, lastPosition+len(expectedEvents)+1, 0)
 	if err != nil {
-		t.Error("there should not be a error")
+		t.Error("there should be no error:", err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("incorrect number of loaded events: %d", len(events))
 	}
 
-	assert.Equal(t, snapshot.Version, loaded.Version)
-	assert.Equal(t, snapshot.AggregateType, loaded.AggregateType)
-	assert.Equal(t, snapshot.State, loaded.State)
+	if err := eh.CompareEvents(events[0], event5,
+		eh.IgnorePositionMetadata(),
+	); err != nil {
+		t.Error("the event was incorrect:", err)
+	}
+
+	if pos, ok := eh.GlobalPosition(events[0]); !ok || pos != lastPosition+len(expectedEvents)+1 {
+		t.Error("the event position should be correct:", events[0], pos)
+	}
 }
 
 func eventsToString(events []eh.Event) string {
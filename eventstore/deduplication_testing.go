@@ -0,0 +1,103 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// DeduplicationAcceptanceTest is the acceptance test that all implementations
+// of EventStore that deduplicate saves by command ID should pass. It should
+// manually be called from a test case in each implementation:
+//
+//	func TestEventStore(t *testing.T) {
+//	    store := NewEventStore(WithDeduplication())
+//	    eventstore.DeduplicationAcceptanceTest(t, store, context.Background())
+//	}
+func DeduplicationAcceptanceTest(t *testing.T, store eh.EventStore, ctx context.Context) {
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	cmdID1 := uuid.New()
+	cmdID2 := uuid.New()
+
+	newEvent := func(id uuid.UUID, version int, cmdID uuid.UUID) eh.Event {
+		var options []eh.EventOption
+		if cmdID != uuid.Nil {
+			options = append(options, eh.WithMetadata(map[string]interface{}{
+				eh.CommandIDMetadataKey: cmdID.String(),
+			}))
+		}
+
+		return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+			append(options, eh.ForAggregate(mocks.AggregateType, id, version))...)
+	}
+
+	// A command creating multiple events.
+	if err := store.Save(ctx, []eh.Event{
+		newEvent(id, 1, cmdID1),
+		newEvent(id, 2, cmdID1),
+	}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Retrying the command should be a duplicate save, both for the same and a
+	// later aggregate version.
+	err := store.Save(ctx, []eh.Event{
+		newEvent(id, 1, cmdID1),
+		newEvent(id, 2, cmdID1),
+	}, 0)
+	if !errors.Is(err, eh.ErrDuplicateSave) {
+		t.Error("there should be a duplicate save error:", err)
+	}
+
+	err = store.Save(ctx, []eh.Event{newEvent(id, 3, cmdID1)}, 2)
+	if !errors.Is(err, eh.ErrDuplicateSave) {
+		t.Error("there should be a duplicate save error:", err)
+	}
+
+	// Other commands and events without command IDs should be saved.
+	if err := store.Save(ctx, []eh.Event{newEvent(id, 3, cmdID2)}, 2); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{newEvent(id, 4, uuid.Nil)}, 3); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{newEvent(id, 5, uuid.Nil)}, 4); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// The command IDs are unique per aggregate.
+	if err := store.Save(ctx, []eh.Event{newEvent(uuid.New(), 1, cmdID1)}, 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	events, err := store.Load(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(events) != 5 {
+		t.Error("there should be 5 events:", len(events))
+	}
+}
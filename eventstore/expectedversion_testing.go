@@ -0,0 +1,131 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// ExpectedVersionAcceptanceTest is the acceptance test that all implementations
+// of EventStore supporting the expected version modes AnyVersion, NoStream and
+// StreamExists should pass. It should manually be called from a test case in
+// each implementation:
+//
+//	func TestEventStore(t *testing.T) {
+//	    store := NewEventStore()
+//	    eventstore.ExpectedVersionAcceptanceTest(t, store, context.Background())
+//	}
+func ExpectedVersionAcceptanceTest(t *testing.T, store eh.EventStore, ctx context.Context) {
+	id1 := uuid.New()
+	id2 := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	newEvent := func(id uuid.UUID, content string, version int) eh.Event {
+		return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: content}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, version))
+	}
+
+	// Save with NoStream for a new aggregate.
+	event1 := newEvent(id1, "event1", 1)
+	event2 := newEvent(id1, "event2", 2)
+
+	if err := store.Save(ctx, []eh.Event{event1, event2}, eh.NoStream); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Save with NoStream for an existing aggregate.
+	err := store.Save(ctx, []eh.Event{newEvent(id1, "existing", 1)}, eh.NoStream)
+	if !errors.Is(err, eh.ErrStreamExists) {
+		t.Error("there should be a ErrStreamExists error:", err)
+	}
+
+	// Save with NoStream and incorrect versions.
+	err = store.Save(ctx, []eh.Event{newEvent(id2, "incorrect", 2)}, eh.NoStream)
+	if !errors.Is(err, eh.ErrIncorrectEventVersion) {
+		t.Error("there should be a ErrIncorrectEventVersion error:", err)
+	}
+
+	// Save with StreamExists for a new aggregate.
+	err = store.Save(ctx, []eh.Event{newEvent(id2, "missing", 1)}, eh.StreamExists)
+	if !errors.Is(err, eh.ErrStreamNotFound) {
+		t.Error("there should be a ErrStreamNotFound error:", err)
+	}
+
+	// Save with StreamExists for an existing aggregate, the versions of the
+	// events are assigned by the store.
+	event3 := newEvent(id1, "event3", 0)
+	event4 := newEvent(id1, "event4", 0)
+
+	if err := store.Save(ctx, []eh.Event{event3, event4}, eh.StreamExists); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Save with AnyVersion for a new aggregate.
+	event5 := newEvent(id2, "event5", 0)
+
+	if err := store.Save(ctx, []eh.Event{event5}, eh.AnyVersion); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Save with AnyVersion for an existing aggregate.
+	event6 := newEvent(id1, "event6", 0)
+
+	if err := store.Save(ctx, []eh.Event{event6}, eh.AnyVersion); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Save with an exact version after the versions assigned by the store.
+	event7 := newEvent(id1, "event7", 6)
+
+	if err := store.Save(ctx, []eh.Event{event7}, 5); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	for id, expected := range map[uuid.UUID][]eh.Event{
+		id1: {event1, event2, event3, event4, event6, event7},
+		id2: {event5},
+	} {
+		events, err := store.Load(ctx, id)
+		if err != nil {
+			t.Error("there should be no error:", err)
+		}
+
+		if len(events) != len(expected) {
+			t.Errorf("incorrect number of loaded events for %s: %d", id, len(events))
+
+			continue
+		}
+
+		for i, event := range events {
+			if err := eh.CompareEvents(event, expected[i],
+				eh.IgnoreVersion(),
+				eh.IgnorePositionMetadata(),
+			); err != nil {
+				t.Error("the event was incorrect:", err)
+			}
+
+			if event.Version() != i+1 {
+				t.Error("the event version should be correct:", event, event.Version())
+			}
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// Replace implements the Replace method of the eventhorizon.EventStoreMaintenance interface.
+func (s *EventStore) Replace(ctx context.Context, event eh.Event) error {
+	id := event.AggregateID()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, ok := s.index[id]
+	if !ok {
+		return &eh.EventStoreError{
+			Err:         eh.ErrAggregateNotFound,
+			Op:          eh.EventStoreOpReplace,
+			AggregateID: id,
+			Events:      []eh.Event{event},
+		}
+	}
+
+	if event.Version() < 1 || event.Version() > len(idx.refs) {
+		return &eh.EventStoreError{
+			Err:         eh.ErrEventNotFound,
+			Op:          eh.EventStoreOpReplace,
+			AggregateID: id,
+			Events:      []eh.Event{event},
+		}
+	}
+
+	ref := idx.refs[event.Version()-1]
+
+	replacement, err := newRecord(event, ref.position)
+	if err != nil {
+		return &eh.EventStoreError{
+			Err:         err,
+			Op:          eh.EventStoreOpReplace,
+			AggregateID: id,
+			Events:      []eh.Event{event},
+		}
+	}
+
+	if err := s.rewrite(func(rec *record) bool {
+		if rec.Position != ref.position {
+			return false
+		}
+
+		replacement.Commit = rec.Commit
+		*rec = *replacement
+
+		return true
+	}); err != nil {
+		return &eh.EventStoreError{
+			Err:         fmt.Errorf("could not replace event: %w", err),
+			Op:          eh.EventStoreOpReplace,
+			AggregateID: id,
+			Events:      []eh.Event{event},
+		}
+	}
+
+	return nil
+}
+
+// RenameEvent implements the RenameEvent method of the eventhorizon.EventStoreMaintenance interface.
+func (s *EventStore) RenameEvent(ctx context.Context, from, to eh.EventType) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.rewrite(func(rec *record) bool {
+		if rec.EventType != from {
+			return false
+		}
+
+		rec.EventType = to
+
+		return true
+	}); err != nil {
+		return &eh.EventStoreError{
+			Err: fmt.Errorf("could not rename events: %w", err),
+			Op:  eh.EventStoreOpRename,
+		}
+	}
+
+	return nil
+}
+
+// Remove implements the Remove method of the eventhorizon.EventStoreMaintenance interface.
+// The records of the events are replaced with placeholders to keep the global
+// positions of later events, any snapshot of the aggregate is also removed.
+func (s *EventStore) Remove(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.snapshotPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return &eh.EventStoreError{
+			Err:         fmt.Errorf("could not remove snapshot: %w", err),
+			Op:          eh.EventStoreOpRemove,
+			AggregateID: id,
+		}
+	}
+
+	if _, ok := s.index[id]; !ok {
+		return nil
+	}
+
+	if err := s.rewrite(func(rec *record) bool {
+		if rec.AggregateID != id {
+			return false
+		}
+
+		*rec = record{
+			Position: rec.Position,
+			Commit:   rec.Commit,
+		}
+
+		return true
+	}); err != nil {
+		return &eh.EventStoreError{
+			Err:         fmt.Errorf("could not remove events: %w", err),
+			Op:          eh.EventStoreOpRemove,
+			AggregateID: id,
+		}
+	}
+
+	return nil
+}
+
+// Clear implements the Clear method of the eventhorizon.EventStoreMaintenance interface.
+// It removes all events and snapshots.
+func (s *EventStore) Clear(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.clear(); err != nil {
+		return &eh.EventStoreError{
+			Err: fmt.Errorf("could not clear event store: %w", err),
+			Op:  eh.EventStoreOpClear,
+		}
+	}
+
+	return nil
+}
+
+func (s *EventStore) clear() error {
+	if err := s.closeSegments(); err != nil {
+		return err
+	}
+
+	for _, dir := range []string{s.eventsDir, s.snapshotsDir} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+
+		if err := syncDir(dir); err != nil {
+			return err
+		}
+	}
+
+	return s.open()
+}
+
+// rewrite rewrites all segments that have records changed by the update func,
+// which should return true if the record was changed. The segments are
+// replaced atomically and the index is rebuilt. Must be called with the lock held.
+func (s *EventStore) rewrite(update func(rec *record) bool) error {
+	if s.syncPolicy != SyncNever {
+		if err := s.sync(); err != nil {
+			return err
+		}
+	}
+
+	for _, seg := range s.segments {
+		records, _ := seg.scan()
+
+		var (
+			data    []byte
+			changed bool
+		)
+
+		for _, r := range records {
+			if update(r.record) {
+				changed = true
+			}
+
+			frame, err := encodeFrame(r.record)
+			if err != nil {
+				return fmt.Errorf("could not encode event: %w", err)
+			}
+
+			data = append(data, frame...)
+		}
+
+		if !changed {
+			continue
+		}
+
+		if err := writeFileAtomic(seg.path, data); err != nil {
+			return fmt.Errorf("could not write segment: %w", err)
+		}
+	}
+
+	// Reopen all segments to use the new files and offsets.
+	if err := s.closeSegments(); err != nil {
+		return err
+	}
+
+	return s.open()
+}
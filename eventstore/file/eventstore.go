@@ -0,0 +1,691 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	jsonCodec "github.com/Clarilab/eventhorizon/codec/json"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+const (
+	eventsDirName    = "events"
+	snapshotsDirName = "snapshots"
+
+	defaultSegmentSize  = 64 << 20
+	defaultSyncInterval = time.Second
+)
+
+// EventStore is an eventhorizon.EventStore that stores events in append-only
+// segment files in a local directory, for use where no database is available.
+// Each save is written as a batch of checksummed records where the last one is
+// marked as committed, a torn write at the end of the last segment (from a
+// crash during a save) is discarded when opening the store.
+//
+// All aggregates are indexed in memory when opening the store, the index points
+// directly to the records in the segment files. The global position of events
+// is kept track of and stored as metadata on load.
+//
+// The directory must only be used by one EventStore (and process) at a time.
+type EventStore struct {
+	dir          string
+	eventsDir    string
+	snapshotsDir string
+	eventHandler eh.EventHandler
+	syncPolicy   SyncPolicy
+	syncInterval time.Duration
+	segmentSize  int64
+	retention    int
+
+	segments []*segment
+	index    map[uuid.UUID]*aggregateIndex
+	all      []recordRef
+	position int
+	dirty    bool
+	mu       sync.RWMutex
+
+	cctx   context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// aggregateIndex is the in memory index of the records of an aggregate, where
+// the index in the refs is the version (minus one).
+type aggregateIndex struct {
+	aggregateType eh.AggregateType
+	refs          []recordRef
+}
+
+// recordRef is the location of a record in a segment.
+type recordRef struct {
+	segment  *segment
+	offset   int64
+	size     int64
+	position int
+}
+
+// NewEventStore creates a new EventStore using the directory as storage. The
+// directory is created if it does not exist, existing events are indexed.
+func NewEventStore(dir string, options ...Option) (*EventStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("missing directory")
+	}
+
+	s := &EventStore{
+		dir:          dir,
+		eventsDir:    filepath.Join(dir, eventsDirName),
+		snapshotsDir: filepath.Join(dir, snapshotsDirName),
+		syncPolicy:   SyncAlways,
+		syncInterval: defaultSyncInterval,
+		segmentSize:  defaultSegmentSize,
+		retention:    1,
+	}
+
+	for i := range options {
+		if err := options[i](s); err != nil {
+			return nil, fmt.Errorf("error while applying option: %w", err)
+		}
+	}
+
+	for _, d := range []string{s.eventsDir, s.snapshotsDir} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return nil, fmt.Errorf("could not create directory: %w", err)
+		}
+	}
+
+	if err := s.open(); err != nil {
+		s.closeSegments()
+
+		return nil, fmt.Errorf("could not open event store: %w", err)
+	}
+
+	s.cctx, s.cancel = context.WithCancel(context.Background())
+
+	if s.syncPolicy == SyncInterval {
+		s.wg.Add(1)
+
+		go s.syncPeriodically(s.cctx)
+	}
+
+	return s, nil
+}
+
+// Save implements the Save method of the eventhorizon.EventStore interface.
+func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersion int) error {
+	if err := s.save(ctx, events, originalVersion); err != nil {
+		return err
+	}
+
+	// Let the optional event handler handle the events.
+	if s.eventHandler != nil {
+		for _, e := range events {
+			if err := s.eventHandler.HandleEvent(ctx, e); err != nil {
+				return &eh.EventHandlerError{
+					Err:   err,
+					Event: e,
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// This method needs to be separate from the Save() method to not lock the mutex during publishing.
+func (s *EventStore) save(ctx context.Context, events []eh.Event, originalVersion int) error {
+	if len(events) == 0 {
+		return &eh.EventStoreError{
+			Err: eh.ErrMissingEvents,
+			Op:  eh.EventStoreOpSave,
+		}
+	}
+
+	id := events[0].AggregateID()
+	at := events[0].AggregateType()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		buf  []byte
+		recs = make([]*record, len(events))
+		lens = make([]int64, len(events))
+	)
+
+	// Build all event records, with incrementing versions starting from the
+	// original aggregate version.
+	for i, event := range events {
+		// Only accept events belonging to the same aggregate.
+		if event.AggregateID() != id {
+			return &eh.EventStoreError{
+				Err:              eh.ErrMismatchedEventAggregateIDs,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		if event.AggregateType() != at {
+			return &eh.EventStoreError{
+				Err:              eh.ErrMismatchedEventAggregateTypes,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		// Only accept events that apply to the correct aggregate version.
+		if event.Version() != originalVersion+i+1 {
+			return &eh.EventStoreError{
+				Err:              eh.ErrIncorrectEventVersion,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		rec, err := newRecord(event, s.position+i+1)
+		if err != nil {
+			return &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		rec.Commit = i == len(events)-1
+
+		frame, err := encodeFrame(rec)
+		if err != nil {
+			return &eh.EventStoreError{
+				Err:              fmt.Errorf("could not encode event: %w", err),
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		recs[i] = rec
+		lens[i] = int64(len(frame))
+		buf = append(buf, frame...)
+	}
+
+	// Only save if the version of the aggregate is matching (ie not changed
+	// since loading the aggregate).
+	currentVersion := 0
+	if idx, ok := s.index[id]; ok {
+		currentVersion = len(idx.refs)
+	}
+
+	if currentVersion != originalVersion {
+		return &eh.EventStoreError{
+			Err:              eh.ErrEventConflictFromOtherSave,
+			Op:               eh.EventStoreOpSave,
+			AggregateType:    at,
+			AggregateID:      id,
+			AggregateVersion: originalVersion,
+			Events:           events,
+		}
+	}
+
+	seg, err := s.writableSegment(int64(len(buf)))
+	if err != nil {
+		return &eh.EventStoreError{
+			Err:              fmt.Errorf("could not create segment: %w", err),
+			Op:               eh.EventStoreOpSave,
+			AggregateType:    at,
+			AggregateID:      id,
+			AggregateVersion: originalVersion,
+			Events:           events,
+		}
+	}
+
+	if err := s.write(seg, buf); err != nil {
+		return &eh.EventStoreError{
+			Err:              fmt.Errorf("could not write events: %w", err),
+			Op:               eh.EventStoreOpSave,
+			AggregateType:    at,
+			AggregateID:      id,
+			AggregateVersion: originalVersion,
+			Events:           events,
+		}
+	}
+
+	offset := seg.size - int64(len(buf))
+
+	for i, rec := range recs {
+		if err := s.addToIndex(rec, recordRef{
+			segment:  seg,
+			offset:   offset,
+			size:     lens[i],
+			position: rec.Position,
+		}); err != nil {
+			return &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		offset += lens[i]
+	}
+
+	return nil
+}
+
+// Load implements the Load method of the eventhorizon.EventStore interface.
+func (s *EventStore) Load(ctx context.Context, id uuid.UUID) ([]eh.Event, error) {
+	return s.LoadFrom(ctx, id, 1)
+}
+
+// LoadFrom implements LoadFrom method of the eventhorizon.EventStore interface.
+func (s *EventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([]eh.Event, error) {
+	return s.load(ctx, id, func(v int) bool { return v >= version })
+}
+
+// LoadUntil implements LoadUntil method of the eventhorizon.EventStore interface.
+func (s *EventStore) LoadUntil(ctx context.Context, id uuid.UUID, version int) ([]eh.Event, error) {
+	return s.load(ctx, id, func(v int) bool { return v <= version })
+}
+
+func (s *EventStore) load(ctx context.Context, id uuid.UUID, include func(version int) bool) ([]eh.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	idx, ok := s.index[id]
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err:         eh.ErrAggregateNotFound,
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
+	var events []eh.Event
+
+	for i, ref := range idx.refs {
+		if !include(i + 1) {
+			continue
+		}
+
+		event, err := s.readEvent(ctx, ref)
+		if err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    idx.aggregateType,
+				AggregateID:      id,
+				AggregateVersion: i + 1,
+				Events:           events,
+			}
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// LoadAllFrom implements the LoadAllFrom method of the eventhorizon.GlobalEventStore interface.
+func (s *EventStore) LoadAllFrom(ctx context.Context, position, limit int) ([]eh.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := sort.Search(len(s.all), func(i int) bool {
+		return s.all[i].position >= position
+	})
+
+	var events []eh.Event
+
+	for _, ref := range s.all[start:] {
+		if limit > 0 && len(events) >= limit {
+			break
+		}
+
+		event, err := s.readEvent(ctx, ref)
+		if err != nil {
+			return nil, &eh.EventStoreError{
+				Err:    err,
+				Op:     eh.EventStoreOpLoad,
+				Events: events,
+			}
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// Close implements the Close method of the eventhorizon.EventStore interface.
+func (s *EventStore) Close() error {
+	s.cancel()
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	if s.syncPolicy != SyncNever {
+		err = s.sync()
+	}
+
+	if cerr := s.closeSegments(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// Dir returns the directory of the event store.
+func (s *EventStore) Dir() string { return s.dir }
+
+// open opens all segments and builds the index, recovering from a torn write
+// at the end of the last segment. Must be called with the lock held (or
+// during creation).
+func (s *EventStore) open() error {
+	ids, err := listSegments(s.eventsDir)
+	if err != nil {
+		return fmt.Errorf("could not list segments: %w", err)
+	}
+
+	s.segments = nil
+	s.index = map[uuid.UUID]*aggregateIndex{}
+	s.all = nil
+	s.position = 0
+	s.dirty = false
+
+	for i, id := range ids {
+		seg, err := openSegment(s.eventsDir, id)
+		if err != nil {
+			return fmt.Errorf("could not open segment: %w", err)
+		}
+
+		s.segments = append(s.segments, seg)
+
+		records, committed := seg.scan()
+
+		if committed < seg.size {
+			if i != len(ids)-1 {
+				return fmt.Errorf("%w: %s", ErrCorruptSegment, seg.path)
+			}
+
+			// Discard the torn write at the end of the last segment.
+			if err := seg.file.Truncate(committed); err != nil {
+				return fmt.Errorf("could not truncate segment: %w", err)
+			}
+
+			if err := seg.file.Sync(); err != nil {
+				return fmt.Errorf("could not sync segment: %w", err)
+			}
+
+			seg.size = committed
+		}
+
+		for _, r := range records {
+			if r.record.Position <= s.position {
+				return fmt.Errorf("%w: unordered position %d in %s", ErrCorruptSegment, r.record.Position, seg.path)
+			}
+
+			s.position = r.record.Position
+
+			// Skip placeholders for removed events, only kept for the position.
+			if r.record.AggregateID == uuid.Nil {
+				continue
+			}
+
+			if err := s.addToIndex(r.record, recordRef{
+				segment:  seg,
+				offset:   r.offset,
+				size:     r.size,
+				position: r.record.Position,
+			}); err != nil {
+				return fmt.Errorf("%w: %s", err, seg.path)
+			}
+		}
+	}
+
+	if len(s.segments) == 0 {
+		seg, err := openSegment(s.eventsDir, 1)
+		if err != nil {
+			return fmt.Errorf("could not create segment: %w", err)
+		}
+
+		s.segments = append(s.segments, seg)
+
+		if err := syncDir(s.eventsDir); err != nil {
+			return fmt.Errorf("could not sync directory: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// addToIndex adds a record to the index. Must be called with the lock held.
+func (s *EventStore) addToIndex(rec *record, ref recordRef) error {
+	idx, ok := s.index[rec.AggregateID]
+	if !ok {
+		idx = &aggregateIndex{aggregateType: rec.AggregateType}
+		s.index[rec.AggregateID] = idx
+	}
+
+	if rec.Version != len(idx.refs)+1 {
+		return fmt.Errorf("%w: incorrect version %d for %s", ErrCorruptSegment, rec.Version, rec.AggregateID)
+	}
+
+	idx.refs = append(idx.refs, ref)
+	s.all = append(s.all, ref)
+	s.position = rec.Position
+
+	return nil
+}
+
+// writableSegment returns the segment to write to, starting a new segment if
+// the current one would become too large. Must be called with the lock held.
+func (s *EventStore) writableSegment(size int64) (*segment, error) {
+	seg := s.segments[len(s.segments)-1]
+	if seg.size == 0 || seg.size+size <= s.segmentSize {
+		return seg, nil
+	}
+
+	if s.syncPolicy != SyncNever {
+		if err := s.sync(); err != nil {
+			return nil, err
+		}
+	}
+
+	next, err := openSegment(s.eventsDir, seg.id+1)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syncDir(s.eventsDir); err != nil {
+		next.file.Close()
+
+		return nil, err
+	}
+
+	s.segments = append(s.segments, next)
+
+	return next, nil
+}
+
+// write appends the data to the segment, removing any partially written data
+// on errors. Must be called with the lock held.
+func (s *EventStore) write(seg *segment, data []byte) error {
+	if _, err := seg.file.WriteAt(data, seg.size); err != nil {
+		if terr := seg.file.Truncate(seg.size); terr != nil {
+			log.Printf("eventhorizon: could not truncate segment %s after failed write: %s", seg.path, terr)
+		}
+
+		return err
+	}
+
+	seg.size += int64(len(data))
+	s.dirty = true
+
+	if s.syncPolicy == SyncAlways {
+		return s.sync()
+	}
+
+	return nil
+}
+
+// sync syncs the last segment if it has unsynced writes. Must be called with
+// the lock held.
+func (s *EventStore) sync() error {
+	if !s.dirty || len(s.segments) == 0 {
+		return nil
+	}
+
+	if err := s.segments[len(s.segments)-1].file.Sync(); err != nil {
+		return fmt.Errorf("could not sync segment: %w", err)
+	}
+
+	s.dirty = false
+
+	return nil
+}
+
+func (s *EventStore) syncPeriodically(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			err := s.sync()
+			s.mu.Unlock()
+
+			if err != nil {
+				log.Printf("eventhorizon: could not sync event store: %s", err)
+			}
+		}
+	}
+}
+
+// closeSegments closes all segment files. Must be called with the lock held.
+func (s *EventStore) closeSegments() error {
+	var err error
+
+	for _, seg := range s.segments {
+		if cerr := seg.file.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	s.segments = nil
+
+	return err
+}
+
+// readEvent reads the event for a record. Must be called with the lock held.
+func (s *EventStore) readEvent(ctx context.Context, ref recordRef) (eh.Event, error) {
+	rec, err := ref.segment.read(ref.offset, ref.size)
+	if err != nil {
+		return nil, err
+	}
+
+	return rec.event(ctx)
+}
+
+// newRecord returns a new record for an event.
+func newRecord(event eh.Event, position int) (*record, error) {
+	rec := &record{
+		Position:      position,
+		EventType:     event.EventType(),
+		Timestamp:     event.Timestamp(),
+		AggregateType: event.AggregateType(),
+		AggregateID:   event.AggregateID(),
+		Version:       event.Version(),
+		Metadata:      event.Metadata(),
+	}
+
+	// Marshal event data if there is any.
+	if event.Data() != nil {
+		var err error
+
+		if rec.RawData, err = json.Marshal(event.Data()); err != nil {
+			return nil, fmt.Errorf("could not marshal event data: %w", err)
+		}
+
+		rec.SchemaVersion = eh.EventSchemaVersion(event.EventType())
+	}
+
+	return rec, nil
+}
+
+// event returns the event for the record, with the global position set.
+func (r *record) event(ctx context.Context) (eh.Event, error) {
+	var data eh.EventData
+
+	// Create an event of the correct type and decode from raw JSON.
+	if len(r.RawData) > 0 {
+		rawData, err := jsonCodec.UpcastRawData(ctx, r.EventType, r.SchemaVersion, r.RawData)
+		if err != nil {
+			return nil, fmt.Errorf("could not upcast event data: %w", err)
+		}
+
+		if data, err = eh.CreateEventData(r.EventType); err != nil {
+			return nil, fmt.Errorf("could not create event data: %w", err)
+		}
+
+		if err := json.Unmarshal(rawData, data); err != nil {
+			return nil, fmt.Errorf("could not unmarshal event data: %w", err)
+		}
+	}
+
+	return eh.NewEvent(
+		r.EventType,
+		data,
+		r.Timestamp,
+		eh.ForAggregate(
+			r.AggregateType,
+			r.AggregateID,
+			r.Version,
+		),
+		eh.WithMetadata(r.Metadata),
+		eh.WithGlobalPosition(r.Position),
+	), nil
+}
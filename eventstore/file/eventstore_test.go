@@ -0,0 +1,423 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/eventstore"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+func TestEventStore(t *testing.T) {
+	store, err := NewEventStore(t.TempDir())
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if store == nil {
+		t.Fatal("there should be a store")
+	}
+
+	eventstore.AcceptanceTest(t, store, context.Background())
+	eventstore.GlobalAcceptanceTest(t, store, store, context.Background())
+	eventstore.SnapshotAcceptanceTest(t, store, context.Background())
+	eventstore.UpcastAcceptanceTest(t, store, context.Background())
+
+	if err := store.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+func TestSnapshotHistory(t *testing.T) {
+	store, err := NewEventStore(t.TempDir(), WithSnapshotRetention(3))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	eventstore.SnapshotHistoryAcceptanceTest(t, store, context.Background())
+}
+
+func TestLoadSingleSnapshotFile(t *testing.T) {
+	type TestData struct {
+		Data string
+	}
+
+	eh.RegisterSnapshotData("SingleSnapshotFile", func(uuid.UUID) eh.SnapshotData { return &TestData{} })
+	defer eh.UnregisterSnapshotData("SingleSnapshotFile")
+
+	dir := t.TempDir()
+
+	store, err := NewEventStore(dir)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	// Snapshot files were written with a single snapshot before keeping a history.
+	id := uuid.New()
+	if err := os.WriteFile(store.snapshotPath(id), []byte(
+		`{"aggregate_type":"SingleSnapshotFile","version":3,"timestamp":"2009-11-10T23:00:00Z","state":{"Data":"old"}}`,
+	), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := store.LoadSnapshotAt(context.Background(), id, 3)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if snapshot == nil || snapshot.Version != 3 || snapshot.State.(*TestData).Data != "old" {
+		t.Error("the snapshot should be loaded:", snapshot)
+	}
+}
+
+func TestEventStoreMaintenance(t *testing.T) {
+	store, err := NewEventStore(t.TempDir())
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	eventstore.MaintenanceAcceptanceTest(t, store, store, context.Background())
+}
+
+func TestNewEventStoreOptions(t *testing.T) {
+	if _, err := NewEventStore(""); err == nil || err.Error() != "missing directory" {
+		t.Error("there should be a missing directory error:", err)
+	}
+
+	if _, err := NewEventStore(t.TempDir(), WithSegmentSize(0)); err == nil ||
+		err.Error() != "error while applying option: invalid segment size: 0" {
+		t.Error("there should be an option error:", err)
+	}
+
+	if _, err := NewEventStore(t.TempDir(), WithSyncPolicy(SyncPolicy(42))); err == nil ||
+		err.Error() != "error while applying option: invalid sync policy: 42" {
+		t.Error("there should be an option error:", err)
+	}
+
+	if _, err := NewEventStore(t.TempDir(), WithSnapshotRetention(-1)); err == nil ||
+		err.Error() != "error while applying option: invalid snapshot retention: -1" {
+		t.Error("there should be an option error:", err)
+	}
+
+	for _, option := range []Option{
+		WithSyncPolicy(SyncNever),
+		WithSyncInterval(10 * time.Millisecond),
+	} {
+		store, err := NewEventStore(t.TempDir(), option)
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		eventstore.AcceptanceTest(t, store, context.Background())
+
+		if err := store.Close(); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+}
+
+func TestEventStoreReopen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Use a small segment size to span multiple segments.
+	store, err := NewEventStore(dir, WithSegmentSize(512))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	var saved []eh.Event
+
+	for i := 1; i <= 10; i++ {
+		event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, i))
+		if err := store.Save(ctx, []eh.Event{event}, i-1); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		saved = append(saved, event)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	segments, err := listSegments(filepath.Join(dir, eventsDirName))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(segments) < 2 {
+		t.Error("there should be multiple segments:", segments)
+	}
+
+	store, err = NewEventStore(dir, WithSegmentSize(512))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	events, err := store.Load(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(events) != len(saved) {
+		t.Fatal("there should be all events:", len(events))
+	}
+
+	for i, event := range events {
+		if err := eh.CompareEvents(event, saved[i], eh.IgnorePositionMetadata()); err != nil {
+			t.Error("the event was incorrect:", err)
+		}
+
+		if pos, ok := eh.GlobalPosition(event); !ok || pos != i+1 {
+			t.Error("the position should be correct:", pos)
+		}
+	}
+
+	// The positions should continue after reopening.
+	event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 11))
+	if err := store.Save(ctx, []eh.Event{event}, 10); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	events, err = store.LoadAllFrom(ctx, 11, 0)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(events) != 1 || events[0].Version() != 11 {
+		t.Fatal("there should be the new event:", events)
+	}
+
+	if pos, _ := eh.GlobalPosition(events[0]); pos != 11 {
+		t.Error("the position should be correct:", pos)
+	}
+}
+
+func TestEventStoreTornWrite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := NewEventStore(dir)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event1 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 1))
+
+	if err := store.Save(ctx, []eh.Event{event1}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	path := filepath.Join(dir, eventsDirName, segmentName(1))
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	committedSize := info.Size()
+
+	// Simulate a crash during a save of two events, where the first record
+	// was written completely and the second only partially.
+	event2 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event2"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 2))
+	event3 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event3"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 3))
+
+	rec2, err := newRecord(event2, 2)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	rec3, err := newRecord(event3, 3)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	rec3.Commit = true
+
+	frame2, err := encodeFrame(rec2)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	frame3, err := encodeFrame(rec3)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, err := f.Write(append(frame2, frame3[:len(frame3)/2]...)); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	f.Close()
+
+	store, err = NewEventStore(dir)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	// The torn save should be discarded.
+	events, err := store.Load(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(events) != 1 {
+		t.Fatal("there should be one event:", len(events))
+	}
+
+	if err := eh.CompareEvents(events[0], event1, eh.IgnorePositionMetadata()); err != nil {
+		t.Error("the event was incorrect:", err)
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Size() != committedSize {
+		t.Error("the segment should be truncated:", info.Size(), committedSize)
+	}
+
+	// The store should be usable after the recovery.
+	if err := store.Save(ctx, []eh.Event{event2, event3}, 1); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	events, err = store.Load(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(events) != 3 {
+		t.Fatal("there should be three events:", len(events))
+	}
+}
+
+func TestEventStoreCorruptSegment(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := NewEventStore(dir, WithSegmentSize(256))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	for i := 1; i <= 4; i++ {
+		event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, i))
+		if err := store.Save(ctx, []eh.Event{event}, i-1); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	if err := store.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Corrupt the first segment, which can not be recovered.
+	path := filepath.Join(dir, eventsDirName, segmentName(1))
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	data[len(data)-2] ^= 0xff
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, err := NewEventStore(dir, WithSegmentSize(256)); !errors.Is(err, ErrCorruptSegment) {
+		t.Error("there should be a corrupt segment error:", err)
+	}
+}
+
+func TestWithEventHandler(t *testing.T) {
+	h := &mocks.EventBus{}
+
+	store, err := NewEventStore(t.TempDir(), WithEventHandler(h))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	ctx := context.Background()
+
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event1 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 1))
+
+	if err := store.Save(ctx, []eh.Event{event1}, 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(h.Events) != 1 {
+		t.Fatal("there should be one handled event:", len(h.Events))
+	}
+
+	if err := eh.CompareEvents(h.Events[0], event1); err != nil {
+		t.Error("the handled event was incorrect:", err)
+	}
+}
+
+func BenchmarkEventStore(b *testing.B) {
+	store, err := NewEventStore(b.TempDir(), WithSyncPolicy(SyncNever))
+	if err != nil {
+		b.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	eventstore.Benchmark(b, store)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"fmt"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+)
+
+// SyncPolicy is the policy for when to sync written events to disk.
+type SyncPolicy int
+
+const (
+	// SyncAlways syncs after each save, no saved events are lost on a crash.
+	SyncAlways SyncPolicy = iota
+	// SyncInterval syncs periodically in the background, events saved since
+	// the last sync can be lost on a crash (but not on a process restart).
+	SyncInterval
+	// SyncNever leaves syncing to the OS.
+	SyncNever
+)
+
+// Option is an option setter used to configure creation.
+type Option func(*EventStore) error
+
+// WithEventHandler adds an event handler that will be called after saving events.
+// An example would be to add an event bus to publish events.
+func WithEventHandler(h eh.EventHandler) Option {
+	return func(s *EventStore) error {
+		if h == nil {
+			return eh.ErrMissingHandler
+		}
+
+		s.eventHandler = h
+
+		return nil
+	}
+}
+
+// WithSyncPolicy sets the policy for syncing to disk, default SyncAlways.
+func WithSyncPolicy(p SyncPolicy) Option {
+	return func(s *EventStore) error {
+		if p < SyncAlways || p > SyncNever {
+			return fmt.Errorf("invalid sync policy: %d", p)
+		}
+
+		s.syncPolicy = p
+
+		return nil
+	}
+}
+
+// WithSyncInterval uses the SyncInterval policy with the interval, default 1 second.
+func WithSyncInterval(d time.Duration) Option {
+	return func(s *EventStore) error {
+		if d <= 0 {
+			return fmt.Errorf("invalid sync interval: %s", d)
+		}
+
+		s.syncPolicy = SyncInterval
+		s.syncInterval = d
+
+		return nil
+	}
+}
+
+// WithSegmentSize sets the size in bytes after which a new segment file is
+// started, default 64MB. A single save is never split over segments.
+func WithSegmentSize(size int64) Option {
+	return func(s *EventStore) error {
+		if size <= 0 {
+			return fmt.Errorf("invalid segment size: %d", size)
+		}
+
+		s.segmentSize = size
+
+		return nil
+	}
+}
+
+// WithSnapshotRetention keeps the last n snapshots of each aggregate, default 1.
+// Use 0 to keep all snapshots.
+func WithSnapshotRetention(n int) Option {
+	return func(s *EventStore) error {
+		if n < 0 {
+			return fmt.Errorf("invalid snapshot retention: %d", n)
+		}
+
+		s.retention = n
+
+		return nil
+	}
+}
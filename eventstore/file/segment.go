@@ -0,0 +1,295 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// Each record in a segment file is stored as a frame with a header containing
+// the payload length and a CRC32 (Castagnoli) checksum of the payload,
+// followed by the JSON encoded payload.
+const (
+	frameHeaderSize = 8
+	segmentExt      = ".log"
+	tmpExt          = ".tmp"
+)
+
+// ErrCorruptSegment is when a segment file, other than the last one, contains
+// invalid data and can not be recovered automatically.
+var ErrCorruptSegment = errors.New("corrupt segment")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// segment is an append-only file of event records.
+type segment struct {
+	id   int
+	path string
+	file *os.File
+	size int64
+}
+
+// record is the internal event record stored in the segment files.
+type record struct {
+	Position      int                    `json:"position"`
+	EventType     eh.EventType           `json:"event_type"`
+	Timestamp     time.Time              `json:"timestamp"`
+	AggregateType eh.AggregateType       `json:"aggregate_type"`
+	AggregateID   uuid.UUID              `json:"aggregate_id"`
+	Version       int                    `json:"version"`
+	RawData       json.RawMessage        `json:"data,omitempty"`
+	SchemaVersion int                    `json:"schema_version,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	// Commit marks the last record of a save, records after the last commit
+	// are from a torn write and are discarded when opening the store.
+	Commit bool `json:"commit,omitempty"`
+}
+
+// scannedRecord is a record together with its location in the segment.
+type scannedRecord struct {
+	record *record
+	offset int64
+	size   int64
+}
+
+// segmentName returns the file name of a segment.
+func segmentName(id int) string {
+	return fmt.Sprintf("%08d%s", id, segmentExt)
+}
+
+// listSegments returns the IDs of all segments in a dir in order, removing any
+// temporary files left from an interrupted rewrite.
+func listSegments(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		if strings.HasSuffix(name, tmpExt) {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				return nil, fmt.Errorf("could not remove temporary file: %w", err)
+			}
+
+			continue
+		}
+
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+
+		id, err := strconv.Atoi(strings.TrimSuffix(name, segmentExt))
+		if err != nil {
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	return ids, nil
+}
+
+// openSegment opens (or creates) a segment file.
+func openSegment(dir string, id int) (*segment, error) {
+	path := filepath.Join(dir, segmentName(id))
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+
+		return nil, err
+	}
+
+	return &segment{
+		id:   id,
+		path: path,
+		file: f,
+		size: info.Size(),
+	}, nil
+}
+
+// scan reads all committed records of the segment. It returns the records and
+// the offset after the last committed record, which is less than the size of
+// the segment if the end of it is torn or corrupt.
+func (seg *segment) scan() ([]scannedRecord, int64) {
+	var (
+		records   []scannedRecord
+		pending   []scannedRecord
+		committed int64
+		offset    int64
+	)
+
+	r := io.NewSectionReader(seg.file, 0, seg.size)
+	header := make([]byte, frameHeaderSize)
+
+	for offset < seg.size {
+		if _, err := io.ReadFull(r, header); err != nil {
+			break
+		}
+
+		length := int64(binary.BigEndian.Uint32(header[0:4]))
+		sum := binary.BigEndian.Uint32(header[4:8])
+
+		if length == 0 || offset+frameHeaderSize+length > seg.size {
+			break
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			break
+		}
+
+		if crc32.Checksum(payload, crcTable) != sum {
+			break
+		}
+
+		rec := &record{}
+		if err := json.Unmarshal(payload, rec); err != nil {
+			break
+		}
+
+		pending = append(pending, scannedRecord{
+			record: rec,
+			offset: offset,
+			size:   frameHeaderSize + length,
+		})
+
+		offset += frameHeaderSize + length
+
+		if rec.Commit {
+			records = append(records, pending...)
+			pending = nil
+			committed = offset
+		}
+	}
+
+	return records, committed
+}
+
+// read reads and verifies a single record from the segment.
+func (seg *segment) read(offset, size int64) (*record, error) {
+	buf := make([]byte, size)
+	if _, err := seg.file.ReadAt(buf, offset); err != nil {
+		return nil, fmt.Errorf("could not read record: %w", err)
+	}
+
+	payload := buf[frameHeaderSize:]
+	if int64(binary.BigEndian.Uint32(buf[0:4])) != int64(len(payload)) ||
+		crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(buf[4:8]) {
+		return nil, fmt.Errorf("%w: invalid record at offset %d in %s", ErrCorruptSegment, offset, seg.path)
+	}
+
+	rec := &record{}
+	if err := json.Unmarshal(payload, rec); err != nil {
+		return nil, fmt.Errorf("could not decode record: %w", err)
+	}
+
+	return rec, nil
+}
+
+// encodeFrame encodes a record as a frame with a header.
+func encodeFrame(rec *record) ([]byte, error) {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := make([]byte, frameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.Checksum(payload, crcTable))
+	copy(frame[frameHeaderSize:], payload)
+
+	return frame, nil
+}
+
+// writeFileAtomic writes a file by writing to a temporary file that is synced
+// and renamed, to never leave a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + tmpExt
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+
+		return err
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir syncs a directory to persist renames and new files.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	// Syncing directories is not supported on all platforms.
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return err
+	}
+
+	return nil
+}
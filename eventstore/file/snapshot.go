@@ -0,0 +1,281 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// snapshotRecord is the internal snapshot record, the snapshots of an aggregate
+// are stored sorted by version in one file per aggregate.
+type snapshotRecord struct {
+	AggregateType eh.AggregateType `json:"aggregate_type"`
+	Version       int              `json:"version"`
+	Timestamp     time.Time        `json:"timestamp"`
+	SchemaVersion int              `json:"schema_version,omitempty"`
+	RawState      json.RawMessage  `json:"state"`
+}
+
+// LoadSnapshot implements the LoadSnapshot method of the eventhorizon.SnapshotStore interface.
+// Returns nil if there is no snapshot for the aggregate.
+func (s *EventStore) LoadSnapshot(ctx context.Context, id uuid.UUID) (*eh.Snapshot, error) {
+	return s.loadSnapshot(id, func(records []snapshotRecord) int {
+		return len(records) - 1
+	})
+}
+
+// LoadSnapshotAt implements the LoadSnapshotAt method of the eventhorizon.SnapshotHistoryStore interface.
+func (s *EventStore) LoadSnapshotAt(ctx context.Context, id uuid.UUID, maxVersion int) (*eh.Snapshot, error) {
+	return s.loadSnapshot(id, func(records []snapshotRecord) int {
+		return sort.Search(len(records), func(i int) bool {
+			return records[i].Version > maxVersion
+		}) - 1
+	})
+}
+
+// loadSnapshot loads the snapshot at the index returned by the selector, or
+// nil if the index is negative.
+func (s *EventStore) loadSnapshot(id uuid.UUID, selector func([]snapshotRecord) int) (*eh.Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records, err := s.readSnapshots(id)
+	if err != nil {
+		return nil, &eh.EventStoreError{
+			Err:         err,
+			Op:          eh.EventStoreOpLoadSnapshot,
+			AggregateID: id,
+		}
+	}
+
+	i := selector(records)
+	if i < 0 {
+		return nil, nil
+	}
+
+	record := records[i]
+
+	if err := eh.CheckSnapshotSchemaVersion(record.AggregateType, record.SchemaVersion); err != nil {
+		return nil, &eh.EventStoreError{
+			Err:              err,
+			Op:               eh.EventStoreOpLoadSnapshot,
+			AggregateType:    record.AggregateType,
+			AggregateID:      id,
+			AggregateVersion: record.Version,
+		}
+	}
+
+	snapshot := &eh.Snapshot{
+		Version:       record.Version,
+		AggregateType: record.AggregateType,
+		Timestamp:     record.Timestamp,
+		SchemaVersion: record.SchemaVersion,
+	}
+
+	if snapshot.State, err = eh.CreateSnapshotData(id, record.AggregateType); err != nil {
+		return nil, &eh.EventStoreError{
+			Err:           fmt.Errorf("could not create snapshot data: %w", err),
+			Op:            eh.EventStoreOpLoadSnapshot,
+			AggregateType: record.AggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	if err := json.Unmarshal(record.RawState, snapshot.State); err != nil {
+		return nil, &eh.EventStoreError{
+			Err:           fmt.Errorf("could not decode snapshot state: %w", err),
+			Op:            eh.EventStoreOpLoadSnapshot,
+			AggregateType: record.AggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	return snapshot, nil
+}
+
+// SaveSnapshot implements the SaveSnapshot method of the eventhorizon.SnapshotStore interface.
+// A snapshot with the same version is replaced and snapshots outside of the
+// retention are pruned.
+func (s *EventStore) SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot eh.Snapshot) error {
+	if snapshot.AggregateType == "" {
+		return &eh.EventStoreError{
+			Err:           fmt.Errorf("aggregate type is empty"),
+			Op:            eh.EventStoreOpSaveSnapshot,
+			AggregateID:   id,
+			AggregateType: snapshot.AggregateType,
+		}
+	}
+
+	if snapshot.State == nil {
+		return &eh.EventStoreError{
+			Err:           fmt.Errorf("snapshots state is nil"),
+			Op:            eh.EventStoreOpSaveSnapshot,
+			AggregateID:   id,
+			AggregateType: snapshot.AggregateType,
+		}
+	}
+
+	record := snapshotRecord{
+		AggregateType: snapshot.AggregateType,
+		Version:       snapshot.Version,
+		Timestamp:     snapshot.Timestamp,
+		SchemaVersion: eh.SnapshotSchemaVersion(snapshot.AggregateType),
+	}
+
+	var err error
+	if record.RawState, err = json.Marshal(snapshot.State); err != nil {
+		return &eh.EventStoreError{
+			Err:           fmt.Errorf("could not encode snapshot state: %w", err),
+			Op:            eh.EventStoreOpSaveSnapshot,
+			AggregateID:   id,
+			AggregateType: snapshot.AggregateType,
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.readSnapshots(id)
+	if err != nil {
+		return &eh.EventStoreError{
+			Err:           err,
+			Op:            eh.EventStoreOpSaveSnapshot,
+			AggregateID:   id,
+			AggregateType: snapshot.AggregateType,
+		}
+	}
+
+	i := sort.Search(len(records), func(i int) bool {
+		return records[i].Version >= record.Version
+	})
+	if i < len(records) && records[i].Version == record.Version {
+		records[i] = record
+	} else {
+		records = append(records[:i], append([]snapshotRecord{record}, records[i:]...)...)
+	}
+
+	if s.retention > 0 && len(records) > s.retention {
+		records = records[len(records)-s.retention:]
+	}
+
+	if err := s.writeSnapshots(id, records); err != nil {
+		return &eh.EventStoreError{
+			Err:           err,
+			Op:            eh.EventStoreOpSaveSnapshot,
+			AggregateID:   id,
+			AggregateType: snapshot.AggregateType,
+		}
+	}
+
+	return nil
+}
+
+// RemoveSnapshotsAfter implements the RemoveSnapshotsAfter method of the eventhorizon.SnapshotHistoryStore interface.
+func (s *EventStore) RemoveSnapshotsAfter(ctx context.Context, id uuid.UUID, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.readSnapshots(id)
+	if err != nil {
+		return &eh.EventStoreError{
+			Err:         err,
+			Op:          eh.EventStoreOpRemoveSnapshots,
+			AggregateID: id,
+		}
+	}
+
+	i := sort.Search(len(records), func(i int) bool {
+		return records[i].Version > version
+	})
+	if i == len(records) {
+		return nil
+	}
+
+	if err := s.writeSnapshots(id, records[:i]); err != nil {
+		return &eh.EventStoreError{
+			Err:         err,
+			Op:          eh.EventStoreOpRemoveSnapshots,
+			AggregateID: id,
+		}
+	}
+
+	return nil
+}
+
+// readSnapshots reads the snapshots of an aggregate sorted by version. Must be
+// called with the lock held.
+func (s *EventStore) readSnapshots(id uuid.UUID) ([]snapshotRecord, error) {
+	data, err := os.ReadFile(s.snapshotPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read snapshot: %w", err)
+	}
+
+	// Files written before keeping a history contain a single snapshot.
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '{' {
+		var record snapshotRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("could not decode snapshot: %w", err)
+		}
+
+		return []snapshotRecord{record}, nil
+	}
+
+	var records []snapshotRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("could not decode snapshot: %w", err)
+	}
+
+	return records, nil
+}
+
+// writeSnapshots writes the snapshots of an aggregate, removing the file if
+// there are none. Must be called with the lock held.
+func (s *EventStore) writeSnapshots(id uuid.UUID, records []snapshotRecord) error {
+	if len(records) == 0 {
+		if err := os.Remove(s.snapshotPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not remove snapshot: %w", err)
+		}
+
+		return nil
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("could not encode snapshot: %w", err)
+	}
+
+	if err := writeFileAtomic(s.snapshotPath(id), data); err != nil {
+		return fmt.Errorf("could not write snapshot: %w", err)
+	}
+
+	return nil
+}
+
+func (s *EventStore) snapshotPath(id uuid.UUID) string {
+	return filepath.Join(s.snapshotsDir, id.String()+".json")
+}
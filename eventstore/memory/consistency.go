@@ -0,0 +1,107 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// CheckConsistency implements the CheckConsistency method of the
+// eventhorizon.EventStoreChecker interface. The version of an aggregate is
+// repaired to match its last event.
+func (s *EventStore) CheckConsistency(ctx context.Context, repair bool) ([]eh.Inconsistency, error) {
+	if repair {
+		s.dbMu.Lock()
+		defer s.dbMu.Unlock()
+	} else {
+		s.dbMu.RLock()
+		defer s.dbMu.RUnlock()
+	}
+
+	var (
+		inconsistencies []eh.Inconsistency
+		unregistered    = map[eh.EventType]int{}
+		ids             = make([]uuid.UUID, 0, len(s.db))
+	)
+
+	for id := range s.db {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+
+	for _, id := range ids {
+		aggregate := s.db[id]
+
+		var (
+			aggregateType eh.AggregateType
+			versions      = make([]int, 0, len(aggregate.Events))
+		)
+
+		for _, event := range aggregate.Events {
+			if event == nil {
+				continue
+			}
+
+			aggregateType = event.AggregateType()
+			versions = append(versions, event.Version())
+
+			if event.Data() != nil && !eh.IsEventDataRegistered(event.EventType()) {
+				unregistered[event.EventType()]++
+			}
+		}
+
+		inconsistencies = append(inconsistencies, eh.CheckEventVersions(aggregateType, id, versions, true)...)
+
+		lastVersion := 0
+		if len(versions) > 0 {
+			lastVersion = versions[len(versions)-1]
+		}
+
+		if aggregate.Version != lastVersion {
+			inconsistency := eh.Inconsistency{
+				Kind:          eh.InconsistencyStreamMismatch,
+				AggregateType: aggregateType,
+				AggregateID:   id,
+				Details:       fmt.Sprintf("version %d, last event version %d", aggregate.Version, lastVersion),
+			}
+
+			if repair {
+				aggregate.Version = lastVersion
+				s.db[id] = aggregate
+				inconsistency.Repaired = true
+			}
+
+			inconsistencies = append(inconsistencies, inconsistency)
+		}
+
+		if records := s.snapshots[id]; len(records) > 0 && records[len(records)-1].Version > aggregate.Version {
+			inconsistencies = append(inconsistencies, eh.Inconsistency{
+				Kind:          eh.InconsistencySnapshotAhead,
+				AggregateType: aggregateType,
+				AggregateID:   id,
+				Version:       records[len(records)-1].Version,
+				Details:       fmt.Sprintf("aggregate version %d", aggregate.Version),
+			})
+		}
+	}
+
+	return append(inconsistencies, eh.UnregisteredEventDataInconsistencies(unregistered)...), nil
+}
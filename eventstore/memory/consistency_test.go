@@ -0,0 +1,110 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+func TestEventStore_CheckConsistency(t *testing.T) {
+	store, err := NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	id := uuid.New()
+
+	var events []eh.Event
+	for i := 1; i <= 3; i++ {
+		events = append(events, eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, i)))
+	}
+
+	if err := store.Save(ctx, events, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	inconsistencies, err := store.CheckConsistency(ctx, false)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(inconsistencies) != 0 {
+		t.Fatal("there should be no inconsistencies:", inconsistencies)
+	}
+
+	// Remove the second event, with a lagging aggregate version, a snapshot
+	// newer than the aggregate and an event with unregistered data.
+	store.dbMu.Lock()
+	aggregate := store.db[id]
+	aggregate.Events = []eh.Event{aggregate.Events[0], aggregate.Events[2],
+		eh.NewEvent("UnregisteredEvent", &mocks.EventData{Content: "event"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, 4)),
+	}
+	aggregate.Version = 2
+	store.db[id] = aggregate
+	store.snapshots[id] = []snapshotRecord{{AggregateType: mocks.AggregateType, Version: 5}}
+	store.dbMu.Unlock()
+
+	if inconsistencies, err = store.CheckConsistency(ctx, true); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	var kinds []string
+	for _, i := range inconsistencies {
+		kinds = append(kinds, string(i.Kind))
+	}
+
+	if strings.Join(kinds, ",") != "version_gap,stream_mismatch,snapshot_ahead,unregistered_event_data" {
+		t.Fatal("the inconsistencies should be found:", inconsistencies)
+	}
+
+	if inconsistencies[0].Version != 2 {
+		t.Error("the gap should start at version 2:", inconsistencies[0])
+	}
+
+	if !inconsistencies[1].Repaired {
+		t.Error("the stream mismatch should be repaired:", inconsistencies[1])
+	}
+
+	if inconsistencies[3].EventType != "UnregisteredEvent" {
+		t.Error("the unregistered event type should be reported:", inconsistencies[3])
+	}
+
+	if store.db[id].Version != 4 {
+		t.Error("the aggregate version should be repaired:", store.db[id].Version)
+	}
+
+	// The repaired version is no longer reported.
+	if inconsistencies, err = store.CheckConsistency(ctx, false); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	for _, i := range inconsistencies {
+		if i.Kind == eh.InconsistencyStreamMismatch {
+			t.Error("there should be no stream mismatch:", i)
+		}
+	}
+}
@@ -17,6 +17,7 @@ package memory
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	eh "github.com/Clarilab/eventhorizon"
 	"github.com/Clarilab/eventhorizon/uuid"
@@ -77,6 +78,7 @@ func (s *EventStore) Replace(ctx context.Context, event eh.Event) error {
 	defer s.dbMu.Unlock()
 
 	aggregate.Events[idx] = e
+	s.db[id] = s.withKeys(aggregate)
 
 	return nil
 }
@@ -121,11 +123,20 @@ func (s *EventStore) RenameEvent(ctx context.Context, from, to eh.EventType) err
 }
 
 // Remove implements the Remove method of the eventhorizon.EventStoreMaintenance interface.
+// Any snapshot of the aggregate is also removed.
 func (s *EventStore) Remove(ctx context.Context, id uuid.UUID) error {
 	s.dbMu.Lock()
 	defer s.dbMu.Unlock()
 
 	delete(s.db, id)
+	delete(s.snapshots, id)
+
+	// Keep the global positions of other events, but remove the references.
+	for i, ref := range s.all {
+		if ref.AggregateID == id {
+			s.all[i] = eventRef{}
+		}
+	}
 
 	return nil
 }
@@ -136,6 +147,176 @@ func (s *EventStore) Clear(ctx context.Context) error {
 	defer s.dbMu.Unlock()
 
 	s.db = map[uuid.UUID]aggregateRecord{}
+	s.all = nil
+	s.snapshots = map[uuid.UUID][]snapshotRecord{}
 
 	return nil
 }
+
+// Transform implements the Transform method of the eventhorizon.EventStoreTransformer interface.
+// The events are transformed in the order of their global position, which is
+// also used as checkpoint.
+func (s *EventStore) Transform(ctx context.Context, matcher eh.EventMatcher, transform eh.EventTransformFunc, options ...eh.TransformOption) (*eh.TransformReport, error) {
+	opts := eh.NewTransformOptions(options...)
+	report := &eh.TransformReport{Checkpoint: opts.Checkpoint}
+
+	position := 0
+
+	if opts.Checkpoint != "" {
+		var err error
+		if position, err = strconv.Atoi(opts.Checkpoint); err != nil {
+			return report, &eh.EventStoreError{
+				Err: fmt.Errorf("invalid checkpoint: %w", err),
+				Op:  eh.EventStoreOpTransform,
+			}
+		}
+	}
+
+	for {
+		batch, last, err := s.matchingEvents(ctx, matcher, position, opts.BatchSize)
+		if err != nil {
+			return report, err
+		}
+
+		if last == position {
+			return report, nil
+		}
+
+		var changes []eh.TransformChange
+
+		for _, event := range batch {
+			transformed, err := eh.TransformEvent(event, transform)
+			if err != nil {
+				return report, &eh.EventStoreError{
+					Err:              err,
+					Op:               eh.EventStoreOpTransform,
+					AggregateType:    event.AggregateType(),
+					AggregateID:      event.AggregateID(),
+					AggregateVersion: event.Version(),
+					Events:           []eh.Event{event},
+				}
+			}
+
+			if transformed != nil {
+				changes = append(changes, eh.TransformChange{Before: event, After: transformed})
+			}
+		}
+
+		if opts.DryRun {
+			report.Changes = append(report.Changes, changes...)
+		} else if err := s.replaceEvents(ctx, changes); err != nil {
+			return report, err
+		}
+
+		position = last
+		report.Matched += len(batch)
+		report.Changed += len(changes)
+		report.Checkpoint = strconv.Itoa(last)
+
+		if opts.Progress != nil {
+			if err := opts.Progress(ctx, *report); err != nil {
+				return report, &eh.EventStoreError{
+					Err: fmt.Errorf("could not report progress: %w", err),
+					Op:  eh.EventStoreOpTransform,
+				}
+			}
+		}
+	}
+}
+
+// matchingEvents returns copies of up to limit events after the position that
+// match, and the position of the last event that was checked.
+func (s *EventStore) matchingEvents(ctx context.Context, matcher eh.EventMatcher, position, limit int) ([]eh.Event, int, error) {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+
+	var events []eh.Event
+
+	for ; position < len(s.all) && len(events) < limit; position++ {
+		ref := s.all[position]
+
+		// Skip positions of removed aggregates.
+		aggregate, ok := s.db[ref.AggregateID]
+		if !ok || ref.Version < 1 || ref.Version > len(aggregate.Events) {
+			continue
+		}
+
+		event := aggregate.Events[ref.Version-1]
+		if event == nil || !matcher.Match(event) {
+			continue
+		}
+
+		e, err := copyEvent(ctx, event)
+		if err != nil {
+			return nil, position, &eh.EventStoreError{
+				Err:              fmt.Errorf("could not copy event: %w", err),
+				Op:               eh.EventStoreOpTransform,
+				AggregateType:    event.AggregateType(),
+				AggregateID:      event.AggregateID(),
+				AggregateVersion: event.Version(),
+			}
+		}
+
+		events = append(events, e)
+	}
+
+	return events, position, nil
+}
+
+// replaceEvents stores the transformed events of the changes.
+func (s *EventStore) replaceEvents(ctx context.Context, changes []eh.TransformChange) error {
+	s.dbMu.Lock()
+	defer s.dbMu.Unlock()
+
+	// Update the idempotency keys of the aggregates with replaced events, also
+	// when stopping at an error.
+	replaced := map[uuid.UUID]struct{}{}
+
+	defer func() {
+		for id := range replaced {
+			s.db[id] = s.withKeys(s.db[id])
+		}
+	}()
+
+	for _, change := range changes {
+		event := change.After
+
+		e, err := copyEvent(ctx, event)
+		if err != nil {
+			return &eh.EventStoreError{
+				Err:              fmt.Errorf("could not copy event: %w", err),
+				Op:               eh.EventStoreOpTransform,
+				AggregateType:    event.AggregateType(),
+				AggregateID:      event.AggregateID(),
+				AggregateVersion: event.Version(),
+				Events:           []eh.Event{event},
+			}
+		}
+
+		aggregate, ok := s.db[event.AggregateID()]
+		if !ok || event.Version() > len(aggregate.Events) {
+			return &eh.EventStoreError{
+				Err:              eh.ErrEventNotFound,
+				Op:               eh.EventStoreOpTransform,
+				AggregateType:    event.AggregateType(),
+				AggregateID:      event.AggregateID(),
+				AggregateVersion: event.Version(),
+				Events:           []eh.Event{event},
+			}
+		}
+
+		aggregate.Events[event.Version()-1] = e
+		replaced[event.AggregateID()] = struct{}{}
+	}
+
+	return nil
+}
+
+// withKeys returns the aggregate with the idempotency keys of its events, used
+// after replacing events that may have changed their metadata.
+func (s *EventStore) withKeys(aggregate aggregateRecord) aggregateRecord {
+	aggregate.Keys = nil
+	s.addKeys(&aggregate, aggregate.Events)
+
+	return aggregate
+}
@@ -32,4 +32,5 @@ func TestEventStoreMaintenance(t *testing.T) {
 	}
 
 	eventstore.MaintenanceAcceptanceTest(t, store, store, context.Background())
+	eventstore.TransformAcceptanceTest(t, store, store, context.Background())
 }
@@ -29,14 +29,23 @@ import (
 // memory and not persisted. Useful for testing and experimenting.
 type EventStore struct {
 	db           map[uuid.UUID]aggregateRecord
+	all          []eventRef
+	snapshots    map[uuid.UUID][]snapshotRecord
+	retention    int
+	dedupKey     string
 	dbMu         sync.RWMutex
 	eventHandler eh.EventHandler
+	watchers     map[chan struct{}]struct{}
+	watchersMu   sync.Mutex
 }
 
 // NewEventStore creates a new EventStore using memory as storage.
 func NewEventStore(options ...Option) (*EventStore, error) {
 	s := &EventStore{
-		db: map[uuid.UUID]aggregateRecord{},
+		db:        map[uuid.UUID]aggregateRecord{},
+		snapshots: map[uuid.UUID][]snapshotRecord{},
+		retention: 1,
+		watchers:  map[chan struct{}]struct{}{},
 	}
 
 	for _, option := range options {
@@ -61,16 +70,54 @@ func WithEventHandler(h eh.EventHandler) Option {
 	}
 }
 
+// WithSnapshotRetention keeps the last n snapshots of each aggregate, default 1.
+// Use 0 to keep all snapshots.
+func WithSnapshotRetention(n int) Option {
+	return func(s *EventStore) error {
+		if n < 0 {
+			return fmt.Errorf("invalid snapshot retention: %d", n)
+		}
+
+		s.retention = n
+
+		return nil
+	}
+}
+
+// WithDeduplication rejects saves for an aggregate with ErrDuplicateSave if an
+// earlier save has events with the same command ID in their metadata (as added
+// by eventhorizon.FromCommand), which makes retrying commands idempotent.
+func WithDeduplication() Option {
+	return WithDeduplicationKey(eh.CommandIDMetadataKey)
+}
+
+// WithDeduplicationKey is like WithDeduplication but uses a custom metadata key
+// as idempotency key. Events without the key are never seen as duplicates.
+func WithDeduplicationKey(key string) Option {
+	return func(s *EventStore) error {
+		if key == "" {
+			return fmt.Errorf("missing deduplication key")
+		}
+
+		s.dedupKey = key
+
+		return nil
+	}
+}
+
 // Save implements the Save method of the eventhorizon.EventStore interface.
 func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersion int) error {
-	if err := s.save(ctx, events, originalVersion); err != nil {
+	saved, err := s.save(ctx, events, originalVersion)
+	if err != nil {
 		return err
 	}
 
+	s.notifyWatchers()
+
 	// Let the optional event handler handle the events. Aborts the transaction
 	// in case of error.
 	if s.eventHandler != nil {
-		for _, e := range events {
+		for _, e := range saved {
 			if err := s.eventHandler.HandleEvent(ctx, e); err != nil {
 				return &eh.EventHandlerError{
 					Err:   err,
@@ -84,27 +131,183 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 }
 
 // This method needs to be separate from the Save() method to not lock the mutex during publishing.
-func (s *EventStore) save(ctx context.Context, events []eh.Event, originalVersion int) error {
+func (s *EventStore) save(ctx context.Context, events []eh.Event, originalVersion int) ([]eh.Event, error) {
 	s.dbMu.Lock()
 	defer s.dbMu.Unlock()
 
+	p, err := s.prepare(ctx, events, originalVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	s.commit(p)
+
+	return p.events, nil
+}
+
+// SaveMany implements the SaveMany method of the eventhorizon.MultiStreamEventStore interface.
+func (s *EventStore) SaveMany(ctx context.Context, streams []eh.EventStreamSave) error {
+	saved, err := s.saveMany(ctx, streams)
+	if err != nil {
+		return err
+	}
+
+	s.notifyWatchers()
+
+	// Let the optional event handler handle the events.
+	if s.eventHandler != nil {
+		for _, e := range saved {
+			if err := s.eventHandler.HandleEvent(ctx, e); err != nil {
+				return &eh.EventHandlerError{
+					Err:   err,
+					Event: e,
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *EventStore) saveMany(ctx context.Context, streams []eh.EventStreamSave) ([]eh.Event, error) {
+	if len(streams) == 0 {
+		return nil, &eh.EventStoreError{
+			Err: eh.ErrMissingEvents,
+			Op:  eh.EventStoreOpSave,
+		}
+	}
+
+	s.dbMu.Lock()
+	defer s.dbMu.Unlock()
+
+	// Validate all streams before committing any of them.
+	saves := make([]*pendingSave, len(streams))
+	ids := make(map[uuid.UUID]struct{}, len(streams))
+
+	for i, stream := range streams {
+		if len(stream.Events) > 0 {
+			id := stream.Events[0].AggregateID()
+			if _, ok := ids[id]; ok {
+				return nil, &eh.EventStoreError{
+					Err:              eh.ErrDuplicateEventStream,
+					Op:               eh.EventStoreOpSave,
+					AggregateType:    stream.Events[0].AggregateType(),
+					AggregateID:      id,
+					AggregateVersion: stream.OriginalVersion,
+					Events:           stream.Events,
+				}
+			}
+
+			ids[id] = struct{}{}
+		}
+
+		var err error
+		if saves[i], err = s.prepare(ctx, stream.Events, stream.OriginalVersion); err != nil {
+			return nil, err
+		}
+	}
+
+	var saved []eh.Event
+
+	for _, p := range saves {
+		s.commit(p)
+
+		saved = append(saved, p.events...)
+	}
+
+	return saved, nil
+}
+
+// pendingSave is a validated save of events for an aggregate, ready to be committed.
+type pendingSave struct {
+	// events are the saved events, passed on to the event handler.
+	events []eh.Event
+	// dbEvents are copies of the events to store.
+	dbEvents []eh.Event
+	// version is the version of the aggregate that the events are appended to.
+	version int
+}
+
+// prepare validates the events to save for an aggregate and copies them for
+// storing, must be called with the write lock held.
+func (s *EventStore) prepare(ctx context.Context, events []eh.Event, originalVersion int) (*pendingSave, error) {
 	if len(events) == 0 {
-		return &eh.EventStoreError{
+		return nil, &eh.EventStoreError{
 			Err: eh.ErrMissingEvents,
 			Op:  eh.EventStoreOpSave,
 		}
 	}
 
-	dbEvents := make([]eh.Event, len(events))
 	id := events[0].AggregateID()
 	at := events[0].AggregateType()
+	aggregate, exists := s.db[id]
+
+	// Reject events that have already been saved by an earlier save, before
+	// checking the version which has changed since then.
+	if exists && s.isDuplicateSave(aggregate, events) {
+		return nil, &eh.EventStoreError{
+			Err:              eh.ErrDuplicateSave,
+			Op:               eh.EventStoreOpSave,
+			AggregateType:    at,
+			AggregateID:      id,
+			AggregateVersion: originalVersion,
+			Events:           events,
+		}
+	}
+
+	p := &pendingSave{
+		events:   events,
+		dbEvents: make([]eh.Event, len(events)),
+		version:  originalVersion,
+	}
+
+	// Resolve the version to append to for the expected version modes, the
+	// events are assigned new versions for AnyVersion and StreamExists.
+	assignVersions := false
+
+	switch originalVersion {
+	case eh.AnyVersion:
+		p.version = aggregate.Version
+		assignVersions = true
+	case eh.StreamExists:
+		if !exists {
+			return nil, &eh.EventStoreError{
+				Err:              eh.ErrStreamNotFound,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		p.version = aggregate.Version
+		assignVersions = true
+	case eh.NoStream:
+		if exists {
+			return nil, &eh.EventStoreError{
+				Err:              eh.ErrStreamExists,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		p.version = 0
+	}
+
+	if assignVersions {
+		p.events = make([]eh.Event, len(events))
+	}
 
 	// Build all event records, with incrementing versions starting from the
 	// original aggregate version.
 	for i, event := range events {
 		// Only accept events belonging to the same aggregate.
 		if event.AggregateID() != id {
-			return &eh.EventStoreError{
+			return nil, &eh.EventStoreError{
 				Err:              eh.ErrMismatchedEventAggregateIDs,
 				Op:               eh.EventStoreOpSave,
 				AggregateType:    at,
@@ -115,7 +318,7 @@ func (s *EventStore) save(ctx context.Context, events []eh.Event, originalVersio
 		}
 
 		if event.AggregateType() != at {
-			return &eh.EventStoreError{
+			return nil, &eh.EventStoreError{
 				Err:              eh.ErrMismatchedEventAggregateTypes,
 				Op:               eh.EventStoreOpSave,
 				AggregateType:    at,
@@ -125,9 +328,12 @@ func (s *EventStore) save(ctx context.Context, events []eh.Event, originalVersio
 			}
 		}
 
-		// Only accept events that apply to the correct aggregate version.
-		if event.Version() != originalVersion+i+1 {
-			return &eh.EventStoreError{
+		if assignVersions {
+			event = eh.EventWithVersion(event, p.version+i+1)
+			p.events[i] = event
+		} else if event.Version() != p.version+i+1 {
+			// Only accept events that apply to the correct aggregate version.
+			return nil, &eh.EventStoreError{
 				Err:              eh.ErrIncorrectEventVersion,
 				Op:               eh.EventStoreOpSave,
 				AggregateType:    at,
@@ -140,7 +346,7 @@ func (s *EventStore) save(ctx context.Context, events []eh.Event, originalVersio
 		// Create the event record with timestamp.
 		e, err := copyEvent(ctx, event)
 		if err != nil {
-			return &eh.EventStoreError{
+			return nil, &eh.EventStoreError{
 				Err:              fmt.Errorf("could not copy event: %w", err),
 				Op:               eh.EventStoreOpSave,
 				AggregateType:    at,
@@ -150,42 +356,90 @@ func (s *EventStore) save(ctx context.Context, events []eh.Event, originalVersio
 			}
 		}
 
-		dbEvents[i] = e
+		p.dbEvents[i] = e
 	}
 
-	// Either insert a new aggregate or append to an existing.
-	if originalVersion == 0 {
-		aggregate := aggregateRecord{
-			AggregateID: id,
-			Version:     len(dbEvents),
-			Events:      dbEvents,
+	// Only append if version of aggregate is matching (ie not changed since
+	// loading the aggregate).
+	if p.version > 0 && exists && aggregate.Version != p.version {
+		return nil, &eh.EventStoreError{
+			Err:              eh.ErrEventConflictFromOtherSave,
+			Op:               eh.EventStoreOpSave,
+			AggregateType:    at,
+			AggregateID:      id,
+			AggregateVersion: originalVersion,
+			Events:           events,
 		}
+	}
 
-		s.db[id] = aggregate
-	} else {
-		// Increment aggregate version on insert of new event record, and
-		// only insert if version of aggregate is matching (ie not changed
-		// since loading the aggregate).
-		if aggregate, ok := s.db[id]; ok {
-			if aggregate.Version != originalVersion {
-				return &eh.EventStoreError{
-					Err:              eh.ErrEventConflictFromOtherSave,
-					Op:               eh.EventStoreOpSave,
-					AggregateType:    at,
-					AggregateID:      id,
-					AggregateVersion: originalVersion,
-					Events:           events,
-				}
+	return p, nil
+}
+
+// isDuplicateSave returns true if deduplication is used and any of the stored
+// events of the aggregate has the idempotency key of the events to save.
+func (s *EventStore) isDuplicateSave(aggregate aggregateRecord, events []eh.Event) bool {
+	for _, e := range events {
+		if key, ok := s.idempotencyKey(e); ok {
+			if _, ok := aggregate.Keys[key]; ok {
+				return true
 			}
+		}
+	}
+
+	return false
+}
+
+// idempotencyKey returns the idempotency key of an event if deduplication is used.
+func (s *EventStore) idempotencyKey(event eh.Event) (string, bool) {
+	if s.dedupKey == "" || event == nil {
+		return "", false
+	}
 
-			aggregate.Version += len(dbEvents)
-			aggregate.Events = append(aggregate.Events, dbEvents...)
+	v, ok := event.Metadata()[s.dedupKey]
+	if !ok {
+		return "", false
+	}
+
+	return fmt.Sprint(v), true
+}
 
-			s.db[id] = aggregate
+// addKeys adds the idempotency keys of events to the keys of an aggregate.
+func (s *EventStore) addKeys(aggregate *aggregateRecord, events []eh.Event) {
+	for _, e := range events {
+		if key, ok := s.idempotencyKey(e); ok {
+			if aggregate.Keys == nil {
+				aggregate.Keys = map[string]struct{}{}
+			}
+
+			aggregate.Keys[key] = struct{}{}
 		}
 	}
+}
 
-	return nil
+// commit stores prepared events, must be called with the write lock held.
+func (s *EventStore) commit(p *pendingSave) {
+	id := p.dbEvents[0].AggregateID()
+
+	// Either insert a new aggregate or append to an existing.
+	if p.version == 0 {
+		aggregate := aggregateRecord{
+			AggregateID: id,
+			Version:     len(p.dbEvents),
+			Events:      p.dbEvents,
+		}
+		s.addKeys(&aggregate, p.dbEvents)
+
+		s.db[id] = aggregate
+		s.appendToAll(p.dbEvents)
+	} else if aggregate, ok := s.db[id]; ok {
+		// Increment aggregate version on insert of new event record.
+		aggregate.Version += len(p.dbEvents)
+		aggregate.Events = append(aggregate.Events, p.dbEvents...)
+		s.addKeys(&aggregate, p.dbEvents)
+
+		s.db[id] = aggregate
+		s.appendToAll(p.dbEvents)
+	}
 }
 
 // Load implements the Load method of the eventhorizon.EventStore interface.
@@ -208,9 +462,9 @@ func (s *EventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([
 		}
 	}
 
-	events := make([]eh.Event, len(aggregate.Events))
+	events := make([]eh.Event, 0, len(aggregate.Events))
 
-	for i, event := range aggregate.Events {
+	for _, event := range aggregate.Events {
 		if event.Version() < version {
 			continue
 		}
@@ -220,19 +474,89 @@ func (s *EventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([
 			return nil, &eh.EventStoreError{
 				Err:              fmt.Errorf("could not copy event: %w", err),
 				Op:               eh.EventStoreOpLoad,
-				AggregateType:    e.AggregateType(),
+				AggregateType:    event.AggregateType(),
 				AggregateID:      id,
-				AggregateVersion: e.Version(),
+				AggregateVersion: event.Version(),
 				Events:           events,
 			}
 		}
 
-		events[i] = e
+		events = append(events, e)
 	}
 
 	return events, nil
 }
 
+// LoadFromIter implements the LoadFromIter method of the eventhorizon.StreamingEventStore interface.
+// The events are copied one by one when iterating.
+func (s *EventStore) LoadFromIter(ctx context.Context, id uuid.UUID, version int) (eh.Iter, error) {
+	if version < 1 {
+		version = 1
+	}
+
+	return &loadIter{
+		store:   s,
+		id:      id,
+		version: version,
+	}, nil
+}
+
+// loadIter iterates over the events of an aggregate, the iterator is not thread safe.
+type loadIter struct {
+	store   *EventStore
+	id      uuid.UUID
+	version int
+	event   eh.Event
+	err     error
+}
+
+func (i *loadIter) Next(ctx context.Context) bool {
+	if i.err != nil {
+		return false
+	}
+
+	i.store.dbMu.RLock()
+	defer i.store.dbMu.RUnlock()
+
+	aggregate := i.store.db[i.id]
+
+	for i.version <= len(aggregate.Events) {
+		event := aggregate.Events[i.version-1]
+		i.version++
+
+		if event == nil {
+			continue
+		}
+
+		e, err := copyEvent(ctx, event)
+		if err != nil {
+			i.err = &eh.EventStoreError{
+				Err:              fmt.Errorf("could not copy event: %w", err),
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    event.AggregateType(),
+				AggregateID:      i.id,
+				AggregateVersion: event.Version(),
+			}
+
+			return false
+		}
+
+		i.event = e
+
+		return true
+	}
+
+	return false
+}
+
+func (i *loadIter) Value() interface{} {
+	return i.event
+}
+
+func (i *loadIter) Close(ctx context.Context) error {
+	return i.err
+}
+
 // LoadUntil implements LoadUntil method of the eventhorizon.EventStore interface.
 // LoadUntil loads all events from the first up to the given version for the aggregate id from the store.
 func (s *EventStore) LoadUntil(ctx context.Context, id uuid.UUID, version int) ([]eh.Event, error) {
@@ -248,9 +572,9 @@ func (s *EventStore) LoadUntil(ctx context.Context, id uuid.UUID, version int) (
 		}
 	}
 
-	events := make([]eh.Event, len(aggregate.Events))
+	events := make([]eh.Event, 0, len(aggregate.Events))
 
-	for i, event := range aggregate.Events {
+	for _, event := range aggregate.Events {
 		if event.Version() > version {
 			continue
 		}
@@ -260,23 +584,196 @@ func (s *EventStore) LoadUntil(ctx context.Context, id uuid.UUID, version int) (
 			return nil, &eh.EventStoreError{
 				Err:              fmt.Errorf("could not copy event: %w", err),
 				Op:               eh.EventStoreOpLoad,
-				AggregateType:    e.AggregateType(),
+				AggregateType:    event.AggregateType(),
 				AggregateID:      id,
-				AggregateVersion: e.Version(),
+				AggregateVersion: event.Version(),
 				Events:           events,
 			}
 		}
 
-		events[i] = e
+		events = append(events, e)
 	}
 
 	return events, nil
 }
 
+// LoadAllFrom implements the LoadAllFrom method of the eventhorizon.GlobalEventStore interface.
+func (s *EventStore) LoadAllFrom(ctx context.Context, position, limit int) ([]eh.Event, error) {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+
+	if position < 1 {
+		position = 1
+	}
+
+	var events []eh.Event
+
+	for i := position - 1; i < len(s.all); i++ {
+		if limit > 0 && len(events) >= limit {
+			break
+		}
+
+		ref := s.all[i]
+
+		// Skip positions of removed aggregates.
+		aggregate, ok := s.db[ref.AggregateID]
+		if !ok || ref.Version < 1 || ref.Version > len(aggregate.Events) {
+			continue
+		}
+
+		event := aggregate.Events[ref.Version-1]
+		if event == nil {
+			continue
+		}
+
+		e, err := copyEvent(ctx, event, eh.WithGlobalPosition(i+1))
+		if err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              fmt.Errorf("could not copy event: %w", err),
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    event.AggregateType(),
+				AggregateID:      event.AggregateID(),
+				AggregateVersion: event.Version(),
+				Events:           events,
+			}
+		}
+
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// QueryEvents implements the QueryEvents method of the eventhorizon.EventQuerier interface.
+// The events are matched one by one when iterating, in the order of their global position.
+func (s *EventStore) QueryEvents(ctx context.Context, query eh.EventQuery) (eh.Iter, error) {
+	return &queryIter{
+		store: s,
+		query: query,
+	}, nil
+}
+
+// queryIter iterates over the global ordered log, the iterator is not thread safe.
+type queryIter struct {
+	store    *EventStore
+	query    eh.EventQuery
+	position int
+	count    int
+	event    eh.Event
+	err      error
+}
+
+func (i *queryIter) Next(ctx context.Context) bool {
+	if i.err != nil || (i.query.Limit > 0 && i.count >= i.query.Limit) {
+		return false
+	}
+
+	i.store.dbMu.RLock()
+	defer i.store.dbMu.RUnlock()
+
+	for i.position < len(i.store.all) {
+		ref := i.store.all[i.position]
+		i.position++
+
+		// Skip positions of removed aggregates.
+		aggregate, ok := i.store.db[ref.AggregateID]
+		if !ok || ref.Version < 1 || ref.Version > len(aggregate.Events) {
+			continue
+		}
+
+		event := aggregate.Events[ref.Version-1]
+		if event == nil || !i.query.Matches(event) {
+			continue
+		}
+
+		e, err := copyEvent(ctx, event, eh.WithGlobalPosition(i.position))
+		if err != nil {
+			i.err = &eh.EventStoreError{
+				Err:              fmt.Errorf("could not copy event: %w", err),
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    event.AggregateType(),
+				AggregateID:      event.AggregateID(),
+				AggregateVersion: event.Version(),
+			}
+
+			return false
+		}
+
+		i.event = e
+		i.count++
+
+		return true
+	}
+
+	return false
+}
+
+func (i *queryIter) Value() interface{} {
+	return i.event
+}
+
+func (i *queryIter) Close(ctx context.Context) error {
+	return i.err
+}
+
+// WatchAll implements the WatchAll method of the eventhorizon.GlobalEventWatcher interface.
+func (s *EventStore) WatchAll(ctx context.Context) (<-chan struct{}, error) {
+	ch := make(chan struct{}, 1)
+
+	s.watchersMu.Lock()
+	s.watchers[ch] = struct{}{}
+	s.watchersMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		s.watchersMu.Lock()
+		delete(s.watchers, ch)
+		close(ch)
+		s.watchersMu.Unlock()
+	}()
+
+	return ch, nil
+}
+
+// notifyWatchers notifies all watchers about new events, without blocking on
+// watchers that already have a pending notification.
+func (s *EventStore) notifyWatchers() {
+	s.watchersMu.Lock()
+	defer s.watchersMu.Unlock()
+
+	for ch := range s.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// appendToAll adds the events to the global ordered log, must be called with
+// the write lock held.
+func (s *EventStore) appendToAll(events []eh.Event) {
+	for _, e := range events {
+		s.all = append(s.all, eventRef{
+			AggregateID: e.AggregateID(),
+			Version:     e.Version(),
+		})
+	}
+}
+
+// eventRef is a reference to a stored event, used as an entry in the global
+// ordered log where the index is the global position (minus one).
+type eventRef struct {
+	AggregateID uuid.UUID
+	Version     int
+}
+
 type aggregateRecord struct {
 	AggregateID uuid.UUID
 	Version     int
 	Events      []eh.Event
+	// Keys are the idempotency keys of the events, used for deduplication.
+	Keys map[string]struct{}
 	// Snapshot    eh.Aggregate
 }
 
@@ -285,8 +782,8 @@ func (s *EventStore) Close() error {
 	return nil
 }
 
-// copyEvent duplicates an event.
-func copyEvent(ctx context.Context, event eh.Event) (eh.Event, error) {
+// copyEvent duplicates an event, optionally with additional event options.
+func copyEvent(ctx context.Context, event eh.Event, options ...eh.EventOption) (eh.Event, error) {
 	var data eh.EventData
 
 	// Copy data if there is any.
@@ -299,15 +796,28 @@ func copyEvent(ctx context.Context, event eh.Event) (eh.Event, error) {
 		copier.Copy(data, event.Data())
 	}
 
-	return eh.NewEvent(
-		event.EventType(),
-		data,
-		event.Timestamp(),
+	// Copy the metadata to not modify the original when adding to it.
+	var metadata map[string]interface{}
+	if event.Metadata() != nil || len(options) > 0 {
+		metadata = make(map[string]interface{}, len(event.Metadata()))
+		for k, v := range event.Metadata() {
+			metadata[k] = v
+		}
+	}
+
+	options = append([]eh.EventOption{
 		eh.ForAggregate(
 			event.AggregateType(),
 			event.AggregateID(),
 			event.Version(),
 		),
-		eh.WithMetadata(event.Metadata()),
+		eh.WithMetadata(metadata),
+	}, options...)
+
+	return eh.NewEvent(
+		event.EventType(),
+		data,
+		event.Timestamp(),
+		options...,
 	), nil
 }
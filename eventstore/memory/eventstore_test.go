@@ -16,6 +16,7 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -36,12 +37,64 @@ func TestEventStore(t *testing.T) {
 	}
 
 	eventstore.AcceptanceTest(t, store, context.Background())
+	eventstore.GlobalAcceptanceTest(t, store, store, context.Background())
+	eventstore.QueryAcceptanceTest(t, store, store, context.Background())
+	eventstore.StreamingAcceptanceTest(t, store, store, context.Background())
+	eventstore.SaveManyAcceptanceTest(t, store, context.Background())
+	eventstore.ExpectedVersionAcceptanceTest(t, store, context.Background())
+	eventstore.SnapshotAcceptanceTest(t, store, context.Background())
 
 	if err := store.Close(); err != nil {
 		t.Error("there should be no error:", err)
 	}
 }
 
+func TestWithDeduplication(t *testing.T) {
+	store, err := NewEventStore(WithDeduplication())
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eventstore.DeduplicationAcceptanceTest(t, store, context.Background())
+
+	if _, err := NewEventStore(WithDeduplicationKey("")); err == nil {
+		t.Error("there should be an error for an empty key")
+	}
+}
+
+func TestWithDeduplication_Replace(t *testing.T) {
+	store, err := NewEventStore(WithDeduplicationKey("key"))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	newEvent := func(key string, version int) eh.Event {
+		return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, version),
+			eh.WithMetadata(map[string]interface{}{"key": key}))
+	}
+
+	if err := store.Save(ctx, []eh.Event{newEvent("a", 1)}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Replacing the event should update the idempotency keys.
+	if err := store.Replace(ctx, newEvent("b", 1)); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{newEvent("b", 2)}, 1); !errors.Is(err, eh.ErrDuplicateSave) {
+		t.Error("there should be a duplicate save error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{newEvent("a", 2)}, 1); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
 func TestWithEventHandler(t *testing.T) {
 	h := &mocks.EventBus{}
 
@@ -96,6 +149,26 @@ func TestWithEventHandler(t *testing.T) {
 			t.Error("the event version should be correct:", event, event.Version())
 		}
 	}
+
+	// The handled events should have the versions assigned by the store.
+	event2 := eh.NewEventForAggregate(mocks.EventType, &mocks.EventData{Content: "event2"},
+		timestamp, mocks.AggregateType, id1, 0)
+
+	if err := store.Save(ctx, []eh.Event{event2}, eh.AnyVersion); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(h.Events) != 2 {
+		t.Fatal("there should be two handled events:", len(h.Events))
+	}
+
+	if err := eh.CompareEvents(h.Events[1], event2, eh.IgnoreVersion()); err != nil {
+		t.Error("the handled event was incorrect:", err)
+	}
+
+	if h.Events[1].Version() != 2 {
+		t.Error("the handled event version should be assigned:", h.Events[1].Version())
+	}
 }
 
 func BenchmarkEventStore(b *testing.B) {
@@ -110,3 +183,16 @@ func BenchmarkEventStore(b *testing.B) {
 
 	eventstore.Benchmark(b, store)
 }
+
+func TestSnapshotHistory(t *testing.T) {
+	store, err := NewEventStore(WithSnapshotRetention(3))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eventstore.SnapshotHistoryAcceptanceTest(t, store, context.Background())
+
+	if _, err := NewEventStore(WithSnapshotRetention(-1)); err == nil {
+		t.Error("there should be an error")
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// snapshotRecord is the stored snapshot, the state is stored as JSON to not
+// share it with the caller and to behave like the persisted stores.
+type snapshotRecord struct {
+	AggregateType eh.AggregateType
+	Version       int
+	Timestamp     time.Time
+	SchemaVersion int
+	RawState      []byte
+}
+
+// LoadSnapshot implements the LoadSnapshot method of the eventhorizon.SnapshotStore interface.
+// Returns nil if there is no snapshot for the aggregate.
+func (s *EventStore) LoadSnapshot(ctx context.Context, id uuid.UUID) (*eh.Snapshot, error) {
+	s.dbMu.RLock()
+	records := s.snapshots[id]
+	s.dbMu.RUnlock()
+
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	return newSnapshot(id, records[len(records)-1])
+}
+
+// LoadSnapshotAt implements the LoadSnapshotAt method of the eventhorizon.SnapshotHistoryStore interface.
+func (s *EventStore) LoadSnapshotAt(ctx context.Context, id uuid.UUID, maxVersion int) (*eh.Snapshot, error) {
+	s.dbMu.RLock()
+	records := s.snapshots[id]
+	s.dbMu.RUnlock()
+
+	// The records are sorted by version.
+	i := sort.Search(len(records), func(i int) bool {
+		return records[i].Version > maxVersion
+	})
+	if i == 0 {
+		return nil, nil
+	}
+
+	return newSnapshot(id, records[i-1])
+}
+
+// SaveSnapshot implements the SaveSnapshot method of the eventhorizon.SnapshotStore interface.
+// A snapshot with the same version is replaced and snapshots outside of the
+// retention are pruned.
+func (s *EventStore) SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot eh.Snapshot) error {
+	if snapshot.AggregateType == "" {
+		return &eh.EventStoreError{
+			Err:           fmt.Errorf("aggregate type is empty"),
+			Op:            eh.EventStoreOpSaveSnapshot,
+			AggregateID:   id,
+			AggregateType: snapshot.AggregateType,
+		}
+	}
+
+	if snapshot.State == nil {
+		return &eh.EventStoreError{
+			Err:           fmt.Errorf("snapshots state is nil"),
+			Op:            eh.EventStoreOpSaveSnapshot,
+			AggregateID:   id,
+			AggregateType: snapshot.AggregateType,
+		}
+	}
+
+	record := snapshotRecord{
+		AggregateType: snapshot.AggregateType,
+		Version:       snapshot.Version,
+		Timestamp:     snapshot.Timestamp,
+		SchemaVersion: eh.SnapshotSchemaVersion(snapshot.AggregateType),
+	}
+
+	var err error
+	if record.RawState, err = json.Marshal(snapshot.State); err != nil {
+		return &eh.EventStoreError{
+			Err:           fmt.Errorf("could not encode snapshot state: %w", err),
+			Op:            eh.EventStoreOpSaveSnapshot,
+			AggregateID:   id,
+			AggregateType: snapshot.AggregateType,
+		}
+	}
+
+	s.dbMu.Lock()
+	defer s.dbMu.Unlock()
+
+	// The records are copied on write as loading reads them without the lock.
+	old := s.snapshots[id]
+
+	i := sort.Search(len(old), func(i int) bool {
+		return old[i].Version >= record.Version
+	})
+
+	records := make([]snapshotRecord, 0, len(old)+1)
+	records = append(records, old[:i]...)
+	records = append(records, record)
+
+	if i < len(old) && old[i].Version == record.Version {
+		i++
+	}
+
+	records = append(records, old[i:]...)
+
+	if s.retention > 0 && len(records) > s.retention {
+		records = records[len(records)-s.retention:]
+	}
+
+	s.snapshots[id] = records
+
+	return nil
+}
+
+// RemoveSnapshotsAfter implements the RemoveSnapshotsAfter method of the eventhorizon.SnapshotHistoryStore interface.
+func (s *EventStore) RemoveSnapshotsAfter(ctx context.Context, id uuid.UUID, version int) error {
+	s.dbMu.Lock()
+	defer s.dbMu.Unlock()
+
+	records := s.snapshots[id]
+
+	i := sort.Search(len(records), func(i int) bool {
+		return records[i].Version > version
+	})
+	if i == 0 {
+		delete(s.snapshots, id)
+
+		return nil
+	}
+
+	s.snapshots[id] = records[:i:i]
+
+	return nil
+}
+
+// newSnapshot decodes a stored snapshot record.
+func newSnapshot(id uuid.UUID, record snapshotRecord) (*eh.Snapshot, error) {
+	if err := eh.CheckSnapshotSchemaVersion(record.AggregateType, record.SchemaVersion); err != nil {
+		return nil, &eh.EventStoreError{
+			Err:              err,
+			Op:               eh.EventStoreOpLoadSnapshot,
+			AggregateType:    record.AggregateType,
+			AggregateID:      id,
+			AggregateVersion: record.Version,
+		}
+	}
+
+	snapshot := &eh.Snapshot{
+		Version:       record.Version,
+		AggregateType: record.AggregateType,
+		Timestamp:     record.Timestamp,
+		SchemaVersion: record.SchemaVersion,
+	}
+
+	var err error
+	if snapshot.State, err = eh.CreateSnapshotData(id, record.AggregateType); err != nil {
+		return nil, &eh.EventStoreError{
+			Err:           fmt.Errorf("could not create snapshot data: %w", err),
+			Op:            eh.EventStoreOpLoadSnapshot,
+			AggregateType: record.AggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	if err := json.Unmarshal(record.RawState, snapshot.State); err != nil {
+		return nil, &eh.EventStoreError{
+			Err:           fmt.Errorf("could not decode snapshot state: %w", err),
+			Op:            eh.EventStoreOpLoadSnapshot,
+			AggregateType: record.AggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2015 - The Event Horizon authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoOptions "go.mongodb.org/mongo-driver/mongo/options"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// CheckConsistency implements the CheckConsistency method of the
+// eventhorizon.EventStoreChecker interface. The version of an aggregate
+// document is repaired to match its last event.
+func (s *EventStore) CheckConsistency(ctx context.Context, repair bool) ([]eh.Inconsistency, error) {
+	var (
+		inconsistencies []eh.Inconsistency
+		unregistered    = map[eh.EventType]int{}
+	)
+
+	if err := s.database.DatabaseExec(ctx, func(ctx context.Context, db *mongo.Database) error {
+		aggregates := db.Collection(s.collectionName)
+
+		// Only the fields needed for the check are loaded, not the event data.
+		cursor, err := aggregates.Aggregate(ctx, mongo.Pipeline{
+			{{Key: "$project", Value: bson.M{
+				"version": 1,
+				"events": bson.M{"$map": bson.M{
+					"input": "$events",
+					"as":    "e",
+					"in": bson.M{
+						"version":        "$$e.version",
+						"event_type":     "$$e.event_type",
+						"aggregate_type": "$$e.aggregate_type",
+						"has_data":       bson.M{"$ne": bson.A{bson.M{"$type": "$$e.data"}, "missing"}},
+					},
+				}},
+			}}},
+			{{Key: "$sort", Value: bson.M{"_id": 1}}},
+		}, mongoOptions.Aggregate().SetAllowDiskUse(true))
+		if err != nil {
+			return fmt.Errorf("could not find aggregates: %w", err)
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var aggregate struct {
+				ID      uuid.UUID `bson:"_id"`
+				Version int       `bson:"version"`
+				Events  []struct {
+					Version       int              `bson:"version"`
+					EventType     eh.EventType     `bson:"event_type"`
+					AggregateType eh.AggregateType `bson:"aggregate_type"`
+					HasData       bool             `bson:"has_data"`
+				} `bson:"events"`
+			}
+
+			if err := cursor.Decode(&aggregate); err != nil {
+				return fmt.Errorf("could not decode aggregate: %w", err)
+			}
+
+			var (
+				aggregateType eh.AggregateType
+				versions      = make([]int, 0, len(aggregate.Events))
+			)
+
+			for _, e := range aggregate.Events {
+				aggregateType = e.AggregateType
+				versions = append(versions, e.Version)
+
+				if e.HasData && !eh.IsEventDataRegistered(e.EventType) {
+					unregistered[e.EventType]++
+				}
+			}
+
+			sort.Ints(versions)
+
+			inconsistencies = append(inconsistencies,
+				eh.CheckEventVersions(aggregateType, aggregate.ID, versions, true)...)
+
+			lastVersion := 0
+			if len(versions) > 0 {
+				lastVersion = versions[len(versions)-1]
+			}
+
+			if aggregate.Version != lastVersion {
+				inconsistency := eh.Inconsistency{
+					Kind:          eh.InconsistencyStreamMismatch,
+					AggregateType: aggregateType,
+					AggregateID:   aggregate.ID,
+					Details:       fmt.Sprintf("version %d, last event version %d", aggregate.Version, lastVersion),
+				}
+
+				if repair {
+					if _, err := aggregates.UpdateOne(ctx,
+						bson.M{"_id": aggregate.ID, "version": aggregate.Version},
+						bson.M{"$set": bson.M{"version": lastVersion}},
+					); err != nil {
+						return fmt.Errorf("could not repair aggregate %s: %w", aggregate.ID, err)
+					}
+
+					inconsistency.Repaired = true
+				}
+
+				inconsistencies = append(inconsistencies, inconsistency)
+			}
+		}
+
+		if err := cursor.Err(); err != nil {
+			return fmt.Errorf("could not find aggregates: %w", err)
+		}
+
+		snapshots, err := db.Collection(s.snapshotsCollectionName).Find(ctx, bson.M{},
+			mongoOptions.Find().
+				SetProjection(bson.M{"snapshots": bson.M{"$slice": -1}, "snapshots.state": 0}).
+				SetSort(bson.M{"_id": 1}),
+		)
+		if err != nil {
+			return fmt.Errorf("could not find snapshots: %w", err)
+		}
+		defer snapshots.Close(ctx)
+
+		for snapshots.Next(ctx) {
+			var record snapshotsRecord
+			if err := snapshots.Decode(&record); err != nil {
+				return fmt.Errorf("could not decode snapshot: %w", err)
+			}
+
+			if len(record.Snapshots) == 0 {
+				continue
+			}
+
+			snapshot := record.Snapshots[len(record.Snapshots)-1]
+
+			var aggregate struct {
+				Version int `bson:"version"`
+			}
+
+			if err := aggregates.FindOne(ctx, bson.M{"_id": record.AggregateID},
+				mongoOptions.FindOne().SetProjection(bson.M{"version": 1}),
+			).Decode(&aggregate); err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				return fmt.Errorf("could not find aggregate %s: %w", record.AggregateID, err)
+			}
+
+			if snapshot.Version > aggregate.Version {
+				inconsistencies = append(inconsistencies, eh.Inconsistency{
+					Kind:          eh.InconsistencySnapshotAhead,
+					AggregateType: snapshot.AggregateType,
+					AggregateID:   record.AggregateID,
+					Version:       snapshot.Version,
+					Details:       fmt.Sprintf("aggregate version %d", aggregate.Version),
+				})
+			}
+		}
+
+		return snapshots.Err()
+	}); err != nil {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("could not check consistency: %w", err),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+
+	return append(inconsistencies, eh.UnregisteredEventDataInconsistencies(unregistered)...), nil
+}
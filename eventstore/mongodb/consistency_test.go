@@ -0,0 +1,115 @@
+// Copyright (c) 2015 - The Event Horizon authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+func TestCheckConsistencyIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// Use MongoDB in Docker with fallback to localhost.
+	addr := os.Getenv("MONGODB_ADDR")
+	if addr == "" {
+		addr = "localhost:27017"
+	}
+
+	url := "mongodb://" + addr
+
+	// Get a random DB name.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	db := "test-" + hex.EncodeToString(b)
+
+	t.Log("using DB:", db)
+
+	store, err := NewEventStore(url, db)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	ctx := context.Background()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	id := uuid.New()
+
+	var events []eh.Event
+	for i := 1; i <= 3; i++ {
+		events = append(events, eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, i)))
+	}
+
+	if err := store.Save(ctx, events, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	inconsistencies, err := store.CheckConsistency(ctx, false)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(inconsistencies) != 0 {
+		t.Fatal("there should be no inconsistencies:", inconsistencies)
+	}
+
+	// Remove the second event and let the aggregate version lag behind.
+	if err := store.database.CollectionExec(ctx, store.collectionName, func(ctx context.Context, c *mongo.Collection) error {
+		_, err := c.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+			"$pull": bson.M{"events": bson.M{"version": 2}},
+			"$set":  bson.M{"version": 1},
+		})
+
+		return err
+	}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if inconsistencies, err = store.CheckConsistency(ctx, true); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(inconsistencies) != 2 ||
+		inconsistencies[0].Kind != eh.InconsistencyVersionGap ||
+		inconsistencies[1].Kind != eh.InconsistencyStreamMismatch || !inconsistencies[1].Repaired {
+		t.Fatal("the inconsistencies should be found:", inconsistencies)
+	}
+
+	if inconsistencies, err = store.CheckConsistency(ctx, false); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(inconsistencies) != 1 || inconsistencies[0].Kind != eh.InconsistencyVersionGap {
+		t.Error("only the gap should remain after the repair:", inconsistencies)
+	}
+}
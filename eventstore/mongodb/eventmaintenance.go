@@ -20,6 +20,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	mongoOptions "go.mongodb.org/mongo-driver/mongo/options"
 
 	// Register uuid.UUID as BSON type.
 	_ "github.com/Clarilab/eventhorizon/codec/bson"
@@ -108,6 +109,187 @@ func (s *EventStore) Replace(ctx context.Context, event eh.Event) error {
 	return nil
 }
 
+// Transform implements the Transform method of the eventhorizon.EventStoreTransformer interface.
+// The events are transformed per aggregate in the order of the aggregate IDs,
+// a batch always contains all matching events of an aggregate. The checkpoint
+// is the ID of the last aggregate in a completed batch.
+func (s *EventStore) Transform(ctx context.Context, matcher eh.EventMatcher, transform eh.EventTransformFunc, options ...eh.TransformOption) (*eh.TransformReport, error) {
+	opts := eh.NewTransformOptions(options...)
+	report := &eh.TransformReport{Checkpoint: opts.Checkpoint}
+
+	checkpoint := opts.Checkpoint
+
+	if checkpoint != "" {
+		if _, err := uuid.Parse(checkpoint); err != nil {
+			return report, &eh.EventStoreError{
+				Err: fmt.Errorf("invalid checkpoint: %w", err),
+				Op:  eh.EventStoreOpTransform,
+			}
+		}
+	}
+
+	for {
+		batch, last, err := s.matchingEvents(ctx, matcher, checkpoint, opts.BatchSize)
+		if err != nil {
+			return report, err
+		}
+
+		if last == checkpoint {
+			return report, nil
+		}
+
+		var changes []eh.TransformChange
+
+		for _, event := range batch {
+			transformed, err := eh.TransformEvent(event, transform)
+			if err != nil {
+				return report, &eh.EventStoreError{
+					Err:              err,
+					Op:               eh.EventStoreOpTransform,
+					AggregateType:    event.AggregateType(),
+					AggregateID:      event.AggregateID(),
+					AggregateVersion: event.Version(),
+					Events:           []eh.Event{event},
+				}
+			}
+
+			if transformed != nil {
+				changes = append(changes, eh.TransformChange{Before: event, After: transformed})
+			}
+		}
+
+		if opts.DryRun {
+			report.Changes = append(report.Changes, changes...)
+		} else if err := s.replaceEvents(ctx, changes); err != nil {
+			return report, err
+		}
+
+		checkpoint = last
+		report.Matched += len(batch)
+		report.Changed += len(changes)
+		report.Checkpoint = last
+
+		if opts.Progress != nil {
+			if err := opts.Progress(ctx, *report); err != nil {
+				return report, &eh.EventStoreError{
+					Err: fmt.Errorf("could not report progress: %w", err),
+					Op:  eh.EventStoreOpTransform,
+				}
+			}
+		}
+	}
+}
+
+// matchingEvents returns the matching events of the aggregates after the
+// checkpoint, until at least limit events are matched, and the ID of the
+// last aggregate that was checked.
+func (s *EventStore) matchingEvents(ctx context.Context, matcher eh.EventMatcher, checkpoint string, limit int) ([]eh.Event, string, error) {
+	var events []eh.Event
+
+	last := checkpoint
+
+	filter := bson.M{}
+	if checkpoint != "" {
+		filter["_id"] = bson.M{"$gt": checkpoint}
+	}
+
+	if err := s.database.CollectionExec(ctx, s.collectionName, func(ctx context.Context, c *mongo.Collection) error {
+		cursor, err := c.Find(ctx, filter, mongoOptions.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+		if err != nil {
+			return &eh.EventStoreError{
+				Err: fmt.Errorf("could not find aggregates: %w", err),
+				Op:  eh.EventStoreOpTransform,
+			}
+		}
+
+		defer cursor.Close(ctx)
+
+		for len(events) < limit && cursor.Next(ctx) {
+			var aggregate aggregateRecord
+			if err := cursor.Decode(&aggregate); err != nil {
+				return &eh.EventStoreError{
+					Err: fmt.Errorf("could not decode aggregate: %w", err),
+					Op:  eh.EventStoreOpTransform,
+				}
+			}
+
+			// Only the data of matching events is decoded, so that events
+			// with data that can't be decoded, for example of unregistered
+			// event types, are skipped if not matched.
+			var matching []evt
+
+			for _, e := range aggregate.Events {
+				if matcher.Match(e.header()) {
+					matching = append(matching, e)
+				}
+			}
+
+			aggregateEvents, err := decodeEvents(ctx, aggregate.AggregateID, matching)
+			if err != nil {
+				return err
+			}
+
+			events = append(events, aggregateEvents...)
+
+			last = aggregate.AggregateID.String()
+		}
+
+		if err := cursor.Err(); err != nil {
+			return &eh.EventStoreError{
+				Err: fmt.Errorf("could not iterate aggregates: %w", err),
+				Op:  eh.EventStoreOpTransform,
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, checkpoint, err
+	}
+
+	return events, last, nil
+}
+
+// replaceEvents stores the transformed events of the changes.
+func (s *EventStore) replaceEvents(ctx context.Context, changes []eh.TransformChange) error {
+	for _, change := range changes {
+		event := change.After
+
+		e, err := newEvt(ctx, event)
+		if err != nil {
+			return err
+		}
+
+		if err := s.database.CollectionExec(ctx, s.collectionName, func(ctx context.Context, c *mongo.Collection) error {
+			if r, err := c.UpdateOne(ctx,
+				bson.M{
+					"_id":            event.AggregateID(),
+					"events.version": event.Version(),
+				},
+				bson.M{
+					"$set": bson.M{"events.$": *e},
+				},
+			); err != nil {
+				return fmt.Errorf("could not replace event: %w", err)
+			} else if r.MatchedCount == 0 {
+				return eh.ErrEventNotFound
+			}
+
+			return nil
+		}); err != nil {
+			return &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpTransform,
+				AggregateType:    event.AggregateType(),
+				AggregateID:      event.AggregateID(),
+				AggregateVersion: event.Version(),
+				Events:           []eh.Event{event},
+			}
+		}
+	}
+
+	return nil
+}
+
 // RenameEvent implements the RenameEvent method of the eventhorizon.EventStoreMaintenance interface.
 func (s *EventStore) RenameEvent(ctx context.Context, from, to eh.EventType) error {
 	const errMessage = "could not rename event: %w"
@@ -138,6 +320,7 @@ func (s *EventStore) RenameEvent(ctx context.Context, from, to eh.EventType) err
 }
 
 // Remove implements the Remove method of the eventhorizon.EventStoreMaintenance interface.
+// Any snapshot of the aggregate is also removed.
 func (s *EventStore) Remove(ctx context.Context, id uuid.UUID) error {
 	const errMessage = "could not remove event: %w"
 
@@ -159,10 +342,25 @@ func (s *EventStore) Remove(ctx context.Context, id uuid.UUID) error {
 		return fmt.Errorf(errMessage, err)
 	}
 
+	if err := s.database.CollectionExec(ctx, s.snapshotsCollectionName, func(ctx context.Context, c *mongo.Collection) error {
+		if _, err := c.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+			return &eh.EventStoreError{
+				Err:         fmt.Errorf("could not delete snapshot for aggregate '%s': %w", id, err),
+				Op:          eh.EventStoreOpRemove,
+				AggregateID: id,
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf(errMessage, err)
+	}
+
 	return nil
 }
 
 // Clear implements the Clear method of the eventhorizon.EventStoreMaintenance interface.
+// It removes all events and snapshots.
 func (s *EventStore) Clear(ctx context.Context) error {
 	if err := s.database.CollectionDrop(ctx, s.collectionName); err != nil {
 		return &eh.EventStoreError{
@@ -171,5 +369,12 @@ func (s *EventStore) Clear(ctx context.Context) error {
 		}
 	}
 
+	if err := s.database.CollectionDrop(ctx, s.snapshotsCollectionName); err != nil {
+		return &eh.EventStoreError{
+			Err: fmt.Errorf("could not drop snapshots: %w", err),
+			Op:  eh.EventStoreOpClear,
+		}
+	}
+
 	return nil
 }
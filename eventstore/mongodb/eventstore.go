@@ -27,26 +27,29 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 
-	// Register uuid.UUID as BSON type.
-	_ "github.com/Clarilab/eventhorizon/codec/bson"
+	// Used for upcasting event data, also registers uuid.UUID as BSON type.
+	bsonCodec "github.com/Clarilab/eventhorizon/codec/bson"
 
 	eh "github.com/Clarilab/eventhorizon"
 	"github.com/Clarilab/eventhorizon/uuid"
 )
 
 const (
-	defaultCollectionName = "events"
+	defaultCollectionName          = "events"
+	defaultSnapshotsCollectionName = "snapshots"
 )
 
 // EventStore implements an eventhorizon.EventStore for MongoDB using a single
 // collection with one document per aggregate/stream which holds its events
 // as values.
 type EventStore struct {
-	database              eh.MongoDB
-	dbOwnership           dbOwnership
-	collectionName        string
-	eventHandlerAfterSave eh.EventHandler
-	eventHandlerInTX      eh.EventHandler
+	database                eh.MongoDB
+	dbOwnership             dbOwnership
+	collectionName          string
+	snapshotsCollectionName string
+	snapshotRetention       int
+	eventHandlerAfterSave   eh.EventHandler
+	eventHandlerInTX        eh.EventHandler
 }
 
 type dbOwnership int
@@ -87,9 +90,11 @@ func newMongoDBEventStore(db eh.MongoDB, dbOwnership dbOwnership, options ...Opt
 	}
 
 	s := &EventStore{
-		dbOwnership:    dbOwnership,
-		database:       db,
-		collectionName: defaultCollectionName,
+		dbOwnership:             dbOwnership,
+		database:                db,
+		collectionName:          defaultCollectionName,
+		snapshotsCollectionName: defaultSnapshotsCollectionName,
+		snapshotRetention:       1,
 	}
 
 	for i := range options {
@@ -120,6 +125,15 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 	id := events[0].AggregateID()
 	at := events[0].AggregateType()
 
+	// The versions of the events are assigned when saving for the expected
+	// version modes AnyVersion and StreamExists.
+	assignVersions := originalVersion == eh.AnyVersion || originalVersion == eh.StreamExists
+
+	version := originalVersion
+	if originalVersion == eh.NoStream {
+		version = 0
+	}
+
 	// Build all event records, with incrementing versions starting from the
 	// original aggregate version.
 	for i, event := range events {
@@ -147,7 +161,7 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 		}
 
 		// Only accept events that apply to the correct aggregate version.
-		if event.Version() != originalVersion+i+1 {
+		if !assignVersions && event.Version() != version+i+1 {
 			return &eh.EventStoreError{
 				Err:              eh.ErrIncorrectEventVersion,
 				Op:               eh.EventStoreOpSave,
@@ -175,10 +189,28 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 	}
 
 	// Run the operation in a transaction if using an outbox, otherwise it's not needed.
+	saved := events
+
 	saveEvents := func(ctx mongo.SessionContext, c *mongo.Collection) error {
+		if assignVersions {
+			var err error
+			if version, err = s.aggregateVersion(ctx, c, id); err != nil {
+				return err
+			}
+
+			if version == 0 && originalVersion == eh.StreamExists {
+				return eh.ErrStreamNotFound
+			}
+
+			saved = make([]eh.Event, len(events))
+			for i := range dbEvents {
+				dbEvents[i].Version = version + i + 1
+				saved[i] = eh.EventWithVersion(events[i], version+i+1)
+			}
+		}
 
 		// Either insert a new aggregate or append to an existing.
-		if originalVersion == 0 {
+		if version == 0 {
 			aggregate := aggregateRecord{
 				AggregateID: id,
 				Version:     len(dbEvents),
@@ -186,6 +218,10 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 			}
 
 			if _, err := c.InsertOne(ctx, aggregate); err != nil {
+				if originalVersion == eh.NoStream && mongo.IsDuplicateKeyError(err) {
+					return eh.ErrStreamExists
+				}
+
 				return fmt.Errorf("could not insert events (new): %w", err)
 			}
 		} else {
@@ -195,7 +231,7 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 			if r, err := c.UpdateOne(ctx,
 				bson.M{
 					"_id":     id,
-					"version": originalVersion,
+					"version": version,
 				},
 				bson.M{
 					"$push": bson.M{"events": bson.M{"$each": dbEvents}},
@@ -225,8 +261,8 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 				}
 			}
 
-			for i := range events {
-				if err := s.eventHandlerInTX.HandleEvent(ctx, events[i]); err != nil {
+			for i := range saved {
+				if err := s.eventHandlerInTX.HandleEvent(ctx, saved[i]); err != nil {
 					return fmt.Errorf("could not handle event in transaction: %w", err)
 				}
 			}
@@ -265,7 +301,7 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 
 	// Let the optional event handler handle the events.
 	if s.eventHandlerAfterSave != nil {
-		for _, e := range events {
+		for _, e := range saved {
 			if err := s.eventHandlerAfterSave.HandleEvent(ctx, e); err != nil {
 				return &eh.EventHandlerError{
 					Err:   err,
@@ -278,6 +314,24 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 	return nil
 }
 
+// aggregateVersion returns the current version of an aggregate, or 0 if there
+// are no events for it.
+func (s *EventStore) aggregateVersion(ctx context.Context, c *mongo.Collection, id uuid.UUID) (int, error) {
+	var aggregate struct {
+		Version int `bson:"version"`
+	}
+
+	if err := c.FindOne(ctx, bson.M{"_id": id},
+		mongoOptions.FindOne().SetProjection(bson.M{"version": 1}),
+	).Decode(&aggregate); errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("could not find aggregate version: %w", err)
+	}
+
+	return aggregate.Version, nil
+}
+
 // Load implements the Load method of the eventhorizon.EventStore interface.
 func (s *EventStore) Load(ctx context.Context, id uuid.UUID) ([]eh.Event, error) {
 	return s.LoadFrom(ctx, id, 1)
@@ -344,12 +398,28 @@ func (s *EventStore) load(ctx context.Context, id uuid.UUID, options *mongoOptio
 		return nil, fmt.Errorf(errMessage, err)
 	}
 
-	events := make([]eh.Event, len(aggregate.Events))
+	return decodeEvents(ctx, id, aggregate.Events)
+}
 
-	for i, e := range aggregate.Events {
+// decodeEvents decodes the event records of an aggregate.
+func decodeEvents(ctx context.Context, id uuid.UUID, records []evt) ([]eh.Event, error) {
+	events := make([]eh.Event, len(records))
+
+	for i, e := range records {
 		// Create an event of the correct type and decode from raw BSON.
 		if len(e.RawData) > 0 {
 			var err error
+			if e.RawData, err = bsonCodec.UpcastRawData(ctx, e.EventType, e.SchemaVersion, e.RawData); err != nil {
+				return nil, &eh.EventStoreError{
+					Err:              fmt.Errorf("could not upcast event data: %w", err),
+					Op:               eh.EventStoreOpLoad,
+					AggregateType:    e.AggregateType,
+					AggregateID:      id,
+					AggregateVersion: e.Version,
+					Events:           events,
+				}
+			}
+
 			if e.data, err = eh.CreateEventData(e.EventType); err != nil {
 				return nil, &eh.EventStoreError{
 					Err:              fmt.Errorf("could not create event data: %w", err),
@@ -406,6 +476,9 @@ func (s *EventStore) Close() error {
 // EventsCollectionName returns the name of the events collection.
 func (s *EventStore) EventsCollectionName() string { return s.collectionName }
 
+// SnapshotsCollectionName returns the name of the snapshots collection.
+func (s *EventStore) SnapshotsCollectionName() string { return s.snapshotsCollectionName }
+
 // aggregateRecord is the Database representation of an aggregate.
 type aggregateRecord struct {
 	AggregateID uuid.UUID `bson:"_id"`
@@ -421,6 +494,7 @@ type evt struct {
 	EventType     eh.EventType           `bson:"event_type"`
 	RawData       bson.Raw               `bson:"data,omitempty"`
 	data          eh.EventData           `bson:"-"`
+	SchemaVersion int                    `bson:"schema_version,omitempty"`
 	Timestamp     time.Time              `bson:"timestamp"`
 	AggregateType eh.AggregateType       `bson:"aggregate_type"`
 	AggregateID   uuid.UUID              `bson:"_id"`
@@ -428,6 +502,22 @@ type evt struct {
 	Metadata      map[string]interface{} `bson:"metadata"`
 }
 
+// header returns the event of the record without its data, used to match
+// events before decoding their data.
+func (e *evt) header() eh.Event {
+	return eh.NewEvent(
+		e.EventType,
+		nil,
+		e.Timestamp,
+		eh.ForAggregate(
+			e.AggregateType,
+			e.AggregateID,
+			e.Version,
+		),
+		eh.WithMetadata(e.Metadata),
+	)
+}
+
 // newEvt returns a new evt for an event.
 func newEvt(ctx context.Context, event eh.Event) (*evt, error) {
 	e := &evt{
@@ -447,6 +537,8 @@ func newEvt(ctx context.Context, event eh.Event) (*evt, error) {
 		if err != nil {
 			return nil, fmt.Errorf("could not marshal event data: %w", err)
 		}
+
+		e.SchemaVersion = eh.EventSchemaVersion(event.EventType())
 	}
 
 	return e, nil
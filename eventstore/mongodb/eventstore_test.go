@@ -61,6 +61,23 @@ func TestEventStoreIntegration(t *testing.T) {
 	}
 
 	eventstore.AcceptanceTest(t, store, context.Background())
+	eventstore.UpcastAcceptanceTest(t, store, context.Background())
+	eventstore.ExpectedVersionAcceptanceTest(t, store, context.Background())
+	eventstore.SnapshotAcceptanceTest(t, store, context.Background())
+
+	if err := store.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	store, err = NewEventStore(url, db,
+		WithSnapshotCollectionName("history_snapshots"),
+		WithSnapshotRetention(3),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eventstore.SnapshotHistoryAcceptanceTest(t, store, context.Background())
 
 	if err := store.Close(); err != nil {
 		t.Error("there should be no error:", err)
@@ -108,6 +125,19 @@ func TestWithCollectionNameIntegration(t *testing.T) {
 		t.Fatal("events collection should use custom collection name")
 	}
 
+	store, err = NewEventStore(url, db,
+		WithSnapshotCollectionName("foo_snapshots"),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	if store.SnapshotsCollectionName() != "foo_snapshots" {
+		t.Fatal("snapshots collection should use custom collection name")
+	}
+
 	// providing empty collection names should result in an error
 	_, err = NewEventStore(url, db,
 		WithCollectionName(""),
@@ -0,0 +1,191 @@
+// Copyright (c) 2015 - The Event Horizon authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoOptions "go.mongodb.org/mongo-driver/mongo/options"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// TimestampCursor is the position of an event in the order used by LoadAllByTimestamp.
+type TimestampCursor struct {
+	Timestamp   time.Time `bson:"timestamp"`
+	AggregateID uuid.UUID `bson:"aggregate_id"`
+	Version     int       `bson:"version"`
+}
+
+// CursorForEvent returns the cursor of an event loaded by LoadAllByTimestamp.
+func CursorForEvent(event eh.Event) TimestampCursor {
+	return TimestampCursor{
+		Timestamp:   event.Timestamp(),
+		AggregateID: event.AggregateID(),
+		Version:     event.Version(),
+	}
+}
+
+// LoadAllByTimestamp loads events from all aggregates ordered by timestamp,
+// with the aggregate ID and version as tie breakers, starting after the cursor
+// or from the first event if nil. At most limit events are returned, a limit of 0 or less loads all
+// remaining events.
+//
+// The events are sorted on the server with disk use allowed, which is slow for
+// large collections and should not be used for regular reads. To read all
+// events use LoadAllByTimestampIter, which only sorts them once.
+func (s *EventStore) LoadAllByTimestamp(ctx context.Context, after *TimestampCursor, limit int) ([]eh.Event, error) {
+	const errMessage = "could not load events: %w"
+
+	iter, err := s.loadAllByTimestamp(ctx, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf(errMessage, err)
+	}
+
+	var events []eh.Event
+
+	for iter.Next(ctx) {
+		events = append(events, iter.event)
+	}
+
+	if err := iter.Close(ctx); err != nil {
+		return nil, fmt.Errorf(errMessage, err)
+	}
+
+	return events, nil
+}
+
+// LoadAllByTimestampIter returns an iterator over the events from all
+// aggregates in the order of LoadAllByTimestamp, starting after the cursor or
+// from the first event if nil. The events are sorted once on the server and
+// read in batches from a single cursor, which is used to migrate events to
+// stores that keep track of the global position of events, as this store does
+// not. The iterator must be closed.
+func (s *EventStore) LoadAllByTimestampIter(ctx context.Context, after *TimestampCursor) (eh.Iter, error) {
+	iter, err := s.loadAllByTimestamp(ctx, after, 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not load events: %w", err)
+	}
+
+	return iter, nil
+}
+
+func (s *EventStore) loadAllByTimestamp(ctx context.Context, after *TimestampCursor, limit int) (*timestampIter, error) {
+	var pipeline mongo.Pipeline
+
+	if after != nil {
+		// Skip the aggregates without any events after the cursor before
+		// unwinding their events.
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{
+			"events.timestamp": bson.M{"$gte": after.Timestamp},
+		}}})
+	}
+
+	pipeline = append(pipeline,
+		bson.D{{Key: "$unwind", Value: "$events"}},
+		bson.D{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$events"}}},
+	)
+
+	if after != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"$or": bson.A{
+			bson.M{"timestamp": bson.M{"$gt": after.Timestamp}},
+			bson.M{"timestamp": after.Timestamp, "_id": bson.M{"$gt": after.AggregateID}},
+			bson.M{"timestamp": after.Timestamp, "_id": after.AggregateID, "version": bson.M{"$gt": after.Version}},
+		}}}})
+	}
+
+	pipeline = append(pipeline, bson.D{{Key: "$sort", Value: bson.D{
+		{Key: "timestamp", Value: 1},
+		{Key: "_id", Value: 1},
+		{Key: "version", Value: 1},
+	}}})
+
+	if limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+	}
+
+	var cursor *mongo.Cursor
+
+	if err := s.database.CollectionExec(ctx, s.collectionName, func(ctx context.Context, c *mongo.Collection) error {
+		var err error
+		if cursor, err = c.Aggregate(ctx, pipeline, mongoOptions.Aggregate().SetAllowDiskUse(true)); err != nil {
+			return &eh.EventStoreError{
+				Err: fmt.Errorf("could not find events: %w", err),
+				Op:  eh.EventStoreOpLoad,
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return &timestampIter{cursor: cursor}, nil
+}
+
+// timestampIter iterates over the events of a cursor, the iterator is not thread safe.
+type timestampIter struct {
+	cursor *mongo.Cursor
+	event  eh.Event
+	err    error
+}
+
+func (i *timestampIter) Next(ctx context.Context) bool {
+	if i.err != nil || !i.cursor.Next(ctx) {
+		return false
+	}
+
+	var record evt
+	if err := i.cursor.Decode(&record); err != nil {
+		i.err = &eh.EventStoreError{
+			Err: fmt.Errorf("could not decode event: %w", err),
+			Op:  eh.EventStoreOpLoad,
+		}
+
+		return false
+	}
+
+	events, err := decodeEvents(ctx, record.AggregateID, []evt{record})
+	if err != nil {
+		i.err = err
+
+		return false
+	}
+
+	i.event = events[0]
+
+	return true
+}
+
+func (i *timestampIter) Value() interface{} {
+	return i.event
+}
+
+func (i *timestampIter) Close(ctx context.Context) error {
+	if err := i.cursor.Close(ctx); err != nil {
+		return err
+	}
+
+	if i.err != nil {
+		return i.err
+	}
+
+	return i.cursor.Err()
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2015 - The Event Horizon authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+func TestLoadAllByTimestampIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// Use MongoDB in Docker with fallback to localhost.
+	addr := os.Getenv("MONGODB_ADDR")
+	if addr == "" {
+		addr = "localhost:27017"
+	}
+
+	url := "mongodb://" + addr
+
+	// Get a random DB name.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	db := "test-" + hex.EncodeToString(b)
+
+	t.Log("using DB:", db)
+
+	store, err := NewEventStore(url, db)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	ctx := context.Background()
+	id1, id2 := uuid.New(), uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	// The events of the aggregates are interleaved in time.
+	for i, e := range []struct {
+		id      uuid.UUID
+		version int
+		offset  time.Duration
+	}{
+		{id1, 1, 0},
+		{id2, 1, time.Second},
+		{id1, 2, 2 * time.Second},
+		{id2, 2, 3 * time.Second},
+	} {
+		event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp.Add(e.offset),
+			eh.ForAggregate(mocks.AggregateType, e.id, e.version))
+		if err := store.Save(ctx, []eh.Event{event}, e.version-1); err != nil {
+			t.Fatal("there should be no error saving event", i, err)
+		}
+	}
+
+	events, err := store.LoadAllByTimestamp(ctx, nil, 3)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(events) != 3 {
+		t.Fatal("there should be 3 events:", len(events))
+	}
+
+	for i, expected := range []uuid.UUID{id1, id2, id1} {
+		if events[i].AggregateID() != expected {
+			t.Error("the events should be in timestamp order:", i, events[i])
+		}
+	}
+
+	cursor := CursorForEvent(events[2])
+
+	if events, err = store.LoadAllByTimestamp(ctx, &cursor, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(events) != 1 || events[0].AggregateID() != id2 || events[0].Version() != 2 {
+		t.Error("the last event should be loaded after the cursor:", events)
+	}
+
+	// All events should be iterated from a single cursor.
+	iter, err := store.LoadAllByTimestampIter(ctx, nil)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	var versions []int
+
+	for iter.Next(ctx) {
+		event, ok := iter.Value().(eh.Event)
+		if !ok {
+			t.Fatal("the value should be an event:", iter.Value())
+		}
+
+		versions = append(versions, event.Version())
+	}
+
+	if err := iter.Close(ctx); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(versions) != 4 {
+		t.Error("all events should be iterated:", versions)
+	}
+}
@@ -59,3 +59,31 @@ func WithCollectionName(eventsColl string) Option {
 		return nil
 	}
 }
+
+// WithSnapshotCollectionName uses a different snapshot collection than the default "snapshots".
+func WithSnapshotCollectionName(snapshotColl string) Option {
+	return func(s *EventStore) error {
+		if snapshotColl == "" {
+			return fmt.Errorf("missing snapshot collection name")
+		}
+
+		s.snapshotsCollectionName = snapshotColl
+
+		return nil
+	}
+}
+
+// WithSnapshotRetention keeps the last n snapshots of each aggregate, default 1.
+// Use 0 to keep all snapshots, beware of the 16MB document size limit as all
+// snapshots of an aggregate are stored in the same document.
+func WithSnapshotRetention(n int) Option {
+	return func(s *EventStore) error {
+		if n < 0 {
+			return fmt.Errorf("invalid snapshot retention: %d", n)
+		}
+
+		s.snapshotRetention = n
+
+		return nil
+	}
+}
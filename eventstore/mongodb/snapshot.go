@@ -0,0 +1,244 @@
+// Copyright (c) 2015 - The Event Horizon authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoOptions "go.mongodb.org/mongo-driver/mongo/options"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// snapshotsRecord is the Database representation of the snapshots of an
+// aggregate, which are kept sorted by version.
+type snapshotsRecord struct {
+	AggregateID uuid.UUID        `bson:"_id"`
+	Snapshots   []snapshotRecord `bson:"snapshots"`
+}
+
+// snapshotRecord is the Database representation of a snapshot.
+type snapshotRecord struct {
+	AggregateType eh.AggregateType `bson:"aggregate_type"`
+	Version       int              `bson:"version"`
+	Timestamp     time.Time        `bson:"timestamp"`
+	SchemaVersion int              `bson:"schema_version,omitempty"`
+	RawState      bson.Raw         `bson:"state"`
+}
+
+// LoadSnapshot implements the LoadSnapshot method of the eventhorizon.SnapshotStore interface.
+// Returns nil if there is no snapshot for the aggregate.
+func (s *EventStore) LoadSnapshot(ctx context.Context, id uuid.UUID) (*eh.Snapshot, error) {
+	return s.loadSnapshot(ctx, id, bson.M{
+		"snapshots": bson.M{"$slice": -1},
+	})
+}
+
+// LoadSnapshotAt implements the LoadSnapshotAt method of the eventhorizon.SnapshotHistoryStore interface.
+func (s *EventStore) LoadSnapshotAt(ctx context.Context, id uuid.UUID, maxVersion int) (*eh.Snapshot, error) {
+	return s.loadSnapshot(ctx, id, bson.M{
+		"snapshots": bson.M{
+			"$filter": bson.M{
+				"input": "$snapshots",
+				"as":    "snapshots",
+				"cond": bson.M{
+					"$lte": []interface{}{"$$snapshots.version", maxVersion},
+				},
+			},
+		},
+	})
+}
+
+// loadSnapshot loads the last snapshot of the projected snapshots.
+func (s *EventStore) loadSnapshot(ctx context.Context, id uuid.UUID, projection bson.M) (*eh.Snapshot, error) {
+	const errMessage = "could not load snapshot: %w"
+
+	var record snapshotsRecord
+
+	if err := s.database.CollectionExec(ctx, s.snapshotsCollectionName, func(ctx context.Context, c *mongo.Collection) error {
+		if err := c.FindOne(ctx, bson.M{"_id": id},
+			mongoOptions.FindOne().SetProjection(projection),
+		).Decode(&record); err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return &eh.EventStoreError{
+				Err:         fmt.Errorf("could not decode snapshot: %w", err),
+				Op:          eh.EventStoreOpLoadSnapshot,
+				AggregateID: id,
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf(errMessage, err)
+	}
+
+	// There is no (matching) snapshot for the aggregate.
+	if len(record.Snapshots) == 0 {
+		return nil, nil
+	}
+
+	r := record.Snapshots[len(record.Snapshots)-1]
+
+	if err := eh.CheckSnapshotSchemaVersion(r.AggregateType, r.SchemaVersion); err != nil {
+		return nil, &eh.EventStoreError{
+			Err:              err,
+			Op:               eh.EventStoreOpLoadSnapshot,
+			AggregateType:    r.AggregateType,
+			AggregateID:      id,
+			AggregateVersion: r.Version,
+		}
+	}
+
+	snapshot := &eh.Snapshot{
+		Version:       r.Version,
+		AggregateType: r.AggregateType,
+		Timestamp:     r.Timestamp,
+		SchemaVersion: r.SchemaVersion,
+	}
+
+	var err error
+	if snapshot.State, err = eh.CreateSnapshotData(id, r.AggregateType); err != nil {
+		return nil, &eh.EventStoreError{
+			Err:           fmt.Errorf("could not create snapshot data: %w", err),
+			Op:            eh.EventStoreOpLoadSnapshot,
+			AggregateType: r.AggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	if err := bson.Unmarshal(r.RawState, snapshot.State); err != nil {
+		return nil, &eh.EventStoreError{
+			Err:           fmt.Errorf("could not unmarshal snapshot state: %w", err),
+			Op:            eh.EventStoreOpLoadSnapshot,
+			AggregateType: r.AggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	return snapshot, nil
+}
+
+// SaveSnapshot implements the SaveSnapshot method of the eventhorizon.SnapshotStore interface.
+// A snapshot with the same version is replaced and snapshots outside of the
+// retention are pruned.
+func (s *EventStore) SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot eh.Snapshot) error {
+	const errMessage = "could not save snapshot: %w"
+
+	if snapshot.AggregateType == "" {
+		return &eh.EventStoreError{
+			Err:           fmt.Errorf("aggregate type is empty"),
+			Op:            eh.EventStoreOpSaveSnapshot,
+			AggregateID:   id,
+			AggregateType: snapshot.AggregateType,
+		}
+	}
+
+	if snapshot.State == nil {
+		return &eh.EventStoreError{
+			Err:           fmt.Errorf("snapshots state is nil"),
+			Op:            eh.EventStoreOpSaveSnapshot,
+			AggregateID:   id,
+			AggregateType: snapshot.AggregateType,
+		}
+	}
+
+	record := snapshotRecord{
+		AggregateType: snapshot.AggregateType,
+		Version:       snapshot.Version,
+		Timestamp:     snapshot.Timestamp,
+		SchemaVersion: eh.SnapshotSchemaVersion(snapshot.AggregateType),
+	}
+
+	var err error
+	if record.RawState, err = bson.Marshal(snapshot.State); err != nil {
+		return &eh.EventStoreError{
+			Err:           fmt.Errorf("could not marshal snapshot state: %w", err),
+			Op:            eh.EventStoreOpSaveSnapshot,
+			AggregateID:   id,
+			AggregateType: snapshot.AggregateType,
+		}
+	}
+
+	push := bson.M{
+		"$each": []snapshotRecord{record},
+		"$sort": bson.M{"version": 1},
+	}
+	if s.snapshotRetention > 0 {
+		push["$slice"] = -s.snapshotRetention
+	}
+
+	if err := s.database.CollectionExec(ctx, s.snapshotsCollectionName, func(ctx context.Context, c *mongo.Collection) error {
+		// Remove any snapshot with the same version before adding the new one.
+		if _, err := c.UpdateOne(ctx,
+			bson.M{"_id": id},
+			bson.M{"$pull": bson.M{"snapshots": bson.M{"version": record.Version}}},
+		); err != nil {
+			return &eh.EventStoreError{
+				Err:           fmt.Errorf("could not replace snapshot: %w", err),
+				Op:            eh.EventStoreOpSaveSnapshot,
+				AggregateID:   id,
+				AggregateType: snapshot.AggregateType,
+			}
+		}
+
+		if _, err := c.UpdateOne(ctx,
+			bson.M{"_id": id},
+			bson.M{"$push": bson.M{"snapshots": push}},
+			mongoOptions.Update().SetUpsert(true),
+		); err != nil {
+			return &eh.EventStoreError{
+				Err:           fmt.Errorf("could not save snapshot: %w", err),
+				Op:            eh.EventStoreOpSaveSnapshot,
+				AggregateID:   id,
+				AggregateType: snapshot.AggregateType,
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf(errMessage, err)
+	}
+
+	return nil
+}
+
+// RemoveSnapshotsAfter implements the RemoveSnapshotsAfter method of the eventhorizon.SnapshotHistoryStore interface.
+func (s *EventStore) RemoveSnapshotsAfter(ctx context.Context, id uuid.UUID, version int) error {
+	const errMessage = "could not remove snapshots: %w"
+
+	if err := s.database.CollectionExec(ctx, s.snapshotsCollectionName, func(ctx context.Context, c *mongo.Collection) error {
+		if _, err := c.UpdateOne(ctx,
+			bson.M{"_id": id},
+			bson.M{"$pull": bson.M{"snapshots": bson.M{"version": bson.M{"$gt": version}}}},
+		); err != nil {
+			return &eh.EventStoreError{
+				Err:         fmt.Errorf("could not remove snapshots: %w", err),
+				Op:          eh.EventStoreOpRemoveSnapshots,
+				AggregateID: id,
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf(errMessage, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,367 @@
+// Copyright (c) 2021 - The Event Horizon authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb_v2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoOptions "go.mongodb.org/mongo-driver/mongo/options"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// streamSummary is the state of a stream according to its events.
+type streamSummary struct {
+	aggregateType eh.AggregateType
+	version       int
+	position      int
+	updatedAt     interface{}
+}
+
+// CheckConsistency implements the CheckConsistency method of the
+// eventhorizon.EventStoreChecker interface. Stream documents, including the
+// global position in the $all stream, that are behind their events are
+// repaired to match them. Streams ahead of their events are only reported, as
+// rolling them back could let a concurrent save write a duplicate version.
+//
+// Events before the first stored event of an aggregate are not reported as a
+// gap, as they can have been archived by Truncate. A stream without events is
+// only reported if it is not covered by a snapshot.
+func (s *EventStore) CheckConsistency(ctx context.Context, repair bool) ([]eh.Inconsistency, error) {
+	var (
+		inconsistencies []eh.Inconsistency
+		unregistered    = map[eh.EventType]int{}
+		summaries       = map[uuid.UUID]*streamSummary{}
+		snapshots       = map[uuid.UUID]int{}
+		maxPosition     int
+	)
+
+	if err := s.database.DatabaseExec(ctx, func(ctx context.Context, db *mongo.Database) error {
+		streams := db.Collection(s.streamsCollectionName)
+
+		// The latest snapshot version of each aggregate.
+		cursor, err := db.Collection(s.snapshotsCollectionName).Aggregate(ctx, mongo.Pipeline{
+			{{Key: "$group", Value: bson.M{
+				"_id":     "$aggregate_id",
+				"version": bson.M{"$max": "$version"},
+			}}},
+		}, mongoOptions.Aggregate().SetAllowDiskUse(true))
+		if err != nil {
+			return fmt.Errorf("could not find snapshots: %w", err)
+		}
+
+		for cursor.Next(ctx) {
+			var record struct {
+				AggregateID uuid.UUID `bson:"_id"`
+				Version     int       `bson:"version"`
+			}
+
+			if err := cursor.Decode(&record); err != nil {
+				cursor.Close(ctx)
+
+				return fmt.Errorf("could not decode snapshot: %w", err)
+			}
+
+			snapshots[record.AggregateID] = record.Version
+		}
+
+		if err := cursor.Err(); err != nil {
+			cursor.Close(ctx)
+
+			return fmt.Errorf("could not find snapshots: %w", err)
+		}
+
+		cursor.Close(ctx)
+
+		// Only the fields needed for the check are loaded, not the event data.
+		if cursor, err = db.Collection(s.eventsCollectionName).Aggregate(ctx, mongo.Pipeline{
+			{{Key: "$sort", Value: bson.D{{Key: "aggregate_id", Value: 1}, {Key: "version", Value: 1}}}},
+			{{Key: "$project", Value: bson.M{
+				"aggregate_id":   1,
+				"aggregate_type": 1,
+				"version":        1,
+				"event_type":     1,
+				"timestamp":      1,
+				"has_data":       bson.M{"$ne": bson.A{bson.M{"$type": "$data"}, "missing"}},
+			}}},
+		}, mongoOptions.Aggregate().SetAllowDiskUse(true)); err != nil {
+			return fmt.Errorf("could not find events: %w", err)
+		}
+		defer cursor.Close(ctx)
+
+		var (
+			current  uuid.UUID
+			versions []int
+		)
+
+		checkVersions := func() {
+			if summary, ok := summaries[current]; ok {
+				inconsistencies = append(inconsistencies,
+					eh.CheckEventVersions(summary.aggregateType, current, versions, false)...)
+			}
+		}
+
+		for cursor.Next(ctx) {
+			var e struct {
+				Position      int              `bson:"_id"`
+				AggregateID   uuid.UUID        `bson:"aggregate_id"`
+				AggregateType eh.AggregateType `bson:"aggregate_type"`
+				Version       int              `bson:"version"`
+				EventType     eh.EventType     `bson:"event_type"`
+				Timestamp     interface{}      `bson:"timestamp"`
+				HasData       bool             `bson:"has_data"`
+			}
+
+			if err := cursor.Decode(&e); err != nil {
+				return fmt.Errorf("could not decode event: %w", err)
+			}
+
+			if e.AggregateID != current {
+				checkVersions()
+
+				current = e.AggregateID
+				versions = versions[:0]
+				summaries[current] = &streamSummary{}
+			}
+
+			versions = append(versions, e.Version)
+
+			summary := summaries[current]
+			summary.aggregateType = e.AggregateType
+			summary.version = e.Version
+			summary.updatedAt = e.Timestamp
+
+			if e.Position > summary.position {
+				summary.position = e.Position
+			}
+
+			if e.Position > maxPosition {
+				maxPosition = e.Position
+			}
+
+			if e.HasData && !eh.IsEventDataRegistered(e.EventType) {
+				unregistered[e.EventType]++
+			}
+		}
+
+		if err := cursor.Err(); err != nil {
+			return fmt.Errorf("could not find events: %w", err)
+		}
+
+		checkVersions()
+
+		streamCursor, err := streams.Find(ctx, bson.M{"_id": bson.M{"$ne": "$all"}},
+			mongoOptions.Find().SetSort(bson.M{"_id": 1}),
+		)
+		if err != nil {
+			return fmt.Errorf("could not find streams: %w", err)
+		}
+		defer streamCursor.Close(ctx)
+
+		seen := map[uuid.UUID]bool{}
+
+		for streamCursor.Next(ctx) {
+			var strm stream
+			if err := streamCursor.Decode(&strm); err != nil {
+				return fmt.Errorf("could not decode stream: %w", err)
+			}
+
+			seen[strm.ID] = true
+
+			if version, ok := snapshots[strm.ID]; ok && version > strm.Version {
+				inconsistencies = append(inconsistencies, eh.Inconsistency{
+					Kind:          eh.InconsistencySnapshotAhead,
+					AggregateType: strm.AggregateType,
+					AggregateID:   strm.ID,
+					Version:       version,
+					Details:       fmt.Sprintf("stream version %d", strm.Version),
+				})
+			}
+
+			// Events can have been saved after reading the events, the last
+			// event of a stream that seems ahead of its events is read again.
+			summary, ok := summaries[strm.ID]
+			if !ok || strm.Version > summary.version {
+				last, err := s.lastEventSummary(ctx, db, strm.ID)
+				if err != nil {
+					return err
+				}
+
+				if last != nil {
+					summary, ok = last, true
+				}
+			}
+
+			if !ok {
+				if snapshots[strm.ID] < strm.Version {
+					inconsistencies = append(inconsistencies, eh.Inconsistency{
+						Kind:          eh.InconsistencyStreamMismatch,
+						AggregateType: strm.AggregateType,
+						AggregateID:   strm.ID,
+						Details:       fmt.Sprintf("stream version %d without events", strm.Version),
+					})
+				}
+
+				continue
+			}
+
+			if strm.Version == summary.version && strm.Position == summary.position {
+				continue
+			}
+
+			inconsistency := eh.Inconsistency{
+				Kind:          eh.InconsistencyStreamMismatch,
+				AggregateType: summary.aggregateType,
+				AggregateID:   strm.ID,
+				Details: fmt.Sprintf("stream version %d at position %d, last event version %d at position %d",
+					strm.Version, strm.Position, summary.version, summary.position),
+			}
+
+			// Only streams behind their events are repaired, to never roll
+			// back the version of a stream which would allow duplicate versions.
+			// The stream is not updated if it was saved to since reading it.
+			if repair && summary.version >= strm.Version {
+				res, err := streams.UpdateOne(ctx,
+					bson.M{"_id": strm.ID, "version": strm.Version, "position": strm.Position},
+					bson.M{"$set": bson.M{"version": summary.version, "position": summary.position}},
+				)
+				if err != nil {
+					return fmt.Errorf("could not repair stream %s: %w", strm.ID, err)
+				}
+
+				inconsistency.Repaired = res.MatchedCount == 1
+			}
+
+			inconsistencies = append(inconsistencies, inconsistency)
+		}
+
+		if err := streamCursor.Err(); err != nil {
+			return fmt.Errorf("could not find streams: %w", err)
+		}
+
+		// Events without a stream document.
+		ids := make([]uuid.UUID, 0, len(summaries))
+
+		for id := range summaries {
+			if !seen[id] {
+				ids = append(ids, id)
+			}
+		}
+
+		sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+
+		for _, id := range ids {
+			summary := summaries[id]
+			inconsistency := eh.Inconsistency{
+				Kind:          eh.InconsistencyStreamMismatch,
+				AggregateType: summary.aggregateType,
+				AggregateID:   id,
+				Details:       fmt.Sprintf("no stream, last event version %d at position %d", summary.version, summary.position),
+			}
+
+			if repair {
+				if _, err := streams.InsertOne(ctx, bson.M{
+					"_id":            id,
+					"position":       summary.position,
+					"aggregate_type": summary.aggregateType,
+					"version":        summary.version,
+					"updated_at":     summary.updatedAt,
+				}); err == nil {
+					inconsistency.Repaired = true
+				} else if !mongo.IsDuplicateKeyError(err) {
+					// The stream has been created by a save since reading the streams.
+					return fmt.Errorf("could not repair stream %s: %w", id, err)
+				}
+			}
+
+			inconsistencies = append(inconsistencies, inconsistency)
+		}
+
+		// The global position must not be behind the stored events, or new
+		// events would get the position of existing events.
+		var all struct {
+			Position int `bson:"position"`
+		}
+
+		if err := streams.FindOne(ctx, bson.M{"_id": "$all"}).Decode(&all); err != nil {
+			return fmt.Errorf("could not find the $all stream: %w", err)
+		}
+
+		if all.Position < maxPosition {
+			inconsistency := eh.Inconsistency{
+				Kind:    eh.InconsistencyStreamMismatch,
+				Details: fmt.Sprintf("global position %d, last event position %d", all.Position, maxPosition),
+			}
+
+			if repair {
+				res, err := streams.UpdateOne(ctx,
+					bson.M{"_id": "$all", "position": bson.M{"$lt": maxPosition}},
+					bson.M{"$set": bson.M{"position": maxPosition}},
+				)
+				if err != nil {
+					return fmt.Errorf("could not repair the $all stream: %w", err)
+				}
+
+				inconsistency.Repaired = res.MatchedCount == 1
+			}
+
+			inconsistencies = append(inconsistencies, inconsistency)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("could not check consistency: %w", err),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+
+	return append(inconsistencies, eh.UnregisteredEventDataInconsistencies(unregistered)...), nil
+}
+
+// lastEventSummary returns the state of a stream according to its last event,
+// or nil if there are no events.
+func (s *EventStore) lastEventSummary(ctx context.Context, db *mongo.Database, id uuid.UUID) (*streamSummary, error) {
+	var e struct {
+		Position      int              `bson:"_id"`
+		AggregateType eh.AggregateType `bson:"aggregate_type"`
+		Version       int              `bson:"version"`
+		Timestamp     interface{}      `bson:"timestamp"`
+	}
+
+	if err := db.Collection(s.eventsCollectionName).FindOne(ctx,
+		bson.M{"aggregate_id": id},
+		mongoOptions.FindOne().
+			SetSort(bson.M{"version": -1}).
+			SetProjection(bson.M{"aggregate_type": 1, "version": 1, "timestamp": 1}),
+	).Decode(&e); errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not find events: %w", err)
+	}
+
+	return &streamSummary{
+		aggregateType: e.AggregateType,
+		version:       e.Version,
+		position:      e.Position,
+		updatedAt:     e.Timestamp,
+	}, nil
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2021 - The Event Horizon authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb_v2_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoOptions "go.mongodb.org/mongo-driver/mongo/options"
+
+	eh "github.com/Clarilab/eventhorizon"
+	mongodb "github.com/Clarilab/eventhorizon/eventstore/mongodb_v2"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+func TestCheckConsistencyIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// Use MongoDB in Docker with fallback to localhost.
+	addr := os.Getenv("MONGODB_ADDR")
+	if addr == "" {
+		addr = "localhost:27017"
+	}
+
+	url := "mongodb://" + addr
+
+	// Get a random DB name.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	db := "test-" + hex.EncodeToString(b)
+
+	t.Log("using DB:", db)
+
+	ctx := context.Background()
+
+	client, err := mongo.Connect(ctx, mongoOptions.Client().ApplyURI(url))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer client.Disconnect(ctx)
+
+	store, err := mongodb.NewEventStoreWithClient(client, db)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	id1 := uuid.New()
+	id2 := uuid.New()
+
+	for _, id := range []uuid.UUID{id1, id2} {
+		var events []eh.Event
+		for i := 1; i <= 2; i++ {
+			events = append(events, eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+				eh.ForAggregate(mocks.AggregateType, id, i)))
+		}
+
+		if err := store.Save(ctx, events, 0); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	inconsistencies, err := store.CheckConsistency(ctx, false)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(inconsistencies) != 0 {
+		t.Fatal("there should be no inconsistencies:", inconsistencies)
+	}
+
+	// Let the first stream lag behind, remove the second stream and reset the
+	// global position.
+	streams := client.Database(db).Collection("streams")
+
+	if _, err := streams.UpdateOne(ctx, bson.M{"_id": id1}, bson.M{"$set": bson.M{"version": 1, "position": 1}}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, err := streams.DeleteOne(ctx, bson.M{"_id": id2}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, err := streams.UpdateOne(ctx, bson.M{"_id": "$all"}, bson.M{"$set": bson.M{"position": 2}}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if inconsistencies, err = store.CheckConsistency(ctx, true); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(inconsistencies) != 3 {
+		t.Fatal("the inconsistencies should be found:", inconsistencies)
+	}
+
+	for _, i := range inconsistencies {
+		if i.Kind != eh.InconsistencyStreamMismatch || !i.Repaired {
+			t.Error("the stream should be repaired:", i)
+		}
+	}
+
+	if inconsistencies, err = store.CheckConsistency(ctx, false); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(inconsistencies) != 0 {
+		t.Error("there should be no inconsistencies after the repair:", inconsistencies)
+	}
+
+	// New events should get new positions after the repair.
+	event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id2, 3))
+	if err := store.Save(ctx, []eh.Event{event}, 2); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// A stream ahead of its events should not be rolled back.
+	if _, err := streams.UpdateOne(ctx, bson.M{"_id": id1}, bson.M{"$set": bson.M{"version": 5}}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if inconsistencies, err = store.CheckConsistency(ctx, true); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(inconsistencies) != 1 || inconsistencies[0].Repaired {
+		t.Error("the stream should be reported but not repaired:", inconsistencies)
+	}
+
+	var strm struct {
+		Version int `bson:"version"`
+	}
+
+	if err := streams.FindOne(ctx, bson.M{"_id": id1}).Decode(&strm); err != nil || strm.Version != 5 {
+		t.Error("the stream version should be kept:", strm.Version, err)
+	}
+}
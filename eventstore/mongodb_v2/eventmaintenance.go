@@ -18,9 +18,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	mongoOptions "go.mongodb.org/mongo-driver/mongo/options"
 
 	// Register uuid.UUID as BSON type.
 	_ "github.com/Clarilab/eventhorizon/codec/bson"
@@ -98,6 +100,197 @@ func (s *EventStore) Replace(ctx context.Context, event eh.Event) error {
 	return nil
 }
 
+// Transform implements the Transform method of the eventhorizon.EventStoreTransformer interface.
+// The events are transformed in the order of their global position, which is
+// also used as checkpoint. Each batch is replaced in a transaction.
+func (s *EventStore) Transform(ctx context.Context, matcher eh.EventMatcher, transform eh.EventTransformFunc, options ...eh.TransformOption) (*eh.TransformReport, error) {
+	opts := eh.NewTransformOptions(options...)
+	report := &eh.TransformReport{Checkpoint: opts.Checkpoint}
+
+	position := 0
+
+	if opts.Checkpoint != "" {
+		var err error
+		if position, err = strconv.Atoi(opts.Checkpoint); err != nil {
+			return report, &eh.EventStoreError{
+				Err: fmt.Errorf("invalid checkpoint: %w", err),
+				Op:  eh.EventStoreOpTransform,
+			}
+		}
+	}
+
+	for {
+		batch, last, err := s.matchingEvents(ctx, matcher, position, opts.BatchSize)
+		if err != nil {
+			return report, err
+		}
+
+		if last == position {
+			return report, nil
+		}
+
+		var changes []eh.TransformChange
+
+		for _, event := range batch {
+			transformed, err := eh.TransformEvent(event, transform)
+			if err != nil {
+				return report, &eh.EventStoreError{
+					Err:              err,
+					Op:               eh.EventStoreOpTransform,
+					AggregateType:    event.AggregateType(),
+					AggregateID:      event.AggregateID(),
+					AggregateVersion: event.Version(),
+					Events:           []eh.Event{event},
+				}
+			}
+
+			if transformed != nil {
+				changes = append(changes, eh.TransformChange{Before: event, After: transformed})
+			}
+		}
+
+		if opts.DryRun {
+			report.Changes = append(report.Changes, changes...)
+		} else if err := s.replaceEvents(ctx, changes); err != nil {
+			return report, err
+		}
+
+		position = last
+		report.Matched += len(batch)
+		report.Changed += len(changes)
+		report.Checkpoint = strconv.Itoa(last)
+
+		if opts.Progress != nil {
+			if err := opts.Progress(ctx, *report); err != nil {
+				return report, &eh.EventStoreError{
+					Err: fmt.Errorf("could not report progress: %w", err),
+					Op:  eh.EventStoreOpTransform,
+				}
+			}
+		}
+	}
+}
+
+// matchingEvents returns up to limit events after the position that match,
+// and the position of the last event that was checked.
+func (s *EventStore) matchingEvents(ctx context.Context, matcher eh.EventMatcher, position, limit int) ([]eh.Event, int, error) {
+	var events []eh.Event
+
+	last := position
+
+	if err := s.database.CollectionExec(ctx, s.eventsCollectionName, func(ctx context.Context, c *mongo.Collection) error {
+		cursor, err := c.Find(ctx,
+			bson.M{"_id": bson.M{"$gt": position}},
+			mongoOptions.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetBatchSize(int32(limit)),
+		)
+		if err != nil {
+			return &eh.EventStoreError{
+				Err: fmt.Errorf("could not find events: %w", err),
+				Op:  eh.EventStoreOpTransform,
+			}
+		}
+
+		defer cursor.Close(ctx)
+
+		for len(events) < limit && cursor.Next(ctx) {
+			var e evt
+			if err := cursor.Decode(&e); err != nil {
+				return &eh.EventStoreError{
+					Err: fmt.Errorf("could not decode event: %w", err),
+					Op:  eh.EventStoreOpTransform,
+				}
+			}
+
+			last = e.Position
+
+			// Only the data of matching events is decoded, so that events
+			// with data that can't be decoded, for example of unregistered
+			// event types, are skipped if not matched.
+			if !matcher.Match(e.header()) {
+				continue
+			}
+
+			event, err := decodeEventData(ctx, e)
+			if err != nil {
+				err.Op = eh.EventStoreOpTransform
+
+				return err
+			}
+
+			events = append(events, event)
+		}
+
+		if err := cursor.Err(); err != nil {
+			return &eh.EventStoreError{
+				Err: fmt.Errorf("could not iterate events: %w", err),
+				Op:  eh.EventStoreOpTransform,
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, position, err
+	}
+
+	return events, last, nil
+}
+
+// replaceEvents stores the transformed events of the changes in a transaction.
+func (s *EventStore) replaceEvents(ctx context.Context, changes []eh.TransformChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	// The event that failed to be replaced, used for the error. Not set if
+	// the transaction failed before or after replacing the events.
+	var failed eh.Event
+
+	if err := s.database.CollectionExecWithTransaction(ctx, s.eventsCollectionName, func(txCtx mongo.SessionContext, c *mongo.Collection) error {
+		for _, change := range changes {
+			failed = change.After
+
+			position, ok := eh.GlobalPosition(change.Before)
+			if !ok {
+				return fmt.Errorf("missing position of original event")
+			}
+
+			e, err := newEvt(txCtx, change.After)
+			if err != nil {
+				return err
+			}
+
+			e.Position = position
+			e.Metadata["position"] = position
+
+			if r, err := c.ReplaceOne(txCtx, bson.M{"_id": position}, e); err != nil {
+				return fmt.Errorf("could not replace event: %w", err)
+			} else if r.MatchedCount == 0 {
+				return eh.ErrEventNotFound
+			}
+		}
+
+		failed = nil
+
+		return nil
+	}); err != nil {
+		storeErr := &eh.EventStoreError{
+			Err: err,
+			Op:  eh.EventStoreOpTransform,
+		}
+
+		if failed != nil {
+			storeErr.AggregateType = failed.AggregateType()
+			storeErr.AggregateID = failed.AggregateID()
+			storeErr.AggregateVersion = failed.Version()
+			storeErr.Events = []eh.Event{failed}
+		}
+
+		return storeErr
+	}
+
+	return nil
+}
+
 // RenameEvent implements the RenameEvent method of the eventhorizon.EventStoreMaintenance interface.
 func (s *EventStore) RenameEvent(ctx context.Context, from, to eh.EventType) error {
 	const errMessage = "could not rename event: %w"
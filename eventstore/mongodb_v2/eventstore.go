@@ -15,12 +15,9 @@
 package mongodb_v2
 
 import (
-	"bytes"
-	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -30,10 +27,11 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 
-	// Register uuid.UUID as BSON type.
-	_ "github.com/Clarilab/eventhorizon/codec/bson"
+	// Used for upcasting event data, also registers uuid.UUID as BSON type.
+	bsonCodec "github.com/Clarilab/eventhorizon/codec/bson"
 
 	eh "github.com/Clarilab/eventhorizon"
+	snapshotCodec "github.com/Clarilab/eventhorizon/codec/snapshot"
 	"github.com/Clarilab/eventhorizon/uuid"
 )
 
@@ -41,6 +39,7 @@ const (
 	defaultEventsCollectionName    = "events"
 	defaultStreamsCollectionName   = "streams"
 	defaultSnapshotsCollectionName = "snapshots"
+	defaultArchiveCollectionName   = "events_archive"
 )
 
 // EventStore is an eventhorizon.EventStore for MongoDB, using one collection
@@ -52,6 +51,10 @@ type EventStore struct {
 	eventsCollectionName    string
 	streamsCollectionName   string
 	snapshotsCollectionName string
+	archiveCollectionName   string
+	snapshotRetention       int
+	dedupKey                string
+	snapshotCodecs          *snapshotCodec.Codecs
 	eventHandlers           []eh.EventHandler
 	eventHandlersInTX       []eh.EventHandler
 }
@@ -103,6 +106,8 @@ func newMongoDBEventStore(db eh.MongoDB, dbOwnership dbOwnership, options ...Opt
 		eventsCollectionName:    defaultEventsCollectionName,
 		streamsCollectionName:   defaultStreamsCollectionName,
 		snapshotsCollectionName: defaultSnapshotsCollectionName,
+		archiveCollectionName:   defaultArchiveCollectionName,
+		snapshotCodecs:          snapshotCodec.NewCodecs(snapshotCodec.Gzip()),
 	}
 
 	for i := range options {
@@ -130,6 +135,27 @@ func newMongoDBEventStore(db eh.MongoDB, dbOwnership dbOwnership, options ...Opt
 			return fmt.Errorf("could not ensure events index: %w", err)
 		}
 
+		// Indexes used by QueryEvents, the events are sorted by their ID.
+		if _, err := c.Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "event_type", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "aggregate_type", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "timestamp", Value: 1}}},
+		}); err != nil {
+			return fmt.Errorf("could not ensure events query indexes: %w", err)
+		}
+
+		// Index used to find duplicate saves.
+		if s.dedupKey != "" {
+			if _, err := c.Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys: bson.D{{Key: "aggregate_id", Value: 1}, {Key: "metadata." + s.dedupKey, Value: 1}},
+				Options: mongoOptions.Index().SetPartialFilterExpression(bson.M{
+					"metadata." + s.dedupKey: bson.M{"$exists": true},
+				}),
+			}); err != nil {
+				return fmt.Errorf("could not ensure events deduplication index: %w", err)
+			}
+		}
+
 		return nil
 	}); err != nil {
 		return nil, fmt.Errorf("could not ensure events indexes: %w", err)
@@ -178,23 +204,166 @@ func newMongoDBEventStore(db eh.MongoDB, dbOwnership dbOwnership, options ...Opt
 
 // Save implements the Save method of the eventhorizon.EventStore interface.
 func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersion int) error {
-	if len(events) == 0 {
+	dbEvents, err := s.prepareEvents(ctx, events, originalVersion)
+	if err != nil {
+		return err
+	}
+
+	id := events[0].AggregateID()
+	at := events[0].AggregateType()
+
+	var saved []eh.Event
+
+	if err := s.database.DatabaseExecWithTransaction(ctx, func(txCtx mongo.SessionContext, db *mongo.Database) error {
+		var err error
+		if saved, err = s.saveEvents(txCtx, db, events, dbEvents, originalVersion); err != nil {
+			return err
+		}
+
+		// Let the optional in-TX event handlers handle the events.
+		if err := runEventHandlers(txCtx, s.eventHandlersInTX, saved); err != nil {
+			return err
+		}
+
+		return nil
+	}); err != nil {
+		return &eh.EventStoreError{
+			Err:              err,
+			Op:               eh.EventStoreOpSave,
+			AggregateType:    at,
+			AggregateID:      id,
+			AggregateVersion: originalVersion,
+			Events:           events,
+		}
+	}
+
+	// Let the optional event handlers handle the events.
+	if err := runEventHandlers(ctx, s.eventHandlers, saved); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveMany implements the SaveMany method of the eventhorizon.MultiStreamEventStore interface.
+// All streams are saved in a single transaction, either all or none of them are saved.
+func (s *EventStore) SaveMany(ctx context.Context, streams []eh.EventStreamSave) error {
+	if len(streams) == 0 {
 		return &eh.EventStoreError{
 			Err: eh.ErrMissingEvents,
 			Op:  eh.EventStoreOpSave,
 		}
 	}
 
+	dbEvents := make([][]interface{}, len(streams))
+	ids := make(map[uuid.UUID]struct{}, len(streams))
+
+	var events []eh.Event
+
+	for i, stream := range streams {
+		if len(stream.Events) > 0 {
+			id := stream.Events[0].AggregateID()
+			if _, ok := ids[id]; ok {
+				return &eh.EventStoreError{
+					Err:              eh.ErrDuplicateEventStream,
+					Op:               eh.EventStoreOpSave,
+					AggregateType:    stream.Events[0].AggregateType(),
+					AggregateID:      id,
+					AggregateVersion: stream.OriginalVersion,
+					Events:           stream.Events,
+				}
+			}
+
+			ids[id] = struct{}{}
+		}
+
+		var err error
+		if dbEvents[i], err = s.prepareEvents(ctx, stream.Events, stream.OriginalVersion); err != nil {
+			return err
+		}
+
+		events = append(events, stream.Events...)
+	}
+
+	var (
+		saved []eh.Event
+		// The stream that failed to save, used for the error.
+		failed eh.EventStreamSave
+	)
+
+	if err := s.database.DatabaseExecWithTransaction(ctx, func(txCtx mongo.SessionContext, db *mongo.Database) error {
+		saved = nil
+
+		for i, stream := range streams {
+			streamSaved, err := s.saveEvents(txCtx, db, stream.Events, dbEvents[i], stream.OriginalVersion)
+			if err != nil {
+				failed = stream
+
+				return err
+			}
+
+			saved = append(saved, streamSaved...)
+		}
+
+		// Let the optional in-TX event handlers handle the events.
+		if err := runEventHandlers(txCtx, s.eventHandlersInTX, saved); err != nil {
+			return err
+		}
+
+		return nil
+	}); err != nil {
+		storeErr := &eh.EventStoreError{
+			Err:    err,
+			Op:     eh.EventStoreOpSave,
+			Events: events,
+		}
+
+		if len(failed.Events) > 0 {
+			storeErr.AggregateType = failed.Events[0].AggregateType()
+			storeErr.AggregateID = failed.Events[0].AggregateID()
+			storeErr.AggregateVersion = failed.OriginalVersion
+			storeErr.Events = failed.Events
+		}
+
+		return storeErr
+	}
+
+	// Let the optional event handlers handle the events.
+	if err := runEventHandlers(ctx, s.eventHandlers, saved); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// prepareEvents validates the events of an aggregate and creates the records to store.
+func (s *EventStore) prepareEvents(ctx context.Context, events []eh.Event, originalVersion int) ([]interface{}, error) {
+	if len(events) == 0 {
+		return nil, &eh.EventStoreError{
+			Err: eh.ErrMissingEvents,
+			Op:  eh.EventStoreOpSave,
+		}
+	}
+
 	dbEvents := make([]interface{}, len(events))
 	id := events[0].AggregateID()
 	at := events[0].AggregateType()
 
+	// The versions of the events are assigned when saving for the expected
+	// version modes AnyVersion and StreamExists.
+	assignVersions := originalVersion == eh.AnyVersion || originalVersion == eh.StreamExists
+
+	version := originalVersion
+	if originalVersion == eh.NoStream {
+		version = 0
+	}
+
 	// Build all event records, with incrementing versions starting from the
 	// original aggregate version.
 	for i, event := range events {
 		// Only accept events belonging to the same aggregate.
 		if event.AggregateID() != id {
-			return &eh.EventStoreError{
+			return nil, &eh.EventStoreError{
 				Err:              eh.ErrMismatchedEventAggregateIDs,
 				Op:               eh.EventStoreOpSave,
 				AggregateType:    at,
@@ -205,7 +374,7 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 		}
 
 		if event.AggregateType() != at {
-			return &eh.EventStoreError{
+			return nil, &eh.EventStoreError{
 				Err:              eh.ErrMismatchedEventAggregateTypes,
 				Op:               eh.EventStoreOpSave,
 				AggregateType:    at,
@@ -216,8 +385,8 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 		}
 
 		// Only accept events that apply to the correct aggregate version.
-		if event.Version() != originalVersion+i+1 {
-			return &eh.EventStoreError{
+		if !assignVersions && event.Version() != version+i+1 {
+			return nil, &eh.EventStoreError{
 				Err:              eh.ErrIncorrectEventVersion,
 				Op:               eh.EventStoreOpSave,
 				AggregateType:    at,
@@ -230,131 +399,156 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 		// Create the event record for the DB.
 		e, err := newEvt(ctx, event)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		dbEvents[i] = e
 	}
 
-	if err := s.database.DatabaseExecWithTransaction(ctx, func(txCtx mongo.SessionContext, db *mongo.Database) error {
-		// Fetch and increment global version in the all-stream.
-		res := db.Collection(s.streamsCollectionName).FindOneAndUpdate(txCtx,
-			bson.M{"_id": "$all"},
-			bson.M{"$inc": bson.M{"position": len(dbEvents)}},
-		)
-		if res.Err() != nil {
-			return fmt.Errorf("could not increment global position: %w", res.Err())
+	return dbEvents, nil
+}
+
+// saveEvents stores the prepared events of an aggregate and updates its stream,
+// must be called within a transaction. Returns the saved events, which have
+// new versions for the expected version modes AnyVersion and StreamExists.
+func (s *EventStore) saveEvents(txCtx mongo.SessionContext, db *mongo.Database,
+	events []eh.Event, dbEvents []interface{}, originalVersion int,
+) ([]eh.Event, error) {
+	saved := events
+	version := originalVersion
+
+	// Reject events that have already been saved by an earlier save, before
+	// checking the version which has changed since then.
+	if err := s.checkDuplicateSave(txCtx, db, events); err != nil {
+		return nil, err
+	}
+
+	switch originalVersion {
+	case eh.NoStream:
+		version = 0
+	case eh.AnyVersion, eh.StreamExists:
+		var err error
+		if version, err = s.streamVersion(txCtx, db, events[0].AggregateID()); err != nil {
+			return nil, err
 		}
 
-		allStream := struct {
-			Position int
-		}{}
-		if err := res.Decode(&allStream); err != nil {
-			return fmt.Errorf("could not decode global position: %w", err)
+		if version == 0 && originalVersion == eh.StreamExists {
+			return nil, eh.ErrStreamNotFound
 		}
 
-		// Use the global position as ID for the stored events.
-		// This natively prevents duplicate events to be written.
-		var strm *stream
+		saved = make([]eh.Event, len(events))
+
 		for i, e := range dbEvents {
 			event, ok := e.(*evt)
 			if !ok {
-				return fmt.Errorf("event is of incorrect type %T", e)
+				return nil, fmt.Errorf("event is of incorrect type %T", e)
 			}
 
-			event.Position = allStream.Position + i + 1
-			// Also store the position in the event metadata.
-			event.Metadata["position"] = event.Position
-
-			// Use the last event to set the new stream position.
-			if i == len(dbEvents)-1 {
-				strm = &stream{
-					ID:            event.AggregateID,
-					Position:      event.Position,
-					AggregateType: event.AggregateType,
-					Version:       event.Version,
-					UpdatedAt:     event.Timestamp,
-				}
-			}
-		}
-
-		// Store events.
-		insert, err := db.Collection(s.eventsCollectionName).InsertMany(txCtx, dbEvents)
-		if err != nil {
-			return fmt.Errorf("could not insert events: %w", err)
+			event.Version = version + i + 1
+			saved[i] = eh.EventWithVersion(events[i], event.Version)
 		}
+	}
 
-		// Check that all inserted events got the requested ID (position),
-		// instead of a generated ID by MongoDB.
-		for _, e := range dbEvents {
-			event, ok := e.(*evt)
-			if !ok {
-				return fmt.Errorf("event is of incorrect type %T", e)
-			}
+	// Fetch and increment global version in the all-stream.
+	res := db.Collection(s.streamsCollectionName).FindOneAndUpdate(txCtx,
+		bson.M{"_id": "$all"},
+		bson.M{"$inc": bson.M{"position": len(dbEvents)}},
+	)
+	if res.Err() != nil {
+		return nil, fmt.Errorf("could not increment global position: %w", res.Err())
+	}
 
-			found := false
-			for _, id := range insert.InsertedIDs {
-				if pos, ok := id.(int32); ok && event.Position == int(pos) {
-					found = true
+	allStream := struct {
+		Position int
+	}{}
+	if err := res.Decode(&allStream); err != nil {
+		return nil, fmt.Errorf("could not decode global position: %w", err)
+	}
 
-					break
-				}
-			}
+	// Use the global position as ID for the stored events.
+	// This natively prevents duplicate events to be written.
+	var strm *stream
+	for i, e := range dbEvents {
+		event, ok := e.(*evt)
+		if !ok {
+			return nil, fmt.Errorf("event is of incorrect type %T", e)
+		}
 
-			if !found {
-				return fmt.Errorf("inserted event %s at pos %d not found",
-					event.AggregateID, event.Position)
+		event.Position = allStream.Position + i + 1
+		// Also store the position in the event metadata.
+		event.Metadata["position"] = event.Position
+
+		// Use the last event to set the new stream position.
+		if i == len(dbEvents)-1 {
+			strm = &stream{
+				ID:            event.AggregateID,
+				Position:      event.Position,
+				AggregateType: event.AggregateType,
+				Version:       event.Version,
+				UpdatedAt:     event.Timestamp,
 			}
 		}
+	}
 
-		// Update the stream.
-		if originalVersion == 0 {
-			if _, err := db.Collection(s.streamsCollectionName).InsertOne(txCtx, strm); err != nil {
-				return fmt.Errorf("could not insert stream: %w", err)
-			}
-		} else {
-			if res, err := db.Collection(s.streamsCollectionName).UpdateOne(txCtx,
-				bson.M{
-					"_id":     strm.ID,
-					"version": originalVersion,
-				},
-				bson.M{
-					"$set": bson.M{
-						"position":   strm.Position,
-						"updated_at": strm.UpdatedAt,
-					},
-					"$inc": bson.M{"version": len(dbEvents)},
-				},
-			); err != nil {
-				return fmt.Errorf("could not update stream: %w", err)
-			} else if res.MatchedCount == 0 {
-				return eh.ErrEventConflictFromOtherSave
-			}
+	// Store events.
+	insert, err := db.Collection(s.eventsCollectionName).InsertMany(txCtx, dbEvents)
+	if err != nil {
+		return nil, fmt.Errorf("could not insert events: %w", err)
+	}
+
+	// Check that all inserted events got the requested ID (position),
+	// instead of a generated ID by MongoDB.
+	for _, e := range dbEvents {
+		event, ok := e.(*evt)
+		if !ok {
+			return nil, fmt.Errorf("event is of incorrect type %T", e)
 		}
 
-		// Let the optional in-TX event handlers handle the events.
-		if err := runEventHandlers(txCtx, s.eventHandlersInTX, events); err != nil {
-			return err
+		found := false
+		for _, id := range insert.InsertedIDs {
+			if pos, ok := id.(int32); ok && event.Position == int(pos) {
+				found = true
+
+				break
+			}
 		}
 
-		return nil
-	}); err != nil {
-		return &eh.EventStoreError{
-			Err:              err,
-			Op:               eh.EventStoreOpSave,
-			AggregateType:    at,
-			AggregateID:      id,
-			AggregateVersion: originalVersion,
-			Events:           events,
+		if !found {
+			return nil, fmt.Errorf("inserted event %s at pos %d not found",
+				event.AggregateID, event.Position)
 		}
 	}
 
-	// Let the optional event handlers handle the events.
-	if err := runEventHandlers(ctx, s.eventHandlers, events); err != nil {
-		return err
+	// Update the stream.
+	if version == 0 {
+		if _, err := db.Collection(s.streamsCollectionName).InsertOne(txCtx, strm); err != nil {
+			if originalVersion == eh.NoStream && mongo.IsDuplicateKeyError(err) {
+				return nil, eh.ErrStreamExists
+			}
+
+			return nil, fmt.Errorf("could not insert stream: %w", err)
+		}
+	} else {
+		if res, err := db.Collection(s.streamsCollectionName).UpdateOne(txCtx,
+			bson.M{
+				"_id":     strm.ID,
+				"version": version,
+			},
+			bson.M{
+				"$set": bson.M{
+					"position":   strm.Position,
+					"updated_at": strm.UpdatedAt,
+				},
+				"$inc": bson.M{"version": len(dbEvents)},
+			},
+		); err != nil {
+			return nil, fmt.Errorf("could not update stream: %w", err)
+		} else if res.MatchedCount == 0 {
+			return nil, eh.ErrEventConflictFromOtherSave
+		}
 	}
 
-	return nil
+	return saved, nil
 }
 
 func runEventHandlers(ctx context.Context, handlers []eh.EventHandler, events []eh.Event) error {
@@ -376,6 +570,56 @@ func runEventHandlers(ctx context.Context, handlers []eh.EventHandler, events []
 	return nil
 }
 
+// checkDuplicateSave returns ErrDuplicateSave if deduplication is used and any
+// of the stored events of the aggregate has the idempotency key of the events
+// to save. Must be called within the save transaction, concurrent saves are
+// serialized by the update of the global position.
+func (s *EventStore) checkDuplicateSave(ctx context.Context, db *mongo.Database, events []eh.Event) error {
+	if s.dedupKey == "" {
+		return nil
+	}
+
+	var keys []interface{}
+
+	for _, e := range events {
+		if v, ok := e.Metadata()[s.dedupKey]; ok {
+			keys = append(keys, v)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	n, err := db.Collection(s.eventsCollectionName).CountDocuments(ctx, bson.M{
+		"aggregate_id":           events[0].AggregateID(),
+		"metadata." + s.dedupKey: bson.M{"$in": keys},
+	}, mongoOptions.Count().SetLimit(1))
+	if err != nil {
+		return fmt.Errorf("could not check for duplicate save: %w", err)
+	}
+
+	if n > 0 {
+		return eh.ErrDuplicateSave
+	}
+
+	return nil
+}
+
+// streamVersion returns the current version of an aggregate stream, or 0 if
+// there are no events for it.
+func (s *EventStore) streamVersion(ctx context.Context, db *mongo.Database, id uuid.UUID) (int, error) {
+	var strm stream
+
+	if err := db.Collection(s.streamsCollectionName).FindOne(ctx, bson.M{"_id": id}).Decode(&strm); errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("could not find stream: %w", err)
+	}
+
+	return strm.Version, nil
+}
+
 // Load implements the Load method of the eventhorizon.EventStore interface.
 func (s *EventStore) Load(ctx context.Context, id uuid.UUID) ([]eh.Event, error) {
 	return s.LoadFrom(ctx, id, 0)
@@ -414,6 +658,31 @@ func (s *EventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([
 	return result, nil
 }
 
+// LoadFromIter implements the LoadFromIter method of the eventhorizon.StreamingEventStore interface.
+// The events are decoded one by one from a cursor while iterating.
+func (s *EventStore) LoadFromIter(ctx context.Context, id uuid.UUID, version int) (eh.Iter, error) {
+	var cursor *mongo.Cursor
+
+	opts := mongoOptions.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	if err := s.database.CollectionExec(ctx, s.eventsCollectionName, func(ctx context.Context, c *mongo.Collection) (err error) {
+		cursor, err = c.Find(ctx, bson.M{"aggregate_id": id, "version": bson.M{"$gte": version}}, opts)
+		if err != nil {
+			return &eh.EventStoreError{
+				Err:         fmt.Errorf("could not find event: %w", err),
+				Op:          eh.EventStoreOpLoad,
+				AggregateID: id,
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("could not load events: %w", err)
+	}
+
+	return &queryIter{cursor: cursor}, nil
+}
+
 // LoadUntil implements LoadUntil method of the eventhorizon.EventStore interface.
 // LoadUntil loads all events from the first up to the given version for the aggregate id from the store.
 func (s *EventStore) LoadUntil(ctx context.Context, id uuid.UUID, version int) ([]eh.Event, error) {
@@ -452,55 +721,14 @@ func (s *EventStore) loadFromCursor(ctx context.Context, id uuid.UUID, cursor *m
 	var events []eh.Event
 
 	for cursor.Next(ctx) {
-		var e evt
-		if err := cursor.Decode(&e); err != nil {
-			return nil, &eh.EventStoreError{
-				Err:         fmt.Errorf("could not decode event: %w", err),
-				Op:          eh.EventStoreOpLoad,
-				AggregateID: id,
-				Events:      events,
-			}
-		}
-
-		// Create an event of the correct type and decode from raw BSON.
-		if len(e.RawData) > 0 {
-			var err error
-			if e.data, err = eh.CreateEventData(e.EventType); err != nil {
-				return nil, &eh.EventStoreError{
-					Err:              fmt.Errorf("could not create event data: %w", err),
-					Op:               eh.EventStoreOpLoad,
-					AggregateType:    e.AggregateType,
-					AggregateID:      id,
-					AggregateVersion: e.Version,
-					Events:           events,
-				}
-			}
-
-			if err := bson.Unmarshal(e.RawData, e.data); err != nil {
-				return nil, &eh.EventStoreError{
-					Err:              fmt.Errorf("could not unmarshal event data: %w", err),
-					Op:               eh.EventStoreOpLoad,
-					AggregateType:    e.AggregateType,
-					AggregateID:      id,
-					AggregateVersion: e.Version,
-					Events:           events,
-				}
-			}
+		event, err := decodeEvent(ctx, cursor)
+		if err != nil {
+			err.AggregateID = id
+			err.Events = events
 
-			e.RawData = nil
+			return nil, err
 		}
 
-		event := eh.NewEvent(
-			e.EventType,
-			e.data,
-			e.Timestamp,
-			eh.ForAggregate(
-				e.AggregateType,
-				e.AggregateID,
-				e.Version,
-			),
-			eh.WithMetadata(e.Metadata),
-		)
 		events = append(events, event)
 	}
 
@@ -515,65 +743,277 @@ func (s *EventStore) loadFromCursor(ctx context.Context, id uuid.UUID, cursor *m
 	return events, nil
 }
 
-// Close implements the Close method of the eventhorizon.EventStore interface.
-func (s *EventStore) Close() error {
-	if s.dbOwnership == externalDB {
-		// Don't close a client we don't own.
+// LoadAllFrom implements the LoadAllFrom method of the eventhorizon.GlobalEventStore interface.
+func (s *EventStore) LoadAllFrom(ctx context.Context, position, limit int) ([]eh.Event, error) {
+	const errMessage = "could not load all events: %w"
+
+	var events []eh.Event
+
+	// The global position is used as ID for the events.
+	opts := mongoOptions.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	if err := s.database.CollectionExec(ctx, s.eventsCollectionName, func(ctx context.Context, c *mongo.Collection) error {
+		cursor, err := c.Find(ctx, bson.M{"_id": bson.M{"$gte": position}}, opts)
+		if err != nil {
+			return &eh.EventStoreError{
+				Err: fmt.Errorf("could not find events: %w", err),
+				Op:  eh.EventStoreOpLoad,
+			}
+		}
+
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			event, err := decodeEvent(ctx, cursor)
+			if err != nil {
+				err.Events = events
+
+				return err
+			}
+
+			events = append(events, event)
+		}
+
+		if err := cursor.Err(); err != nil {
+			return &eh.EventStoreError{
+				Err:    fmt.Errorf("could not iterate events: %w", err),
+				Op:     eh.EventStoreOpLoad,
+				Events: events,
+			}
+		}
+
 		return nil
+	}); err != nil {
+		return nil, fmt.Errorf(errMessage, err)
 	}
 
-	return s.database.Close()
+	return events, nil
 }
 
-// EventsCollectionName returns the name of the events collection.
-func (s *EventStore) EventsCollectionName() string { return s.eventsCollectionName }
+// QueryEvents implements the QueryEvents method of the eventhorizon.EventQuerier interface.
+// The events are streamed from a cursor, sorted by their global position.
+func (s *EventStore) QueryEvents(ctx context.Context, query eh.EventQuery) (eh.Iter, error) {
+	const errMessage = "could not query events: %w"
 
-// StreamsCollectionName returns the name of the streams collection.
-func (s *EventStore) StreamsCollectionName() string { return s.streamsCollectionName }
+	filter := bson.M{}
 
-// SnapshotsCollectionName returns the name of the snapshots collection.
-func (s *EventStore) SnapshotsCollectionName() string { return s.snapshotsCollectionName }
+	if len(query.EventTypes) > 0 {
+		filter["event_type"] = bson.M{"$in": query.EventTypes}
+	}
 
-type SnapshotRecord struct {
-	AggregateID   uuid.UUID        `bson:"aggregate_id"`
-	RawData       []byte           `bson:"data"`
-	Timestamp     time.Time        `bson:"timestamp"`
-	Version       int              `bson:"version"`
-	AggregateType eh.AggregateType `bson:"aggregate_type"`
+	if len(query.AggregateTypes) > 0 {
+		filter["aggregate_type"] = bson.M{"$in": query.AggregateTypes}
+	}
+
+	timestamp := bson.M{}
+	if !query.From.IsZero() {
+		timestamp["$gte"] = query.From
+	}
+
+	if !query.Until.IsZero() {
+		timestamp["$lt"] = query.Until
+	}
+
+	if len(timestamp) > 0 {
+		filter["timestamp"] = timestamp
+	}
+
+	for k, v := range query.Metadata {
+		filter["metadata."+k] = v
+	}
+
+	opts := mongoOptions.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	if query.Limit > 0 {
+		opts.SetLimit(int64(query.Limit))
+	}
+
+	var cursor *mongo.Cursor
+
+	if err := s.database.CollectionExec(ctx, s.eventsCollectionName, func(ctx context.Context, c *mongo.Collection) (err error) {
+		if cursor, err = c.Find(ctx, filter, opts); err != nil {
+			return &eh.EventStoreError{
+				Err: fmt.Errorf("could not find events: %w", err),
+				Op:  eh.EventStoreOpLoad,
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf(errMessage, err)
+	}
+
+	return &queryIter{cursor: cursor}, nil
 }
 
-func (r *SnapshotRecord) decompress() error {
-	byteReader := bytes.NewReader(r.RawData)
-	reader, err := gzip.NewReader(byteReader)
+// queryIter iterates over the events of a cursor, the iterator is not thread safe.
+type queryIter struct {
+	cursor *mongo.Cursor
+	event  eh.Event
+	err    error
+}
 
+func (i *queryIter) Next(ctx context.Context) bool {
+	if i.err != nil || !i.cursor.Next(ctx) {
+		return false
+	}
+
+	event, err := decodeEvent(ctx, i.cursor)
 	if err != nil {
+		i.err = err
+
+		return false
+	}
+
+	i.event = event
+
+	return true
+}
+
+func (i *queryIter) Value() interface{} {
+	return i.event
+}
+
+func (i *queryIter) Close(ctx context.Context) error {
+	if err := i.cursor.Close(ctx); err != nil {
 		return err
 	}
 
-	r.RawData, err = io.ReadAll(reader)
+	if i.err != nil {
+		return i.err
+	}
 
-	return err
+	return i.cursor.Err()
 }
 
-func (r *SnapshotRecord) compress() error {
-	var b bytes.Buffer
-	w := gzip.NewWriter(&b)
+// WatchAll implements the WatchAll method of the eventhorizon.GlobalEventWatcher interface.
+// It uses a change stream on the events collection to get notified about inserts.
+func (s *EventStore) WatchAll(ctx context.Context) (<-chan struct{}, error) {
+	notifyCh := make(chan struct{}, 1)
+	resumeToken := bson.Raw{}
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"operationType": "insert"}}},
+	}
 
-	if _, err := w.Write(r.RawData); err != nil {
-		return err //nolint:wrapcheck
+	if err := s.database.CollectionWatchChangeStream(ctx, s.eventsCollectionName, pipeline, &resumeToken,
+		func(ctx context.Context, changes <-chan bson.Raw) error {
+			go func() {
+				defer close(notifyCh)
+
+				for range changes {
+					select {
+					case notifyCh <- struct{}{}:
+					default:
+					}
+				}
+			}()
+
+			return nil
+		}); err != nil {
+		return nil, fmt.Errorf("could not watch events: %w", err)
+	}
+
+	return notifyCh, nil
+}
+
+// decodeEvent decodes the current event of the cursor, including its data.
+func decodeEvent(ctx context.Context, cursor *mongo.Cursor) (eh.Event, *eh.EventStoreError) {
+	var e evt
+	if err := cursor.Decode(&e); err != nil {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("could not decode event: %w", err),
+			Op:  eh.EventStoreOpLoad,
+		}
 	}
 
-	if err := w.Flush(); err != nil {
-		return err //nolint:wrapcheck
+	return decodeEventData(ctx, e)
+}
+
+// decodeEventData decodes the data of an event record and returns the event.
+func decodeEventData(ctx context.Context, e evt) (eh.Event, *eh.EventStoreError) {
+	// Create an event of the correct type and decode from raw BSON.
+	if len(e.RawData) > 0 {
+		var err error
+		if e.RawData, err = bsonCodec.UpcastRawData(ctx, e.EventType, e.SchemaVersion, e.RawData); err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              fmt.Errorf("could not upcast event data: %w", err),
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    e.AggregateType,
+				AggregateID:      e.AggregateID,
+				AggregateVersion: e.Version,
+			}
+		}
+
+		if e.data, err = eh.CreateEventData(e.EventType); err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              fmt.Errorf("could not create event data: %w", err),
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    e.AggregateType,
+				AggregateID:      e.AggregateID,
+				AggregateVersion: e.Version,
+			}
+		}
+
+		if err := bson.Unmarshal(e.RawData, e.data); err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              fmt.Errorf("could not unmarshal event data: %w", err),
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    e.AggregateType,
+				AggregateID:      e.AggregateID,
+				AggregateVersion: e.Version,
+			}
+		}
+
+		e.RawData = nil
 	}
 
-	if err := w.Close(); err != nil {
-		return err //nolint:wrapcheck
+	return eh.NewEvent(
+		e.EventType,
+		e.data,
+		e.Timestamp,
+		eh.ForAggregate(
+			e.AggregateType,
+			e.AggregateID,
+			e.Version,
+		),
+		eh.WithMetadata(e.Metadata),
+	), nil
+}
+
+// Close implements the Close method of the eventhorizon.EventStore interface.
+func (s *EventStore) Close() error {
+	if s.dbOwnership == externalDB {
+		// Don't close a client we don't own.
+		return nil
 	}
 
-	r.RawData = b.Bytes()
+	return s.database.Close()
+}
 
-	return nil
+// EventsCollectionName returns the name of the events collection.
+func (s *EventStore) EventsCollectionName() string { return s.eventsCollectionName }
+
+// StreamsCollectionName returns the name of the streams collection.
+func (s *EventStore) StreamsCollectionName() string { return s.streamsCollectionName }
+
+// SnapshotsCollectionName returns the name of the snapshots collection.
+func (s *EventStore) SnapshotsCollectionName() string { return s.snapshotsCollectionName }
+
+// ArchiveCollectionName returns the name of the archive collection.
+func (s *EventStore) ArchiveCollectionName() string { return s.archiveCollectionName }
+
+// SnapshotRecord is the stored record of a snapshot. The data is encoded with
+// the snapshot codec named by Codec, records without a codec are gzipped.
+type SnapshotRecord struct {
+	AggregateID   uuid.UUID        `bson:"aggregate_id"`
+	RawData       []byte           `bson:"data"`
+	Codec         string           `bson:"codec,omitempty"`
+	Timestamp     time.Time        `bson:"timestamp"`
+	Version       int              `bson:"version"`
+	AggregateType eh.AggregateType `bson:"aggregate_type"`
+	SchemaVersion int              `bson:"schema_version,omitempty"`
 }
 
 // stream is a stream of events, often containing the events for an aggregate.
@@ -596,9 +1036,26 @@ type evt struct {
 	Version       int                    `bson:"version"`
 	RawData       bson.Raw               `bson:"data,omitempty"`
 	data          eh.EventData           `bson:"-"`
+	SchemaVersion int                    `bson:"schema_version,omitempty"`
 	Metadata      map[string]interface{} `bson:"metadata"`
 }
 
+// header returns the event of the record without its data, used to match
+// events before decoding their data.
+func (e *evt) header() eh.Event {
+	return eh.NewEvent(
+		e.EventType,
+		nil,
+		e.Timestamp,
+		eh.ForAggregate(
+			e.AggregateType,
+			e.AggregateID,
+			e.Version,
+		),
+		eh.WithMetadata(e.Metadata),
+	)
+}
+
 // newEvt returns a new evt for an event.
 func newEvt(_ context.Context, event eh.Event) (*evt, error) {
 	e := &evt{
@@ -624,6 +1081,8 @@ func newEvt(_ context.Context, event eh.Event) (*evt, error) {
 				Err: fmt.Errorf("could not marshal event data: %w", err),
 			}
 		}
+
+		e.SchemaVersion = eh.EventSchemaVersion(event.EventType())
 	}
 
 	return e, nil
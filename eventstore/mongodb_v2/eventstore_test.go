@@ -63,13 +63,41 @@ func TestEventStoreIntegration(t *testing.T) {
 
 	eventstore.AcceptanceTest(t, store, context.Background())
 
+	eventstore.GlobalAcceptanceTest(t, store, store, context.Background())
+
+	eventstore.QueryAcceptanceTest(t, store, store, context.Background())
+	eventstore.StreamingAcceptanceTest(t, store, store, context.Background())
+
+	eventstore.SaveManyAcceptanceTest(t, store, context.Background())
+
+	eventstore.ExpectedVersionAcceptanceTest(t, store, context.Background())
+
 	eventstore.SnapshotAcceptanceTest(t, store, context.Background())
 
+	eventstore.UpcastAcceptanceTest(t, store, context.Background())
+
 	if err := store.Close(); err != nil {
 		t.Error("there should be no error:", err)
 	}
 }
 
+func TestWithDeduplicationIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	url, db := makeDB(t)
+
+	store, err := mongodb.NewEventStore(url, db, mongodb.WithDeduplication())
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	eventstore.DeduplicationAcceptanceTest(t, store, context.Background())
+}
+
 func TestWithCollectionNamesIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
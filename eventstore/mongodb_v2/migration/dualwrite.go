@@ -0,0 +1,160 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// ErrMissingEventStore is when a DualWriteEventStore is created without both stores.
+var ErrMissingEventStore = errors.New("missing event store")
+
+// DualWriteEventStore is an event store that saves events to a primary and a
+// secondary store, for example the mongodb and mongodb_v2 stores during a
+// migration. Events and snapshots are loaded from the primary store. Errors
+// when saving to the secondary store do not fail the save but are sent on the
+// Errors channel, the events can then be copied later with Migrate.
+//
+// To cut over, first use the old store as primary, then switch to the new
+// store as primary once all events are migrated, and finally use only the
+// new store.
+type DualWriteEventStore struct {
+	primary   eh.EventStore
+	secondary eh.EventStore
+	errCh     chan error
+}
+
+// NewDualWriteEventStore creates a new DualWriteEventStore.
+func NewDualWriteEventStore(primary, secondary eh.EventStore) (*DualWriteEventStore, error) {
+	if primary == nil || secondary == nil {
+		return nil, ErrMissingEventStore
+	}
+
+	return &DualWriteEventStore{
+		primary:   primary,
+		secondary: secondary,
+		errCh:     make(chan error, 100),
+	}, nil
+}
+
+// Save implements the Save method of the eventhorizon.EventStore interface.
+func (s *DualWriteEventStore) Save(ctx context.Context, events []eh.Event, originalVersion int) error {
+	if err := s.primary.Save(ctx, events, originalVersion); err != nil {
+		return err
+	}
+
+	if err := s.secondary.Save(ctx, events, originalVersion); err != nil {
+		s.sendError(err, events[0].AggregateType(), events[0].AggregateID(), events)
+	}
+
+	return nil
+}
+
+// Load implements the Load method of the eventhorizon.EventStore interface.
+func (s *DualWriteEventStore) Load(ctx context.Context, id uuid.UUID) ([]eh.Event, error) {
+	return s.primary.Load(ctx, id)
+}
+
+// LoadFrom implements the LoadFrom method of the eventhorizon.EventStore interface.
+func (s *DualWriteEventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([]eh.Event, error) {
+	return s.primary.LoadFrom(ctx, id, version)
+}
+
+// LoadUntil implements the LoadUntil method of the eventhorizon.EventStore interface.
+func (s *DualWriteEventStore) LoadUntil(ctx context.Context, id uuid.UUID, version int) ([]eh.Event, error) {
+	return s.primary.LoadUntil(ctx, id, version)
+}
+
+// LoadSnapshot implements the LoadSnapshot method of the eventhorizon.SnapshotStore interface.
+func (s *DualWriteEventStore) LoadSnapshot(ctx context.Context, id uuid.UUID) (*eh.Snapshot, error) {
+	snapshotStore, ok := eh.FindEventStore[eh.SnapshotStore](s.primary)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err:         fmt.Errorf("event store does not support snapshots"),
+			Op:          eh.EventStoreOpLoadSnapshot,
+			AggregateID: id,
+		}
+	}
+
+	return snapshotStore.LoadSnapshot(ctx, id)
+}
+
+// SaveSnapshot implements the SaveSnapshot method of the eventhorizon.SnapshotStore
+// interface. The snapshot is also saved to the secondary store if supported.
+func (s *DualWriteEventStore) SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot eh.Snapshot) error {
+	snapshotStore, ok := eh.FindEventStore[eh.SnapshotStore](s.primary)
+	if !ok {
+		return &eh.EventStoreError{
+			Err:         fmt.Errorf("event store does not support snapshots"),
+			Op:          eh.EventStoreOpSaveSnapshot,
+			AggregateID: id,
+		}
+	}
+
+	if err := snapshotStore.SaveSnapshot(ctx, id, snapshot); err != nil {
+		return err
+	}
+
+	if secondary, ok := eh.FindEventStore[eh.SnapshotStore](s.secondary); ok {
+		if err := secondary.SaveSnapshot(ctx, id, snapshot); err != nil {
+			s.sendError(err, snapshot.AggregateType, id, nil)
+		}
+	}
+
+	return nil
+}
+
+// Errors returns an error channel where errors from saving to the secondary
+// store will be sent. It is closed by Close.
+func (s *DualWriteEventStore) Errors() <-chan error {
+	return s.errCh
+}
+
+// Close implements the Close method of the eventhorizon.EventStore interface,
+// it closes both stores.
+func (s *DualWriteEventStore) Close() error {
+	defer close(s.errCh)
+
+	primaryErr := s.primary.Close()
+	secondaryErr := s.secondary.Close()
+
+	if primaryErr != nil {
+		return primaryErr
+	}
+
+	return secondaryErr
+}
+
+func (s *DualWriteEventStore) sendError(err error, aggregateType eh.AggregateType, id uuid.UUID, events []eh.Event) {
+	err = &eh.EventStoreError{
+		Err:           fmt.Errorf("could not save to secondary store: %w", err),
+		Op:            eh.EventStoreOpSave,
+		AggregateType: aggregateType,
+		AggregateID:   id,
+		Events:        events,
+	}
+
+	select {
+	case s.errCh <- err:
+	default:
+		log.Printf("eventhorizon: missed error in dual write event store: %s", err)
+	}
+}
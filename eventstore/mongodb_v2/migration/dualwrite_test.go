@@ -0,0 +1,109 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/eventstore/memory"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+func TestDualWriteEventStore(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := NewDualWriteEventStore(nil, nil); !errors.Is(err, ErrMissingEventStore) {
+		t.Error("there should be a ErrMissingEventStore error:", err)
+	}
+
+	primary, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	secondary, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	store, err := NewDualWriteEventStore(primary, secondary)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event1 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 1))
+
+	if err := store.Save(ctx, []eh.Event{event1}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	for _, s := range []eh.EventStore{primary, secondary} {
+		events, err := s.Load(ctx, id)
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		assert.Len(t, events, 1)
+	}
+
+	// Errors from the secondary store should not fail the save.
+	store.secondary = &failingStore{secondary}
+
+	event2 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event2"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 2))
+
+	if err := store.Save(ctx, []eh.Event{event2}, 1); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	select {
+	case err := <-store.Errors():
+		var esErr *eh.EventStoreError
+		if !errors.As(err, &esErr) || esErr.AggregateID != id {
+			t.Error("there should be an event store error for the aggregate:", err)
+		}
+	default:
+		t.Error("there should be an error")
+	}
+
+	events, err := store.Load(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assert.Len(t, events, 2)
+
+	if err := store.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+type failingStore struct {
+	eh.EventStore
+}
+
+func (s *failingStore) Save(ctx context.Context, events []eh.Event, originalVersion int) error {
+	return errors.New("save failed")
+}
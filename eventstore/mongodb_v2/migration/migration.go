@@ -0,0 +1,294 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package migration migrates events from the mongodb (v1) event store, which
+// stores all events of an aggregate in one document, to the mongodb_v2 event
+// store. Migrate copies the events in timestamp order so that they get global
+// positions in that order, and can be resumed. A DualWriteEventStore can be used
+// to write to both stores while services are moved over.
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoOptions "go.mongodb.org/mongo-driver/mongo/options"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/eventstore/mongodb"
+)
+
+// DefaultBatchSize is the default number of events migrated per batch.
+const DefaultBatchSize = 1000
+
+// Source is a source of events in timestamp order, implemented by the
+// mongodb (v1) event store. The iterator should return all events after the
+// cursor, or from the first event if nil.
+type Source interface {
+	LoadAllByTimestampIter(ctx context.Context, after *mongodb.TimestampCursor) (eh.Iter, error)
+}
+
+// Checkpoint keeps track of the last migrated event, to resume a migration.
+type Checkpoint interface {
+	// Load returns the cursor of the last migrated event, or nil if none.
+	Load(ctx context.Context) (*mongodb.TimestampCursor, error)
+	// Save saves the cursor of the last migrated event.
+	Save(ctx context.Context, cursor mongodb.TimestampCursor) error
+}
+
+type config struct {
+	batchSize  int
+	checkpoint Checkpoint
+}
+
+// Option is an option setter used to configure a migration.
+type Option func(*config) error
+
+// WithBatchSize sets the number of events loaded per batch, default DefaultBatchSize.
+func WithBatchSize(n int) Option {
+	return func(c *config) error {
+		if n < 1 {
+			return fmt.Errorf("invalid batch size: %d", n)
+		}
+
+		c.batchSize = n
+
+		return nil
+	}
+}
+
+// WithCheckpoint resumes the migration after the last migrated event of the
+// checkpoint, and saves it after each batch.
+func WithCheckpoint(checkpoint Checkpoint) Option {
+	return func(c *config) error {
+		if checkpoint == nil {
+			return fmt.Errorf("missing checkpoint")
+		}
+
+		c.checkpoint = checkpoint
+
+		return nil
+	}
+}
+
+// Migrate copies all events from the source to the target store, usually a
+// mongodb_v2 event store, in timestamp order. The target must support the
+// eventhorizon.NoStream version mode. Events that already exist in the target,
+// from an earlier run or from a DualWriteEventStore, are skipped, so a
+// migration can be resumed even without a checkpoint. Returns the number of
+// copied events.
+//
+// The events of an aggregate must have increasing timestamps, as the migration
+// fails if an event is copied before the previous event of its aggregate.
+func Migrate(ctx context.Context, source Source, target eh.EventStore, options ...Option) (int, error) {
+	c := &config{
+		batchSize: DefaultBatchSize,
+	}
+
+	for i := range options {
+		if err := options[i](c); err != nil {
+			return 0, fmt.Errorf("error while applying option: %w", err)
+		}
+	}
+
+	var (
+		after *mongodb.TimestampCursor
+		err   error
+	)
+
+	if c.checkpoint != nil {
+		if after, err = c.checkpoint.Load(ctx); err != nil {
+			return 0, fmt.Errorf("could not load checkpoint: %w", err)
+		}
+	}
+
+	// All events are read from a single iterator, which only sorts them once.
+	iter, err := source.LoadAllByTimestampIter(ctx, after)
+	if err != nil {
+		return 0, fmt.Errorf("could not load events: %w", err)
+	}
+
+	count, err := migrate(ctx, c, iter, target)
+	if closeErr := iter.Close(ctx); closeErr != nil && err == nil {
+		err = fmt.Errorf("could not load events: %w", closeErr)
+	}
+
+	return count, err
+}
+
+// migrate copies the events of the iterator in batches, saving the checkpoint
+// after each batch.
+func migrate(ctx context.Context, c *config, iter eh.Iter, target eh.EventStore) (int, error) {
+	var count int
+
+	for {
+		events, err := nextBatch(ctx, iter, c.batchSize)
+		if err != nil {
+			return count, err
+		}
+
+		if len(events) == 0 {
+			return count, nil
+		}
+
+		// Consecutive events of an aggregate are saved together.
+		for start := 0; start < len(events); {
+			end := start + 1
+			for end < len(events) && events[end].AggregateID() == events[start].AggregateID() &&
+				events[end].Version() == events[end-1].Version()+1 {
+				end++
+			}
+
+			n, err := copyEvents(ctx, target, events[start:end])
+			if err != nil {
+				return count, err
+			}
+
+			count += n
+			start = end
+		}
+
+		if c.checkpoint != nil {
+			if err := c.checkpoint.Save(ctx, mongodb.CursorForEvent(events[len(events)-1])); err != nil {
+				return count, fmt.Errorf("could not save checkpoint: %w", err)
+			}
+		}
+	}
+}
+
+// nextBatch returns the next batch of at most n events from the iterator.
+func nextBatch(ctx context.Context, iter eh.Iter, n int) ([]eh.Event, error) {
+	events := make([]eh.Event, 0, n)
+
+	for len(events) < n && iter.Next(ctx) {
+		event, ok := iter.Value().(eh.Event)
+		if !ok {
+			return nil, fmt.Errorf("loaded value is of incorrect type %T", iter.Value())
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// copyEvents saves the events of an aggregate, skipping the events that
+// already exist in the target. Returns the number of saved events.
+func copyEvents(ctx context.Context, target eh.EventStore, events []eh.Event) (int, error) {
+	err := target.Save(ctx, events, originalVersion(events[0]))
+	if err == nil {
+		return len(events), nil
+	}
+
+	existing, loadErr := target.LoadFrom(ctx, events[0].AggregateID(), events[0].Version())
+	if loadErr != nil || len(existing) == 0 {
+		return 0, fmt.Errorf("could not save events: %w", err)
+	}
+
+	if len(existing) >= len(events) {
+		return 0, nil
+	}
+
+	events = events[len(existing):]
+
+	if err := target.Save(ctx, events, originalVersion(events[0])); err != nil {
+		return 0, fmt.Errorf("could not save events: %w", err)
+	}
+
+	return len(events), nil
+}
+
+// originalVersion returns the version to save an event at, a new stream for
+// the first event is required to not exist.
+func originalVersion(event eh.Event) int {
+	if event.Version() == 1 {
+		return eh.NoStream
+	}
+
+	return event.Version() - 1
+}
+
+// MongoDBCheckpoint is a Checkpoint stored as a document in a MongoDB collection.
+type MongoDBCheckpoint struct {
+	db             eh.MongoDB
+	collectionName string
+	name           string
+}
+
+// NewMongoDBCheckpoint creates a checkpoint stored in the collection, using the
+// name as ID of its document to allow multiple migrations.
+func NewMongoDBCheckpoint(db eh.MongoDB, collectionName, name string) (*MongoDBCheckpoint, error) {
+	if db == nil {
+		return nil, fmt.Errorf("missing DB")
+	}
+
+	if collectionName == "" || name == "" {
+		return nil, fmt.Errorf("missing collection or checkpoint name")
+	}
+
+	return &MongoDBCheckpoint{
+		db:             db,
+		collectionName: collectionName,
+		name:           name,
+	}, nil
+}
+
+// Load implements the Load method of the Checkpoint interface.
+func (c *MongoDBCheckpoint) Load(ctx context.Context) (*mongodb.TimestampCursor, error) {
+	var (
+		cursor mongodb.TimestampCursor
+		found  bool
+	)
+
+	if err := c.db.CollectionExec(ctx, c.collectionName, func(ctx context.Context, coll *mongo.Collection) error {
+		err := coll.FindOne(ctx, bson.M{"_id": c.name}).Decode(&cursor)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		found = true
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("could not load checkpoint: %w", err)
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	return &cursor, nil
+}
+
+// Save implements the Save method of the Checkpoint interface.
+func (c *MongoDBCheckpoint) Save(ctx context.Context, cursor mongodb.TimestampCursor) error {
+	if err := c.db.CollectionExec(ctx, c.collectionName, func(ctx context.Context, coll *mongo.Collection) error {
+		_, err := coll.UpdateOne(ctx,
+			bson.M{"_id": c.name},
+			bson.M{"$set": cursor},
+			mongoOptions.Update().SetUpsert(true),
+		)
+
+		return err
+	}); err != nil {
+		return fmt.Errorf("could not save checkpoint: %w", err)
+	}
+
+	return nil
+}
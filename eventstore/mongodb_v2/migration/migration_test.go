@@ -0,0 +1,155 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/eventstore/memory"
+	"github.com/Clarilab/eventhorizon/eventstore/mongodb"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// source is a Source of events already in timestamp order.
+type source struct {
+	events []eh.Event
+}
+
+func (s *source) LoadAllByTimestampIter(ctx context.Context, after *mongodb.TimestampCursor) (eh.Iter, error) {
+	start := 0
+
+	if after != nil {
+		for i, e := range s.events {
+			if mongodb.CursorForEvent(e) == *after {
+				start = i + 1
+			}
+		}
+	}
+
+	return &sourceIter{events: s.events[start:], i: -1}, nil
+}
+
+// sourceIter iterates over the events of a source.
+type sourceIter struct {
+	events []eh.Event
+	i      int
+}
+
+func (i *sourceIter) Next(ctx context.Context) bool {
+	i.i++
+
+	return i.i < len(i.events)
+}
+
+func (i *sourceIter) Value() interface{} {
+	return i.events[i.i]
+}
+
+func (i *sourceIter) Close(ctx context.Context) error {
+	return nil
+}
+
+// checkpoint is an in memory Checkpoint.
+type checkpoint struct {
+	cursor *mongodb.TimestampCursor
+}
+
+func (c *checkpoint) Load(ctx context.Context) (*mongodb.TimestampCursor, error) {
+	return c.cursor, nil
+}
+
+func (c *checkpoint) Save(ctx context.Context, cursor mongodb.TimestampCursor) error {
+	c.cursor = &cursor
+
+	return nil
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	id1, id2 := uuid.New(), uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	newEvent := func(id uuid.UUID, version int, offset time.Duration) eh.Event {
+		return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp.Add(offset),
+			eh.ForAggregate(mocks.AggregateType, id, version))
+	}
+
+	src := &source{events: []eh.Event{
+		newEvent(id1, 1, 0),
+		newEvent(id1, 2, time.Second),
+		newEvent(id2, 1, 2*time.Second),
+		newEvent(id1, 3, 3*time.Second),
+	}}
+
+	target, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// The first event of id2 has already been saved, as by a dual write.
+	if err := target.Save(ctx, src.events[2:3], 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	cp := &checkpoint{}
+
+	n, err := Migrate(ctx, src, target, WithBatchSize(3), WithCheckpoint(cp))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assert.Equal(t, 3, n)
+
+	if assert.NotNil(t, cp.cursor) {
+		assert.Equal(t, mongodb.CursorForEvent(src.events[3]), *cp.cursor)
+	}
+
+	// The global positions should follow the timestamps, except for the event
+	// that was saved before.
+	all, err := target.LoadAllFrom(ctx, 1, 0)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if assert.Len(t, all, 4) {
+		for i, expected := range []eh.Event{src.events[2], src.events[0], src.events[1], src.events[3]} {
+			assert.Equal(t, expected.AggregateID(), all[i].AggregateID())
+			assert.Equal(t, expected.Version(), all[i].Version())
+		}
+	}
+
+	// Resuming from the checkpoint should only copy new events.
+	src.events = append(src.events, newEvent(id2, 2, 4*time.Second))
+
+	if n, err = Migrate(ctx, src, target, WithCheckpoint(cp)); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assert.Equal(t, 1, n)
+
+	// Migrating again without a checkpoint should skip all existing events.
+	if n, err = Migrate(ctx, src, target); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assert.Equal(t, 0, n)
+
+}
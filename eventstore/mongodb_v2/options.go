@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	eh "github.com/Clarilab/eventhorizon"
+	snapshotCodec "github.com/Clarilab/eventhorizon/codec/snapshot"
 	"github.com/Clarilab/eventhorizon/mongoutils"
 )
 
@@ -69,6 +70,27 @@ func WithEventHandlersInTX(h ...eh.EventHandler) Option {
 	}
 }
 
+// WithDeduplication rejects saves for an aggregate with ErrDuplicateSave if an
+// earlier save has events with the same command ID in their metadata (as added
+// by eventhorizon.FromCommand), which makes retrying commands idempotent.
+func WithDeduplication() Option {
+	return WithDeduplicationKey(eh.CommandIDMetadataKey)
+}
+
+// WithDeduplicationKey is like WithDeduplication but uses a custom metadata key
+// as idempotency key. Events without the key are never seen as duplicates.
+func WithDeduplicationKey(key string) Option {
+	return func(s *EventStore) error {
+		if key == "" {
+			return fmt.Errorf("missing deduplication key")
+		}
+
+		s.dedupKey = key
+
+		return nil
+	}
+}
+
 // WithCollectionNames uses different collections from the default "events" and "streams" collections.
 // Will return an error if provided parameters are equal.
 func WithCollectionNames(eventsColl, streamsColl string) Option {
@@ -100,3 +122,47 @@ func WithSnapshotCollectionName(snapshotColl string) Option {
 		return nil
 	}
 }
+
+// WithSnapshotRetention keeps the last n snapshots of each aggregate, older
+// snapshots are deleted when saving. Default 0, which keeps all snapshots.
+func WithSnapshotRetention(n int) Option {
+	return func(s *EventStore) error {
+		if n < 0 {
+			return fmt.Errorf("invalid snapshot retention: %d", n)
+		}
+
+		s.snapshotRetention = n
+
+		return nil
+	}
+}
+
+// WithSnapshotCodec encodes new snapshots with the codec, default gzip. The
+// codec is stored with each snapshot, snapshots encoded with the built in
+// codecs can always be loaded, other codecs used for existing snapshots (for
+// example an encrypting codec wrapping another codec) must be passed as decoders.
+func WithSnapshotCodec(codec eh.SnapshotCodec, decoders ...eh.SnapshotCodec) Option {
+	return func(s *EventStore) error {
+		if codec == nil {
+			return fmt.Errorf("missing snapshot codec")
+		}
+
+		s.snapshotCodecs = snapshotCodec.NewCodecs(codec, decoders...)
+
+		return nil
+	}
+}
+
+// WithArchiveCollectionName uses a different collection from the default
+// "events_archive" collection for events archived by Truncate.
+func WithArchiveCollectionName(archiveColl string) Option {
+	return func(s *EventStore) error {
+		if err := mongoutils.CheckCollectionName(archiveColl); err != nil {
+			return fmt.Errorf("archive collection: %w", err)
+		}
+
+		s.archiveCollectionName = archiveColl
+
+		return nil
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	eh "github.com/Clarilab/eventhorizon"
+	snapshotCodec "github.com/Clarilab/eventhorizon/codec/snapshot"
 	"github.com/Clarilab/eventhorizon/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -15,16 +16,30 @@ import (
 )
 
 func (s *EventStore) LoadSnapshot(ctx context.Context, id uuid.UUID) (*eh.Snapshot, error) {
+	return s.loadSnapshot(ctx, id, bson.M{"aggregate_id": id})
+}
+
+// LoadSnapshotAt implements the LoadSnapshotAt method of the eventhorizon.SnapshotHistoryStore interface.
+func (s *EventStore) LoadSnapshotAt(ctx context.Context, id uuid.UUID, maxVersion int) (*eh.Snapshot, error) {
+	return s.loadSnapshot(ctx, id, bson.M{
+		"aggregate_id": id,
+		"version":      bson.M{"$lte": maxVersion},
+	})
+}
+
+// loadSnapshot loads the snapshot with the highest version matching the filter.
+func (s *EventStore) loadSnapshot(ctx context.Context, id uuid.UUID, filter bson.M) (*eh.Snapshot, error) {
 	const errMessage = "could not load snapshot: %w"
 
 	var (
 		record   = new(SnapshotRecord)
 		snapshot = new(eh.Snapshot)
+		found    bool
 		err      error
 	)
 
 	if err = s.database.CollectionExec(ctx, s.snapshotsCollectionName, func(ctx context.Context, c *mongo.Collection) error {
-		err = c.FindOne(ctx, bson.M{"aggregate_id": id}, mongoOptions.FindOne().SetSort(bson.M{"version": -1})).Decode(record)
+		err = c.FindOne(ctx, filter, mongoOptions.FindOne().SetSort(bson.M{"version": -1})).Decode(record)
 		if err != nil {
 			if errors.Is(err, mongo.ErrNoDocuments) {
 				return nil
@@ -37,11 +52,28 @@ func (s *EventStore) LoadSnapshot(ctx context.Context, id uuid.UUID) (*eh.Snapsh
 			}
 		}
 
+		found = true
+
 		return nil
 	}); err != nil {
 		return nil, fmt.Errorf(errMessage, err)
 	}
 
+	// There is no snapshot for the aggregate.
+	if !found {
+		return nil, nil
+	}
+
+	if err = eh.CheckSnapshotSchemaVersion(record.AggregateType, record.SchemaVersion); err != nil {
+		return nil, &eh.EventStoreError{
+			Err:              err,
+			Op:               eh.EventStoreOpLoadSnapshot,
+			AggregateType:    record.AggregateType,
+			AggregateID:      id,
+			AggregateVersion: record.Version,
+		}
+	}
+
 	if snapshot.State, err = eh.CreateSnapshotData(record.AggregateID, record.AggregateType); err != nil {
 		return nil, &eh.EventStoreError{
 			Err:         fmt.Errorf("could not decode snapshot: %w", err),
@@ -50,9 +82,15 @@ func (s *EventStore) LoadSnapshot(ctx context.Context, id uuid.UUID) (*eh.Snapsh
 		}
 	}
 
-	if err = record.decompress(); err != nil {
+	// Snapshots saved before the codec was stored are gzipped.
+	codec := record.Codec
+	if codec == "" {
+		codec = snapshotCodec.GzipName
+	}
+
+	if record.RawData, err = s.snapshotCodecs.Decode(ctx, codec, record.RawData); err != nil {
 		return nil, &eh.EventStoreError{
-			Err:         fmt.Errorf("could not decompress snapshot: %w", err),
+			Err:         fmt.Errorf("could not decode snapshot data: %w", err),
 			Op:          eh.EventStoreOpLoadSnapshot,
 			AggregateID: id,
 		}
@@ -95,24 +133,29 @@ func (s *EventStore) SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot eh
 		AggregateType: snapshot.AggregateType,
 		Timestamp:     time.Now(),
 		Version:       snapshot.Version,
+		SchemaVersion: eh.SnapshotSchemaVersion(snapshot.AggregateType),
 	}
 
+	snapshot.SchemaVersion = record.SchemaVersion
+
 	if record.RawData, err = json.Marshal(snapshot); err != nil {
 		return
 	}
 
-	if err = record.compress(); err != nil {
+	if record.Codec, record.RawData, err = s.snapshotCodecs.Encode(ctx, record.RawData); err != nil {
 		return &eh.EventStoreError{
-			Err:         fmt.Errorf("could not compress snapshot: %w", err),
+			Err:         fmt.Errorf("could not encode snapshot data: %w", err),
 			Op:          eh.EventStoreOpSaveSnapshot,
 			AggregateID: id,
 		}
 	}
 
 	if err = s.database.CollectionExec(ctx, s.snapshotsCollectionName, func(ctx context.Context, c *mongo.Collection) error {
-		if _, err := c.InsertOne(ctx,
+		// A snapshot with the same version is replaced.
+		if _, err := c.ReplaceOne(ctx,
+			bson.M{"aggregate_id": id, "version": record.Version},
 			record,
-			mongoOptions.InsertOne(),
+			mongoOptions.Replace().SetUpsert(true),
 		); err != nil {
 			return &eh.EventStoreError{
 				Err:         fmt.Errorf("could not save snapshot: %w", err),
@@ -121,6 +164,41 @@ func (s *EventStore) SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot eh
 			}
 		}
 
+		if s.snapshotRetention == 0 {
+			return nil
+		}
+
+		// Find the newest snapshot outside of the retention and delete it
+		// together with all older snapshots.
+		var pruned SnapshotRecord
+
+		if err := c.FindOne(ctx,
+			bson.M{"aggregate_id": id},
+			mongoOptions.FindOne().
+				SetSort(bson.M{"version": -1}).
+				SetSkip(int64(s.snapshotRetention)).
+				SetProjection(bson.M{"version": 1}),
+		).Decode(&pruned); errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		} else if err != nil {
+			return &eh.EventStoreError{
+				Err:         fmt.Errorf("could not find snapshots to prune: %w", err),
+				Op:          eh.EventStoreOpSaveSnapshot,
+				AggregateID: id,
+			}
+		}
+
+		if _, err := c.DeleteMany(ctx, bson.M{
+			"aggregate_id": id,
+			"version":      bson.M{"$lte": pruned.Version},
+		}); err != nil {
+			return &eh.EventStoreError{
+				Err:         fmt.Errorf("could not prune snapshots: %w", err),
+				Op:          eh.EventStoreOpSaveSnapshot,
+				AggregateID: id,
+			}
+		}
+
 		return nil
 	}); err != nil {
 		return fmt.Errorf(errMessage, err)
@@ -128,3 +206,38 @@ func (s *EventStore) SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot eh
 
 	return nil
 }
+
+// RemoveSnapshotsAfter implements the RemoveSnapshotsAfter method of the eventhorizon.SnapshotHistoryStore interface.
+// Returns ErrSnapshotCoversTruncatedEvents if the snapshots to remove are needed
+// to load the aggregate after its events have been truncated, see Truncate.
+func (s *EventStore) RemoveSnapshotsAfter(ctx context.Context, id uuid.UUID, version int) error {
+	if err := s.database.DatabaseExecWithTransaction(ctx, func(txCtx mongo.SessionContext, db *mongo.Database) error {
+		truncated, err := s.truncatedVersion(txCtx, db, id)
+		if err != nil {
+			return err
+		}
+
+		// Keep at least one snapshot that covers the truncated events.
+		if version < truncated {
+			return ErrSnapshotCoversTruncatedEvents
+		}
+
+		if _, err := db.Collection(s.snapshotsCollectionName).DeleteMany(txCtx, bson.M{
+			"aggregate_id": id,
+			"version":      bson.M{"$gt": version},
+		}); err != nil {
+			return fmt.Errorf("could not delete snapshots: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return &eh.EventStoreError{
+			Err:              err,
+			Op:               eh.EventStoreOpRemoveSnapshots,
+			AggregateID:      id,
+			AggregateVersion: version,
+		}
+	}
+
+	return nil
+}
@@ -20,9 +20,14 @@ import (
 	"encoding/hex"
 	"os"
 	"testing"
+	"time"
 
+	eh "github.com/Clarilab/eventhorizon"
+	snapshotCodec "github.com/Clarilab/eventhorizon/codec/snapshot"
+	"github.com/Clarilab/eventhorizon/encryption/memory"
 	"github.com/Clarilab/eventhorizon/eventstore"
 	mongodb "github.com/Clarilab/eventhorizon/eventstore/mongodb_v2"
+	"github.com/Clarilab/eventhorizon/uuid"
 )
 
 func TestEventStoreSnapshotStoreIntegration(t *testing.T) {
@@ -60,4 +65,102 @@ func TestEventStoreSnapshotStoreIntegration(t *testing.T) {
 	defer store.Close()
 
 	eventstore.SnapshotAcceptanceTest(t, store, context.Background())
+
+	historyStore, err := mongodb.NewEventStore(url, db,
+		mongodb.WithSnapshotCollectionName("history_snapshots"),
+		mongodb.WithSnapshotRetention(3),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer historyStore.Close()
+
+	eventstore.SnapshotHistoryAcceptanceTest(t, historyStore, context.Background())
+}
+
+func TestWithSnapshotCodecIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// Use MongoDB in Docker with fallback to localhost.
+	addr := os.Getenv("MONGODB_ADDR")
+	if addr == "" {
+		addr = "localhost:27017"
+	}
+
+	url := "mongodb://" + addr
+
+	// Get a random DB name.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	db := "test-" + hex.EncodeToString(b)
+
+	t.Log("using DB:", db)
+
+	encrypting, err := snapshotCodec.NewAESGCM(snapshotCodec.Zstd(), memory.NewKeyStore(), "key-1")
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	store, err := mongodb.NewEventStore(url, db,
+		mongodb.WithSnapshotCodec(encrypting),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	eventstore.SnapshotAcceptanceTest(t, store, context.Background())
+
+	// Snapshots saved with other codecs should still load.
+	type TestData struct {
+		Data string
+	}
+
+	const aggregateType = eh.AggregateType("SnapshotCodecTest")
+
+	eh.RegisterSnapshotData(aggregateType, func(uuid.UUID) eh.SnapshotData { return &TestData{} })
+	defer eh.UnregisterSnapshotData(aggregateType)
+
+	gzipStore, err := mongodb.NewEventStore(url, db,
+		mongodb.WithSnapshotCodec(snapshotCodec.Gzip(), encrypting),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer gzipStore.Close()
+
+	ctx := context.Background()
+	gzipID, encryptedID := uuid.New(), uuid.New()
+
+	for id, s := range map[uuid.UUID]*mongodb.EventStore{gzipID: gzipStore, encryptedID: store} {
+		if err := s.SaveSnapshot(ctx, id, eh.Snapshot{
+			Version:       1,
+			AggregateType: aggregateType,
+			Timestamp:     time.Now(),
+			State:         &TestData{Data: id.String()},
+		}); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	for _, s := range []*mongodb.EventStore{gzipStore, store} {
+		for _, id := range []uuid.UUID{gzipID, encryptedID} {
+			loaded, err := s.LoadSnapshot(ctx, id)
+			if err != nil {
+				t.Fatal("there should be no error:", err)
+			}
+
+			if loaded == nil || loaded.State.(*TestData).Data != id.String() {
+				t.Error("the loaded snapshot should be correct:", loaded)
+			}
+		}
+	}
 }
@@ -0,0 +1,340 @@
+// Copyright (c) 2021 - The Event Horizon authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb_v2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoOptions "go.mongodb.org/mongo-driver/mongo/options"
+
+	eh "github.com/Clarilab/eventhorizon"
+	jsonCodec "github.com/Clarilab/eventhorizon/codec/json"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// ErrNoCoveringSnapshot is when truncating events that are not covered by a
+// snapshot of the aggregate.
+var ErrNoCoveringSnapshot = errors.New("no snapshot covering the truncated events")
+
+// ErrSnapshotCoversTruncatedEvents is when removing the snapshots that are
+// needed to load an aggregate with truncated events.
+var ErrSnapshotCoversTruncatedEvents = errors.New("snapshot covers truncated events")
+
+// TruncateOption is an option for Truncate.
+type TruncateOption func(*truncateOptions)
+
+type truncateOptions struct {
+	beforeVersion int
+	beforeTime    time.Time
+	writer        io.Writer
+}
+
+// TruncateBeforeVersion only truncates the events before the version, which
+// must be covered by the latest snapshot.
+func TruncateBeforeVersion(version int) TruncateOption {
+	return func(o *truncateOptions) {
+		o.beforeVersion = version
+	}
+}
+
+// TruncateBefore only truncates the events up to the first event at or after
+// the time, which must be covered by the latest snapshot.
+func TruncateBefore(t time.Time) TruncateOption {
+	return func(o *truncateOptions) {
+		o.beforeTime = t
+	}
+}
+
+// TruncateToWriter archives the truncated events as newline delimited JSON to
+// the writer, instead of to the archive collection.
+func TruncateToWriter(w io.Writer) TruncateOption {
+	return func(o *truncateOptions) {
+		o.writer = w
+	}
+}
+
+// Truncate archives the events of an aggregate that are covered by its latest
+// snapshot and removes them from the events collection, by default all events
+// up to the version of the snapshot. The events are archived to the archive
+// collection, see WithArchiveCollectionName, or to a writer with TruncateToWriter.
+// Returns ErrNoCoveringSnapshot if there is no snapshot covering the events.
+//
+// After truncation the aggregate should be loaded from the snapshot using
+// LoadFrom, the archived events are no longer included in Load, LoadAllFrom
+// or QueryEvents. The snapshots covering the archived events can not be removed
+// with RemoveSnapshotsAfter. Returns the number of archived events.
+func (s *EventStore) Truncate(ctx context.Context, id uuid.UUID, options ...TruncateOption) (int, error) {
+	opts := truncateOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	snapshotVersion, err := s.latestSnapshotVersion(ctx, id)
+	if err != nil {
+		return 0, &eh.EventStoreError{
+			Err:         err,
+			Op:          eh.EventStoreOpTruncate,
+			AggregateID: id,
+		}
+	}
+
+	version, err := s.truncateVersion(ctx, id, snapshotVersion, opts)
+	if err != nil {
+		return 0, &eh.EventStoreError{
+			Err:              err,
+			Op:               eh.EventStoreOpTruncate,
+			AggregateID:      id,
+			AggregateVersion: snapshotVersion,
+		}
+	}
+
+	if version < 1 {
+		return 0, nil
+	}
+
+	filter := bson.M{
+		"aggregate_id": id,
+		"version":      bson.M{"$lte": version},
+	}
+
+	if opts.writer != nil {
+		if err := s.exportEvents(ctx, filter, opts.writer); err != nil {
+			return 0, &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpTruncate,
+				AggregateID:      id,
+				AggregateVersion: version,
+			}
+		}
+	}
+
+	var archived int
+
+	if err := s.database.DatabaseExecWithTransaction(ctx, func(txCtx mongo.SessionContext, db *mongo.Database) error {
+		events := db.Collection(s.eventsCollectionName)
+
+		if opts.writer == nil {
+			// Copy the stored events as is to the archive collection.
+			cursor, err := events.Find(txCtx, filter, mongoOptions.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+			if err != nil {
+				return fmt.Errorf("could not find events: %w", err)
+			}
+
+			var docs []interface{}
+
+			for cursor.Next(txCtx) {
+				docs = append(docs, bson.Raw(append([]byte{}, cursor.Current...)))
+			}
+
+			if err := cursor.Err(); err != nil {
+				return fmt.Errorf("could not iterate events: %w", err)
+			}
+
+			if err := cursor.Close(txCtx); err != nil {
+				return fmt.Errorf("could not close cursor: %w", err)
+			}
+
+			if len(docs) > 0 {
+				if _, err := db.Collection(s.archiveCollectionName).InsertMany(txCtx, docs); err != nil {
+					return fmt.Errorf("could not archive events: %w", err)
+				}
+			}
+		}
+
+		res, err := events.DeleteMany(txCtx, filter)
+		if err != nil {
+			return fmt.Errorf("could not delete events: %w", err)
+		}
+
+		archived = int(res.DeletedCount)
+
+		return nil
+	}); err != nil {
+		return 0, &eh.EventStoreError{
+			Err:              err,
+			Op:               eh.EventStoreOpTruncate,
+			AggregateID:      id,
+			AggregateVersion: version,
+		}
+	}
+
+	return archived, nil
+}
+
+// latestSnapshotVersion returns the version of the latest snapshot of the
+// aggregate, or ErrNoCoveringSnapshot if there is none or if it is outdated.
+func (s *EventStore) latestSnapshotVersion(ctx context.Context, id uuid.UUID) (int, error) {
+	var record struct {
+		Version       int              `bson:"version"`
+		AggregateType eh.AggregateType `bson:"aggregate_type"`
+		SchemaVersion int              `bson:"schema_version"`
+	}
+
+	if err := s.database.CollectionExec(ctx, s.snapshotsCollectionName, func(ctx context.Context, c *mongo.Collection) error {
+		return c.FindOne(ctx, bson.M{"aggregate_id": id},
+			mongoOptions.FindOne().
+				SetSort(bson.M{"version": -1}).
+				SetProjection(bson.M{"version": 1, "aggregate_type": 1, "schema_version": 1}),
+		).Decode(&record)
+	}); errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, ErrNoCoveringSnapshot
+	} else if err != nil {
+		return 0, fmt.Errorf("could not find snapshot: %w", err)
+	}
+
+	// An outdated snapshot can not be used instead of the events.
+	if err := eh.CheckSnapshotSchemaVersion(record.AggregateType, record.SchemaVersion); err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrNoCoveringSnapshot, err)
+	}
+
+	return record.Version, nil
+}
+
+// truncateVersion returns the version up to which the events should be truncated.
+func (s *EventStore) truncateVersion(ctx context.Context, id uuid.UUID, snapshotVersion int, opts truncateOptions) (int, error) {
+	version := snapshotVersion
+
+	if opts.beforeVersion > 0 {
+		if opts.beforeVersion-1 > snapshotVersion {
+			return 0, ErrNoCoveringSnapshot
+		}
+
+		version = opts.beforeVersion - 1
+	}
+
+	if !opts.beforeTime.IsZero() {
+		// Find the first event at or after the time, all events before it are
+		// truncated to keep the remaining events contiguous.
+		var first struct {
+			Version int `bson:"version"`
+		}
+
+		if err := s.database.CollectionExec(ctx, s.eventsCollectionName, func(ctx context.Context, c *mongo.Collection) error {
+			return c.FindOne(ctx,
+				bson.M{
+					"aggregate_id": id,
+					"timestamp":    bson.M{"$gte": opts.beforeTime},
+				},
+				mongoOptions.FindOne().
+					SetSort(bson.M{"version": 1}).
+					SetProjection(bson.M{"version": 1}),
+			).Decode(&first)
+		}); errors.Is(err, mongo.ErrNoDocuments) {
+			// All events are before the time, the last one must be covered.
+			var stream stream
+
+			if err := s.database.CollectionExec(ctx, s.streamsCollectionName, func(ctx context.Context, c *mongo.Collection) error {
+				return c.FindOne(ctx, bson.M{"_id": id}).Decode(&stream)
+			}); errors.Is(err, mongo.ErrNoDocuments) {
+				return 0, nil
+			} else if err != nil {
+				return 0, fmt.Errorf("could not find stream: %w", err)
+			}
+
+			if stream.Version > snapshotVersion {
+				return 0, ErrNoCoveringSnapshot
+			}
+
+			if stream.Version < version {
+				version = stream.Version
+			}
+		} else if err != nil {
+			return 0, fmt.Errorf("could not find events: %w", err)
+		} else {
+			if first.Version-1 > snapshotVersion {
+				return 0, ErrNoCoveringSnapshot
+			}
+
+			if first.Version-1 < version {
+				version = first.Version - 1
+			}
+		}
+	}
+
+	return version, nil
+}
+
+// exportEvents writes the events matching the filter as newline delimited JSON.
+func (s *EventStore) exportEvents(ctx context.Context, filter bson.M, w io.Writer) error {
+	codec := &jsonCodec.EventCodec{}
+
+	return s.database.CollectionExec(ctx, s.eventsCollectionName, func(ctx context.Context, c *mongo.Collection) error {
+		cursor, err := c.Find(ctx, filter, mongoOptions.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+		if err != nil {
+			return fmt.Errorf("could not find events: %w", err)
+		}
+
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			event, decodeErr := decodeEvent(ctx, cursor)
+			if decodeErr != nil {
+				return decodeErr
+			}
+
+			b, err := codec.MarshalEvent(ctx, event)
+			if err != nil {
+				return fmt.Errorf("could not encode event: %w", err)
+			}
+
+			if _, err := w.Write(append(b, '\n')); err != nil {
+				return fmt.Errorf("could not write event: %w", err)
+			}
+		}
+
+		if err := cursor.Err(); err != nil {
+			return fmt.Errorf("could not iterate events: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// truncatedVersion returns the version up to which the events of an aggregate
+// have been truncated, or 0 if none of the events have been truncated. Must be
+// called within a transaction.
+func (s *EventStore) truncatedVersion(ctx context.Context, db *mongo.Database, id uuid.UUID) (int, error) {
+	var first struct {
+		Version int `bson:"version"`
+	}
+
+	err := db.Collection(s.eventsCollectionName).FindOne(ctx,
+		bson.M{"aggregate_id": id},
+		mongoOptions.FindOne().
+			SetSort(bson.M{"version": 1}).
+			SetProjection(bson.M{"version": 1}),
+	).Decode(&first)
+	if err == nil {
+		return first.Version - 1, nil
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, fmt.Errorf("could not find events: %w", err)
+	}
+
+	// A stream without events has had all of its events truncated.
+	var strm stream
+	if err := db.Collection(s.streamsCollectionName).FindOne(ctx, bson.M{"_id": id}).Decode(&strm); errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("could not find stream: %w", err)
+	}
+
+	return strm.Version, nil
+}
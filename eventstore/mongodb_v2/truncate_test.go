@@ -0,0 +1,215 @@
+// Copyright (c) 2021 - The Event Horizon authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb_v2_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	jsonCodec "github.com/Clarilab/eventhorizon/codec/json"
+	mongodb "github.com/Clarilab/eventhorizon/eventstore/mongodb_v2"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+func TestTruncateIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// Use MongoDB in Docker with fallback to localhost.
+	addr := os.Getenv("MONGODB_ADDR")
+	if addr == "" {
+		addr = "localhost:27017"
+	}
+
+	url := "mongodb://" + addr
+
+	// Get a random DB name.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	db := "test-" + hex.EncodeToString(b)
+
+	t.Log("using DB:", db)
+
+	store, err := mongodb.NewEventStore(url, db,
+		mongodb.WithArchiveCollectionName("archive"),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	if store.ArchiveCollectionName() != "archive" {
+		t.Error("the archive collection name should be correct:", store.ArchiveCollectionName())
+	}
+
+	ctx := context.Background()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	saveEvents := func(id uuid.UUID, n int) {
+		var events []eh.Event
+
+		for i := 1; i <= n; i++ {
+			events = append(events, eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"},
+				timestamp.Add(time.Duration(i)*time.Hour),
+				eh.ForAggregate(mocks.AggregateType, id, i)))
+		}
+
+		if err := store.Save(ctx, events, 0); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	saveSnapshot := func(id uuid.UUID, version int) {
+		if err := store.SaveSnapshot(ctx, id, eh.Snapshot{
+			Version:       version,
+			AggregateType: mocks.AggregateType,
+			Timestamp:     timestamp,
+			State:         &mocks.EventData{Content: "state"},
+		}); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	id := uuid.New()
+	saveEvents(id, 4)
+
+	// Truncate without a snapshot.
+	if _, err := store.Truncate(ctx, id); !errors.Is(err, mongodb.ErrNoCoveringSnapshot) {
+		t.Error("there should be a ErrNoCoveringSnapshot error:", err)
+	}
+
+	saveSnapshot(id, 3)
+
+	// Truncate events not covered by the snapshot.
+	if _, err := store.Truncate(ctx, id, mongodb.TruncateBeforeVersion(5)); !errors.Is(err, mongodb.ErrNoCoveringSnapshot) {
+		t.Error("there should be a ErrNoCoveringSnapshot error:", err)
+	}
+
+	if _, err := store.Truncate(ctx, id, mongodb.TruncateBefore(timestamp.Add(5*time.Hour))); !errors.Is(err, mongodb.ErrNoCoveringSnapshot) {
+		t.Error("there should be a ErrNoCoveringSnapshot error:", err)
+	}
+
+	// Truncate before a version.
+	n, err := store.Truncate(ctx, id, mongodb.TruncateBeforeVersion(2))
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if n != 1 {
+		t.Error("there should be one archived event:", n)
+	}
+
+	// Truncate before a time.
+	n, err = store.Truncate(ctx, id, mongodb.TruncateBefore(timestamp.Add(2*time.Hour+time.Minute)))
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if n != 1 {
+		t.Error("there should be one archived event:", n)
+	}
+
+	// Truncate up to the snapshot.
+	n, err = store.Truncate(ctx, id)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if n != 1 {
+		t.Error("there should be one archived event:", n)
+	}
+
+	if n, err := store.Truncate(ctx, id); err != nil || n != 0 {
+		t.Error("there should be no more archived events:", n, err)
+	}
+
+	// Loading from the snapshot should still work.
+	events, err := store.LoadFrom(ctx, id, 4)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(events) != 1 || events[0].Version() != 4 {
+		t.Error("the event after the snapshot should be loaded:", events)
+	}
+
+	events, err = store.Load(ctx, id)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(events) != 1 {
+		t.Error("the archived events should not be loaded:", events)
+	}
+
+	// The snapshot covering the truncated events should not be removed.
+	if err := store.RemoveSnapshotsAfter(ctx, id, 2); !errors.Is(err, mongodb.ErrSnapshotCoversTruncatedEvents) {
+		t.Error("there should be a ErrSnapshotCoversTruncatedEvents error:", err)
+	}
+
+	if err := store.RemoveSnapshotsAfter(ctx, id, 3); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if snapshot, err := store.LoadSnapshot(ctx, id); err != nil || snapshot == nil || snapshot.Version != 3 {
+		t.Error("the covering snapshot should be kept:", snapshot, err)
+	}
+
+	// Truncate to a writer.
+	id2 := uuid.New()
+	saveEvents(id2, 3)
+	saveSnapshot(id2, 2)
+
+	var buf bytes.Buffer
+
+	n, err = store.Truncate(ctx, id2, mongodb.TruncateToWriter(&buf))
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if n != 2 {
+		t.Error("there should be two archived events:", n)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatal("there should be two exported events:", buf.String())
+	}
+
+	codec := &jsonCodec.EventCodec{}
+
+	event, _, err := codec.UnmarshalEvent(ctx, []byte(lines[0]))
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if event.AggregateID() != id2 || event.Version() != 1 {
+		t.Error("the exported event should be correct:", event)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// QueryAcceptanceTest is the acceptance test that all implementations of
+// EventQuerier should pass. It should manually be called from a test case
+// in each implementation:
+//
+//	func TestEventStore(t *testing.T) {
+//	    store := NewEventStore()
+//	    eventstore.QueryAcceptanceTest(t, store, store, context.Background())
+//	}
+func QueryAcceptanceTest(t *testing.T, store eh.EventStore, querier eh.EventQuerier, ctx context.Context) {
+	// Use a unique aggregate type to not match events from other tests.
+	aggregateType := eh.AggregateType("QueryAggregate-" + uuid.New().String())
+	id1 := uuid.New()
+	id2 := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	event1 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp,
+		eh.ForAggregate(aggregateType, id1, 1),
+		eh.WithMetadata(map[string]interface{}{"user": "user1"}))
+	event2 := eh.NewEvent(mocks.EventOtherType, nil, timestamp.Add(time.Hour),
+		eh.ForAggregate(aggregateType, id1, 2),
+		eh.WithMetadata(map[string]interface{}{"user": "user2"}))
+	event3 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event3"}, timestamp.Add(2*time.Hour),
+		eh.ForAggregate(aggregateType, id2, 1),
+		eh.WithMetadata(map[string]interface{}{"user": "user1"}))
+
+	if err := store.Save(ctx, []eh.Event{event1, event2}, 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{event3}, 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	testCases := map[string]struct {
+		query    eh.EventQuery
+		expected []eh.Event
+	}{
+		"aggregate type": {
+			eh.EventQuery{AggregateTypes: []eh.AggregateType{aggregateType}},
+			[]eh.Event{event1, event2, event3},
+		},
+		"event type": {
+			eh.EventQuery{
+				EventTypes:     []eh.EventType{mocks.EventType},
+				AggregateTypes: []eh.AggregateType{aggregateType},
+			},
+			[]eh.Event{event1, event3},
+		},
+		"time range": {
+			eh.EventQuery{
+				AggregateTypes: []eh.AggregateType{aggregateType},
+				From:           timestamp.Add(time.Hour),
+				Until:          timestamp.Add(2 * time.Hour),
+			},
+			[]eh.Event{event2},
+		},
+		"metadata": {
+			eh.EventQuery{
+				AggregateTypes: []eh.AggregateType{aggregateType},
+				Metadata:       map[string]interface{}{"user": "user1"},
+			},
+			[]eh.Event{event1, event3},
+		},
+		"limit": {
+			eh.EventQuery{
+				AggregateTypes: []eh.AggregateType{aggregateType},
+				Limit:          2,
+			},
+			[]eh.Event{event1, event2},
+		},
+		"no match": {
+			eh.EventQuery{
+				AggregateTypes: []eh.AggregateType{aggregateType},
+				EventTypes:     []eh.EventType{"NoSuchEvent"},
+			},
+			nil,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			iter, err := querier.QueryEvents(ctx, tc.query)
+			if err != nil {
+				t.Fatal("there should be no error:", err)
+			}
+
+			var events []eh.Event
+
+			for iter.Next(ctx) {
+				event, ok := iter.Value().(eh.Event)
+				if !ok {
+					t.Fatal("the value should be an event:", iter.Value())
+				}
+
+				events = append(events, event)
+			}
+
+			if err := iter.Close(ctx); err != nil {
+				t.Error("there should be no error:", err)
+			}
+
+			if len(events) != len(tc.expected) {
+				t.Fatal("there should be the correct number of events:", len(events), len(tc.expected))
+			}
+
+			for i, event := range events {
+				if err := eh.CompareEvents(event, tc.expected[i], eh.IgnorePositionMetadata()); err != nil {
+					t.Error("the event should be correct:", err)
+				}
+
+				if _, ok := eh.GlobalPosition(event); !ok {
+					t.Error("the event should have a global position")
+				}
+			}
+		})
+	}
+}
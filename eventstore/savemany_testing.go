@@ -0,0 +1,137 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// SaveManyAcceptanceTest is the acceptance test that all implementations of
+// MultiStreamEventStore should pass. It should manually be called from a test
+// case in each implementation:
+//
+//	func TestEventStore(t *testing.T) {
+//	    store := NewEventStore()
+//	    eventstore.SaveManyAcceptanceTest(t, store, context.Background())
+//	}
+func SaveManyAcceptanceTest(t *testing.T, store interface {
+	eh.EventStore
+	eh.MultiStreamEventStore
+}, ctx context.Context) {
+	id1 := uuid.New()
+	id2 := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	// Save no streams.
+	err := store.SaveMany(ctx, nil)
+	if !errors.Is(err, eh.ErrMissingEvents) {
+		t.Error("there should be a ErrMissingEvents error:", err)
+	}
+
+	// Save multiple new streams.
+	event1 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id1, 1))
+	event2 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event2"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id2, 1))
+	event3 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event3"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id2, 2))
+
+	if err := store.SaveMany(ctx, []eh.EventStreamSave{
+		{Events: []eh.Event{event1}, OriginalVersion: 0},
+		{Events: []eh.Event{event2, event3}, OriginalVersion: 0},
+	}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Save to existing streams.
+	event4 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event4"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id1, 2))
+	event5 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event5"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id2, 3))
+
+	if err := store.SaveMany(ctx, []eh.EventStreamSave{
+		{Events: []eh.Event{event4}, OriginalVersion: 1},
+		{Events: []eh.Event{event5}, OriginalVersion: 2},
+	}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Save with a conflict in one stream, none of the streams should be saved.
+	event6 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event6"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id1, 3))
+	conflicting := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "conflicting"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id2, 3))
+
+	err = store.SaveMany(ctx, []eh.EventStreamSave{
+		{Events: []eh.Event{event6}, OriginalVersion: 2},
+		{Events: []eh.Event{conflicting}, OriginalVersion: 2},
+	})
+	if !errors.Is(err, eh.ErrEventConflictFromOtherSave) {
+		t.Error("there should be a ErrEventConflictFromOtherSave error:", err)
+	}
+
+	storeErr := &eh.EventStoreError{}
+	if !errors.As(err, &storeErr) || storeErr.AggregateID != id2 {
+		t.Error("the error should be for the conflicting aggregate:", err)
+	}
+
+	// Save the same stream twice.
+	event7 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event7"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id1, 4))
+
+	err = store.SaveMany(ctx, []eh.EventStreamSave{
+		{Events: []eh.Event{event6}, OriginalVersion: 2},
+		{Events: []eh.Event{event7}, OriginalVersion: 3},
+	})
+	if !errors.Is(err, eh.ErrDuplicateEventStream) {
+		t.Error("there should be a ErrDuplicateEventStream error:", err)
+	}
+
+	for id, expected := range map[uuid.UUID][]eh.Event{
+		id1: {event1, event4},
+		id2: {event2, event3, event5},
+	} {
+		events, err := store.Load(ctx, id)
+		if err != nil {
+			t.Error("there should be no error:", err)
+		}
+
+		if len(events) != len(expected) {
+			t.Errorf("incorrect number of loaded events for %s: %d", id, len(events))
+
+			continue
+		}
+
+		for i, event := range events {
+			if err := eh.CompareEvents(event, expected[i],
+				eh.IgnoreVersion(),
+				eh.IgnorePositionMetadata(),
+			); err != nil {
+				t.Error("the event was incorrect:", err)
+			}
+
+			if event.Version() != i+1 {
+				t.Error("the event version should be correct:", event, event.Version())
+			}
+		}
+	}
+}
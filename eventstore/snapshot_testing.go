@@ -0,0 +1,209 @@
+// Copyright (c) 2016 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// SnapshotAcceptanceTest is the acceptance test that all implementations of
+// SnapshotStore should pass. It should manually be called from a test case in
+// each implementation:
+//
+//	func TestEventStore(t *testing.T) {
+//	    store := NewEventStore()
+//	    eventstore.SnapshotAcceptanceTest(t, store, context.Background())
+//	}
+func SnapshotAcceptanceTest(t *testing.T, store eh.SnapshotStore, ctx context.Context) {
+	type TestData struct {
+		Data string
+	}
+
+	const aggregateType = eh.AggregateType("SnapshotAcceptanceTest")
+
+	eh.RegisterSnapshotData(aggregateType, func(uuid.UUID) eh.SnapshotData { return &TestData{} })
+	defer eh.UnregisterSnapshotData(aggregateType)
+
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	// Save snapshot without aggregate type.
+	eventStoreErr := &eh.EventStoreError{}
+
+	err := store.SaveSnapshot(ctx, id, eh.Snapshot{})
+	if !errors.As(err, &eventStoreErr) {
+		t.Error("there should be a event store error:", err)
+	}
+
+	// Save snapshot without state.
+	err = store.SaveSnapshot(ctx, id, eh.Snapshot{
+		AggregateType: aggregateType,
+	})
+	if !errors.As(err, &eventStoreErr) {
+		t.Error("there should be a event store error:", err)
+	}
+
+	// Load non-existing snapshot.
+	loaded, err := store.LoadSnapshot(ctx, id)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if loaded != nil {
+		t.Error("there should be no snapshot:", loaded)
+	}
+
+	// Save and load snapshot.
+	snapshot := eh.Snapshot{
+		Version:       1,
+		AggregateType: aggregateType,
+		Timestamp:     timestamp,
+		State: &TestData{
+			Data: "this is incredible data",
+		},
+	}
+
+	if err := store.SaveSnapshot(ctx, id, snapshot); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	loaded, err = store.LoadSnapshot(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if loaded == nil {
+		t.Fatal("there should be a snapshot")
+	}
+
+	assert.Equal(t, snapshot.Version, loaded.Version)
+	assert.Equal(t, snapshot.AggregateType, loaded.AggregateType)
+	assert.True(t, snapshot.Timestamp.Equal(loaded.Timestamp), "the timestamp should be correct")
+	assert.Equal(t, snapshot.State, loaded.State)
+	assert.Equal(t, 1, loaded.SchemaVersion)
+
+	// Changing the saved state should not change the stored snapshot.
+	snapshot.State.(*TestData).Data = "this is changed data"
+
+	loaded, err = store.LoadSnapshot(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assert.Equal(t, &TestData{Data: "this is incredible data"}, loaded.State)
+
+	// Save and load newer snapshot.
+	snapshot = eh.Snapshot{
+		Version:       2,
+		AggregateType: aggregateType,
+		Timestamp:     timestamp.Add(time.Second),
+		State: &TestData{
+			Data: "this is new incredible data",
+		},
+	}
+
+	if err := store.SaveSnapshot(ctx, id, snapshot); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	loaded, err = store.LoadSnapshot(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if loaded == nil {
+		t.Fatal("there should be a snapshot")
+	}
+
+	assert.Equal(t, snapshot.Version, loaded.Version)
+	assert.Equal(t, snapshot.AggregateType, loaded.AggregateType)
+	assert.Equal(t, snapshot.State, loaded.State)
+
+	// Snapshots of other aggregates should not be affected.
+	otherID := uuid.New()
+	other := eh.Snapshot{
+		Version:       5,
+		AggregateType: aggregateType,
+		Timestamp:     timestamp,
+		State: &TestData{
+			Data: "this is other data",
+		},
+	}
+
+	if err := store.SaveSnapshot(ctx, otherID, other); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	loaded, err = store.LoadSnapshot(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assert.Equal(t, snapshot.Version, loaded.Version)
+	assert.Equal(t, snapshot.State, loaded.State)
+
+	loaded, err = store.LoadSnapshot(ctx, otherID)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if loaded == nil {
+		t.Fatal("there should be a snapshot")
+	}
+
+	assert.Equal(t, other.Version, loaded.Version)
+	assert.Equal(t, other.State, loaded.State)
+
+	// Snapshots with an outdated schema version should not be loaded.
+	eh.UnregisterSnapshotData(aggregateType)
+	eh.RegisterSnapshotData(aggregateType, func(uuid.UUID) eh.SnapshotData { return &TestData{} },
+		eh.WithSnapshotSchemaVersion(2),
+	)
+
+	_, err = store.LoadSnapshot(ctx, id)
+	if !errors.Is(err, eh.ErrSnapshotSchemaMismatch) {
+		t.Error("there should be a ErrSnapshotSchemaMismatch error:", err)
+	}
+
+	if !errors.As(err, &eventStoreErr) || eventStoreErr.AggregateID != id {
+		t.Error("there should be a event store error for the aggregate:", err)
+	}
+
+	// Saving a new snapshot uses the current schema version.
+	snapshot.Version = 3
+	if err := store.SaveSnapshot(ctx, id, snapshot); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	loaded, err = store.LoadSnapshot(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if loaded == nil {
+		t.Fatal("there should be a snapshot")
+	}
+
+	assert.Equal(t, snapshot.Version, loaded.Version)
+	assert.Equal(t, snapshot.State, loaded.State)
+	assert.Equal(t, 2, loaded.SchemaVersion)
+}
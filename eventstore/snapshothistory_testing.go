@@ -0,0 +1,169 @@
+// Copyright (c) 2016 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventstore
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// SnapshotHistoryAcceptanceTest is the acceptance test that all implementations
+// of SnapshotHistoryStore should pass. The store must be configured to keep the
+// last 3 snapshots of each aggregate. It should manually be called from a test
+// case in each implementation:
+//
+//	func TestEventStore(t *testing.T) {
+//	    store := NewEventStore(WithSnapshotRetention(3))
+//	    eventstore.SnapshotHistoryAcceptanceTest(t, store, context.Background())
+//	}
+func SnapshotHistoryAcceptanceTest(t *testing.T, store eh.SnapshotHistoryStore, ctx context.Context) {
+	type TestData struct {
+		Data string
+	}
+
+	const aggregateType = eh.AggregateType("SnapshotHistoryAcceptanceTest")
+
+	eh.RegisterSnapshotData(aggregateType, func(uuid.UUID) eh.SnapshotData { return &TestData{} })
+	defer eh.UnregisterSnapshotData(aggregateType)
+
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	snapshot := func(version int, data string) eh.Snapshot {
+		return eh.Snapshot{
+			Version:       version,
+			AggregateType: aggregateType,
+			Timestamp:     timestamp.Add(time.Duration(version) * time.Second),
+			State:         &TestData{Data: data},
+		}
+	}
+
+	assertSnapshot := func(loaded *eh.Snapshot, err error, version int, data string) {
+		t.Helper()
+
+		if err != nil {
+			t.Error("there should be no error:", err)
+
+			return
+		}
+
+		if version == 0 {
+			if loaded != nil {
+				t.Error("there should be no snapshot:", loaded.Version)
+			}
+
+			return
+		}
+
+		if loaded == nil {
+			t.Errorf("there should be a snapshot with version %d", version)
+
+			return
+		}
+
+		if loaded.Version != version {
+			t.Errorf("the snapshot version should be %d: %d", version, loaded.Version)
+		}
+
+		if state, ok := loaded.State.(*TestData); !ok || state.Data != data {
+			t.Errorf("the snapshot state should be %q: %#v", data, loaded.State)
+		}
+	}
+
+	// Load from an aggregate without snapshots.
+	loaded, err := store.LoadSnapshotAt(ctx, id, 10)
+	assertSnapshot(loaded, err, 0, "")
+
+	// Save snapshots at version 2, 4 and 6.
+	for _, v := range []int{2, 4, 6} {
+		if err := store.SaveSnapshot(ctx, id, snapshot(v, fmt.Sprintf("v%d", v))); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	loaded, err = store.LoadSnapshotAt(ctx, id, 1)
+	assertSnapshot(loaded, err, 0, "")
+
+	loaded, err = store.LoadSnapshotAt(ctx, id, 2)
+	assertSnapshot(loaded, err, 2, "v2")
+
+	loaded, err = store.LoadSnapshotAt(ctx, id, 5)
+	assertSnapshot(loaded, err, 4, "v4")
+
+	loaded, err = store.LoadSnapshotAt(ctx, id, 100)
+	assertSnapshot(loaded, err, 6, "v6")
+
+	loaded, err = store.LoadSnapshot(ctx, id)
+	assertSnapshot(loaded, err, 6, "v6")
+
+	// Save a new snapshot for an existing version, which replaces it.
+	if err := store.SaveSnapshot(ctx, id, snapshot(4, "v4 again")); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	loaded, err = store.LoadSnapshotAt(ctx, id, 5)
+	assertSnapshot(loaded, err, 4, "v4 again")
+
+	// Save a fourth snapshot, which prunes the oldest one.
+	if err := store.SaveSnapshot(ctx, id, snapshot(8, "v8")); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	loaded, err = store.LoadSnapshotAt(ctx, id, 3)
+	assertSnapshot(loaded, err, 0, "")
+
+	loaded, err = store.LoadSnapshotAt(ctx, id, 4)
+	assertSnapshot(loaded, err, 4, "v4 again")
+
+	loaded, err = store.LoadSnapshot(ctx, id)
+	assertSnapshot(loaded, err, 8, "v8")
+
+	// Roll back the latest snapshots.
+	if err := store.RemoveSnapshotsAfter(ctx, id, 5); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	loaded, err = store.LoadSnapshot(ctx, id)
+	assertSnapshot(loaded, err, 4, "v4 again")
+
+	loaded, err = store.LoadSnapshotAt(ctx, id, 100)
+	assertSnapshot(loaded, err, 4, "v4 again")
+
+	// Snapshots can be saved again after a roll back.
+	if err := store.SaveSnapshot(ctx, id, snapshot(6, "v6 again")); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	loaded, err = store.LoadSnapshot(ctx, id)
+	assertSnapshot(loaded, err, 6, "v6 again")
+
+	// Roll back all snapshots.
+	if err := store.RemoveSnapshotsAfter(ctx, id, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	loaded, err = store.LoadSnapshot(ctx, id)
+	assertSnapshot(loaded, err, 0, "")
+
+	// Roll back without snapshots.
+	if err := store.RemoveSnapshotsAfter(ctx, uuid.New(), 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// Replace implements the Replace method of the eventhorizon.EventStoreMaintenance interface.
+func (s *EventStore) Replace(ctx context.Context, event eh.Event) error {
+	id := event.AggregateID()
+	at := event.AggregateType()
+	av := event.Version()
+
+	if err := s.withTx(ctx, func(tx *sql.Tx) error {
+		// First check if the aggregate exists, the not found error in the update
+		// query can mean both that the aggregate or the event is not found.
+		var n int
+		if err := tx.QueryRowContext(ctx, s.dialect.Rebind(
+			`SELECT COUNT(*) FROM `+s.streamsTable+` WHERE id = ?`),
+			id.String(),
+		).Scan(&n); err != nil {
+			return fmt.Errorf("could not find aggregate: %w", err)
+		} else if n == 0 {
+			return eh.ErrAggregateNotFound
+		}
+
+		// Create the event record for the DB, the position is kept.
+		e, err := newEvt(event)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, s.dialect.Rebind(
+			`UPDATE `+s.eventsTable+`
+			SET event_type = ?, timestamp = ?, aggregate_type = ?, data = ?, schema_version = ?, metadata = ?
+			WHERE aggregate_id = ? AND version = ?`),
+			e.EventType, e.Timestamp, e.AggregateType, e.RawData, e.SchemaVersion, e.RawMetadata,
+			e.AggregateID, e.Version,
+		)
+		if err != nil {
+			return fmt.Errorf("could not replace event: %w", err)
+		}
+
+		if rows, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("could not replace event: %w", err)
+		} else if rows == 0 {
+			return eh.ErrEventNotFound
+		}
+
+		return nil
+	}); err != nil {
+		return &eh.EventStoreError{
+			Err:              err,
+			Op:               eh.EventStoreOpReplace,
+			AggregateType:    at,
+			AggregateID:      id,
+			AggregateVersion: av,
+			Events:           []eh.Event{event},
+		}
+	}
+
+	return nil
+}
+
+// RenameEvent implements the RenameEvent method of the eventhorizon.EventStoreMaintenance interface.
+func (s *EventStore) RenameEvent(ctx context.Context, from, to eh.EventType) error {
+	if _, err := s.db.ExecContext(ctx, s.dialect.Rebind(
+		`UPDATE `+s.eventsTable+` SET event_type = ? WHERE event_type = ?`),
+		to, from,
+	); err != nil {
+		return &eh.EventStoreError{
+			Err: fmt.Errorf("could not update events of type '%s': %w", from, err),
+			Op:  eh.EventStoreOpRename,
+		}
+	}
+
+	return nil
+}
+
+// Remove implements the Remove method of the eventhorizon.EventStoreMaintenance interface.
+func (s *EventStore) Remove(ctx context.Context, id uuid.UUID) error {
+	if err := s.withTx(ctx, func(tx *sql.Tx) error {
+		for _, table := range []string{s.streamsTable, s.eventsTable, s.snapshotsTable} {
+			column := "aggregate_id"
+			if table == s.streamsTable {
+				column = "id"
+			}
+
+			if _, err := tx.ExecContext(ctx, s.dialect.Rebind(
+				`DELETE FROM `+table+` WHERE `+column+` = ?`),
+				id.String(),
+			); err != nil {
+				return fmt.Errorf("could not delete from %s: %w", table, err)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return &eh.EventStoreError{
+			Err:         err,
+			Op:          eh.EventStoreOpRemove,
+			AggregateID: id,
+		}
+	}
+
+	return nil
+}
+
+// Clear implements the Clear method of the eventhorizon.EventStoreMaintenance interface.
+func (s *EventStore) Clear(ctx context.Context) error {
+	if err := s.withTx(ctx, func(tx *sql.Tx) error {
+		for _, table := range []string{s.eventsTable, s.streamsTable, s.snapshotsTable} {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM `+table); err != nil {
+				return fmt.Errorf("could not clear %s: %w", table, err)
+			}
+		}
+
+		// Make sure the $all stream exists.
+		return s.ensureAllStream(ctx, tx)
+	}); err != nil {
+		return &eh.EventStoreError{
+			Err: err,
+			Op:  eh.EventStoreOpClear,
+		}
+	}
+
+	return nil
+}
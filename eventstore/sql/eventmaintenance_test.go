@@ -0,0 +1,51 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Clarilab/eventhorizon/eventstore"
+	sqlstore "github.com/Clarilab/eventhorizon/eventstore/sql"
+	"github.com/Clarilab/eventhorizon/sqlutils"
+)
+
+func TestEventStoreMaintenance(t *testing.T) {
+	store, err := sqlstore.NewEventStore("sqlite3", filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	eventstore.MaintenanceAcceptanceTest(t, store, store, context.Background())
+}
+
+func TestEventStoreMaintenancePostgresIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	store, err := sqlstore.NewEventStoreWithDB(makePostgresDB(t), sqlutils.PostgreSQL)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	eventstore.MaintenanceAcceptanceTest(t, store, store, context.Background())
+}
@@ -0,0 +1,639 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	jsonCodec "github.com/Clarilab/eventhorizon/codec/json"
+	"github.com/Clarilab/eventhorizon/sqlutils"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+const (
+	defaultEventsTableName    = "events"
+	defaultStreamsTableName   = "streams"
+	defaultSnapshotsTableName = "snapshots"
+
+	// allStreamID is the ID of the stream keeping track of the global position.
+	allStreamID = "$all"
+)
+
+// EventStore is an eventhorizon.EventStore for SQL databases using database/sql,
+// with one table for all events and another to keep track of all
+// aggregates/streams. It also keeps track of the global position of events,
+// stored as metadata. The SQL differences between databases are handled by a
+// sqlutils.Dialect, SQLite and PostgreSQL are supported.
+//
+// Optimistic concurrency is ensured by the version of the stream and a unique
+// (aggregate_id, version) constraint on the events.
+type EventStore struct {
+	db             *sql.DB
+	dbOwnership    dbOwnership
+	dialect        sqlutils.Dialect
+	eventsTable    string
+	streamsTable   string
+	snapshotsTable string
+	retention      int
+	eventHandler   eh.EventHandler
+}
+
+type dbOwnership int
+
+const (
+	internalDB dbOwnership = iota
+	externalDB
+)
+
+// NewEventStore creates a new EventStore by opening a database with a
+// database/sql driver, the driver must be imported by the caller. The dialect
+// is detected from the driver name if not set with WithDialect.
+func NewEventStore(driverName, dataSourceName string, options ...Option) (*EventStore, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("could not open DB: %w", err)
+	}
+
+	// Use the detected dialect as default, can be set with an option.
+	dialect, _ := sqlutils.DialectForDriver(driverName)
+
+	// SQLite only supports one writer at a time.
+	if dialect == sqlutils.SQLite {
+		db.SetMaxOpenConns(1)
+	}
+
+	s, err := newSQLEventStore(db, dialect, internalDB, options...)
+	if err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// NewEventStoreWithDB creates a new EventStore with a DB and the dialect to use.
+func NewEventStoreWithDB(db *sql.DB, dialect sqlutils.Dialect, options ...Option) (*EventStore, error) {
+	return newSQLEventStore(db, dialect, externalDB, options...)
+}
+
+func newSQLEventStore(db *sql.DB, dialect sqlutils.Dialect, dbOwnership dbOwnership, options ...Option) (*EventStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("missing DB")
+	}
+
+	s := &EventStore{
+		db:             db,
+		dbOwnership:    dbOwnership,
+		dialect:        dialect,
+		eventsTable:    defaultEventsTableName,
+		streamsTable:   defaultStreamsTableName,
+		snapshotsTable: defaultSnapshotsTableName,
+	}
+
+	for i := range options {
+		if err := options[i](s); err != nil {
+			return nil, fmt.Errorf("error while applying option: %w", err)
+		}
+	}
+
+	if s.dialect == nil {
+		return nil, fmt.Errorf("missing dialect")
+	}
+
+	ctx := context.Background()
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %w", err)
+	}
+
+	if err := s.createTables(ctx); err != nil {
+		return nil, fmt.Errorf("could not create tables: %w", err)
+	}
+
+	return s, nil
+}
+
+// createTables creates the tables if they don't exist, and makes sure the
+// $all stream exists.
+func (s *EventStore) createTables(ctx context.Context) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS ` + s.eventsTable + ` (
+			position BIGINT PRIMARY KEY,
+			event_type TEXT NOT NULL,
+			timestamp ` + s.dialect.TimestampType() + ` NOT NULL,
+			aggregate_type TEXT NOT NULL,
+			aggregate_id TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			data ` + s.dialect.BinaryType() + `,
+			schema_version INTEGER NOT NULL DEFAULT 0,
+			metadata ` + s.dialect.BinaryType() + `,
+			UNIQUE (aggregate_id, version)
+		)`,
+		`CREATE TABLE IF NOT EXISTS ` + s.streamsTable + ` (
+			id TEXT PRIMARY KEY,
+			position BIGINT NOT NULL,
+			aggregate_type TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			updated_at ` + s.dialect.TimestampType() + ` NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS ` + s.snapshotsTable + ` (
+			aggregate_id TEXT NOT NULL,
+			aggregate_type TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			timestamp ` + s.dialect.TimestampType() + ` NOT NULL,
+			schema_version INTEGER NOT NULL DEFAULT 0,
+			data ` + s.dialect.BinaryType() + `,
+			PRIMARY KEY (aggregate_id, version)
+		)`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	return s.ensureAllStream(ctx, s.db)
+}
+
+// execer is implemented by both sql.DB and sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// ensureAllStream makes sure the $all stream exists.
+func (s *EventStore) ensureAllStream(ctx context.Context, db execer) error {
+	if _, err := db.ExecContext(ctx, s.dialect.Rebind(
+		`INSERT INTO `+s.streamsTable+` (id, position, aggregate_type, version, updated_at)
+		VALUES (?, 0, '', 0, ?) ON CONFLICT (id) DO NOTHING`),
+		allStreamID, time.Now().UTC(),
+	); err != nil {
+		return fmt.Errorf("could not create the $all stream: %w", err)
+	}
+
+	return nil
+}
+
+// Save implements the Save method of the eventhorizon.EventStore interface.
+func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersion int) error {
+	if len(events) == 0 {
+		return &eh.EventStoreError{
+			Err: eh.ErrMissingEvents,
+			Op:  eh.EventStoreOpSave,
+		}
+	}
+
+	id := events[0].AggregateID()
+	at := events[0].AggregateType()
+
+	// Build all event records, with incrementing versions starting from the
+	// original aggregate version.
+	records := make([]*evt, len(events))
+
+	for i, event := range events {
+		// Only accept events belonging to the same aggregate.
+		if event.AggregateID() != id {
+			return &eh.EventStoreError{
+				Err:              eh.ErrMismatchedEventAggregateIDs,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		if event.AggregateType() != at {
+			return &eh.EventStoreError{
+				Err:              eh.ErrMismatchedEventAggregateTypes,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		// Only accept events that apply to the correct aggregate version.
+		if event.Version() != originalVersion+i+1 {
+			return &eh.EventStoreError{
+				Err:              eh.ErrIncorrectEventVersion,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		// Create the event record for the DB.
+		e, err := newEvt(event)
+		if err != nil {
+			return &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		records[i] = e
+	}
+
+	if err := s.withTx(ctx, func(tx *sql.Tx) error {
+		return s.save(ctx, tx, records, originalVersion)
+	}); err != nil {
+		return &eh.EventStoreError{
+			Err:              err,
+			Op:               eh.EventStoreOpSave,
+			AggregateType:    at,
+			AggregateID:      id,
+			AggregateVersion: originalVersion,
+			Events:           events,
+		}
+	}
+
+	// Let the optional event handler handle the events.
+	if s.eventHandler != nil {
+		for _, e := range events {
+			if err := s.eventHandler.HandleEvent(ctx, e); err != nil {
+				return &eh.EventHandlerError{
+					Err:   err,
+					Event: e,
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// save updates the streams and inserts the events in a transaction.
+func (s *EventStore) save(ctx context.Context, tx *sql.Tx, records []*evt, originalVersion int) error {
+	now := time.Now().UTC()
+	first := records[0]
+	n := len(records)
+
+	// Either insert a new stream or increment the version of an existing one,
+	// only if the version is matching (ie not changed since loading the aggregate).
+	if originalVersion == 0 {
+		if _, err := tx.ExecContext(ctx, s.dialect.Rebind(
+			`INSERT INTO `+s.streamsTable+` (id, position, aggregate_type, version, updated_at)
+			VALUES (?, 0, ?, ?, ?)`),
+			first.AggregateID, first.AggregateType, n, now,
+		); err != nil {
+			if s.dialect.IsUniqueViolation(err) {
+				return eh.ErrEventConflictFromOtherSave
+			}
+
+			return fmt.Errorf("could not insert stream: %w", err)
+		}
+	} else {
+		res, err := tx.ExecContext(ctx, s.dialect.Rebind(
+			`UPDATE `+s.streamsTable+` SET version = version + ?, updated_at = ?
+			WHERE id = ? AND version = ?`),
+			n, now, first.AggregateID, originalVersion,
+		)
+		if err != nil {
+			return fmt.Errorf("could not update stream: %w", err)
+		}
+
+		if rows, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("could not update stream: %w", err)
+		} else if rows == 0 {
+			return eh.ErrEventConflictFromOtherSave
+		}
+	}
+
+	// Increment the global position, locking the $all stream until commit.
+	if _, err := tx.ExecContext(ctx, s.dialect.Rebind(
+		`UPDATE `+s.streamsTable+` SET position = position + ?, updated_at = ? WHERE id = ?`),
+		n, now, allStreamID,
+	); err != nil {
+		return fmt.Errorf("could not increment global position: %w", err)
+	}
+
+	var position int
+	if err := tx.QueryRowContext(ctx, s.dialect.Rebind(
+		`SELECT position FROM `+s.streamsTable+` WHERE id = ?`),
+		allStreamID,
+	).Scan(&position); err != nil {
+		return fmt.Errorf("could not get global position: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, s.dialect.Rebind(
+		`UPDATE `+s.streamsTable+` SET position = ? WHERE id = ?`),
+		position, first.AggregateID,
+	); err != nil {
+		return fmt.Errorf("could not update stream position: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, s.dialect.Rebind(
+		`INSERT INTO `+s.eventsTable+`
+		(position, event_type, timestamp, aggregate_type, aggregate_id, version, data, schema_version, metadata)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`))
+	if err != nil {
+		return fmt.Errorf("could not prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for i, e := range records {
+		e.Position = position - n + i + 1
+
+		if _, err := stmt.ExecContext(ctx,
+			e.Position, e.EventType, e.Timestamp, e.AggregateType,
+			e.AggregateID, e.Version, e.RawData, e.SchemaVersion, e.RawMetadata,
+		); err != nil {
+			if s.dialect.IsUniqueViolation(err) {
+				return eh.ErrEventConflictFromOtherSave
+			}
+
+			return fmt.Errorf("could not insert event: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Load implements the Load method of the eventhorizon.EventStore interface.
+func (s *EventStore) Load(ctx context.Context, id uuid.UUID) ([]eh.Event, error) {
+	return s.load(ctx, id, `aggregate_id = ?`, id.String())
+}
+
+// LoadFrom implements LoadFrom method of the eventhorizon.EventStore interface.
+func (s *EventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([]eh.Event, error) {
+	return s.load(ctx, id, `aggregate_id = ? AND version >= ?`, id.String(), version)
+}
+
+// LoadUntil implements LoadUntil method of the eventhorizon.EventStore interface.
+func (s *EventStore) LoadUntil(ctx context.Context, id uuid.UUID, version int) ([]eh.Event, error) {
+	return s.load(ctx, id, `aggregate_id = ? AND version <= ?`, id.String(), version)
+}
+
+func (s *EventStore) load(ctx context.Context, id uuid.UUID, where string, args ...interface{}) ([]eh.Event, error) {
+	events, err := s.query(ctx, `WHERE `+where+` ORDER BY version`, args...)
+	if err != nil {
+		err.Op = eh.EventStoreOpLoad
+		err.AggregateID = id
+
+		return nil, err
+	}
+
+	if len(events) == 0 {
+		return nil, &eh.EventStoreError{
+			Err:         eh.ErrAggregateNotFound,
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
+	return events, nil
+}
+
+// LoadAllFrom implements the LoadAllFrom method of the eventhorizon.GlobalEventStore interface.
+func (s *EventStore) LoadAllFrom(ctx context.Context, position, limit int) ([]eh.Event, error) {
+	query := `WHERE position >= ? ORDER BY position`
+	args := []interface{}{position}
+
+	if limit > 0 {
+		query += ` LIMIT ?`
+
+		args = append(args, limit)
+	}
+
+	events, err := s.query(ctx, query, args...)
+	if err != nil {
+		err.Op = eh.EventStoreOpLoad
+
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// query queries and decodes events, the query should start with the WHERE clause.
+func (s *EventStore) query(ctx context.Context, query string, args ...interface{}) ([]eh.Event, *eh.EventStoreError) {
+	rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(
+		`SELECT position, event_type, timestamp, aggregate_type, aggregate_id, version, data, schema_version, metadata
+		FROM `+s.eventsTable+` `+query), args...)
+	if err != nil {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("could not query events: %w", err),
+		}
+	}
+	defer rows.Close()
+
+	var events []eh.Event
+
+	for rows.Next() {
+		event, err := decodeEvent(ctx, rows)
+		if err != nil {
+			err.Events = events
+
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, &eh.EventStoreError{
+			Err:    fmt.Errorf("could not read events: %w", err),
+			Events: events,
+		}
+	}
+
+	return events, nil
+}
+
+// withTx runs the function in a transaction, which is committed if the
+// function returns no error.
+func (s *EventStore) withTx(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+
+	if err := f(tx); err != nil {
+		if rerr := tx.Rollback(); rerr != nil && !errors.Is(rerr, sql.ErrTxDone) {
+			return fmt.Errorf("could not rollback transaction: %s: %w", rerr, err)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Close implements the Close method of the eventhorizon.EventStore interface.
+func (s *EventStore) Close() error {
+	if s.dbOwnership == externalDB {
+		// Don't close a DB we don't own.
+		return nil
+	}
+
+	return s.db.Close()
+}
+
+// EventsTableName returns the name of the events table.
+func (s *EventStore) EventsTableName() string { return s.eventsTable }
+
+// StreamsTableName returns the name of the streams table.
+func (s *EventStore) StreamsTableName() string { return s.streamsTable }
+
+// SnapshotsTableName returns the name of the snapshots table.
+func (s *EventStore) SnapshotsTableName() string { return s.snapshotsTable }
+
+// evt is the internal event record for the SQL event store used
+// to save and load events from the DB.
+type evt struct {
+	Position      int
+	EventType     eh.EventType
+	Timestamp     time.Time
+	AggregateType eh.AggregateType
+	AggregateID   string
+	Version       int
+	RawData       []byte
+	SchemaVersion int
+	RawMetadata   []byte
+}
+
+// newEvt returns a new evt for an event.
+func newEvt(event eh.Event) (*evt, error) {
+	e := &evt{
+		EventType:     event.EventType(),
+		Timestamp:     event.Timestamp(),
+		AggregateType: event.AggregateType(),
+		AggregateID:   event.AggregateID().String(),
+		Version:       event.Version(),
+	}
+
+	// Marshal event data if there is any.
+	if event.Data() != nil {
+		var err error
+
+		if e.RawData, err = json.Marshal(event.Data()); err != nil {
+			return nil, fmt.Errorf("could not marshal event data: %w", err)
+		}
+
+		e.SchemaVersion = eh.EventSchemaVersion(event.EventType())
+	}
+
+	if len(event.Metadata()) > 0 {
+		var err error
+
+		if e.RawMetadata, err = json.Marshal(event.Metadata()); err != nil {
+			return nil, fmt.Errorf("could not marshal event metadata: %w", err)
+		}
+	}
+
+	return e, nil
+}
+
+// scanner is implemented by both sql.Row and sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// decodeEvent decodes the current event of the rows, including its data.
+func decodeEvent(ctx context.Context, row scanner) (eh.Event, *eh.EventStoreError) {
+	var e evt
+	if err := row.Scan(
+		&e.Position, &e.EventType, &e.Timestamp, &e.AggregateType,
+		&e.AggregateID, &e.Version, &e.RawData, &e.SchemaVersion, &e.RawMetadata,
+	); err != nil {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("could not scan event: %w", err),
+		}
+	}
+
+	id, err := uuid.Parse(e.AggregateID)
+	if err != nil {
+		return nil, &eh.EventStoreError{
+			Err:              fmt.Errorf("could not parse aggregate ID: %w", err),
+			AggregateType:    e.AggregateType,
+			AggregateVersion: e.Version,
+		}
+	}
+
+	// Create an event of the correct type and decode from raw JSON.
+	var data eh.EventData
+	if len(e.RawData) > 0 {
+		if e.RawData, err = jsonCodec.UpcastRawData(ctx, e.EventType, e.SchemaVersion, e.RawData); err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              fmt.Errorf("could not upcast event data: %w", err),
+				AggregateType:    e.AggregateType,
+				AggregateID:      id,
+				AggregateVersion: e.Version,
+			}
+		}
+
+		if data, err = eh.CreateEventData(e.EventType); err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              fmt.Errorf("could not create event data: %w", err),
+				AggregateType:    e.AggregateType,
+				AggregateID:      id,
+				AggregateVersion: e.Version,
+			}
+		}
+
+		if err := json.Unmarshal(e.RawData, data); err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              fmt.Errorf("could not unmarshal event data: %w", err),
+				AggregateType:    e.AggregateType,
+				AggregateID:      id,
+				AggregateVersion: e.Version,
+			}
+		}
+	}
+
+	var metadata map[string]interface{}
+	if len(e.RawMetadata) > 0 {
+		if err := json.Unmarshal(e.RawMetadata, &metadata); err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              fmt.Errorf("could not unmarshal event metadata: %w", err),
+				AggregateType:    e.AggregateType,
+				AggregateID:      id,
+				AggregateVersion: e.Version,
+			}
+		}
+	}
+
+	return eh.NewEvent(
+		e.EventType,
+		data,
+		e.Timestamp,
+		eh.ForAggregate(
+			e.AggregateType,
+			id,
+			e.Version,
+		),
+		eh.WithMetadata(metadata),
+		eh.WithGlobalPosition(e.Position),
+	), nil
+}
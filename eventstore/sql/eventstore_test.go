@@ -0,0 +1,201 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql_test
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/Clarilab/eventhorizon/eventstore"
+	sqlstore "github.com/Clarilab/eventhorizon/eventstore/sql"
+	"github.com/Clarilab/eventhorizon/sqlutils"
+)
+
+// NOTE: Not named "Integration" to enable running with the unit tests.
+func TestEventStore(t *testing.T) {
+	store, err := sqlstore.NewEventStore("sqlite3", filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if store == nil {
+		t.Fatal("there should be a store")
+	}
+
+	eventstore.AcceptanceTest(t, store, context.Background())
+	eventstore.GlobalAcceptanceTest(t, store, store, context.Background())
+	eventstore.SnapshotAcceptanceTest(t, store, context.Background())
+	eventstore.UpcastAcceptanceTest(t, store, context.Background())
+
+	if err := store.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+// NOTE: Not named "Integration" to enable running with the unit tests.
+func TestSnapshotHistory(t *testing.T) {
+	store, err := sqlstore.NewEventStore("sqlite3", filepath.Join(t.TempDir(), "events.db"),
+		sqlstore.WithSnapshotRetention(3),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	eventstore.SnapshotHistoryAcceptanceTest(t, store, context.Background())
+}
+
+func TestEventStorePostgresIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	db := makePostgresDB(t)
+
+	store, err := sqlstore.NewEventStoreWithDB(db, sqlutils.PostgreSQL)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if store == nil {
+		t.Fatal("there should be a store")
+	}
+
+	eventstore.AcceptanceTest(t, store, context.Background())
+	eventstore.GlobalAcceptanceTest(t, store, store, context.Background())
+	eventstore.UpcastAcceptanceTest(t, store, context.Background())
+
+	if err := store.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+func TestNewEventStore(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "events.db")
+
+	if _, err := sqlstore.NewEventStoreWithDB(nil, sqlutils.SQLite); err == nil || err.Error() != "missing DB" {
+		t.Error("there should be a missing DB error:", err)
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer db.Close()
+
+	if _, err := sqlstore.NewEventStoreWithDB(db, nil); err == nil || err.Error() != "missing dialect" {
+		t.Error("there should be a missing dialect error:", err)
+	}
+
+	store, err := sqlstore.NewEventStoreWithDB(db, sqlutils.SQLite)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Closing should not close an external DB.
+	if err := store.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		t.Error("the DB should not be closed:", err)
+	}
+}
+
+func TestWithTableNames(t *testing.T) {
+	store, err := sqlstore.NewEventStore("sqlite3", filepath.Join(t.TempDir(), "events.db"),
+		sqlstore.WithTableNames("foo_events", "foo_streams"),
+		sqlstore.WithSnapshotTableName("foo_snapshots"),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	if store.EventsTableName() != "foo_events" {
+		t.Error("the events table should use the custom name:", store.EventsTableName())
+	}
+
+	if store.StreamsTableName() != "foo_streams" {
+		t.Error("the streams table should use the custom name:", store.StreamsTableName())
+	}
+
+	if store.SnapshotsTableName() != "foo_snapshots" {
+		t.Error("the snapshots table should use the custom name:", store.SnapshotsTableName())
+	}
+
+	eventstore.AcceptanceTest(t, store, context.Background())
+
+	_, err = sqlstore.NewEventStore("sqlite3", filepath.Join(t.TempDir(), "events.db"),
+		sqlstore.WithTableNames("foo events", "foo_streams"))
+	if err == nil || err.Error() != "error while applying option: events table: invalid char in table name" {
+		t.Error("there should be an error:", err)
+	}
+
+	_, err = sqlstore.NewEventStore("sqlite3", filepath.Join(t.TempDir(), "events.db"),
+		sqlstore.WithTableNames("foo", "foo"))
+	if err == nil || err.Error() != "error while applying option: custom table names are equal" {
+		t.Error("there should be an error:", err)
+	}
+}
+
+func makePostgresDB(t *testing.T) *sql.DB {
+	// Use PostgreSQL in Docker with fallback to localhost.
+	addr := os.Getenv("POSTGRES_ADDR")
+	if addr == "" {
+		addr = "localhost:5432"
+	}
+
+	db, err := sql.Open("postgres", "postgres://postgres:postgres@"+addr+"/postgres?sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Get a random DB name.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	name := "test_" + hex.EncodeToString(b)
+
+	t.Log("using DB:", name)
+
+	if _, err := db.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatal(err)
+	}
+
+	db.Close()
+
+	db, err = sql.Open("postgres", "postgres://postgres:postgres@"+addr+"/"+name+"?sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/sqlutils"
+)
+
+// Option is an option setter used to configure creation.
+type Option func(*EventStore) error
+
+// WithDialect uses a dialect instead of the one detected from the driver name.
+func WithDialect(d sqlutils.Dialect) Option {
+	return func(s *EventStore) error {
+		if d == nil {
+			return fmt.Errorf("missing dialect")
+		}
+
+		s.dialect = d
+
+		return nil
+	}
+}
+
+// WithEventHandler adds an event handler that will be called after saving events.
+// An example would be to add an event bus to publish events.
+func WithEventHandler(h eh.EventHandler) Option {
+	return func(s *EventStore) error {
+		if s.eventHandler != nil {
+			return fmt.Errorf("another event handler is already set")
+		}
+
+		s.eventHandler = h
+
+		return nil
+	}
+}
+
+// WithTableNames uses different tables from the default "events" and "streams" tables.
+// Will return an error if provided parameters are equal.
+func WithTableNames(eventsTable, streamsTable string) Option {
+	return func(s *EventStore) error {
+		if err := sqlutils.CheckTableName(eventsTable); err != nil {
+			return fmt.Errorf("events table: %w", err)
+		} else if err := sqlutils.CheckTableName(streamsTable); err != nil {
+			return fmt.Errorf("streams table: %w", err)
+		} else if eventsTable == streamsTable {
+			return fmt.Errorf("custom table names are equal")
+		}
+
+		s.eventsTable = eventsTable
+		s.streamsTable = streamsTable
+
+		return nil
+	}
+}
+
+// WithSnapshotTableName uses a different table from the default "snapshots" table.
+func WithSnapshotTableName(snapshotsTable string) Option {
+	return func(s *EventStore) error {
+		if err := sqlutils.CheckTableName(snapshotsTable); err != nil {
+			return fmt.Errorf("snapshots table: %w", err)
+		}
+
+		s.snapshotsTable = snapshotsTable
+
+		return nil
+	}
+}
+
+// WithSnapshotRetention keeps the last n snapshots of each aggregate, older
+// snapshots are deleted when saving. Default 0, which keeps all snapshots.
+func WithSnapshotRetention(n int) Option {
+	return func(s *EventStore) error {
+		if n < 0 {
+			return fmt.Errorf("invalid snapshot retention: %d", n)
+		}
+
+		s.retention = n
+
+		return nil
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// LoadSnapshot implements the LoadSnapshot method of the eventhorizon.SnapshotStore interface.
+// Returns nil if there is no snapshot for the aggregate.
+func (s *EventStore) LoadSnapshot(ctx context.Context, id uuid.UUID) (*eh.Snapshot, error) {
+	return s.loadSnapshot(ctx, id, s.dialect.Rebind(
+		`SELECT aggregate_type, version, timestamp, schema_version, data FROM `+s.snapshotsTable+`
+		WHERE aggregate_id = ? ORDER BY version DESC LIMIT 1`),
+		id.String(),
+	)
+}
+
+// LoadSnapshotAt implements the LoadSnapshotAt method of the eventhorizon.SnapshotHistoryStore interface.
+func (s *EventStore) LoadSnapshotAt(ctx context.Context, id uuid.UUID, maxVersion int) (*eh.Snapshot, error) {
+	return s.loadSnapshot(ctx, id, s.dialect.Rebind(
+		`SELECT aggregate_type, version, timestamp, schema_version, data FROM `+s.snapshotsTable+`
+		WHERE aggregate_id = ? AND version <= ? ORDER BY version DESC LIMIT 1`),
+		id.String(), maxVersion,
+	)
+}
+
+func (s *EventStore) loadSnapshot(ctx context.Context, id uuid.UUID, query string, args ...interface{}) (*eh.Snapshot, error) {
+	var (
+		snapshot = new(eh.Snapshot)
+		rawState []byte
+	)
+
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(
+		&snapshot.AggregateType, &snapshot.Version, &snapshot.Timestamp, &snapshot.SchemaVersion, &rawState,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, &eh.EventStoreError{
+			Err:         fmt.Errorf("could not load snapshot: %w", err),
+			Op:          eh.EventStoreOpLoadSnapshot,
+			AggregateID: id,
+		}
+	}
+
+	if err := eh.CheckSnapshotSchemaVersion(snapshot.AggregateType, snapshot.SchemaVersion); err != nil {
+		return nil, &eh.EventStoreError{
+			Err:              err,
+			Op:               eh.EventStoreOpLoadSnapshot,
+			AggregateType:    snapshot.AggregateType,
+			AggregateID:      id,
+			AggregateVersion: snapshot.Version,
+		}
+	}
+
+	var err error
+	if snapshot.State, err = eh.CreateSnapshotData(id, snapshot.AggregateType); err != nil {
+		return nil, &eh.EventStoreError{
+			Err:           fmt.Errorf("could not create snapshot data: %w", err),
+			Op:            eh.EventStoreOpLoadSnapshot,
+			AggregateType: snapshot.AggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	if err := json.Unmarshal(rawState, snapshot.State); err != nil {
+		return nil, &eh.EventStoreError{
+			Err:           fmt.Errorf("could not decode snapshot: %w", err),
+			Op:            eh.EventStoreOpLoadSnapshot,
+			AggregateType: snapshot.AggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	return snapshot, nil
+}
+
+// SaveSnapshot implements the SaveSnapshot method of the eventhorizon.SnapshotStore interface.
+// A snapshot with the same version is replaced and snapshots outside of the
+// retention are deleted.
+func (s *EventStore) SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot eh.Snapshot) error {
+	if snapshot.AggregateType == "" {
+		return &eh.EventStoreError{
+			Err:           fmt.Errorf("aggregate type is empty"),
+			Op:            eh.EventStoreOpSaveSnapshot,
+			AggregateID:   id,
+			AggregateType: snapshot.AggregateType,
+		}
+	}
+
+	if snapshot.State == nil {
+		return &eh.EventStoreError{
+			Err:           fmt.Errorf("snapshots state is nil"),
+			Op:            eh.EventStoreOpSaveSnapshot,
+			AggregateID:   id,
+			AggregateType: snapshot.AggregateType,
+		}
+	}
+
+	rawState, err := json.Marshal(snapshot.State)
+	if err != nil {
+		return &eh.EventStoreError{
+			Err:           fmt.Errorf("could not encode snapshot: %w", err),
+			Op:            eh.EventStoreOpSaveSnapshot,
+			AggregateID:   id,
+			AggregateType: snapshot.AggregateType,
+		}
+	}
+
+	timestamp := snapshot.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	if err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, s.dialect.Rebind(
+			`INSERT INTO `+s.snapshotsTable+` (aggregate_id, aggregate_type, version, timestamp, schema_version, data)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (aggregate_id, version) DO UPDATE
+			SET aggregate_type = excluded.aggregate_type, timestamp = excluded.timestamp,
+				schema_version = excluded.schema_version, data = excluded.data`),
+			id.String(), snapshot.AggregateType, snapshot.Version, timestamp.UTC(),
+			eh.SnapshotSchemaVersion(snapshot.AggregateType), rawState,
+		); err != nil {
+			return fmt.Errorf("could not save snapshot: %w", err)
+		}
+
+		if s.retention == 0 {
+			return nil
+		}
+
+		if _, err := tx.ExecContext(ctx, s.dialect.Rebind(
+			`DELETE FROM `+s.snapshotsTable+` WHERE aggregate_id = ? AND version NOT IN (
+				SELECT version FROM `+s.snapshotsTable+` WHERE aggregate_id = ? ORDER BY version DESC LIMIT ?
+			)`),
+			id.String(), id.String(), s.retention,
+		); err != nil {
+			return fmt.Errorf("could not prune snapshots: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return &eh.EventStoreError{
+			Err:           err,
+			Op:            eh.EventStoreOpSaveSnapshot,
+			AggregateID:   id,
+			AggregateType: snapshot.AggregateType,
+		}
+	}
+
+	return nil
+}
+
+// RemoveSnapshotsAfter implements the RemoveSnapshotsAfter method of the eventhorizon.SnapshotHistoryStore interface.
+func (s *EventStore) RemoveSnapshotsAfter(ctx context.Context, id uuid.UUID, version int) error {
+	if _, err := s.db.ExecContext(ctx, s.dialect.Rebind(
+		`DELETE FROM `+s.snapshotsTable+` WHERE aggregate_id = ? AND version > ?`),
+		id.String(), version,
+	); err != nil {
+		return &eh.EventStoreError{
+			Err:         fmt.Errorf("could not remove snapshots: %w", err),
+			Op:          eh.EventStoreOpRemoveSnapshots,
+			AggregateID: id,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// StreamingAcceptanceTest is the acceptance test that all implementations of
+// StreamingEventStore should pass. It should manually be called from a test
+// case in each implementation:
+//
+//	func TestEventStore(t *testing.T) {
+//	    store := NewEventStore()
+//	    eventstore.StreamingAcceptanceTest(t, store, store, context.Background())
+//	}
+func StreamingAcceptanceTest(t *testing.T, store eh.EventStore, streamingStore eh.StreamingEventStore, ctx context.Context) {
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	var events []eh.Event
+	for i := 1; i <= 5; i++ {
+		events = append(events, eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, i)))
+	}
+
+	if err := store.Save(ctx, events, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	testCases := map[string]struct {
+		id       uuid.UUID
+		version  int
+		expected []eh.Event
+	}{
+		"all events": {
+			id, 1, events,
+		},
+		"from version": {
+			id, 4, events[3:],
+		},
+		"after last version": {
+			id, 6, nil,
+		},
+		"no events": {
+			uuid.New(), 1, nil,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			iter, err := streamingStore.LoadFromIter(ctx, tc.id, tc.version)
+			if err != nil {
+				t.Fatal("there should be no error:", err)
+			}
+
+			var loaded []eh.Event
+
+			for iter.Next(ctx) {
+				event, ok := iter.Value().(eh.Event)
+				if !ok {
+					t.Fatal("the value should be an event:", iter.Value())
+				}
+
+				loaded = append(loaded, event)
+			}
+
+			if err := iter.Close(ctx); err != nil {
+				t.Error("there should be no error:", err)
+			}
+
+			if len(loaded) != len(tc.expected) {
+				t.Fatal("there should be the correct number of events:", len(loaded), len(tc.expected))
+			}
+
+			for i, event := range loaded {
+				if err := eh.CompareEvents(event, tc.expected[i], eh.IgnorePositionMetadata()); err != nil {
+					t.Error("the event should be correct:", err)
+				}
+			}
+		})
+	}
+}
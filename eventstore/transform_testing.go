@@ -0,0 +1,221 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// TransformAcceptanceTest is the acceptance test that all implementations of
+// EventStoreTransformer should pass. It should manually be called from a test
+// case in each implementation:
+//
+//	func TestEventStoreTransform(t *testing.T) {
+//	    store := NewEventStore()
+//	    eventstore.TransformAcceptanceTest(t, store, store, context.Background())
+//	}
+func TransformAcceptanceTest(t *testing.T, store eh.EventStore, transformer eh.EventStoreTransformer, ctx context.Context) {
+	// Use a unique aggregate type to not match events from other tests.
+	aggregateType := eh.AggregateType("TransformAggregate-" + uuid.New().String())
+	id1 := uuid.New()
+	id2 := uuid.New()
+	id3 := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	newEvent := func(id uuid.UUID, content string, version int) eh.Event {
+		return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: content}, timestamp,
+			eh.ForAggregate(aggregateType, id, version))
+	}
+
+	if err := store.Save(ctx, []eh.Event{
+		newEvent(id1, "event1", 1),
+		newEvent(id1, "keep", 2),
+		eh.NewEvent(mocks.EventOtherType, nil, timestamp, eh.ForAggregate(aggregateType, id1, 3)),
+		newEvent(id1, "event3", 4),
+	}, 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{
+		newEvent(id2, "event4", 1),
+		newEvent(id2, "event5", 2),
+	}, 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Events of unregistered event types that are not matched should not stop
+	// the transformation.
+	unregisteredType := eh.EventType("TransformUnregisteredEvent-" + uuid.New().String())
+	eh.RegisterEventData(unregisteredType, func() eh.EventData { return &mocks.EventData{} })
+
+	if err := store.Save(ctx, []eh.Event{
+		eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event6"}, timestamp,
+			eh.ForAggregate(aggregateType, id3, 1)),
+		eh.NewEvent(unregisteredType, &mocks.EventData{Content: "unregistered"}, timestamp,
+			eh.ForAggregate(aggregateType, id3, 2)),
+	}, 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	eh.UnregisterEventData(unregisteredType)
+
+	matcher := eh.MatchAll{
+		eh.MatchAggregates{aggregateType},
+		eh.MatchEvents{mocks.EventType},
+	}
+
+	transform := func(e eh.Event) (eh.Event, error) {
+		data, ok := e.Data().(*mocks.EventData)
+		if !ok || data.Content == "keep" {
+			return e, nil
+		}
+
+		return eh.NewEvent(e.EventType(), &mocks.EventData{Content: data.Content + "-transformed"}, e.Timestamp(),
+			eh.ForAggregate(e.AggregateType(), e.AggregateID(), e.Version()),
+			eh.WithMetadata(e.Metadata()),
+		), nil
+	}
+
+	expectContents := func(id uuid.UUID, expected ...string) {
+		t.Helper()
+
+		events, err := store.Load(ctx, id)
+		if err != nil {
+			t.Error("there should be no error:", err)
+		}
+
+		var contents []string
+
+		for _, e := range events {
+			if data, ok := e.Data().(*mocks.EventData); ok {
+				contents = append(contents, data.Content)
+			}
+		}
+
+		if len(contents) != len(expected) {
+			t.Fatalf("incorrect number of events for %s: %v", id, contents)
+		}
+
+		for i := range expected {
+			if contents[i] != expected[i] {
+				t.Errorf("incorrect event content for %s: %v", id, contents)
+
+				break
+			}
+		}
+	}
+
+	// Dry-run without changing any events.
+	report, err := transformer.Transform(ctx, matcher, transform,
+		eh.WithTransformDryRun(),
+		eh.WithTransformBatchSize(1),
+	)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if report.Matched != 6 || report.Changed != 5 {
+		t.Error("the dry-run report should be correct:", report.Matched, report.Changed)
+	}
+
+	if len(report.Changes) != 5 {
+		t.Fatal("there should be 5 reported changes:", len(report.Changes))
+	}
+
+	change := report.Changes[0]
+	if change.Before.AggregateID() != id1 || change.Before.Version() != 1 {
+		t.Error("the first change should be for the first event:", change.Before)
+	}
+
+	if data, ok := change.After.Data().(*mocks.EventData); !ok || data.Content != "event1-transformed" {
+		t.Error("the first change should be transformed:", change.After)
+	}
+
+	expectContents(id1, "event1", "keep", "event3")
+	expectContents(id2, "event4", "event5")
+
+	// Transform with an interruption after the first batch.
+	progressErr := errors.New("interrupted")
+
+	var progress []eh.TransformReport
+
+	report, err = transformer.Transform(ctx, matcher, transform,
+		eh.WithTransformBatchSize(2),
+		eh.WithTransformProgress(func(ctx context.Context, report eh.TransformReport) error {
+			progress = append(progress, report)
+
+			return progressErr
+		}),
+	)
+	if !errors.Is(err, progressErr) {
+		t.Error("there should be a progress error:", err)
+	}
+
+	if len(progress) != 1 || progress[0].Checkpoint != report.Checkpoint {
+		t.Error("the progress should be reported once with the checkpoint:", progress)
+	}
+
+	if report.Checkpoint == "" || report.Matched == 0 || len(report.Changes) != 0 {
+		t.Error("the report of the interrupted transform should be correct:", report)
+	}
+
+	changed := report.Changed
+
+	// Resume after the checkpoint.
+	report, err = transformer.Transform(ctx, matcher, transform,
+		eh.WithTransformBatchSize(2),
+		eh.WithTransformCheckpoint(report.Checkpoint),
+	)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if changed+report.Changed != 5 {
+		t.Error("all events should be changed exactly once:", changed, report.Changed)
+	}
+
+	expectContents(id1, "event1-transformed", "keep", "event3-transformed")
+	expectContents(id2, "event4-transformed", "event5-transformed")
+
+	// Transform with an invalid transformed event.
+	_, err = transformer.Transform(ctx, matcher, func(e eh.Event) (eh.Event, error) {
+		return eh.NewEvent(e.EventType(), e.Data(), e.Timestamp(),
+			eh.ForAggregate(e.AggregateType(), e.AggregateID(), e.Version()+1),
+		), nil
+	})
+	if !errors.Is(err, eh.ErrInvalidTransformedEvent) {
+		t.Error("there should be a ErrInvalidTransformedEvent error:", err)
+	}
+
+	// Transform with an error.
+	transformErr := errors.New("transform error")
+
+	_, err = transformer.Transform(ctx, matcher, func(e eh.Event) (eh.Event, error) {
+		return nil, transformErr
+	})
+	if !errors.Is(err, transformErr) {
+		t.Error("there should be a transform error:", err)
+	}
+
+	expectContents(id1, "event1-transformed", "keep", "event3-transformed")
+	expectContents(id2, "event4-transformed", "event5-transformed")
+}
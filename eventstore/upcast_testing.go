@@ -0,0 +1,102 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// UpcastAcceptanceTest is the acceptance test that all implementations of
+// EventStore that persist the raw event data should pass to support upcasting
+// with eventhorizon.RegisterUpcaster. It should manually be called from a test
+// case in each implementation:
+//
+//	func TestEventStore(t *testing.T) {
+//	    store := NewEventStore()
+//	    eventstore.UpcastAcceptanceTest(t, store, context.Background())
+//	}
+func UpcastAcceptanceTest(t *testing.T, store eh.EventStore, ctx context.Context) {
+	const eventType eh.EventType = "UpcastEvent"
+
+	type upcastDataV1 struct {
+		Name string `json:"name" bson:"name"`
+	}
+
+	type upcastDataV2 struct {
+		FullName string `json:"full_name" bson:"full_name"`
+		Count    int    `json:"count" bson:"count"`
+	}
+
+	eh.RegisterEventData(eventType, func() eh.EventData { return &upcastDataV1{} })
+
+	// Save an event with the first version of the data.
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event1 := eh.NewEvent(eventType, &upcastDataV1{Name: "event1"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 1))
+
+	if err := store.Save(ctx, []eh.Event{event1}, 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Change the data to the second version with an upcaster.
+	eh.UnregisterEventData(eventType)
+	eh.RegisterEventData(eventType, func() eh.EventData { return &upcastDataV2{} })
+	eh.RegisterUpcaster(eventType, 1, func(ctx context.Context, data map[string]interface{}) (map[string]interface{}, error) {
+		data["full_name"] = data["name"]
+		data["count"] = 1
+		delete(data, "name")
+
+		return data, nil
+	})
+
+	defer func() {
+		eh.UnregisterUpcasters(eventType)
+		eh.UnregisterEventData(eventType)
+	}()
+
+	// Save an event with the second version of the data, which should not be
+	// upcasted when loading.
+	event2 := eh.NewEvent(eventType, &upcastDataV2{FullName: "event2", Count: 2}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 2))
+
+	if err := store.Save(ctx, []eh.Event{event2}, 1); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	events, err := store.Load(ctx, id)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatal("there should be two events:", len(events))
+	}
+
+	if !reflect.DeepEqual(events[0].Data(), &upcastDataV2{FullName: "event1", Count: 1}) {
+		t.Error("the first event should be upcasted:", events[0].Data())
+	}
+
+	if !reflect.DeepEqual(events[1].Data(), &upcastDataV2{FullName: "event2", Count: 2}) {
+		t.Error("the second event should not be upcasted:", events[1].Data())
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package validation contains an event store wrapper that validates the data of
+// events before saving them.
+package validation
+
+import (
+	"context"
+	"fmt"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// EventStore is an eventhorizon.EventStore that validates the data of all
+// events with eventhorizon.ValidateEvent before saving them.
+//
+// The optional event store interfaces are implemented by forwarding to the
+// inner store, where events saved, replaced or transformed are also validated.
+// They return an error if the inner store does not support them.
+type EventStore struct {
+	eh.EventStore
+}
+
+// NewEventStore creates a new EventStore.
+func NewEventStore(eventStore eh.EventStore) *EventStore {
+	if eventStore == nil {
+		return nil
+	}
+
+	return &EventStore{
+		EventStore: eventStore,
+	}
+}
+
+// NewEventStoreMiddleware returns an event store middleware that validates
+// events before saving them, see eventhorizon.UseEventStoreMiddleware.
+func NewEventStoreMiddleware() eh.EventStoreMiddleware {
+	return eh.EventStoreMiddleware(func(s eh.EventStore) eh.EventStore {
+		return NewEventStore(s)
+	})
+}
+
+// Save implements the Save method of the eventhorizon.EventStore interface.
+// No events are saved if any of them is invalid.
+func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersion int) error {
+	if err := validateEvents(events, eh.EventStoreOpSave, originalVersion); err != nil {
+		return err
+	}
+
+	return s.EventStore.Save(ctx, events, originalVersion)
+}
+
+// SaveMany implements the SaveMany method of the eventhorizon.MultiStreamEventStore interface.
+// No events are saved if any of them is invalid.
+func (s *EventStore) SaveMany(ctx context.Context, streams []eh.EventStreamSave) error {
+	store, ok := eh.FindEventStore[eh.MultiStreamEventStore](s.EventStore)
+	if !ok {
+		return &eh.EventStoreError{
+			Err: fmt.Errorf("event store does not support saving multiple streams"),
+			Op:  eh.EventStoreOpSave,
+		}
+	}
+
+	for _, stream := range streams {
+		if err := validateEvents(stream.Events, eh.EventStoreOpSave, stream.OriginalVersion); err != nil {
+			return err
+		}
+	}
+
+	return store.SaveMany(ctx, streams)
+}
+
+// LoadFromIter implements the LoadFromIter method of the eventhorizon.StreamingEventStore interface.
+func (s *EventStore) LoadFromIter(ctx context.Context, id uuid.UUID, version int) (eh.Iter, error) {
+	store, ok := eh.FindEventStore[eh.StreamingEventStore](s.EventStore)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err:         fmt.Errorf("event store does not support streaming loads"),
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
+	return store.LoadFromIter(ctx, id, version)
+}
+
+// LoadAllFrom implements the LoadAllFrom method of the eventhorizon.GlobalEventStore interface.
+func (s *EventStore) LoadAllFrom(ctx context.Context, position, limit int) ([]eh.Event, error) {
+	store, ok := eh.FindEventStore[eh.GlobalEventStore](s.EventStore)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("event store does not support global reads"),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+
+	return store.LoadAllFrom(ctx, position, limit)
+}
+
+// QueryEvents implements the QueryEvents method of the eventhorizon.EventQuerier interface.
+func (s *EventStore) QueryEvents(ctx context.Context, query eh.EventQuery) (eh.Iter, error) {
+	querier, ok := eh.FindEventStore[eh.EventQuerier](s.EventStore)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("event store does not support queries"),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+
+	return querier.QueryEvents(ctx, query)
+}
+
+// CheckConsistency implements the CheckConsistency method of the eventhorizon.EventStoreChecker interface.
+func (s *EventStore) CheckConsistency(ctx context.Context, repair bool) ([]eh.Inconsistency, error) {
+	checker, ok := eh.FindEventStore[eh.EventStoreChecker](s.EventStore)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("event store does not support consistency checks"),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+
+	return checker.CheckConsistency(ctx, repair)
+}
+
+// validateEvents validates the events to save, returns an error for the first
+// invalid event.
+func validateEvents(events []eh.Event, op eh.EventStoreOperation, originalVersion int) error {
+	for _, event := range events {
+		if err := eh.ValidateEvent(event); err != nil {
+			storeErr := &eh.EventStoreError{
+				Err:              err,
+				Op:               op,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+
+			if event != nil {
+				storeErr.AggregateType = event.AggregateType()
+				storeErr.AggregateID = event.AggregateID()
+			}
+
+			return storeErr
+		}
+	}
+
+	return nil
+}
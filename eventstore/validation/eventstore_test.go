@@ -0,0 +1,124 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/eventstore"
+	"github.com/Clarilab/eventhorizon/eventstore/memory"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// NOTE: Not named "Integration" to enable running with the unit tests.
+func TestEventStore(t *testing.T) {
+	innerStore, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	store := NewEventStore(innerStore)
+	if store == nil {
+		t.Fatal("there should be a store")
+	}
+
+	eventstore.AcceptanceTest(t, store, context.Background())
+}
+
+func TestEventStore_InvalidEvent(t *testing.T) {
+	innerStore, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	store := eh.UseEventStoreMiddleware(innerStore, NewEventStoreMiddleware())
+
+	ctx := context.Background()
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	err = store.Save(ctx, []eh.Event{
+		eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, 1)),
+		eh.NewEvent(mocks.EventType, &mocks.EventData{}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, 2)),
+	}, 0)
+
+	dataErr := &eh.EventDataError{}
+	if !errors.As(err, &dataErr) {
+		t.Fatal("there should be an event data error:", err)
+	}
+
+	if !reflect.DeepEqual(dataErr.Fields, []string{"Content"}) {
+		t.Error("the invalid fields should be listed:", dataErr.Fields)
+	}
+
+	if _, err := innerStore.Load(ctx, id); !errors.Is(err, eh.ErrAggregateNotFound) {
+		t.Error("no events should be saved:", err)
+	}
+
+	// Events saved with the optional interfaces are also validated.
+	err = store.(eh.MultiStreamEventStore).SaveMany(ctx, []eh.EventStreamSave{{
+		Events: []eh.Event{
+			eh.NewEvent(mocks.EventType, &mocks.EventData{}, timestamp,
+				eh.ForAggregate(mocks.AggregateType, id, 1)),
+		},
+	}})
+	if !errors.As(err, &dataErr) {
+		t.Error("there should be an event data error:", err)
+	}
+
+	if _, err := innerStore.Load(ctx, id); !errors.Is(err, eh.ErrAggregateNotFound) {
+		t.Error("no events should be saved:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{
+		eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, 1)),
+	}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	err = store.(eh.EventStoreMaintenance).Replace(ctx,
+		eh.NewEvent(mocks.EventType, &mocks.EventData{}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, 1)))
+	if !errors.As(err, &dataErr) {
+		t.Error("there should be an event data error:", err)
+	}
+
+	_, err = store.(eh.EventStoreTransformer).Transform(ctx, eh.MatchEvents{mocks.EventType},
+		func(event eh.Event) (eh.Event, error) {
+			return eh.NewEvent(event.EventType(), &mocks.EventData{}, event.Timestamp(),
+				eh.ForAggregate(event.AggregateType(), event.AggregateID(), event.Version())), nil
+		})
+	if !errors.As(err, &dataErr) {
+		t.Error("there should be an event data error:", err)
+	}
+
+	events, err := innerStore.Load(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(events) != 1 || events[0].Data().(*mocks.EventData).Content != "event1" {
+		t.Error("the event should not be changed:", events)
+	}
+}
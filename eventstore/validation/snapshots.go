@@ -0,0 +1,78 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"context"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// LoadSnapshot implements the LoadSnapshot method of the eventhorizon.SnapshotStore interface.
+func (s *EventStore) LoadSnapshot(ctx context.Context, id uuid.UUID) (*eh.Snapshot, error) {
+	store, ok := eh.FindEventStore[eh.SnapshotStore](s.EventStore)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err:         eh.ErrSnapshotsNotSupported,
+			Op:          eh.EventStoreOpLoadSnapshot,
+			AggregateID: id,
+		}
+	}
+
+	return store.LoadSnapshot(ctx, id)
+}
+
+// SaveSnapshot implements the SaveSnapshot method of the eventhorizon.SnapshotStore interface.
+func (s *EventStore) SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot eh.Snapshot) error {
+	store, ok := eh.FindEventStore[eh.SnapshotStore](s.EventStore)
+	if !ok {
+		return &eh.EventStoreError{
+			Err:         eh.ErrSnapshotsNotSupported,
+			Op:          eh.EventStoreOpSaveSnapshot,
+			AggregateID: id,
+		}
+	}
+
+	return store.SaveSnapshot(ctx, id, snapshot)
+}
+
+// LoadSnapshotAt implements the LoadSnapshotAt method of the eventhorizon.SnapshotHistoryStore interface.
+func (s *EventStore) LoadSnapshotAt(ctx context.Context, id uuid.UUID, maxVersion int) (*eh.Snapshot, error) {
+	store, ok := eh.FindEventStore[eh.SnapshotHistoryStore](s.EventStore)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err:         eh.ErrSnapshotsNotSupported,
+			Op:          eh.EventStoreOpLoadSnapshot,
+			AggregateID: id,
+		}
+	}
+
+	return store.LoadSnapshotAt(ctx, id, maxVersion)
+}
+
+// RemoveSnapshotsAfter implements the RemoveSnapshotsAfter method of the eventhorizon.SnapshotHistoryStore interface.
+func (s *EventStore) RemoveSnapshotsAfter(ctx context.Context, id uuid.UUID, version int) error {
+	store, ok := eh.FindEventStore[eh.SnapshotHistoryStore](s.EventStore)
+	if !ok {
+		return &eh.EventStoreError{
+			Err:         eh.ErrSnapshotsNotSupported,
+			Op:          eh.EventStoreOpRemoveSnapshots,
+			AggregateID: id,
+		}
+	}
+
+	return store.RemoveSnapshotsAfter(ctx, id, version)
+}
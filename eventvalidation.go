@@ -0,0 +1,122 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// EventDataValidator can be implemented by event data to validate itself, in
+// addition to the check of its fields, see ValidateEventData.
+type EventDataValidator interface {
+	Validate() error
+}
+
+// EventDataError is returned when the data of an event is invalid.
+type EventDataError struct {
+	// EventType is the type of the event.
+	EventType EventType
+	// Fields are the names of all required fields that are zero-valued.
+	Fields []string
+	// Err is the error returned by the Validate method of the data, if any.
+	Err error
+}
+
+// Error implements the Error method of the error interface.
+func (e *EventDataError) Error() string {
+	var reasons []string
+
+	if len(e.Fields) > 0 {
+		reasons = append(reasons, "missing fields: "+strings.Join(e.Fields, ", "))
+	}
+
+	if e.Err != nil {
+		reasons = append(reasons, e.Err.Error())
+	}
+
+	return fmt.Sprintf("invalid event data for %s: %s", e.EventType, strings.Join(reasons, "; "))
+}
+
+// Unwrap implements the errors.Unwrap method.
+func (e *EventDataError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateEventData validates the data of an event. Like for commands in
+// CheckCommand all public fields of struct data are required to not be
+// zero-valued, except fields tagged with `eh:"optional"`. Data implementing
+// EventDataValidator is also validated with its Validate method. Returns an
+// EventDataError listing all invalid fields, or nil for valid or nil data.
+func ValidateEventData(eventType EventType, data EventData) error {
+	if data == nil {
+		return nil
+	}
+
+	err := &EventDataError{EventType: eventType}
+
+	rv := reflect.Indirect(reflect.ValueOf(data))
+	if rv.Kind() == reflect.Struct {
+		rt := rv.Type()
+
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			if field.PkgPath != "" {
+				continue // Skip private field.
+			}
+
+			if field.Tag.Get("eh") == "optional" {
+				continue // Optional field.
+			}
+
+			var zero bool
+			switch v := rv.Field(i).Interface().(type) {
+			case IsZeroer:
+				zero = v.IsZero()
+			default:
+				// Unlike in commands pointers are allowed, if set.
+				if rv.Field(i).Kind() == reflect.Ptr {
+					zero = rv.Field(i).IsNil()
+				} else {
+					zero = isZero(rv.Field(i))
+				}
+			}
+
+			if zero {
+				err.Fields = append(err.Fields, field.Name)
+			}
+		}
+	}
+
+	if v, ok := data.(EventDataValidator); ok {
+		err.Err = v.Validate()
+	}
+
+	if len(err.Fields) == 0 && err.Err == nil {
+		return nil
+	}
+
+	return err
+}
+
+// ValidateEvent validates the data of an event, see ValidateEventData.
+func ValidateEvent(event Event) error {
+	if event == nil {
+		return ErrMissingEvent
+	}
+
+	return ValidateEventData(event.EventType(), event.Data())
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+type validationTestEventData struct {
+	ID       uuid.UUID
+	Name     string
+	Tags     []string
+	At       time.Time
+	Ref      *string
+	Note     string `eh:"optional"`
+	Count    int
+	internal string
+}
+
+type validationTestValidatedData struct {
+	Count int
+}
+
+var errValidationTestCount = errors.New("count must be positive")
+
+func (d *validationTestValidatedData) Validate() error {
+	if d.Count < 1 {
+		return errValidationTestCount
+	}
+
+	return nil
+}
+
+func TestValidateEventData(t *testing.T) {
+	ref := "ref"
+
+	if err := ValidateEventData("TestEvent", nil); err != nil {
+		t.Error("there should be no error for nil data:", err)
+	}
+
+	if err := ValidateEventData("TestEvent", &validationTestEventData{
+		ID:   uuid.New(),
+		Name: "name",
+		Tags: []string{},
+		At:   time.Now(),
+		Ref:  &ref,
+	}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// All invalid fields should be listed.
+	err := ValidateEventData("TestEvent", &validationTestEventData{Name: "name"})
+
+	dataErr := &EventDataError{}
+	if !errors.As(err, &dataErr) {
+		t.Fatal("there should be an event data error:", err)
+	}
+
+	if dataErr.EventType != "TestEvent" {
+		t.Error("the event type should be correct:", dataErr.EventType)
+	}
+
+	if !reflect.DeepEqual(dataErr.Fields, []string{"ID", "Tags", "At", "Ref"}) {
+		t.Error("the invalid fields should be listed:", dataErr.Fields)
+	}
+
+	if err.Error() != "invalid event data for TestEvent: missing fields: ID, Tags, At, Ref" {
+		t.Error("the error message should be correct:", err)
+	}
+
+	// The Validate method should be used.
+	if err := ValidateEventData("TestEvent", &validationTestValidatedData{Count: 1}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	err = ValidateEventData("TestEvent", &validationTestValidatedData{})
+	if !errors.Is(err, errValidationTestCount) {
+		t.Error("the error of the Validate method should be returned:", err)
+	}
+
+	if err.Error() != "invalid event data for TestEvent: count must be positive" {
+		t.Error("the error message should be correct:", err)
+	}
+
+	if err := ValidateEvent(nil); !errors.Is(err, ErrMissingEvent) {
+		t.Error("there should be a missing event error:", err)
+	}
+
+	if err := ValidateEvent(NewEvent("TestEvent", &validationTestEventData{}, time.Now())); err == nil {
+		t.Error("there should be an error")
+	}
+}
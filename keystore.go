@@ -0,0 +1,47 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"context"
+	"errors"
+)
+
+// KeyStore is a store for encryption keys of subjects, for example aggregates
+// or persons, used to encrypt personal data in events. Deleting the key of a
+// subject makes its encrypted data unreadable, also known as crypto-shredding.
+type KeyStore interface {
+	// Key returns the key for a subject. Returns ErrKeyNotFound if the subject
+	// has no key, or if the key has been deleted.
+	Key(ctx context.Context, subject string) ([]byte, error)
+
+	// CreateKey returns the key for a subject, a new random 32 byte key is
+	// created if the subject has no key.
+	CreateKey(ctx context.Context, subject string) ([]byte, error)
+
+	// DeleteKey deletes the key for a subject. It is not an error to delete a
+	// key that does not exist.
+	DeleteKey(ctx context.Context, subject string) error
+
+	// Close closes the KeyStore.
+	Close() error
+}
+
+var (
+	// ErrMissingKeySubject is when a key is used without a subject.
+	ErrMissingKeySubject = errors.New("missing key subject")
+	// ErrKeyNotFound is when no key exists for a subject.
+	ErrKeyNotFound = errors.New("key not found")
+)
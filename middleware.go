@@ -54,3 +54,54 @@ func UseEventHandlerMiddleware(h EventHandler, middleware ...EventHandlerMiddlew
 
 	return h
 }
+
+// EventStoreMiddleware is a function that middlewares can implement to be
+// able to chain event stores, for example to add tracing or metrics.
+type EventStoreMiddleware func(EventStore) EventStore
+
+// EventStoreChain declares InnerStore that returns the inner store of an event
+// store middleware. This enables FindEventStore to traverse the chain of stores
+// in order to find a store that implements an optional interface, like
+// SnapshotStore, that the middleware doesn't implement itself.
+//
+// Middleware that handles the optional interfaces itself, for example to
+// encrypt the events saved with them, should implement them instead and not
+// implement EventStoreChain or return nil, thereby hindering any further
+// attempt to traverse the chain.
+type EventStoreChain interface {
+	InnerStore() EventStore
+}
+
+// UseEventStoreMiddleware wraps an EventStore in one or more middleware.
+func UseEventStoreMiddleware(s EventStore, middleware ...EventStoreMiddleware) EventStore {
+	// Apply in reverse order.
+	for i := len(middleware) - 1; i >= 0; i-- {
+		m := middleware[i]
+		s = m(s)
+	}
+
+	return s
+}
+
+// FindEventStore returns the first store in the chain of the store that
+// implements T, which is typically one of the optional event store interfaces.
+// Methods of a store found in the chain are not handled by the middleware that
+// wraps it.
+func FindEventStore[T any](store EventStore) (T, bool) {
+	for store != nil {
+		if s, ok := store.(T); ok {
+			return s, true
+		}
+
+		chain, ok := store.(EventStoreChain)
+		if !ok {
+			break
+		}
+
+		store = chain.InnerStore()
+	}
+
+	var zero T
+
+	return zero, false
+}
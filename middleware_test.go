@@ -84,3 +84,191 @@ func TestEventHandlerMiddleware(t *testing.T) {
 		t.Log(order)
 	}
 }
+
+type middlewareTestEventStore struct {
+	EventStore
+	name  string
+	order *[]string
+}
+
+func (s *middlewareTestEventStore) Save(ctx context.Context, events []Event, originalVersion int) error {
+	*s.order = append(*s.order, s.name)
+
+	return s.EventStore.Save(ctx, events, originalVersion)
+}
+
+type middlewareTestSnapshotStore struct {
+	middlewareTestEventStore
+	snapshots int
+}
+
+func (s *middlewareTestSnapshotStore) LoadSnapshot(ctx context.Context, id uuid.UUID) (*Snapshot, error) {
+	s.snapshots++
+
+	return nil, nil
+}
+
+func (s *middlewareTestSnapshotStore) SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot Snapshot) error {
+	s.snapshots++
+
+	return nil
+}
+
+type middlewareTestMaintenanceStore struct {
+	middlewareTestSnapshotStore
+	maintenance int
+}
+
+func (s *middlewareTestMaintenanceStore) Replace(ctx context.Context, event Event) error {
+	s.maintenance++
+
+	return nil
+}
+
+func (s *middlewareTestMaintenanceStore) RenameEvent(ctx context.Context, from, to EventType) error {
+	s.maintenance++
+
+	return nil
+}
+
+func (s *middlewareTestMaintenanceStore) Remove(ctx context.Context, aggregateID uuid.UUID) error {
+	s.maintenance++
+
+	return nil
+}
+
+func (s *middlewareTestMaintenanceStore) Clear(ctx context.Context) error {
+	s.maintenance++
+
+	return nil
+}
+
+type middlewareTestNopEventStore struct {
+	EventStore
+}
+
+func (s middlewareTestNopEventStore) Save(ctx context.Context, events []Event, originalVersion int) error {
+	return nil
+}
+
+type middlewareTestChainEventStore struct {
+	middlewareTestEventStore
+}
+
+func (s *middlewareTestChainEventStore) InnerStore() EventStore {
+	return s.EventStore
+}
+
+func TestEventStoreMiddleware(t *testing.T) {
+	ctx := context.Background()
+	order := []string{}
+	middleware := func(s string) EventStoreMiddleware {
+		return EventStoreMiddleware(func(store EventStore) EventStore {
+			return &middlewareTestChainEventStore{
+				middlewareTestEventStore{EventStore: store, name: s, order: &order},
+			}
+		})
+	}
+
+	inner := &middlewareTestMaintenanceStore{}
+	inner.EventStore = middlewareTestNopEventStore{}
+	inner.name = "inner"
+	inner.order = &order
+
+	s := UseEventStoreMiddleware(inner,
+		middleware("first"),
+		middleware("second"),
+		middleware("third"),
+	)
+	if err := s.Save(ctx, nil, 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(order, []string{"first", "second", "third", "inner"}) {
+		t.Error("the order of middleware should be correct")
+		t.Log(order)
+	}
+
+	// The optional interfaces of the inner store should be found.
+	snapshotStore, ok := FindEventStore[SnapshotStore](s)
+	if !ok {
+		t.Fatal("the store should be a snapshot store")
+	}
+
+	if _, err := snapshotStore.LoadSnapshot(ctx, uuid.New()); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if inner.snapshots != 1 {
+		t.Error("the snapshot should be loaded from the inner store:", inner.snapshots)
+	}
+
+	maintenance, ok := FindEventStore[EventStoreMaintenance](s)
+	if !ok {
+		t.Fatal("the store should support maintenance")
+	}
+
+	if err := maintenance.Clear(ctx); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if inner.maintenance != 1 {
+		t.Error("the maintenance should be done by the inner store:", inner.maintenance)
+	}
+
+	// A middleware that implements an optional interface handles it itself.
+	outer := &middlewareTestSnapshotStore{}
+
+	s = UseEventStoreMiddleware(inner, func(store EventStore) EventStore {
+		outer.EventStore = store
+		outer.name = "outer"
+		outer.order = &order
+
+		return &middlewareTestSnapshotChainEventStore{outer}
+	})
+
+	snapshotStore, ok = FindEventStore[SnapshotStore](s)
+	if !ok {
+		t.Fatal("the store should be a snapshot store")
+	}
+
+	if _, err := snapshotStore.LoadSnapshot(ctx, uuid.New()); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if outer.snapshots != 1 || inner.snapshots != 1 {
+		t.Error("the snapshot should be loaded by the middleware:", outer.snapshots, inner.snapshots)
+	}
+
+	if _, ok := FindEventStore[EventStoreMaintenance](s); !ok {
+		t.Error("the store should support maintenance")
+	}
+
+	// Interfaces not implemented by the inner store are not found.
+	s = UseEventStoreMiddleware(middlewareTestNopEventStore{}, middleware("first"))
+
+	if _, ok := FindEventStore[SnapshotStore](s); ok {
+		t.Error("the store should not be a snapshot store")
+	}
+
+	// The chain is not traversed past middleware that doesn't opt in.
+	s = UseEventStoreMiddleware(inner, middleware("first"), func(store EventStore) EventStore {
+		return &middlewareTestEventStore{EventStore: store, name: "hiding", order: &order}
+	})
+
+	if _, ok := FindEventStore[SnapshotStore](s); ok {
+		t.Error("the store should not be a snapshot store")
+	}
+
+	if _, ok := FindEventStore[EventStoreMaintenance](s); ok {
+		t.Error("the store should not support maintenance")
+	}
+}
+
+type middlewareTestSnapshotChainEventStore struct {
+	*middlewareTestSnapshotStore
+}
+
+func (s *middlewareTestSnapshotChainEventStore) InnerStore() EventStore {
+	return s.EventStore
+}
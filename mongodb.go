@@ -2,7 +2,9 @@ package eventhorizon
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"sync"
 	"time"
 
@@ -14,6 +16,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
+// ErrMongoDBClosed is when watching a change stream after the MongoDB is closed.
+var ErrMongoDBClosed = errors.New("mongodb is closed")
+
 // MongoDB is an interface for a MongoDB database.
 type MongoDB interface {
 	// Ping pings the MongoDB server.
@@ -45,10 +50,11 @@ type BasicMongoDB struct {
 	dbName   string
 	errChan  chan error
 
-	mtx     *sync.Mutex
-	cctx    context.Context
-	cancel  context.CancelFunc
-	closeWG *sync.WaitGroup
+	mtx      *sync.Mutex
+	cctx     context.Context
+	cancel   context.CancelFunc
+	closeMtx *sync.Mutex
+	closeWG  *sync.WaitGroup
 }
 
 // NewMongoDBWithClient returns a new MongoDB instance.
@@ -86,18 +92,24 @@ func NewMongoDBWithClient(client *mongo.Client, dbName string) *BasicMongoDB {
 		mtx:      new(sync.Mutex),
 		cctx:     ctx,
 		cancel:   cancel,
+		closeMtx: new(sync.Mutex),
 		closeWG:  new(sync.WaitGroup),
 	}
 }
 
 // Close implements the Close method of the MongoDB interface.
 func (db *BasicMongoDB) Close() error {
-	db.mtx.Lock()
-	defer db.mtx.Unlock()
-
+	// Stop the change streams before locking, they need the lock to reopen.
+	// No change streams are started after cancelling, see closeMtx.
+	db.closeMtx.Lock()
 	db.cancel()
+	db.closeMtx.Unlock()
+
 	db.closeWG.Wait()
 
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+
 	close(db.errChan)
 
 	return db.client.Disconnect(context.Background())
@@ -175,6 +187,8 @@ func (db *BasicMongoDB) Ping(ctx context.Context, rp *readpref.ReadPref) error {
 }
 
 // CollectionWatchChangeStream implements the CollectionWatchChangeStream method of the MongoDB interface.
+// The change stream is closed when either the context is cancelled or the
+// MongoDB is closed.
 func (db *BasicMongoDB) CollectionWatchChangeStream(
 	ctx context.Context,
 	collectionName string,
@@ -183,9 +197,32 @@ func (db *BasicMongoDB) CollectionWatchChangeStream(
 	fn func(context.Context, <-chan bson.Raw) error,
 	opts ...*options.ChangeStreamOptions,
 ) error {
+	// Don't start watching once Close has been called, as Close may already
+	// be waiting for the running change streams.
+	db.closeMtx.Lock()
+
+	if db.cctx.Err() != nil {
+		db.closeMtx.Unlock()
+
+		return ErrMongoDBClosed
+	}
+
+	db.closeWG.Add(1)
+	db.closeMtx.Unlock()
+
 	changeChan := make(chan bson.Raw)
 
-	go db.listenForChanges(ctx, collectionName, pipeline, resumeToken, changeChan, opts...)
+	// Stop watching when either the caller or Close cancels.
+	watchCtx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(db.cctx, cancel)
+
+	go func() {
+		defer db.closeWG.Done()
+		defer stop()
+		defer cancel()
+
+		db.listenForChanges(watchCtx, collectionName, pipeline, resumeToken, changeChan, opts...)
+	}()
 
 	return fn(ctx, changeChan)
 }
@@ -198,50 +235,60 @@ func (db *BasicMongoDB) listenForChanges(
 	changeChan chan<- bson.Raw,
 	opts ...*options.ChangeStreamOptions,
 ) {
-	const errMessage = "error in change stream: %w"
-
-	db.closeWG.Add(1)
-	defer db.closeWG.Done()
-
 	defer close(changeChan)
 
-	for {
-		select {
-		case <-db.cctx.Done():
-			return
-		default:
-			// open new stream
-			db.mtx.Lock()
-			stream, err := db.database.Collection(collectionName).Watch(ctx, pipeline, opts...)
-			db.mtx.Unlock()
-
-			if err != nil {
-				time.Sleep(time.Second)
+	for ctx.Err() == nil {
+		// open new stream
+		db.mtx.Lock()
+		stream, err := db.database.Collection(collectionName).Watch(ctx, pipeline, opts...)
+		db.mtx.Unlock()
 
-				continue
+		if err != nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
 			}
 
-			// loop to receive events from the stream
-			for stream.Next(ctx) {
-				changeChan <- stream.Current
-			}
+			continue
+		}
 
-			// check for errors or closure of the stream
-			if err := stream.Err(); err != nil {
-				db.errChan <- fmt.Errorf(errMessage, err)
+		// loop to receive events from the stream
+		for stream.Next(ctx) {
+			select {
+			case changeChan <- stream.Current:
+			case <-ctx.Done():
 			}
+		}
 
-			// setting resume token
-			*resumeToken = stream.ResumeToken()
+		// check for errors or closure of the stream
+		if err := stream.Err(); err != nil {
+			db.sendChangeStreamError(ctx, err)
+		}
 
-			// closing the stream
-			if err := stream.Close(ctx); err != nil {
-				db.errChan <- fmt.Errorf(errMessage, err)
-			}
+		// setting resume token
+		*resumeToken = stream.ResumeToken()
+
+		// closing the stream, also when the context is cancelled
+		if err := stream.Close(context.Background()); err != nil {
+			db.sendChangeStreamError(ctx, err)
 		}
 	}
 }
 
+// sendChangeStreamError sends an error on the error channel without blocking,
+// errors caused by closing the change stream are not sent.
+func (db *BasicMongoDB) sendChangeStreamError(ctx context.Context, err error) {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return
+	}
+
+	select {
+	case db.errChan <- fmt.Errorf("error in change stream: %w", err):
+	default:
+		log.Printf("eventhorizon: missed error in change stream: %s", err)
+	}
+}
+
 // CollectionDrop implements the CollectionDrop method of the MongoDB interface.
 func (db *BasicMongoDB) CollectionDrop(ctx context.Context, collectionName string) error {
 	db.mtx.Lock()
@@ -0,0 +1,77 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestBasicMongoDB_SendChangeStreamError(t *testing.T) {
+	db := &BasicMongoDB{errChan: make(chan error, 1)}
+
+	// Errors from closing the change stream should not be sent.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	db.sendChangeStreamError(ctx, errors.New("error"))
+	db.sendChangeStreamError(context.Background(), fmt.Errorf("closed: %w", context.Canceled))
+
+	if len(db.errChan) != 0 {
+		t.Error("there should be no errors:", len(db.errChan))
+	}
+
+	// Sending should not block when nobody reads the errors.
+	for i := 0; i < 3; i++ {
+		db.sendChangeStreamError(context.Background(), errors.New("error"))
+	}
+
+	if len(db.errChan) != 1 {
+		t.Error("there should be one error:", len(db.errChan))
+	}
+}
+
+func TestBasicMongoDB_WatchChangeStreamClosed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	db := &BasicMongoDB{
+		cctx:     ctx,
+		cancel:   cancel,
+		closeMtx: new(sync.Mutex),
+		closeWG:  new(sync.WaitGroup),
+	}
+
+	called := false
+
+	err := db.CollectionWatchChangeStream(context.Background(), "collection", nil, nil,
+		func(ctx context.Context, changes <-chan bson.Raw) error {
+			called = true
+
+			return nil
+		})
+	if !errors.Is(err, ErrMongoDBClosed) {
+		t.Error("there should be a closed error:", err)
+	}
+
+	if called {
+		t.Error("the change stream should not be watched")
+	}
+}
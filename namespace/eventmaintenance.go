@@ -32,7 +32,7 @@ func (s *EventStore) Replace(ctx context.Context, event eh.Event) error {
 		return err
 	}
 
-	maintenance, ok := store.(eh.EventStoreMaintenance)
+	maintenance, ok := eh.FindEventStore[eh.EventStoreMaintenance](store)
 	if !ok {
 		return &eh.EventStoreError{
 			Err: fmt.Errorf("event store does not support event replacement"),
@@ -50,7 +50,7 @@ func (s *EventStore) RenameEvent(ctx context.Context, from, to eh.EventType) err
 		return err
 	}
 
-	maintenance, ok := store.(eh.EventStoreMaintenance)
+	maintenance, ok := eh.FindEventStore[eh.EventStoreMaintenance](store)
 	if !ok {
 		return &eh.EventStoreError{
 			Err: fmt.Errorf("event store does not support renaming events"),
@@ -68,7 +68,7 @@ func (s *EventStore) Remove(ctx context.Context, id uuid.UUID) error {
 		return err
 	}
 
-	maintenance, ok := store.(eh.EventStoreMaintenance)
+	maintenance, ok := eh.FindEventStore[eh.EventStoreMaintenance](store)
 	if !ok {
 		return &eh.EventStoreError{
 			Err: fmt.Errorf("event store does not support removing events storage"),
@@ -86,7 +86,7 @@ func (s *EventStore) Clear(ctx context.Context) error {
 		return err
 	}
 
-	maintenance, ok := store.(eh.EventStoreMaintenance)
+	maintenance, ok := eh.FindEventStore[eh.EventStoreMaintenance](store)
 	if !ok {
 		return &eh.EventStoreError{
 			Err: fmt.Errorf("event store does not support clearing the event storage"),
@@ -96,3 +96,21 @@ func (s *EventStore) Clear(ctx context.Context) error {
 
 	return maintenance.Clear(ctx)
 }
+
+// Transform implements the Transform method of the eventhorizon.EventStoreTransformer interface.
+func (s *EventStore) Transform(ctx context.Context, matcher eh.EventMatcher, transform eh.EventTransformFunc, options ...eh.TransformOption) (*eh.TransformReport, error) {
+	store, err := s.eventStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	transformer, ok := eh.FindEventStore[eh.EventStoreTransformer](store)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("event store does not support transforming events"),
+			Op:  eh.EventStoreOpTransform,
+		}
+	}
+
+	return transformer.Transform(ctx, matcher, transform, options...)
+}
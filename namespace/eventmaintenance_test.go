@@ -63,4 +63,5 @@ func TestEventStoreMaintenanceIntegration(t *testing.T) {
 	defer store.Close()
 
 	eventstore.MaintenanceAcceptanceTest(t, store, store, context.Background())
+	eventstore.TransformAcceptanceTest(t, store, store, context.Background())
 }
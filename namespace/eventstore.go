@@ -16,6 +16,7 @@ package namespace
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -115,6 +116,70 @@ func (s *EventStore) LoadUntil(ctx context.Context, id uuid.UUID, version int) (
 	return store.LoadUntil(ctx, id, version)
 }
 
+// LoadFromIter implements the LoadFromIter method of the eventhorizon.StreamingEventStore
+// interface. Event stores of the namespace that can not stream events load all
+// events at once with LoadFrom instead.
+func (s *EventStore) LoadFromIter(ctx context.Context, id uuid.UUID, version int) (eh.Iter, error) {
+	store, err := s.eventStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if streamingStore, ok := eh.FindEventStore[eh.StreamingEventStore](store); ok {
+		return streamingStore.LoadFromIter(ctx, id, version)
+	}
+
+	events, err := store.LoadFrom(ctx, id, version)
+	if err != nil && !errors.Is(err, eh.ErrAggregateNotFound) {
+		return nil, err
+	}
+
+	return &eventsIter{events: events}, nil
+}
+
+// eventsIter iterates over loaded events.
+type eventsIter struct {
+	events []eh.Event
+	event  eh.Event
+}
+
+func (i *eventsIter) Next(ctx context.Context) bool {
+	if len(i.events) == 0 {
+		return false
+	}
+
+	i.event, i.events = i.events[0], i.events[1:]
+
+	return true
+}
+
+func (i *eventsIter) Value() interface{} {
+	return i.event
+}
+
+func (i *eventsIter) Close(ctx context.Context) error {
+	return nil
+}
+
+// LoadAllFrom implements the LoadAllFrom method of the eventhorizon.GlobalEventStore
+// interface, for the events of the namespace in the context.
+func (s *EventStore) LoadAllFrom(ctx context.Context, position, limit int) ([]eh.Event, error) {
+	store, err := s.eventStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	globalStore, ok := eh.FindEventStore[eh.GlobalEventStore](store)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("event store does not support global reads"),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+
+	return globalStore.LoadAllFrom(ctx, position, limit)
+}
+
 // Close implements the Close method of the eventhorizon.EventStore interface.
 func (s *EventStore) Close() error {
 	s.eventStoresMu.RLock()
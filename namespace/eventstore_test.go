@@ -16,11 +16,13 @@ package namespace
 
 import (
 	"context"
+	"path/filepath"
 	"sync"
 	"testing"
 
 	eh "github.com/Clarilab/eventhorizon"
 	"github.com/Clarilab/eventhorizon/eventstore"
+	"github.com/Clarilab/eventhorizon/eventstore/file"
 	"github.com/Clarilab/eventhorizon/eventstore/memory"
 )
 
@@ -69,11 +71,27 @@ func TestEventStore(t *testing.T) {
 
 	t.Log("testing default namespace")
 	eventstore.AcceptanceTest(t, store, context.Background())
+	eventstore.StreamingAcceptanceTest(t, store, store, context.Background())
 
 	ctx := NewContext(context.Background(), ns)
 
 	t.Log("testing other namespace")
 	eventstore.AcceptanceTest(t, store, ctx)
+	eventstore.StreamingAcceptanceTest(t, store, store, ctx)
+
+	if err := store.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+func TestEventStore_LoadFromIterWithoutStreaming(t *testing.T) {
+	dir := t.TempDir()
+
+	store := NewEventStore(func(ns string) (eh.EventStore, error) {
+		return file.NewEventStore(filepath.Join(dir, ns))
+	})
+
+	eventstore.StreamingAcceptanceTest(t, store, store, context.Background())
 
 	if err := store.Close(); err != nil {
 		t.Error("there should be no error:", err)
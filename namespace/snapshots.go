@@ -16,7 +16,6 @@ package namespace
 
 import (
 	"context"
-	"fmt"
 
 	// Register uuid.UUID as BSON type.
 	_ "github.com/Clarilab/eventhorizon/codec/bson"
@@ -32,10 +31,10 @@ func (s *EventStore) SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot eh
 		return err
 	}
 
-	snapshotStore, ok := store.(eh.SnapshotStore)
+	snapshotStore, ok := eh.FindEventStore[eh.SnapshotStore](store)
 	if !ok {
 		return &eh.EventStoreError{
-			Err: fmt.Errorf("event store does not support snapshots"),
+			Err: eh.ErrSnapshotsNotSupported,
 			Op:  eh.EventStoreOpReplace,
 		}
 	}
@@ -50,13 +49,49 @@ func (s *EventStore) LoadSnapshot(ctx context.Context, id uuid.UUID) (*eh.Snapsh
 		return nil, err
 	}
 
-	snapshotStore, ok := store.(eh.SnapshotStore)
+	snapshotStore, ok := eh.FindEventStore[eh.SnapshotStore](store)
 	if !ok {
 		return nil, &eh.EventStoreError{
-			Err: fmt.Errorf("event store does not support snapshots"),
+			Err: eh.ErrSnapshotsNotSupported,
 			Op:  eh.EventStoreOpReplace,
 		}
 	}
 
 	return snapshotStore.LoadSnapshot(ctx, id)
 }
+
+// LoadSnapshotAt implements the LoadSnapshotAt method of the eventhorizon.SnapshotHistoryStore interface.
+func (s *EventStore) LoadSnapshotAt(ctx context.Context, id uuid.UUID, maxVersion int) (*eh.Snapshot, error) {
+	store, err := s.eventStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	historyStore, ok := eh.FindEventStore[eh.SnapshotHistoryStore](store)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err: eh.ErrSnapshotsNotSupported,
+			Op:  eh.EventStoreOpLoadSnapshot,
+		}
+	}
+
+	return historyStore.LoadSnapshotAt(ctx, id, maxVersion)
+}
+
+// RemoveSnapshotsAfter implements the RemoveSnapshotsAfter method of the eventhorizon.SnapshotHistoryStore interface.
+func (s *EventStore) RemoveSnapshotsAfter(ctx context.Context, id uuid.UUID, version int) error {
+	store, err := s.eventStore(ctx)
+	if err != nil {
+		return err
+	}
+
+	historyStore, ok := eh.FindEventStore[eh.SnapshotHistoryStore](store)
+	if !ok {
+		return &eh.EventStoreError{
+			Err: eh.ErrSnapshotsNotSupported,
+			Op:  eh.EventStoreOpRemoveSnapshots,
+		}
+	}
+
+	return historyStore.RemoveSnapshotsAfter(ctx, id, version)
+}
@@ -23,6 +23,7 @@ import (
 
 	eh "github.com/Clarilab/eventhorizon"
 	"github.com/Clarilab/eventhorizon/eventstore"
+	"github.com/Clarilab/eventhorizon/eventstore/memory"
 	mongodb "github.com/Clarilab/eventhorizon/eventstore/mongodb_v2"
 	"github.com/Clarilab/eventhorizon/namespace"
 )
@@ -65,3 +66,15 @@ func TestEventStoreSnapshotStoreIntegration(t *testing.T) {
 
 	eventstore.SnapshotAcceptanceTest(t, store, context.Background())
 }
+
+// NOTE: Not named "Integration" to enable running with the unit tests.
+func TestEventStoreSnapshotHistory(t *testing.T) {
+	store := namespace.NewEventStore(func(ns string) (eh.EventStore, error) {
+		return memory.NewEventStore(memory.WithSnapshotRetention(3))
+	})
+
+	defer store.Close()
+
+	eventstore.SnapshotHistoryAcceptanceTest(t, store, context.Background())
+	eventstore.SnapshotHistoryAcceptanceTest(t, store, namespace.NewContext(context.Background(), "other"))
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command eh-ndjson exports the events of an event store as newline-delimited
+// JSON, or imports them into an event store:
+//
+//	eh-ndjson export -store mongodb -db app -file events.ndjson
+//	eh-ndjson import -store file -dir ./events -file events.ndjson
+//
+// The data of events must be registered to encode and decode them. Copy this
+// command and import the packages that register the event data of your
+// application, or only use it for events without data.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/Clarilab/eventhorizon/ndjson"
+)
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := ndjson.Run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ndjson
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/eventstore/file"
+	"github.com/Clarilab/eventhorizon/eventstore/mongodb_v2"
+	"github.com/Clarilab/eventhorizon/eventstore/sql"
+	"github.com/Clarilab/eventhorizon/namespace"
+)
+
+// ErrUsage is when the command is run with invalid arguments.
+var ErrUsage = errors.New("invalid usage")
+
+// Run runs the export or import command with the arguments (without the
+// program name), reading from stdin and writing to stdout if no file is set:
+//
+//	export -store mongodb -uri mongodb://localhost:27017 -db app > events.ndjson
+//	import -store sql -driver postgres -dsn postgres://... < events.ndjson
+//
+// The data of events must be registered to encode and decode them, so the
+// command should be built with the packages that register the event data of
+// the application, see cmd/eh-ndjson. SQL drivers must also be imported.
+func Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) < 1 || (args[0] != "export" && args[0] != "import") {
+		fmt.Fprintln(stderr, "usage: eh-ndjson export|import [flags]")
+
+		return ErrUsage
+	}
+
+	cmd := args[0]
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var (
+		storeType      = fs.String("store", "", "event store type: mongodb, sql or file")
+		uri            = fs.String("uri", "mongodb://localhost:27017", "MongoDB URI")
+		db             = fs.String("db", "", "MongoDB database name")
+		driver         = fs.String("driver", "", "SQL driver name")
+		dsn            = fs.String("dsn", "", "SQL data source name")
+		dir            = fs.String("dir", "", "directory of the file event store")
+		ns             = fs.String("namespace", "", "namespace of the events")
+		aggregateTypes = fs.String("aggregate-types", "", "comma separated aggregate types to "+cmd)
+		path           = fs.String("file", "", "file to "+cmd+", default stdout or stdin")
+		batchSize      = fs.Int("batch-size", DefaultBatchSize, "number of events loaded per read")
+	)
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return ErrUsage
+	}
+
+	store, err := openStore(*storeType, *uri, *db, *driver, *dsn, *dir)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	options := []Option{WithBatchSize(*batchSize)}
+
+	if *aggregateTypes != "" {
+		var types []eh.AggregateType
+		for _, t := range strings.Split(*aggregateTypes, ",") {
+			types = append(types, eh.AggregateType(strings.TrimSpace(t)))
+		}
+
+		options = append(options, WithAggregateTypes(types...))
+	}
+
+	if *ns != "" {
+		ctx = namespace.NewContext(ctx, *ns)
+	}
+
+	var n int
+
+	switch cmd {
+	case "export":
+		var f *os.File
+
+		w := stdout
+
+		if *path != "" {
+			if f, err = os.Create(*path); err != nil {
+				return fmt.Errorf("could not create file: %w", err)
+			}
+			defer f.Close()
+
+			w = f
+		}
+
+		if n, err = Export(ctx, store, w, options...); err != nil {
+			return err
+		}
+
+		if f != nil {
+			if err := f.Close(); err != nil {
+				return fmt.Errorf("could not write file: %w", err)
+			}
+		}
+	case "import":
+		r := stdin
+
+		if *path != "" {
+			f, err := os.Open(*path)
+			if err != nil {
+				return fmt.Errorf("could not open file: %w", err)
+			}
+			defer f.Close()
+
+			r = f
+		}
+
+		if n, err = Import(ctx, store, r, options...); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(stderr, "%sed %d events\n", cmd, n)
+
+	return nil
+}
+
+func openStore(storeType, uri, db, driver, dsn, dir string) (eh.EventStore, error) {
+	switch storeType {
+	case "mongodb":
+		if db == "" {
+			return nil, fmt.Errorf("%w: missing -db", ErrUsage)
+		}
+
+		return mongodb_v2.NewEventStore(uri, db)
+	case "sql":
+		if driver == "" || dsn == "" {
+			return nil, fmt.Errorf("%w: missing -driver or -dsn", ErrUsage)
+		}
+
+		return sql.NewEventStore(driver, dsn)
+	case "file":
+		if dir == "" {
+			return nil, fmt.Errorf("%w: missing -dir", ErrUsage)
+		}
+
+		return file.NewEventStore(dir)
+	default:
+		return nil, fmt.Errorf("%w: unknown store %q", ErrUsage, storeType)
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ndjson exports the events of an event store as newline-delimited
+// JSON and imports them into another event store, for example for backups or
+// to seed other environments. Each line is an event encoded with the JSON
+// event codec, including its namespace and other supported context values.
+package ndjson
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/codec/json"
+	"github.com/Clarilab/eventhorizon/namespace"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+var (
+	// ErrGlobalReadsNotSupported is when exporting from an event store that
+	// does not implement the eventhorizon.GlobalEventStore interface.
+	ErrGlobalReadsNotSupported = errors.New("event store does not support global reads")
+	// ErrMissingPosition is when an exported event has no global position.
+	ErrMissingPosition = errors.New("missing global position")
+	// ErrCountMismatch is when the number of events of an aggregate after
+	// importing does not match the number of imported events.
+	ErrCountMismatch = errors.New("event count mismatch")
+)
+
+// DefaultBatchSize is the default number of events loaded per read when exporting.
+const DefaultBatchSize = 1000
+
+type config struct {
+	aggregateTypes map[eh.AggregateType]struct{}
+	batchSize      int
+}
+
+// Option is an option setter used to configure exports and imports.
+type Option func(*config) error
+
+// WithAggregateTypes only exports or imports the events of the aggregate types.
+func WithAggregateTypes(aggregateTypes ...eh.AggregateType) Option {
+	return func(c *config) error {
+		if len(aggregateTypes) == 0 {
+			return fmt.Errorf("missing aggregate types")
+		}
+
+		c.aggregateTypes = make(map[eh.AggregateType]struct{}, len(aggregateTypes))
+		for _, t := range aggregateTypes {
+			c.aggregateTypes[t] = struct{}{}
+		}
+
+		return nil
+	}
+}
+
+// WithBatchSize sets the number of events loaded per read when exporting,
+// default DefaultBatchSize.
+func WithBatchSize(n int) Option {
+	return func(c *config) error {
+		if n < 1 {
+			return fmt.Errorf("invalid batch size: %d", n)
+		}
+
+		c.batchSize = n
+
+		return nil
+	}
+}
+
+func newConfig(options []Option) (*config, error) {
+	c := &config{
+		batchSize: DefaultBatchSize,
+	}
+
+	for i := range options {
+		if err := options[i](c); err != nil {
+			return nil, fmt.Errorf("error while applying option: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *config) matches(event eh.Event) bool {
+	if c.aggregateTypes == nil {
+		return true
+	}
+
+	_, ok := c.aggregateTypes[event.AggregateType()]
+
+	return ok
+}
+
+// Export writes all events of the store as newline-delimited JSON, in the
+// order they were saved. The store must implement the eventhorizon.GlobalEventStore
+// interface; to export the events of a namespace use a namespace.EventStore
+// with the namespace in the context. Returns the number of exported events.
+func Export(ctx context.Context, store eh.EventStore, w io.Writer, options ...Option) (int, error) {
+	c, err := newConfig(options)
+	if err != nil {
+		return 0, err
+	}
+
+	globalStore, ok := eh.FindEventStore[eh.GlobalEventStore](store)
+	if !ok {
+		return 0, ErrGlobalReadsNotSupported
+	}
+
+	var (
+		codec    = &json.EventCodec{}
+		bw       = bufio.NewWriter(w)
+		position = 1
+		count    = 0
+	)
+
+	for {
+		events, err := globalStore.LoadAllFrom(ctx, position, c.batchSize)
+		if err != nil {
+			return count, fmt.Errorf("could not load events: %w", err)
+		}
+
+		if len(events) == 0 {
+			break
+		}
+
+		for _, event := range events {
+			p, ok := eh.GlobalPosition(event)
+			if !ok {
+				return count, fmt.Errorf("%w: %s", ErrMissingPosition, event)
+			}
+
+			position = p + 1
+
+			if !c.matches(event) {
+				continue
+			}
+
+			b, err := codec.MarshalEvent(ctx, event)
+			if err != nil {
+				return count, fmt.Errorf("could not encode event %s: %w", event, err)
+			}
+
+			if _, err := bw.Write(append(b, '\n')); err != nil {
+				return count, fmt.Errorf("could not write event: %w", err)
+			}
+
+			count++
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return count, fmt.Errorf("could not write events: %w", err)
+	}
+
+	return count, nil
+}
+
+// stream is an imported aggregate, per namespace.
+type stream struct {
+	namespace string
+	id        uuid.UUID
+}
+
+// Import saves the events read as newline-delimited JSON to the store, keeping
+// their versions, timestamps and metadata. The events of each aggregate must be
+// in version order and the aggregates must not exist in the store. Events are
+// saved in the namespace they were exported from. After importing the number
+// of events of every imported aggregate is verified. Returns the number of
+// imported events.
+func Import(ctx context.Context, store eh.EventStore, r io.Reader, options ...Option) (int, error) {
+	c, err := newConfig(options)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		codec    = &json.EventCodec{}
+		br       = bufio.NewReader(r)
+		counts   = map[stream]int{}
+		contexts = map[stream]context.Context{}
+		batch    []eh.Event
+		batchCtx context.Context
+		count    = 0
+		line     = 0
+	)
+
+	// Consecutive events of an aggregate are saved together.
+	save := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := store.Save(batchCtx, batch, batch[0].Version()-1); err != nil {
+			return fmt.Errorf("could not save events: %w", err)
+		}
+
+		count += len(batch)
+		batch = nil
+
+		return nil
+	}
+
+	for {
+		b, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return count, fmt.Errorf("could not read events: %w", err)
+		}
+
+		if len(b) > 0 && string(b) != "\n" {
+			line++
+
+			event, eventCtx, err := codec.UnmarshalEvent(ctx, b)
+			if err != nil {
+				return count, fmt.Errorf("could not decode event on line %d: %w", line, err)
+			}
+
+			if c.matches(event) {
+				s := stream{
+					namespace: namespace.FromContext(eventCtx),
+					id:        event.AggregateID(),
+				}
+
+				if len(batch) > 0 && (batch[0].AggregateID() != s.id ||
+					namespace.FromContext(batchCtx) != s.namespace) {
+					if err := save(); err != nil {
+						return count, err
+					}
+				}
+
+				if len(batch) == 0 {
+					batchCtx = eventCtx
+				}
+
+				batch = append(batch, withoutPosition(event))
+				counts[s]++
+				contexts[s] = eventCtx
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+
+	if err := save(); err != nil {
+		return count, err
+	}
+
+	for s, n := range counts {
+		events, err := store.Load(contexts[s], s.id)
+		if err != nil {
+			return count, fmt.Errorf("could not verify events of %s: %w", s.id, err)
+		}
+
+		if len(events) != n {
+			return count, fmt.Errorf("%w: %d events of %s, %d imported",
+				ErrCountMismatch, len(events), s.id, n)
+		}
+	}
+
+	return count, nil
+}
+
+// withoutPosition returns the event without the global position of the
+// exporting store in the metadata, as the importing store sets its own.
+func withoutPosition(event eh.Event) eh.Event {
+	if _, ok := event.Metadata()["position"]; !ok {
+		return event
+	}
+
+	metadata := make(map[string]interface{}, len(event.Metadata()))
+	for k, v := range event.Metadata() {
+		if k != "position" {
+			metadata[k] = v
+		}
+	}
+
+	return eh.NewEvent(event.EventType(), event.Data(), event.Timestamp(),
+		eh.ForAggregate(event.AggregateType(), event.AggregateID(), event.Version()),
+		eh.WithMetadata(metadata),
+	)
+}
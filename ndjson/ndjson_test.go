@@ -0,0 +1,236 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ndjson
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/eventstore/file"
+	"github.com/Clarilab/eventhorizon/eventstore/memory"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/namespace"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+
+	source, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	id1, id2 := uuid.New(), uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	saved := []eh.Event{
+		eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id1, 1),
+			eh.WithMetadata(map[string]interface{}{"user": "alice"})),
+		eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event2"}, timestamp,
+			eh.ForAggregate("Other", id2, 1)),
+		eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event3"}, timestamp.Add(time.Second),
+			eh.ForAggregate(mocks.AggregateType, id1, 2)),
+	}
+
+	for i, event := range saved {
+		if err := source.Save(ctx, []eh.Event{event}, event.Version()-1); err != nil {
+			t.Fatal("there should be no error saving event", i, err)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	n, err := Export(ctx, source, &buf, WithBatchSize(2))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 3, strings.Count(buf.String(), "\n"))
+
+	target, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	n, err = Import(ctx, target, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assert.Equal(t, 3, n)
+
+	events, err := target.Load(ctx, id1)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if assert.Len(t, events, 2) {
+		for i, event := range events {
+			expected := []eh.Event{saved[0], saved[2]}[i]
+			assert.Equal(t, expected.Version(), event.Version())
+			assert.True(t, expected.Timestamp().Equal(event.Timestamp()))
+			assert.Equal(t, expected.Data(), event.Data())
+		}
+
+		assert.Equal(t, "alice", events[0].Metadata()["user"])
+	}
+
+	// Only import one aggregate type.
+	filtered, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if n, err = Import(ctx, filtered, bytes.NewReader(buf.Bytes()), WithAggregateTypes("Other")); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assert.Equal(t, 1, n)
+
+	// Export only one aggregate type.
+	buf.Reset()
+
+	if n, err = Export(ctx, source, &buf, WithAggregateTypes("Other")); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assert.Equal(t, 1, n)
+
+	if _, err := Export(ctx, struct{ eh.EventStore }{source}, &buf); !errors.Is(err, ErrGlobalReadsNotSupported) {
+		t.Error("there should be a ErrGlobalReadsNotSupported error:", err)
+	}
+
+	if _, err := Export(ctx, source, &buf, WithBatchSize(0)); err == nil {
+		t.Error("there should be an error for an invalid batch size")
+	}
+}
+
+func TestExportImport_Namespace(t *testing.T) {
+	newStore := func() *namespace.EventStore {
+		return namespace.NewEventStore(func(ns string) (eh.EventStore, error) {
+			return memory.NewEventStore()
+		})
+	}
+
+	source, target := newStore(), newStore()
+	ctx := namespace.NewContext(context.Background(), "other")
+	id := uuid.New()
+
+	event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, id, 1))
+	if err := source.Save(ctx, []eh.Event{event}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// The default namespace has no events.
+	var buf bytes.Buffer
+
+	n, err := Export(context.Background(), source, &buf)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assert.Equal(t, 0, n)
+
+	if n, err = Export(ctx, source, &buf); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assert.Equal(t, 1, n)
+
+	// The events should be imported into the exported namespace.
+	if _, err := Import(context.Background(), target, &buf); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	events, err := target.Load(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assert.Len(t, events, 1)
+
+	if events, err = target.Load(context.Background(), id); err != nil && !errors.Is(err, eh.ErrAggregateNotFound) {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assert.Len(t, events, 0)
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	sourceDir, targetDir := t.TempDir(), t.TempDir()
+
+	source, err := file.NewEventStore(sourceDir)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	id := uuid.New()
+	event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, id, 1))
+
+	if err := source.Save(ctx, []eh.Event{event}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := source.Close(); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	if err := Run(ctx, []string{"export", "-store", "file", "-dir", sourceDir}, nil, &stdout, &stderr); err != nil {
+		t.Fatal("there should be no error:", err, stderr.String())
+	}
+
+	assert.Contains(t, stderr.String(), "exported 1 events")
+
+	if err := Run(ctx, []string{"import", "-store", "file", "-dir", targetDir}, &stdout, nil, &stderr); err != nil {
+		t.Fatal("there should be no error:", err, stderr.String())
+	}
+
+	target, err := file.NewEventStore(targetDir)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer target.Close()
+
+	events, err := target.Load(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assert.Len(t, events, 1)
+
+	if err := Run(ctx, []string{"export", "-store", "unknown"}, nil, &stdout, &stderr); !errors.Is(err, ErrUsage) {
+		t.Error("there should be a ErrUsage error:", err)
+	}
+
+	if err := Run(ctx, nil, nil, &stdout, &stderr); !errors.Is(err, ErrUsage) {
+		t.Error("there should be a ErrUsage error:", err)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2015 - The Event Horizon authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+
+	"github.com/Clarilab/eventhorizon/sqlutils"
+)
+
+// Option is an option setter used to configure creation.
+type Option func(*Repo) error
+
+// WithDialect uses a dialect instead of the one detected from the driver name.
+func WithDialect(d sqlutils.Dialect) Option {
+	return func(r *Repo) error {
+		if d == nil {
+			return fmt.Errorf("missing dialect")
+		}
+
+		r.dialect = d
+
+		return nil
+	}
+}
+
+// WithTableName uses a different table from the default "repository" table.
+func WithTableName(table string) Option {
+	return func(r *Repo) error {
+		if err := sqlutils.CheckTableName(table); err != nil {
+			return fmt.Errorf("repository table: %w", err)
+		}
+
+		r.tableName = table
+
+		return nil
+	}
+}
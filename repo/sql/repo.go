@@ -0,0 +1,458 @@
+// Copyright (c) 2015 - The Event Horizon authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/sqlutils"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+const (
+	defaultTableName = "repository"
+)
+
+// ErrModelNotSet is when a model factory is not set on the Repo.
+var ErrModelNotSet = errors.New("model not set")
+
+// Repo implements a SQL repository for entities using database/sql. Entities
+// are stored as JSON in a table with one row per entity.
+type Repo struct {
+	db          *sql.DB
+	dbOwnership dbOwnership
+	dialect     sqlutils.Dialect
+	tableName   string
+	newEntity   func() eh.Entity
+}
+
+type dbOwnership int
+
+const (
+	internalDB dbOwnership = iota
+	externalDB
+)
+
+// NewRepo creates a new Repo by opening a database with a database/sql driver,
+// the driver must be imported by the caller. The dialect is detected from the
+// driver name if not set with WithDialect.
+func NewRepo(driverName, dataSourceName string, options ...Option) (*Repo, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("could not open DB: %w", err)
+	}
+
+	// Use the detected dialect as default, can be set with an option.
+	dialect, _ := sqlutils.DialectForDriver(driverName)
+
+	// SQLite only supports one writer at a time.
+	if dialect == sqlutils.SQLite {
+		db.SetMaxOpenConns(1)
+	}
+
+	r, err := newSQLRepo(db, dialect, internalDB, options...)
+	if err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// NewRepoWithDB creates a new Repo with a DB and the dialect to use.
+func NewRepoWithDB(db *sql.DB, dialect sqlutils.Dialect, options ...Option) (*Repo, error) {
+	return newSQLRepo(db, dialect, externalDB, options...)
+}
+
+func newSQLRepo(db *sql.DB, dialect sqlutils.Dialect, dbOwnership dbOwnership, options ...Option) (*Repo, error) {
+	if db == nil {
+		return nil, fmt.Errorf("missing DB")
+	}
+
+	r := &Repo{
+		db:          db,
+		dbOwnership: dbOwnership,
+		dialect:     dialect,
+		tableName:   defaultTableName,
+	}
+
+	for i := range options {
+		if err := options[i](r); err != nil {
+			return nil, fmt.Errorf("error while applying option: %w", err)
+		}
+	}
+
+	if r.dialect == nil {
+		return nil, fmt.Errorf("missing dialect")
+	}
+
+	ctx := context.Background()
+
+	if err := r.db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %w", err)
+	}
+
+	if err := r.createTable(ctx); err != nil {
+		return nil, fmt.Errorf("could not create table: %w", err)
+	}
+
+	return r, nil
+}
+
+func (r *Repo) createTable(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+r.tableName+` (
+		id TEXT PRIMARY KEY,
+		data TEXT NOT NULL
+	)`)
+
+	return err
+}
+
+// InnerRepo implements the InnerRepo method of the eventhorizon.ReadRepo interface.
+func (r *Repo) InnerRepo(ctx context.Context) eh.ReadRepo {
+	return nil
+}
+
+// IntoRepo tries to convert a eh.ReadRepo into a Repo by recursively looking at
+// inner repos. Returns nil if none was found.
+func IntoRepo(ctx context.Context, repo eh.ReadRepo) *Repo {
+	if repo == nil {
+		return nil
+	}
+
+	if r, ok := repo.(*Repo); ok {
+		return r
+	}
+
+	return IntoRepo(ctx, repo.InnerRepo(ctx))
+}
+
+// Find implements the Find method of the eventhorizon.ReadRepo interface.
+func (r *Repo) Find(ctx context.Context, id uuid.UUID) (eh.Entity, error) {
+	const errMessage = "could not find entity: %w"
+
+	if r.newEntity == nil {
+		return nil, &eh.RepoError{
+			Err:      ErrModelNotSet,
+			Op:       eh.RepoOpFind,
+			EntityID: id,
+		}
+	}
+
+	var data string
+
+	if err := r.db.QueryRowContext(ctx, r.dialect.Rebind(
+		`SELECT data FROM `+r.tableName+` WHERE id = ?`), id.String(),
+	).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = eh.ErrEntityNotFound
+		}
+
+		return nil, fmt.Errorf(errMessage, &eh.RepoError{
+			Err:      err,
+			Op:       eh.RepoOpFind,
+			EntityID: id,
+		})
+	}
+
+	entity := r.newEntity()
+	if err := json.Unmarshal([]byte(data), entity); err != nil {
+		return nil, fmt.Errorf(errMessage, &eh.RepoError{
+			Err:      fmt.Errorf("could not unmarshal: %w", err),
+			Op:       eh.RepoOpFind,
+			EntityID: id,
+		})
+	}
+
+	return entity, nil
+}
+
+// FindAll implements the FindAll method of the eventhorizon.ReadRepo interface.
+func (r *Repo) FindAll(ctx context.Context) ([]eh.Entity, error) {
+	const errMessage = "could not find entities: %w"
+
+	if r.newEntity == nil {
+		return nil, &eh.RepoError{
+			Err: ErrModelNotSet,
+			Op:  eh.RepoOpFindAll,
+		}
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT data FROM `+r.tableName+` ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf(errMessage, &eh.RepoError{
+			Err: fmt.Errorf("could not find: %w", err),
+			Op:  eh.RepoOpFindAll,
+		})
+	}
+
+	result, err := r.scanEntities(ctx, rows)
+	if err != nil {
+		return nil, fmt.Errorf(errMessage, &eh.RepoError{
+			Err: err,
+			Op:  eh.RepoOpFindAll,
+		})
+	}
+
+	return result, nil
+}
+
+// FindCustom finds entities with a custom WHERE clause, with ? placeholders
+// for the args. The entity is stored as JSON in the "data" column and can be
+// queried with the JSON functions of the database, for example
+// "json_extract(data, '$.content') = ?" for SQLite or
+// "data::jsonb->>'content' = ?" for PostgreSQL.
+func (r *Repo) FindCustom(ctx context.Context, where string, args ...interface{}) ([]interface{}, error) {
+	const errMessage = "could not find custom: %w"
+
+	if r.newEntity == nil {
+		return nil, &eh.RepoError{
+			Err: ErrModelNotSet,
+			Op:  eh.RepoOpFindQuery,
+		}
+	}
+
+	rows, err := r.queryCustom(ctx, where, args...)
+	if err != nil {
+		return nil, fmt.Errorf(errMessage, &eh.RepoError{
+			Err: fmt.Errorf("could not find: %w", err),
+			Op:  eh.RepoOpFindQuery,
+		})
+	}
+
+	entities, err := r.scanEntities(ctx, rows)
+	if err != nil {
+		return nil, fmt.Errorf(errMessage, &eh.RepoError{
+			Err: err,
+			Op:  eh.RepoOpFindQuery,
+		})
+	}
+
+	result := make([]interface{}, len(entities))
+	for i, entity := range entities {
+		result[i] = entity
+	}
+
+	return result, nil
+}
+
+// FindCustomIter is like FindCustom but returns an iterator that can be used
+// to stream results of very large datasets.
+func (r *Repo) FindCustomIter(ctx context.Context, where string, args ...interface{}) (eh.Iter, error) {
+	const errMessage = "could not find custom iter: %w"
+
+	if r.newEntity == nil {
+		return nil, &eh.RepoError{
+			Err: ErrModelNotSet,
+			Op:  eh.RepoOpFindQuery,
+		}
+	}
+
+	rows, err := r.queryCustom(ctx, where, args...)
+	if err != nil {
+		return nil, fmt.Errorf(errMessage, &eh.RepoError{
+			Err: fmt.Errorf("could not find: %w", err),
+			Op:  eh.RepoOpFindQuery,
+		})
+	}
+
+	return &iter{
+		rows:      rows,
+		newEntity: r.newEntity,
+	}, nil
+}
+
+func (r *Repo) queryCustom(ctx context.Context, where string, args ...interface{}) (*sql.Rows, error) {
+	query := `SELECT data FROM ` + r.tableName
+	if where != "" {
+		query += ` WHERE ` + where
+	}
+
+	return r.db.QueryContext(ctx, r.dialect.Rebind(query+` ORDER BY id`), args...)
+}
+
+func (r *Repo) scanEntities(ctx context.Context, rows *sql.Rows) ([]eh.Entity, error) {
+	i := &iter{
+		rows:      rows,
+		newEntity: r.newEntity,
+	}
+
+	result := []eh.Entity{}
+
+	for i.Next(ctx) {
+		if i.decodeErr != nil {
+			break
+		}
+
+		result = append(result, i.data)
+	}
+
+	if err := i.Close(ctx); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// The iterator is not thread safe.
+type iter struct {
+	rows      *sql.Rows
+	data      eh.Entity
+	newEntity func() eh.Entity
+	decodeErr error
+}
+
+func (i *iter) Next(ctx context.Context) bool {
+	if !i.rows.Next() {
+		return false
+	}
+
+	var data string
+	if err := i.rows.Scan(&data); err != nil {
+		i.decodeErr = fmt.Errorf("could not scan: %w", err)
+
+		return true
+	}
+
+	item := i.newEntity()
+	if err := json.Unmarshal([]byte(data), item); err != nil {
+		i.decodeErr = fmt.Errorf("could not unmarshal: %w", err)
+	}
+
+	i.data = item
+
+	return true
+}
+
+func (i *iter) Value() interface{} {
+	return i.data
+}
+
+func (i *iter) Close(ctx context.Context) error {
+	if err := i.rows.Close(); err != nil {
+		return err
+	}
+
+	if err := i.rows.Err(); err != nil {
+		return err
+	}
+
+	return i.decodeErr
+}
+
+// Save implements the Save method of the eventhorizon.WriteRepo interface.
+func (r *Repo) Save(ctx context.Context, entity eh.Entity) error {
+	const errMessage = "could not save entity: %w"
+
+	id := entity.EntityID()
+	if id == uuid.Nil {
+		return &eh.RepoError{
+			Err: fmt.Errorf("missing entity ID"),
+			Op:  eh.RepoOpSave,
+		}
+	}
+
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf(errMessage, &eh.RepoError{
+			Err:      fmt.Errorf("could not marshal: %w", err),
+			Op:       eh.RepoOpSave,
+			EntityID: id,
+		})
+	}
+
+	if _, err := r.db.ExecContext(ctx, r.dialect.Rebind(
+		`INSERT INTO `+r.tableName+` (id, data) VALUES (?, ?)
+		ON CONFLICT (id) DO UPDATE SET data = excluded.data`),
+		id.String(), string(data),
+	); err != nil {
+		return fmt.Errorf(errMessage, &eh.RepoError{
+			Err:      fmt.Errorf("could not save/update: %w", err),
+			Op:       eh.RepoOpSave,
+			EntityID: id,
+		})
+	}
+
+	return nil
+}
+
+// Remove implements the Remove method of the eventhorizon.WriteRepo interface.
+func (r *Repo) Remove(ctx context.Context, id uuid.UUID) error {
+	const errMessage = "could not remove entity: %w"
+
+	res, err := r.db.ExecContext(ctx, r.dialect.Rebind(
+		`DELETE FROM `+r.tableName+` WHERE id = ?`), id.String())
+	if err != nil {
+		return fmt.Errorf(errMessage, &eh.RepoError{
+			Err:      err,
+			Op:       eh.RepoOpRemove,
+			EntityID: id,
+		})
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf(errMessage, &eh.RepoError{
+			Err:      err,
+			Op:       eh.RepoOpRemove,
+			EntityID: id,
+		})
+	} else if n == 0 {
+		return fmt.Errorf(errMessage, &eh.RepoError{
+			Err:      eh.ErrEntityNotFound,
+			Op:       eh.RepoOpRemove,
+			EntityID: id,
+		})
+	}
+
+	return nil
+}
+
+// SetEntityFactory sets a factory function that creates concrete entity types.
+func (r *Repo) SetEntityFactory(f func() eh.Entity) {
+	r.newEntity = f
+}
+
+// TableName returns the name of the table used for the entities.
+func (r *Repo) TableName() string {
+	return r.tableName
+}
+
+// Clear clears the read model database.
+func (r *Repo) Clear(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM `+r.tableName); err != nil {
+		return &eh.RepoError{
+			Err: fmt.Errorf("could not clear table: %w", err),
+			Op:  eh.RepoOpClear,
+		}
+	}
+
+	return nil
+}
+
+// Close implements the Close method of the eventhorizon.WriteRepo interface.
+func (r *Repo) Close() error {
+	if r.dbOwnership == externalDB {
+		// Don't close a DB we don't own.
+		return nil
+	}
+
+	return r.db.Close()
+}
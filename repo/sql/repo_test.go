@@ -0,0 +1,201 @@
+// Copyright (c) 2015 - The Event Horizon authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/repo"
+	sqlrepo "github.com/Clarilab/eventhorizon/repo/sql"
+	"github.com/Clarilab/eventhorizon/sqlutils"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+// NOTE: Not named "Integration" to enable running with the unit tests.
+func TestReadRepo(t *testing.T) {
+	r, err := sqlrepo.NewRepo("sqlite3", filepath.Join(t.TempDir(), "repo.db"),
+		sqlrepo.WithTableName("mocks_model"))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if r == nil {
+		t.Fatal("there should be a repository")
+	}
+
+	if r.TableName() != "mocks_model" {
+		t.Error("the table name should be correct:", r.TableName())
+	}
+
+	r.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	if r.InnerRepo(context.Background()) != nil {
+		t.Error("the inner repo should be nil")
+	}
+
+	repo.AcceptanceTest(t, r, context.Background())
+	extraRepoTests(t, r, "json_extract(data, '$.content') = ?")
+
+	if err := r.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+func TestReadRepoPostgresIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// Use PostgreSQL in Docker with fallback to localhost.
+	addr := os.Getenv("POSTGRES_ADDR")
+	if addr == "" {
+		addr = "localhost:5432"
+	}
+
+	db, err := sql.Open("postgres", "postgres://postgres:postgres@"+addr+"/postgres?sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	table := "test_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+
+	t.Log("using table:", table)
+
+	r, err := sqlrepo.NewRepoWithDB(db, sqlutils.PostgreSQL, sqlrepo.WithTableName(table))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	r.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	repo.AcceptanceTest(t, r, context.Background())
+	extraRepoTests(t, r, "data::jsonb->>'content' = ?")
+
+	if err := r.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+func extraRepoTests(t *testing.T, r *sqlrepo.Repo, where string) {
+	ctx := context.Background()
+
+	// Insert a custom item.
+	modelCustom := &mocks.Model{
+		ID:        uuid.New(),
+		Content:   "modelCustom",
+		CreatedAt: time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
+	}
+	if err := r.Save(ctx, modelCustom); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// FindCustom by content.
+	result, err := r.FindCustom(ctx, where, "modelCustom")
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(result) != 1 {
+		t.Fatal("there should be one item:", len(result))
+	}
+
+	if !reflect.DeepEqual(result[0], modelCustom) {
+		t.Error("the item should be correct:", modelCustom)
+	}
+
+	// FindCustom with an invalid query.
+	repoErr := &eh.RepoError{}
+
+	if _, err := r.FindCustom(ctx, "no_such_column = ?", 1); !errors.As(err, &repoErr) || repoErr.Op != eh.RepoOpFindQuery {
+		t.Error("there should be a find query error:", err)
+	}
+
+	// FindCustomIter by content.
+	iter, err := r.FindCustomIter(ctx, where, "modelCustom")
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if iter.Next(ctx) != true {
+		t.Error("the iterator should have results")
+	}
+
+	if !reflect.DeepEqual(iter.Value(), modelCustom) {
+		t.Error("the item should be correct:", modelCustom)
+	}
+
+	if iter.Next(ctx) == true {
+		t.Error("the iterator should have no results")
+	}
+
+	if err := iter.Close(ctx); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Clear the table.
+	if err := r.Clear(ctx); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if result, err := r.FindAll(ctx); err != nil || len(result) != 0 {
+		t.Error("there should be no items:", result, err)
+	}
+}
+
+func TestNewRepo(t *testing.T) {
+	if _, err := sqlrepo.NewRepo("sqlite3", filepath.Join(t.TempDir(), "repo.db"),
+		sqlrepo.WithTableName("1repo")); err == nil ||
+		err.Error() != "error while applying option: repository table: invalid char in table name" {
+		t.Error("there should be an error:", err)
+	}
+
+	if _, err := sqlrepo.NewRepoWithDB(nil, sqlutils.SQLite); err == nil || err.Error() != "missing DB" {
+		t.Error("there should be a missing DB error:", err)
+	}
+
+	r, err := sqlrepo.NewRepo("sqlite3", filepath.Join(t.TempDir(), "repo.db"))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer r.Close()
+
+	if _, err := r.Find(context.Background(), uuid.New()); !errors.Is(err, sqlrepo.ErrModelNotSet) {
+		t.Error("there should be a model not set error:", err)
+	}
+
+	if sqlrepo.IntoRepo(context.Background(), r) != r {
+		t.Error("the repo should be found")
+	}
+}
@@ -35,9 +35,12 @@ type Snapshot struct {
 	AggregateType AggregateType
 	Timestamp     time.Time
 	State         interface{}
+	// SchemaVersion is the schema version of the state, set by the event
+	// stores when saving to the version registered with RegisterSnapshotData.
+	SchemaVersion int
 }
 
-var snapshotDataFactories = make(map[AggregateType]func(uuid2 uuid.UUID) SnapshotData)
+var snapshotDataFactories = make(map[AggregateType]snapshotDataRegistration)
 
 type SnapshotData interface{}
 
@@ -45,17 +48,58 @@ var snapshotDataFactoriesMu sync.RWMutex
 
 var ErrSnapshotDataNotRegistered = errors.New("snapshot data not registered")
 
+// ErrSnapshotSchemaMismatch is when a stored snapshot has another schema version
+// than the one registered for the aggregate type. The snapshot should not be
+// applied, the aggregate must instead be loaded by replaying its events.
+var ErrSnapshotSchemaMismatch = errors.New("snapshot schema version mismatch")
+
+type snapshotDataRegistration struct {
+	factory       func(id uuid.UUID) SnapshotData
+	schemaVersion int
+}
+
+// SnapshotDataOption is an option used when registering snapshot data.
+type SnapshotDataOption func(*snapshotDataRegistration)
+
+// WithSnapshotSchemaVersion sets the schema version of the snapshot state,
+// default 1. It must be increased when the state is changed in a way that
+// makes already stored snapshots invalid, which are then ignored when loading.
+func WithSnapshotSchemaVersion(version int) SnapshotDataOption {
+	return func(r *snapshotDataRegistration) {
+		r.schemaVersion = version
+	}
+}
+
 // RegisterSnapshotData registers an snapshot factory for a type. The factory is
 // used to create concrete snapshot state type when unmarshalling.
 //
 // An example would be:
 //
 //	RegisterSnapshotData("aggregateType1", func() SnapshotData { return &MySnapshotData{} })
-func RegisterSnapshotData(aggregateType AggregateType, factory func(id uuid.UUID) SnapshotData) {
+//
+// A changed state type is registered with a new schema version:
+//
+//	RegisterSnapshotData("aggregateType1", func() SnapshotData { return &MySnapshotData{} },
+//		WithSnapshotSchemaVersion(2),
+//	)
+func RegisterSnapshotData(aggregateType AggregateType, factory func(id uuid.UUID) SnapshotData, options ...SnapshotDataOption) {
 	if aggregateType == AggregateType("") {
 		panic("eventhorizon: attempt to register empty aggregate type")
 	}
 
+	registration := snapshotDataRegistration{
+		factory:       factory,
+		schemaVersion: 1,
+	}
+
+	for _, option := range options {
+		option(&registration)
+	}
+
+	if registration.schemaVersion < 1 {
+		panic(fmt.Sprintf("eventhorizon: attempt to register %q with invalid schema version %d", aggregateType, registration.schemaVersion))
+	}
+
 	snapshotDataFactoriesMu.Lock()
 	defer snapshotDataFactoriesMu.Unlock()
 
@@ -63,7 +107,25 @@ func RegisterSnapshotData(aggregateType AggregateType, factory func(id uuid.UUID
 		panic(fmt.Sprintf("eventhorizon: registering duplicate types for %q", aggregateType))
 	}
 
-	snapshotDataFactories[aggregateType] = factory
+	snapshotDataFactories[aggregateType] = registration
+}
+
+// UnregisterSnapshotData removes the registration of the snapshot factory for
+// a type. This is mainly useful in tests and migrations where the snapshot
+// state needs to be switched.
+func UnregisterSnapshotData(aggregateType AggregateType) {
+	if aggregateType == AggregateType("") {
+		panic("eventhorizon: attempt to unregister empty aggregate type")
+	}
+
+	snapshotDataFactoriesMu.Lock()
+	defer snapshotDataFactoriesMu.Unlock()
+
+	if _, ok := snapshotDataFactories[aggregateType]; !ok {
+		panic(fmt.Sprintf("eventhorizon: unregister of non-registered type %q", aggregateType))
+	}
+
+	delete(snapshotDataFactories, aggregateType)
 }
 
 // CreateSnapshotData create a concrete instance using the registered snapshot factories.
@@ -71,9 +133,38 @@ func CreateSnapshotData(AggregateID uuid.UUID, aggregateType AggregateType) (Sna
 	snapshotDataFactoriesMu.RLock()
 	defer snapshotDataFactoriesMu.RUnlock()
 
-	if factory, ok := snapshotDataFactories[aggregateType]; ok {
-		return factory(AggregateID), nil
+	if registration, ok := snapshotDataFactories[aggregateType]; ok {
+		return registration.factory(AggregateID), nil
 	}
 
 	return nil, ErrSnapshotDataNotRegistered
 }
+
+// SnapshotSchemaVersion returns the schema version of the snapshot state of an
+// aggregate type, which is 1 if not registered with a schema version.
+func SnapshotSchemaVersion(aggregateType AggregateType) int {
+	snapshotDataFactoriesMu.RLock()
+	defer snapshotDataFactoriesMu.RUnlock()
+
+	if registration, ok := snapshotDataFactories[aggregateType]; ok {
+		return registration.schemaVersion
+	}
+
+	return 1
+}
+
+// CheckSnapshotSchemaVersion returns ErrSnapshotSchemaMismatch if a snapshot
+// stored with a schema version can not be applied with the current snapshot
+// state of the aggregate type. Version 0 is treated as 1 for snapshots stored
+// before schema versions were recorded.
+func CheckSnapshotSchemaVersion(aggregateType AggregateType, schemaVersion int) error {
+	if schemaVersion < 1 {
+		schemaVersion = 1
+	}
+
+	if current := SnapshotSchemaVersion(aggregateType); schemaVersion != current {
+		return fmt.Errorf("%w: %s version %d, current version %d", ErrSnapshotSchemaMismatch, aggregateType, schemaVersion, current)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+func TestSnapshotSchemaVersion(t *testing.T) {
+	const aggregateType AggregateType = "TestSnapshotSchemaVersion"
+
+	type state struct{}
+
+	factory := func(uuid.UUID) SnapshotData { return &state{} }
+
+	if v := SnapshotSchemaVersion(aggregateType); v != 1 {
+		t.Error("the schema version should be 1:", v)
+	}
+
+	RegisterSnapshotData(aggregateType, factory)
+
+	if v := SnapshotSchemaVersion(aggregateType); v != 1 {
+		t.Error("the schema version should be 1:", v)
+	}
+
+	if err := CheckSnapshotSchemaVersion(aggregateType, 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	UnregisterSnapshotData(aggregateType)
+	RegisterSnapshotData(aggregateType, factory, WithSnapshotSchemaVersion(2))
+
+	defer UnregisterSnapshotData(aggregateType)
+
+	if v := SnapshotSchemaVersion(aggregateType); v != 2 {
+		t.Error("the schema version should be 2:", v)
+	}
+
+	if err := CheckSnapshotSchemaVersion(aggregateType, 2); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	for _, v := range []int{0, 1, 3} {
+		if err := CheckSnapshotSchemaVersion(aggregateType, v); !errors.Is(err, ErrSnapshotSchemaMismatch) {
+			t.Error("there should be a ErrSnapshotSchemaMismatch error:", v, err)
+		}
+	}
+
+	if _, err := CreateSnapshotData(uuid.New(), aggregateType); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+func TestRegisterSnapshotDataInvalidSchemaVersion(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("there should be a panic")
+		}
+	}()
+
+	RegisterSnapshotData("TestInvalidSchemaVersion", func(uuid.UUID) SnapshotData { return nil },
+		WithSnapshotSchemaVersion(0),
+	)
+}
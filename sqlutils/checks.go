@@ -0,0 +1,44 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlutils
+
+import (
+	"errors"
+)
+
+var (
+	ErrMissingTableName       = errors.New("missing table name")
+	ErrInvalidCharInTableName = errors.New("invalid char in table name")
+)
+
+// CheckTableName checks if a table name is valid. Table names are used as is
+// in queries and are therefore limited to letters, digits and underscores,
+// not starting with a digit.
+func CheckTableName(name string) error {
+	if name == "" {
+		return ErrMissingTableName
+	}
+
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return ErrInvalidCharInTableName
+		}
+	}
+
+	return nil
+}
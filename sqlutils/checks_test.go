@@ -0,0 +1,26 @@
+package sqlutils
+
+import (
+	"testing"
+)
+
+func TestCheckTableName(t *testing.T) {
+	tests := []struct {
+		name    string
+		table   string
+		wantErr error
+	}{
+		{"empty name", "", ErrMissingTableName},
+		{"valid name", "events_v2", nil},
+		{"with spaces", "invalid name", ErrInvalidCharInTableName},
+		{"with quote", "events;drop", ErrInvalidCharInTableName},
+		{"starting with digit", "1events", ErrInvalidCharInTableName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckTableName(tt.table); err != tt.wantErr {
+				t.Errorf("CheckTableName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
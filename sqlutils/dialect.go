@@ -0,0 +1,130 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlutils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownDialect is when no dialect is known for a driver.
+var ErrUnknownDialect = errors.New("unknown SQL dialect")
+
+// Dialect is the SQL dialect differences between databases. Queries are
+// written with ? placeholders and standard SQL that is supported by both
+// SQLite (3.24+) and PostgreSQL, including ON CONFLICT clauses.
+type Dialect interface {
+	// Name returns the name of the dialect.
+	Name() string
+	// Rebind replaces the ? placeholders in a query with the placeholders used
+	// by the dialect.
+	Rebind(query string) string
+	// BinaryType returns the column type for binary data.
+	BinaryType() string
+	// TimestampType returns the column type for timestamps.
+	TimestampType() string
+	// IsUniqueViolation returns true if the error is from a violated unique
+	// (or primary key) constraint.
+	IsUniqueViolation(err error) bool
+}
+
+var (
+	// SQLite is the dialect for SQLite, useful for local use and testing.
+	SQLite Dialect = sqliteDialect{}
+	// PostgreSQL is the dialect for PostgreSQL and compatible databases.
+	PostgreSQL Dialect = postgresDialect{}
+)
+
+// DialectForDriver returns the dialect for a database/sql driver name.
+func DialectForDriver(driverName string) (Dialect, error) {
+	switch driverName {
+	case "sqlite", "sqlite3":
+		return SQLite, nil
+	case "postgres", "pgx", "pgx/v4", "pgx/v5", "cockroachdb":
+		return PostgreSQL, nil
+	default:
+		return nil, fmt.Errorf("%w for driver: %s", ErrUnknownDialect, driverName)
+	}
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+func (sqliteDialect) Rebind(query string) string { return query }
+
+func (sqliteDialect) BinaryType() string { return "BLOB" }
+
+func (sqliteDialect) TimestampType() string { return "TIMESTAMP" }
+
+func (sqliteDialect) IsUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := err.Error()
+
+	return strings.Contains(msg, "UNIQUE constraint failed") ||
+		strings.Contains(msg, "PRIMARY KEY constraint failed")
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+func (postgresDialect) Rebind(query string) string {
+	var (
+		b strings.Builder
+		n int
+	)
+
+	for _, r := range query {
+		if r == '?' {
+			n++
+
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func (postgresDialect) BinaryType() string { return "BYTEA" }
+
+func (postgresDialect) TimestampType() string { return "TIMESTAMPTZ" }
+
+func (postgresDialect) IsUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// Drivers that expose the SQLSTATE, for example pgx.
+	var sqlState interface{ SQLState() string }
+	if errors.As(err, &sqlState) {
+		return sqlState.SQLState() == "23505"
+	}
+
+	msg := err.Error()
+
+	return strings.Contains(msg, "23505") ||
+		strings.Contains(msg, "duplicate key value violates unique constraint")
+}
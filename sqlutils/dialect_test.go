@@ -0,0 +1,60 @@
+package sqlutils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDialectForDriver(t *testing.T) {
+	if d, err := DialectForDriver("sqlite3"); err != nil || d != SQLite {
+		t.Error("the dialect should be SQLite:", d, err)
+	}
+
+	if d, err := DialectForDriver("pgx"); err != nil || d != PostgreSQL {
+		t.Error("the dialect should be PostgreSQL:", d, err)
+	}
+
+	if _, err := DialectForDriver("mysql"); !errors.Is(err, ErrUnknownDialect) {
+		t.Error("there should be an unknown dialect error:", err)
+	}
+}
+
+func TestRebind(t *testing.T) {
+	query := "SELECT * FROM events WHERE aggregate_id = ? AND version >= ?"
+
+	if q := SQLite.Rebind(query); q != query {
+		t.Error("the query should not be changed:", q)
+	}
+
+	if q := PostgreSQL.Rebind(query); q != "SELECT * FROM events WHERE aggregate_id = $1 AND version >= $2" {
+		t.Error("the query should be rebound:", q)
+	}
+}
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "error" }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestIsUniqueViolation(t *testing.T) {
+	if !SQLite.IsUniqueViolation(errors.New("UNIQUE constraint failed: events.aggregate_id, events.version")) {
+		t.Error("there should be a unique violation")
+	}
+
+	if SQLite.IsUniqueViolation(nil) || SQLite.IsUniqueViolation(errors.New("other")) {
+		t.Error("there should be no unique violation")
+	}
+
+	if !PostgreSQL.IsUniqueViolation(fmt.Errorf("wrapped: %w", sqlStateError("23505"))) {
+		t.Error("there should be a unique violation")
+	}
+
+	if PostgreSQL.IsUniqueViolation(sqlStateError("40001")) {
+		t.Error("there should be no unique violation")
+	}
+
+	if !PostgreSQL.IsUniqueViolation(errors.New(`pq: duplicate key value violates unique constraint "events_pkey"`)) {
+		t.Error("there should be a unique violation")
+	}
+}
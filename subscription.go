@@ -0,0 +1,82 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"context"
+	"errors"
+)
+
+// CheckpointStore is a store for the global position that a subscriber has
+// handled events up to, used to resume subscriptions after a restart.
+type CheckpointStore interface {
+	// LoadCheckpoint loads the position of the last handled event for a
+	// subscriber. Returns 0 if the subscriber has no stored checkpoint.
+	LoadCheckpoint(ctx context.Context, subscriber string) (int, error)
+
+	// SaveCheckpoint saves the position of the last handled event for a subscriber.
+	SaveCheckpoint(ctx context.Context, subscriber string, position int) error
+
+	// Close closes the CheckpointStore.
+	Close() error
+}
+
+// ErrMissingSubscriber is when a checkpoint is used without a subscriber name.
+var ErrMissingSubscriber = errors.New("missing subscriber")
+
+// SubscriptionError is an error in a subscription.
+type SubscriptionError struct {
+	// Err is the error.
+	Err error
+	// Subscriber is the name of the subscriber.
+	Subscriber string
+	// Position is the global position where the error happened.
+	Position int
+	// Event is the event handled when the error happened, if any.
+	Event Event
+}
+
+// Error implements the Error method of the errors.Error interface.
+func (e *SubscriptionError) Error() string {
+	str := "subscription"
+
+	if e.Subscriber != "" {
+		str += " '" + e.Subscriber + "'"
+	}
+
+	str += ": "
+
+	if e.Err != nil {
+		str += e.Err.Error()
+	} else {
+		str += "unknown error"
+	}
+
+	if e.Event != nil {
+		str += " [" + e.Event.String() + "]"
+	}
+
+	return str
+}
+
+// Unwrap implements the errors.Unwrap method.
+func (e *SubscriptionError) Unwrap() error {
+	return e.Err
+}
+
+// Cause implements the github.com/pkg/errors Unwrap method.
+func (e *SubscriptionError) Cause() error {
+	return e.Unwrap()
+}
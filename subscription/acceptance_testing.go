@@ -0,0 +1,90 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subscription
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	eh "github.com/Clarilab/eventhorizon"
+)
+
+// CheckpointStoreAcceptanceTest is the acceptance test that all implementations
+// of CheckpointStore should pass. It should manually be called from a test case
+// in each implementation:
+//
+//	func TestCheckpointStore(t *testing.T) {
+//	    store := NewCheckpointStore()
+//	    subscription.CheckpointStoreAcceptanceTest(t, store, context.Background())
+//	}
+func CheckpointStoreAcceptanceTest(t *testing.T, store eh.CheckpointStore, ctx context.Context) {
+	// Missing subscriber.
+	if _, err := store.LoadCheckpoint(ctx, ""); !errors.Is(err, eh.ErrMissingSubscriber) {
+		t.Error("there should be a missing subscriber error:", err)
+	}
+
+	if err := store.SaveCheckpoint(ctx, "", 1); !errors.Is(err, eh.ErrMissingSubscriber) {
+		t.Error("there should be a missing subscriber error:", err)
+	}
+
+	// No stored checkpoint.
+	position, err := store.LoadCheckpoint(ctx, "subscriber1")
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if position != 0 {
+		t.Error("the position should be zero:", position)
+	}
+
+	// Save and load.
+	if err := store.SaveCheckpoint(ctx, "subscriber1", 3); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	position, err = store.LoadCheckpoint(ctx, "subscriber1")
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if position != 3 {
+		t.Error("the position should be correct:", position)
+	}
+
+	// Overwrite.
+	if err := store.SaveCheckpoint(ctx, "subscriber1", 7); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	position, err = store.LoadCheckpoint(ctx, "subscriber1")
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if position != 7 {
+		t.Error("the position should be correct:", position)
+	}
+
+	// Other subscribers are not affected.
+	position, err = store.LoadCheckpoint(ctx, "subscriber2")
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if position != 0 {
+		t.Error("the position should be zero:", position)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"sync"
+
+	eh "github.com/Clarilab/eventhorizon"
+)
+
+// CheckpointStore is an eventhorizon.CheckpointStore where the checkpoints are
+// stored in memory and not persisted. Useful for testing and experimenting.
+type CheckpointStore struct {
+	db   map[string]int
+	dbMu sync.RWMutex
+}
+
+// NewCheckpointStore creates a new CheckpointStore using memory as storage.
+func NewCheckpointStore() *CheckpointStore {
+	return &CheckpointStore{
+		db: map[string]int{},
+	}
+}
+
+// LoadCheckpoint implements the LoadCheckpoint method of the eventhorizon.CheckpointStore interface.
+func (s *CheckpointStore) LoadCheckpoint(ctx context.Context, subscriber string) (int, error) {
+	if subscriber == "" {
+		return 0, eh.ErrMissingSubscriber
+	}
+
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+
+	return s.db[subscriber], nil
+}
+
+// SaveCheckpoint implements the SaveCheckpoint method of the eventhorizon.CheckpointStore interface.
+func (s *CheckpointStore) SaveCheckpoint(ctx context.Context, subscriber string, position int) error {
+	if subscriber == "" {
+		return eh.ErrMissingSubscriber
+	}
+
+	s.dbMu.Lock()
+	defer s.dbMu.Unlock()
+
+	s.db[subscriber] = position
+
+	return nil
+}
+
+// Close implements the Close method of the eventhorizon.CheckpointStore interface.
+func (s *CheckpointStore) Close() error {
+	return nil
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoOptions "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+
+	eh "github.com/Clarilab/eventhorizon"
+)
+
+const (
+	defaultCollectionName = "checkpoints"
+)
+
+// CheckpointStore implements an eventhorizon.CheckpointStore for MongoDB using
+// one document per subscriber.
+type CheckpointStore struct {
+	database       eh.MongoDB
+	dbOwnership    dbOwnership
+	collectionName string
+}
+
+type dbOwnership int
+
+const (
+	internalDB dbOwnership = iota
+	externalDB
+)
+
+// NewCheckpointStore creates a new CheckpointStore with a MongoDB URI: `mongodb://hostname`.
+func NewCheckpointStore(uri, dbName string, options ...Option) (*CheckpointStore, error) {
+	opts := mongoOptions.Client().ApplyURI(uri)
+	opts.SetWriteConcern(writeconcern.New(writeconcern.WMajority()))
+	opts.SetReadConcern(readconcern.Majority())
+	opts.SetReadPreference(readpref.Primary())
+
+	client, err := mongo.Connect(context.Background(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %w", err)
+	}
+
+	return newMongoDBCheckpointStore(eh.NewMongoDBWithClient(client, dbName), internalDB, options...)
+}
+
+// NewCheckpointStoreWithClient creates a new CheckpointStore with a client.
+func NewCheckpointStoreWithClient(client *mongo.Client, dbName string, options ...Option) (*CheckpointStore, error) {
+	return newMongoDBCheckpointStore(eh.NewMongoDBWithClient(client, dbName), externalDB, options...)
+}
+
+// NewMongoDBCheckpointStore creates a new CheckpointStore using the eventhorizon.MongoDB interface.
+func NewMongoDBCheckpointStore(db eh.MongoDB, options ...Option) (*CheckpointStore, error) {
+	return newMongoDBCheckpointStore(db, externalDB, options...)
+}
+
+func newMongoDBCheckpointStore(db eh.MongoDB, dbOwnership dbOwnership, options ...Option) (*CheckpointStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("missing DB")
+	}
+
+	s := &CheckpointStore{
+		database:       db,
+		dbOwnership:    dbOwnership,
+		collectionName: defaultCollectionName,
+	}
+
+	for i := range options {
+		if err := options[i](s); err != nil {
+			return nil, fmt.Errorf("error while applying option: %w", err)
+		}
+	}
+
+	if err := s.database.Ping(context.Background(), readpref.Primary()); err != nil {
+		return nil, fmt.Errorf("could not connect to MongoDB: %w", err)
+	}
+
+	return s, nil
+}
+
+// LoadCheckpoint implements the LoadCheckpoint method of the eventhorizon.CheckpointStore interface.
+func (s *CheckpointStore) LoadCheckpoint(ctx context.Context, subscriber string) (int, error) {
+	const errMessage = "could not load checkpoint: %w"
+
+	if subscriber == "" {
+		return 0, eh.ErrMissingSubscriber
+	}
+
+	var c checkpoint
+
+	if err := s.database.CollectionExec(ctx, s.collectionName, func(ctx context.Context, coll *mongo.Collection) error {
+		if err := coll.FindOne(ctx, bson.M{"_id": subscriber}).Decode(&c); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil
+			}
+
+			return err
+		}
+
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf(errMessage, err)
+	}
+
+	return c.Position, nil
+}
+
+// SaveCheckpoint implements the SaveCheckpoint method of the eventhorizon.CheckpointStore interface.
+func (s *CheckpointStore) SaveCheckpoint(ctx context.Context, subscriber string, position int) error {
+	const errMessage = "could not save checkpoint: %w"
+
+	if subscriber == "" {
+		return eh.ErrMissingSubscriber
+	}
+
+	if err := s.database.CollectionExec(ctx, s.collectionName, func(ctx context.Context, coll *mongo.Collection) error {
+		if _, err := coll.UpdateOne(ctx,
+			bson.M{"_id": subscriber},
+			bson.M{"$set": bson.M{
+				"position":   position,
+				"updated_at": time.Now(),
+			}},
+			mongoOptions.Update().SetUpsert(true),
+		); err != nil {
+			return err
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf(errMessage, err)
+	}
+
+	return nil
+}
+
+// Close implements the Close method of the eventhorizon.CheckpointStore interface.
+func (s *CheckpointStore) Close() error {
+	if s.dbOwnership == externalDB {
+		// Don't close a client we don't own.
+		return nil
+	}
+
+	return s.database.Close()
+}
+
+// CollectionName returns the name of the checkpoints collection.
+func (s *CheckpointStore) CollectionName() string { return s.collectionName }
+
+// checkpoint is the DB representation of a checkpoint for a subscriber.
+type checkpoint struct {
+	Subscriber string    `bson:"_id"`
+	Position   int       `bson:"position"`
+	UpdatedAt  time.Time `bson:"updated_at"`
+}
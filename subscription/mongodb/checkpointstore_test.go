@@ -0,0 +1,92 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/Clarilab/eventhorizon/subscription"
+)
+
+func TestCheckpointStoreIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	url, db := makeDB(t)
+
+	store, err := NewCheckpointStore(url, db)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if store == nil {
+		t.Fatal("there should be a store")
+	}
+
+	defer store.Close()
+
+	subscription.CheckpointStoreAcceptanceTest(t, store, context.Background())
+}
+
+func TestWithCollectionNameIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	url, db := makeDB(t)
+
+	store, err := NewCheckpointStore(url, db, WithCollectionName("foo-checkpoints"))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	if store.CollectionName() != "foo-checkpoints" {
+		t.Fatal("collection name should use custom collection name")
+	}
+
+	_, err = NewCheckpointStore(url, db, WithCollectionName("foo checkpoints"))
+	if err == nil || err.Error() != "error while applying option: checkpoints collection: invalid char in collection name (space)" {
+		t.Fatal("there should be an error:", err)
+	}
+}
+
+func makeDB(t *testing.T) (string, string) {
+	// Use MongoDB in Docker with fallback to localhost.
+	url := os.Getenv("MONGODB_ADDR")
+	if url == "" {
+		url = "localhost:27017"
+	}
+
+	url = "mongodb://" + url
+
+	// Get a random DB name.
+	bs := make([]byte, 4)
+	if _, err := rand.Read(bs); err != nil {
+		t.Fatal(err)
+	}
+
+	db := "test-" + hex.EncodeToString(bs)
+
+	t.Log("using DB:", db)
+
+	return url, db
+}
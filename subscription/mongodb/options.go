@@ -0,0 +1,23 @@
+package mongodb
+
+import (
+	"fmt"
+
+	"github.com/Clarilab/eventhorizon/mongoutils"
+)
+
+// Option is an option setter used to configure creation.
+type Option func(*CheckpointStore) error
+
+// WithCollectionName uses a different collection than the default "checkpoints".
+func WithCollectionName(checkpointsColl string) Option {
+	return func(s *CheckpointStore) error {
+		if err := mongoutils.CheckCollectionName(checkpointsColl); err != nil {
+			return fmt.Errorf("checkpoints collection: %w", err)
+		}
+
+		s.collectionName = checkpointsColl
+
+		return nil
+	}
+}
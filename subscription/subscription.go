@@ -0,0 +1,369 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+)
+
+var (
+	// ErrMissingName is when a subscription is created without a name.
+	ErrMissingName = errors.New("missing subscription name")
+	// ErrMissingEventStore is when a subscription is created without an event store.
+	ErrMissingEventStore = errors.New("missing event store")
+	// ErrMissingCheckpointStore is when a subscription is created without a checkpoint store.
+	ErrMissingCheckpointStore = errors.New("missing checkpoint store")
+	// ErrMissingPosition is when a loaded event has no global position.
+	ErrMissingPosition = errors.New("missing global position")
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultPollInterval  = 5 * time.Second
+	defaultRetryInterval = time.Second
+)
+
+// Subscription is a catch-up subscription on the global event stream of an
+// event store. It first reads all historic events from the last checkpoint
+// of the subscriber and then switches to live tailing of new events, using
+// the eventhorizon.GlobalEventWatcher interface if supported by the store or
+// polling otherwise. The checkpoint is saved after each handled event, which
+// lets a restarted subscriber resume exactly where it stopped.
+//
+// Handling errors are sent on the error channel and the event is retried
+// after a delay, the subscription never skips events.
+type Subscription struct {
+	name          string
+	store         eh.GlobalEventStore
+	checkpoints   eh.CheckpointStore
+	handler       eh.EventHandler
+	matcher       eh.EventMatcher
+	batchSize     int
+	pollInterval  time.Duration
+	retryInterval time.Duration
+
+	position   int
+	positionMu sync.RWMutex
+	notifyCh   <-chan struct{}
+	errCh      chan error
+	cctx       context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+// NewSubscription creates a new subscription for an event handler, the name
+// is used as the subscriber for storing checkpoints.
+func NewSubscription(
+	name string,
+	store eh.GlobalEventStore,
+	checkpoints eh.CheckpointStore,
+	handler eh.EventHandler,
+	options ...Option,
+) (*Subscription, error) {
+	if name == "" {
+		return nil, ErrMissingName
+	}
+
+	if store == nil {
+		return nil, ErrMissingEventStore
+	}
+
+	if checkpoints == nil {
+		return nil, ErrMissingCheckpointStore
+	}
+
+	if handler == nil {
+		return nil, eh.ErrMissingHandler
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &Subscription{
+		name:          name,
+		store:         store,
+		checkpoints:   checkpoints,
+		handler:       handler,
+		batchSize:     defaultBatchSize,
+		pollInterval:  defaultPollInterval,
+		retryInterval: defaultRetryInterval,
+		errCh:         make(chan error, 100),
+		cctx:          ctx,
+		cancel:        cancel,
+	}
+
+	for _, option := range options {
+		if err := option(s); err != nil {
+			cancel()
+
+			return nil, fmt.Errorf("error while applying option: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*Subscription) error
+
+// WithEventMatcher only lets the handler handle events that match, the
+// checkpoint is still moved forward for the other events.
+func WithEventMatcher(m eh.EventMatcher) Option {
+	return func(s *Subscription) error {
+		if m == nil {
+			return eh.ErrMissingMatcher
+		}
+
+		s.matcher = m
+
+		return nil
+	}
+}
+
+// WithBatchSize sets the max number of events to load at a time, default 100.
+func WithBatchSize(size int) Option {
+	return func(s *Subscription) error {
+		if size <= 0 {
+			return fmt.Errorf("invalid batch size: %d", size)
+		}
+
+		s.batchSize = size
+
+		return nil
+	}
+}
+
+// WithPollInterval sets the interval for checking for new events when live
+// tailing. It is used as a fallback in case of missed notifications for event
+// stores that supports watching, default 5 seconds.
+func WithPollInterval(d time.Duration) Option {
+	return func(s *Subscription) error {
+		if d <= 0 {
+			return fmt.Errorf("invalid poll interval: %s", d)
+		}
+
+		s.pollInterval = d
+
+		return nil
+	}
+}
+
+// WithRetryInterval sets the delay before retrying after an error, default 1 second.
+func WithRetryInterval(d time.Duration) Option {
+	return func(s *Subscription) error {
+		if d <= 0 {
+			return fmt.Errorf("invalid retry interval: %s", d)
+		}
+
+		s.retryInterval = d
+
+		return nil
+	}
+}
+
+// Name returns the name of the subscription.
+func (s *Subscription) Name() string {
+	return s.name
+}
+
+// Position returns the global position of the last handled event.
+func (s *Subscription) Position() int {
+	s.positionMu.RLock()
+	defer s.positionMu.RUnlock()
+
+	return s.position
+}
+
+// Start starts the subscription in the background, first catching up from
+// the last checkpoint and then tailing new events until closed.
+func (s *Subscription) Start() {
+	s.wg.Add(1)
+
+	go s.run(s.cctx)
+}
+
+// Close stops the subscription and waits for the handling to finish.
+func (s *Subscription) Close() error {
+	s.cancel()
+	s.wg.Wait()
+
+	return nil
+}
+
+// Errors returns an error channel where async handling errors are sent.
+func (s *Subscription) Errors() <-chan error {
+	return s.errCh
+}
+
+func (s *Subscription) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	// Load the checkpoint, retrying until it works or the subscription is closed.
+	for {
+		position, err := s.checkpoints.LoadCheckpoint(ctx, s.name)
+		if err == nil {
+			s.setPosition(position)
+
+			break
+		}
+
+		s.sendError(&eh.SubscriptionError{
+			Err:        fmt.Errorf("could not load checkpoint: %w", err),
+			Subscriber: s.name,
+		})
+
+		if !s.wait(ctx, s.retryInterval, false) {
+			return
+		}
+	}
+
+	// Watch for new events if supported by the store.
+	if w, ok := s.store.(eh.GlobalEventWatcher); ok {
+		ch, err := w.WatchAll(ctx)
+		if err != nil {
+			s.sendError(&eh.SubscriptionError{
+				Err:        fmt.Errorf("could not watch events, polling instead: %w", err),
+				Subscriber: s.name,
+			})
+		} else {
+			s.notifyCh = ch
+		}
+	}
+
+	for {
+		caughtUp, err := s.handleBatch(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+
+			s.sendError(err)
+
+			if !s.wait(ctx, s.retryInterval, false) {
+				return
+			}
+
+			continue
+		}
+
+		if caughtUp && !s.wait(ctx, s.pollInterval, true) {
+			return
+		}
+	}
+}
+
+// handleBatch loads and handles the next batch of events. Returns true if
+// all events in the store has been handled.
+func (s *Subscription) handleBatch(ctx context.Context) (bool, error) {
+	from := s.Position() + 1
+
+	events, err := s.store.LoadAllFrom(ctx, from, s.batchSize)
+	if err != nil {
+		return false, &eh.SubscriptionError{
+			Err:        fmt.Errorf("could not load events: %w", err),
+			Subscriber: s.name,
+			Position:   from,
+		}
+	}
+
+	for _, event := range events {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		default:
+		}
+
+		position, ok := eh.GlobalPosition(event)
+		if !ok {
+			return false, &eh.SubscriptionError{
+				Err:        ErrMissingPosition,
+				Subscriber: s.name,
+				Event:      event,
+			}
+		}
+
+		if s.matcher == nil || s.matcher.Match(event) {
+			if err := s.handler.HandleEvent(ctx, event); err != nil {
+				return false, &eh.SubscriptionError{
+					Err:        fmt.Errorf("could not handle event (%s): %w", s.handler.HandlerType(), err),
+					Subscriber: s.name,
+					Position:   position,
+					Event:      event,
+				}
+			}
+		}
+
+		// Use a new context to always store the checkpoint of handled events.
+		if err := s.checkpoints.SaveCheckpoint(context.Background(), s.name, position); err != nil {
+			return false, &eh.SubscriptionError{
+				Err:        fmt.Errorf("could not save checkpoint: %w", err),
+				Subscriber: s.name,
+				Position:   position,
+				Event:      event,
+			}
+		}
+
+		s.setPosition(position)
+	}
+
+	return len(events) < s.batchSize, nil
+}
+
+// wait waits for the duration, a notification (if enabled) or until cancelled.
+// Returns false if cancelled.
+func (s *Subscription) wait(ctx context.Context, d time.Duration, notify bool) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	var notifyCh <-chan struct{}
+	if notify {
+		notifyCh = s.notifyCh
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case _, ok := <-notifyCh:
+		if !ok {
+			// Fall back to polling if the watch has ended.
+			s.notifyCh = nil
+		}
+
+		return true
+	case <-t.C:
+		return true
+	}
+}
+
+func (s *Subscription) setPosition(position int) {
+	s.positionMu.Lock()
+	defer s.positionMu.Unlock()
+
+	s.position = position
+}
+
+func (s *Subscription) sendError(err error) {
+	select {
+	case s.errCh <- err:
+	default:
+		log.Printf("eventhorizon: missed error in subscription '%s': %s", s.name, err)
+	}
+}
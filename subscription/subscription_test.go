@@ -0,0 +1,324 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subscription
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/eventstore/memory"
+	"github.com/Clarilab/eventhorizon/mocks"
+	checkpoints "github.com/Clarilab/eventhorizon/subscription/memory"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+func TestNewSubscription(t *testing.T) {
+	store, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	cs := checkpoints.NewCheckpointStore()
+	h := mocks.NewEventHandler("handler")
+
+	if _, err := NewSubscription("", store, cs, h); !errors.Is(err, ErrMissingName) {
+		t.Error("there should be a missing name error:", err)
+	}
+
+	if _, err := NewSubscription("sub", nil, cs, h); !errors.Is(err, ErrMissingEventStore) {
+		t.Error("there should be a missing event store error:", err)
+	}
+
+	if _, err := NewSubscription("sub", store, nil, h); !errors.Is(err, ErrMissingCheckpointStore) {
+		t.Error("there should be a missing checkpoint store error:", err)
+	}
+
+	if _, err := NewSubscription("sub", store, cs, nil); !errors.Is(err, eh.ErrMissingHandler) {
+		t.Error("there should be a missing handler error:", err)
+	}
+
+	if _, err := NewSubscription("sub", store, cs, h, WithBatchSize(0)); err == nil ||
+		err.Error() != "error while applying option: invalid batch size: 0" {
+		t.Error("there should be an option error:", err)
+	}
+
+	s, err := NewSubscription("sub", store, cs, h)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if s.Name() != "sub" {
+		t.Error("the name should be correct:", s.Name())
+	}
+}
+
+func TestSubscription(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	cs := checkpoints.NewCheckpointStore()
+	h := mocks.NewEventHandler("handler")
+
+	// Historic events, saved before the subscription starts.
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	historic := []eh.Event{
+		eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, 1)),
+		eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event2"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, 2)),
+		eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event3"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, 3)),
+	}
+
+	if err := store.Save(ctx, historic, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Use a small batch size and a long poll interval to test both batching
+	// and that live events are notified by the store.
+	s, err := NewSubscription("sub", store, cs, h,
+		WithBatchSize(2),
+		WithPollInterval(time.Hour),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	s.Start()
+
+	for i := 0; i < 3; i++ {
+		if !h.Wait(time.Second) {
+			t.Fatal("did not receive historic event in time")
+		}
+	}
+
+	// Live events, saved after catching up.
+	live := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event4"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 4))
+	if err := store.Save(ctx, []eh.Event{live}, 3); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !h.Wait(time.Second) {
+		t.Fatal("did not receive live event in time")
+	}
+
+	if err := s.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	h.RLock()
+	handled := h.Events
+	h.RUnlock()
+
+	expected := append(historic, live)
+	if len(handled) != len(expected) {
+		t.Fatal("there should be all events:", len(handled))
+	}
+
+	for i, event := range handled {
+		if err := eh.CompareEvents(event, expected[i], eh.IgnorePositionMetadata()); err != nil {
+			t.Error("the event was incorrect:", err)
+		}
+
+		if pos, ok := eh.GlobalPosition(event); !ok || pos != i+1 {
+			t.Error("the position should be correct:", pos)
+		}
+	}
+
+	if s.Position() != 4 {
+		t.Error("the position should be correct:", s.Position())
+	}
+
+	position, err := cs.LoadCheckpoint(ctx, "sub")
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if position != 4 {
+		t.Error("the checkpoint should be correct:", position)
+	}
+
+	// Save while stopped, a restarted subscription should only get new events.
+	stopped := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event5"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 5))
+	if err := store.Save(ctx, []eh.Event{stopped}, 4); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	h.Reset()
+
+	s, err = NewSubscription("sub", store, cs, h)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	s.Start()
+
+	if !h.Wait(time.Second) {
+		t.Fatal("did not receive event in time")
+	}
+
+	if h.Wait(100 * time.Millisecond) {
+		t.Error("there should be no more events")
+	}
+
+	if err := s.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	h.RLock()
+	handled = h.Events
+	h.RUnlock()
+
+	if len(handled) != 1 {
+		t.Fatal("there should be one event:", len(handled))
+	}
+
+	if err := eh.CompareEvents(handled[0], stopped, eh.IgnorePositionMetadata()); err != nil {
+		t.Error("the event was incorrect:", err)
+	}
+}
+
+func TestSubscriptionEventMatcher(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	cs := checkpoints.NewCheckpointStore()
+	h := mocks.NewEventHandler("handler")
+
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	events := []eh.Event{
+		eh.NewEvent(mocks.EventOtherType, nil, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, 1)),
+		eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event2"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, 2)),
+		eh.NewEvent(mocks.EventOtherType, nil, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, 3)),
+	}
+
+	if err := store.Save(ctx, events, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	s, err := NewSubscription("sub", store, cs, h,
+		WithEventMatcher(eh.MatchEvents{mocks.EventType}),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	s.Start()
+
+	if !h.Wait(time.Second) {
+		t.Fatal("did not receive event in time")
+	}
+
+	// The checkpoint is moved past events that are not matched.
+	deadline := time.Now().Add(time.Second)
+	for s.Position() != 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if s.Position() != 3 {
+		t.Error("the position should be correct:", s.Position())
+	}
+
+	h.RLock()
+	defer h.RUnlock()
+
+	if len(h.Events) != 1 || h.Events[0].EventType() != mocks.EventType {
+		t.Error("only the matching event should be handled:", h.Events)
+	}
+}
+
+func TestSubscriptionHandlerError(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	cs := checkpoints.NewCheckpointStore()
+	h := mocks.NewEventHandler("handler")
+	h.Err = errors.New("handler error")
+
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 1))
+
+	if err := store.Save(ctx, []eh.Event{event}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	s, err := NewSubscription("sub", store, cs, h,
+		WithRetryInterval(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	s.Start()
+
+	select {
+	case err := <-s.Errors():
+		subErr := &eh.SubscriptionError{}
+		if !errors.As(err, &subErr) || subErr.Position != 1 || subErr.Subscriber != "sub" {
+			t.Error("there should be a subscription error:", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("there should be an error")
+	}
+
+	if s.Position() != 0 {
+		t.Error("the position should not move on errors:", s.Position())
+	}
+
+	// The event is retried until it succeeds.
+	h.Lock()
+	h.Err = nil
+	h.Unlock()
+
+	if !h.Wait(time.Second) {
+		t.Fatal("did not receive retried event in time")
+	}
+
+	if err := s.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if s.Position() != 1 {
+		t.Error("the position should be correct:", s.Position())
+	}
+}
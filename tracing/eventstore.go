@@ -40,6 +40,14 @@ func NewEventStore(eventStore eh.EventStore) *EventStore {
 	}
 }
 
+// NewEventStoreMiddleware returns an event store middleware that adds tracing
+// spans, see eventhorizon.UseEventStoreMiddleware.
+func NewEventStoreMiddleware() eh.EventStoreMiddleware {
+	return eh.EventStoreMiddleware(func(s eh.EventStore) eh.EventStore {
+		return NewEventStore(s)
+	})
+}
+
 // Save implements the Save method of the eventhorizon.EventStore interface.
 func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersion int) error {
 	sp, ctx := opentracing.StartSpanFromContext(ctx, "EventStore.Save")
@@ -83,3 +91,8 @@ func (s *EventStore) Load(ctx context.Context, id uuid.UUID) ([]eh.Event, error)
 
 	return events, err
 }
+
+// InnerStore implements the InnerStore method of the eventhorizon.EventStoreChain interface.
+func (s *EventStore) InnerStore() eh.EventStore {
+	return s.EventStore
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"testing"
 
+	eh "github.com/Clarilab/eventhorizon"
 	"github.com/Clarilab/eventhorizon/eventstore"
 	"github.com/Clarilab/eventhorizon/eventstore/memory"
 )
@@ -40,3 +41,21 @@ func TestEventStore(t *testing.T) {
 
 	eventstore.AcceptanceTest(t, store, context.Background())
 }
+
+func TestEventStoreMiddleware(t *testing.T) {
+	innerStore, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	store := eh.UseEventStoreMiddleware(innerStore, NewEventStoreMiddleware())
+
+	eventstore.AcceptanceTest(t, store, context.Background())
+
+	snapshotStore, ok := eh.FindEventStore[eh.SnapshotStore](store)
+	if !ok {
+		t.Fatal("the store should be a snapshot store")
+	}
+
+	eventstore.SnapshotAcceptanceTest(t, snapshotStore, context.Background())
+}
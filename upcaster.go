@@ -0,0 +1,157 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrMissingUpcaster is when no upcaster is registered for a schema version
+// that is older than the current schema version of an event type.
+var ErrMissingUpcaster = errors.New("missing upcaster")
+
+// Upcaster transforms the raw stored data of an event from one schema version
+// to the next. The data is the stored payload decoded as JSON, with numbers as
+// json.Number (to not lose the precision of large integers) and nested objects
+// as map[string]interface{}. Data stored as BSON is decoded from relaxed
+// extended JSON, which keeps special BSON types as objects, for example
+// {"$date": ...}.
+type Upcaster func(ctx context.Context, data map[string]interface{}) (map[string]interface{}, error)
+
+// RegisterUpcaster registers an upcaster for an event type that transforms
+// event data stored with the fromVersion schema version to fromVersion+1. The
+// current schema version of an event type is one higher than the highest
+// registered fromVersion, or 1 if there are no upcasters. The current schema
+// version is recorded by the event stores when saving events.
+//
+// An example of changing the data of MyEventType once would be:
+//
+//	RegisterUpcaster(MyEventType, 1, func(ctx context.Context, data map[string]interface{}) (map[string]interface{}, error) {
+//		data["full_name"] = data["name"]
+//		delete(data, "name")
+//
+//		return data, nil
+//	})
+func RegisterUpcaster(eventType EventType, fromVersion int, upcaster Upcaster) {
+	if eventType == EventType("") {
+		panic("eventhorizon: attempt to register upcaster for empty event type")
+	}
+
+	if fromVersion < 1 {
+		panic(fmt.Sprintf("eventhorizon: attempt to register upcaster for %q with invalid version %d", eventType, fromVersion))
+	}
+
+	if upcaster == nil {
+		panic(fmt.Sprintf("eventhorizon: attempt to register nil upcaster for %q", eventType))
+	}
+
+	upcastersMu.Lock()
+	defer upcastersMu.Unlock()
+
+	if _, ok := upcasters[eventType][fromVersion]; ok {
+		panic(fmt.Sprintf("eventhorizon: registering duplicate upcaster for %q version %d", eventType, fromVersion))
+	}
+
+	if upcasters[eventType] == nil {
+		upcasters[eventType] = map[int]Upcaster{}
+	}
+
+	upcasters[eventType][fromVersion] = upcaster
+}
+
+// UnregisterUpcasters removes all upcasters registered for an event type.
+func UnregisterUpcasters(eventType EventType) {
+	if eventType == EventType("") {
+		panic("eventhorizon: attempt to unregister upcasters for empty event type")
+	}
+
+	upcastersMu.Lock()
+	defer upcastersMu.Unlock()
+
+	delete(upcasters, eventType)
+}
+
+// EventSchemaVersion returns the current schema version of the data of an
+// event type, which is 1 if there are no upcasters registered for it.
+func EventSchemaVersion(eventType EventType) int {
+	upcastersMu.RLock()
+	defer upcastersMu.RUnlock()
+
+	return currentSchemaVersion(eventType)
+}
+
+// NeedsUpcast returns true if event data stored with a schema version must be
+// upcasted to be in the current schema version. Version 0 is treated as 1 for
+// data stored before schema versions were recorded.
+func NeedsUpcast(eventType EventType, schemaVersion int) bool {
+	if schemaVersion < 1 {
+		schemaVersion = 1
+	}
+
+	return schemaVersion < EventSchemaVersion(eventType)
+}
+
+// UpcastEventData upcasts event data stored with a schema version to the
+// current schema version of the event type by running all upcasters in order.
+// Version 0 is treated as 1 for data stored before schema versions were recorded.
+func UpcastEventData(ctx context.Context, eventType EventType, schemaVersion int, data map[string]interface{}) (map[string]interface{}, error) {
+	if schemaVersion < 1 {
+		schemaVersion = 1
+	}
+
+	upcastersMu.RLock()
+	current := currentSchemaVersion(eventType)
+	chain := make([]Upcaster, 0, current-schemaVersion)
+
+	for v := schemaVersion; v < current; v++ {
+		u, ok := upcasters[eventType][v]
+		if !ok {
+			upcastersMu.RUnlock()
+
+			return nil, fmt.Errorf("%w: %s version %d", ErrMissingUpcaster, eventType, v)
+		}
+
+		chain = append(chain, u)
+	}
+	upcastersMu.RUnlock()
+
+	for i, u := range chain {
+		var err error
+		if data, err = u(ctx, data); err != nil {
+			return nil, fmt.Errorf("could not upcast %s from version %d: %w", eventType, schemaVersion+i, err)
+		}
+	}
+
+	return data, nil
+}
+
+// currentSchemaVersion must be called with the lock held.
+func currentSchemaVersion(eventType EventType) int {
+	version := 1
+
+	for v := range upcasters[eventType] {
+		if v+1 > version {
+			version = v + 1
+		}
+	}
+
+	return version
+}
+
+var upcasters = make(map[EventType]map[int]Upcaster)
+var upcastersMu sync.RWMutex
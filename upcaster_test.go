@@ -0,0 +1,128 @@
+// Copyright (c) 2014 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUpcastEventData(t *testing.T) {
+	const eventType EventType = "TestEventUpcast"
+
+	defer UnregisterUpcasters(eventType)
+
+	ctx := context.Background()
+
+	if v := EventSchemaVersion(eventType); v != 1 {
+		t.Error("the schema version should be 1:", v)
+	}
+
+	if NeedsUpcast(eventType, 0) || NeedsUpcast(eventType, 1) {
+		t.Error("there should be no need to upcast")
+	}
+
+	RegisterUpcaster(eventType, 1, func(ctx context.Context, data map[string]interface{}) (map[string]interface{}, error) {
+		data["full_name"] = data["name"]
+		delete(data, "name")
+
+		return data, nil
+	})
+	RegisterUpcaster(eventType, 2, func(ctx context.Context, data map[string]interface{}) (map[string]interface{}, error) {
+		data["age"] = 0.0
+
+		return data, nil
+	})
+
+	if v := EventSchemaVersion(eventType); v != 3 {
+		t.Error("the schema version should be 3:", v)
+	}
+
+	if !NeedsUpcast(eventType, 0) || !NeedsUpcast(eventType, 2) || NeedsUpcast(eventType, 3) {
+		t.Error("the need to upcast should be correct")
+	}
+
+	data, err := UpcastEventData(ctx, eventType, 0, map[string]interface{}{"name": "a"})
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(data, map[string]interface{}{"full_name": "a", "age": 0.0}) {
+		t.Error("the data should be upcasted:", data)
+	}
+
+	data, err = UpcastEventData(ctx, eventType, 2, map[string]interface{}{"full_name": "b"})
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(data, map[string]interface{}{"full_name": "b", "age": 0.0}) {
+		t.Error("only the last upcaster should be used:", data)
+	}
+
+	// Missing upcaster in the chain.
+	RegisterUpcaster(eventType, 4, func(ctx context.Context, data map[string]interface{}) (map[string]interface{}, error) {
+		return data, nil
+	})
+
+	if _, err := UpcastEventData(ctx, eventType, 1, map[string]interface{}{}); !errors.Is(err, ErrMissingUpcaster) {
+		t.Error("there should be a missing upcaster error:", err)
+	}
+
+	// Failing upcaster.
+	upcastErr := errors.New("upcast error")
+
+	UnregisterUpcasters(eventType)
+	RegisterUpcaster(eventType, 1, func(ctx context.Context, data map[string]interface{}) (map[string]interface{}, error) {
+		return nil, upcastErr
+	})
+
+	if _, err := UpcastEventData(ctx, eventType, 1, map[string]interface{}{}); !errors.Is(err, upcastErr) {
+		t.Error("there should be an upcast error:", err)
+	}
+}
+
+func TestRegisterUpcasterInvalid(t *testing.T) {
+	noop := func(ctx context.Context, data map[string]interface{}) (map[string]interface{}, error) {
+		return data, nil
+	}
+
+	expectPanic := func(msg string, f func()) {
+		defer func() {
+			if r := recover(); r == nil || r != msg {
+				t.Error("there should have been a panic:", r)
+			}
+		}()
+		f()
+	}
+
+	expectPanic("eventhorizon: attempt to register upcaster for empty event type", func() {
+		RegisterUpcaster("", 1, noop)
+	})
+	expectPanic("eventhorizon: attempt to register upcaster for \"TestEventUpcastInvalid\" with invalid version 0", func() {
+		RegisterUpcaster("TestEventUpcastInvalid", 0, noop)
+	})
+	expectPanic("eventhorizon: attempt to register nil upcaster for \"TestEventUpcastInvalid\"", func() {
+		RegisterUpcaster("TestEventUpcastInvalid", 1, nil)
+	})
+	expectPanic("eventhorizon: registering duplicate upcaster for \"TestEventUpcastInvalid\" version 1", func() {
+		defer UnregisterUpcasters("TestEventUpcastInvalid")
+
+		RegisterUpcaster("TestEventUpcastInvalid", 1, noop)
+		RegisterUpcaster("TestEventUpcastInvalid", 1, noop)
+	})
+}
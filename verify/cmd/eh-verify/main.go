@@ -0,0 +1,44 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command eh-verify checks an event store for inconsistencies, like version
+// gaps or stream documents that disagree with their events, and optionally
+// repairs the stream documents:
+//
+//	eh-verify -store mongodb_v2 -db app -repair
+//
+// Exits with a non-zero status if inconsistencies remain. Copy this command
+// and import the packages that register the event data of your application,
+// otherwise all events with data are reported as unregistered.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/Clarilab/eventhorizon/verify"
+)
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := verify.Run(ctx, os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+
+		os.Exit(1)
+	}
+}
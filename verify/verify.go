@@ -0,0 +1,119 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package verify checks event stores for inconsistencies, see the
+// eventhorizon.EventStoreChecker interface.
+package verify
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/eventstore/mongodb"
+	"github.com/Clarilab/eventhorizon/eventstore/mongodb_v2"
+)
+
+var (
+	// ErrUsage is when the command is run with invalid arguments.
+	ErrUsage = errors.New("invalid usage")
+	// ErrCheckNotSupported is when the event store can not be checked.
+	ErrCheckNotSupported = errors.New("event store does not support consistency checks")
+	// ErrInconsistent is when inconsistencies remain after a check.
+	ErrInconsistent = errors.New("event store is inconsistent")
+)
+
+// Check checks the event store for inconsistencies, optionally repairing the
+// stream records. Returns ErrInconsistent, together with the inconsistencies,
+// if any of them have not been repaired.
+func Check(ctx context.Context, store eh.EventStore, repair bool) ([]eh.Inconsistency, error) {
+	checker, ok := eh.FindEventStore[eh.EventStoreChecker](store)
+	if !ok {
+		return nil, ErrCheckNotSupported
+	}
+
+	inconsistencies, err := checker.CheckConsistency(ctx, repair)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, i := range inconsistencies {
+		if !i.Repaired {
+			return inconsistencies, ErrInconsistent
+		}
+	}
+
+	return inconsistencies, nil
+}
+
+// Run runs the check command with the arguments (without the program name),
+// writing the found inconsistencies to stdout:
+//
+//	-store mongodb_v2 -uri mongodb://localhost:27017 -db app -repair
+//
+// The data of events must be registered to not be reported as unregistered,
+// so the command should be built with the packages that register the event
+// data of the application, see cmd/eh-verify.
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("eh-verify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var (
+		storeType = fs.String("store", "", "event store type: mongodb or mongodb_v2")
+		uri       = fs.String("uri", "mongodb://localhost:27017", "MongoDB URI")
+		db        = fs.String("db", "", "MongoDB database name")
+		repair    = fs.Bool("repair", false, "repair stream documents that are behind their events")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		return ErrUsage
+	}
+
+	store, err := openStore(*storeType, *uri, *db)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	inconsistencies, err := Check(ctx, store, *repair)
+	for _, i := range inconsistencies {
+		fmt.Fprintln(stdout, i)
+	}
+
+	if err != nil && !errors.Is(err, ErrInconsistent) {
+		return err
+	}
+
+	fmt.Fprintf(stderr, "found %d inconsistencies\n", len(inconsistencies))
+
+	return err
+}
+
+func openStore(storeType, uri, db string) (eh.EventStore, error) {
+	if db == "" {
+		return nil, fmt.Errorf("%w: missing -db", ErrUsage)
+	}
+
+	switch storeType {
+	case "mongodb":
+		return mongodb.NewEventStore(uri, db)
+	case "mongodb_v2":
+		return mongodb_v2.NewEventStore(uri, db)
+	default:
+		return nil, fmt.Errorf("%w: unknown store %q", ErrUsage, storeType)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	eh "github.com/Clarilab/eventhorizon"
+	"github.com/Clarilab/eventhorizon/eventstore/memory"
+	"github.com/Clarilab/eventhorizon/mocks"
+	"github.com/Clarilab/eventhorizon/uuid"
+)
+
+func TestCheck(t *testing.T) {
+	store, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	if err := store.Save(ctx, []eh.Event{
+		eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, uuid.New(), 1)),
+	}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	inconsistencies, err := Check(ctx, store, false)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(inconsistencies) != 0 {
+		t.Error("there should be no inconsistencies:", inconsistencies)
+	}
+
+	// Events saved before their data was unregistered.
+	eh.RegisterEventData("UnregisteredEvent", func() eh.EventData { return &mocks.EventData{} })
+
+	if err := store.Save(ctx, []eh.Event{
+		eh.NewEvent("UnregisteredEvent", &mocks.EventData{Content: "event"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, uuid.New(), 1)),
+	}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eh.UnregisterEventData("UnregisteredEvent")
+
+	inconsistencies, err = Check(ctx, store, true)
+	if !errors.Is(err, ErrInconsistent) {
+		t.Error("the error should be correct:", err)
+	}
+
+	if len(inconsistencies) != 1 || inconsistencies[0].Kind != eh.InconsistencyUnregisteredEventData {
+		t.Error("the unregistered event data should be reported:", inconsistencies)
+	}
+
+	if _, err := Check(ctx, &mocks.EventStore{}, false); !errors.Is(err, ErrCheckNotSupported) {
+		t.Error("the error should be correct:", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+
+	for _, args := range [][]string{
+		{"-unknown"},
+		{"-store", "mongodb_v2"},
+		{"-store", "sql", "-db", "app"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if err := Run(ctx, args, &stdout, &stderr); !errors.Is(err, ErrUsage) {
+			t.Error("the error should be correct:", strings.Join(args, " "), err)
+		}
+	}
+}